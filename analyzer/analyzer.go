@@ -0,0 +1,85 @@
+// Package analyzer checks that Go code is in the subset supported by Goose,
+// without producing any Coq output.
+//
+// The Analyzer reports the same errors as the translator. It can be run with
+// `goose check`, with `go vet -vettool=$(which goose-check)`, or from gopls.
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/goose-lang/goose"
+)
+
+var Analyzer = &analysis.Analyzer{
+	Name: "goose",
+	Doc:  "check that code is in the subset of Go supported by Goose",
+	URL:  "https://github.com/goose-lang/goose",
+	Run:  run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	var files []goose.NamedFile
+	for _, f := range pass.Files {
+		name := pass.Fset.File(f.Pos()).Name()
+		// goose never translates tests
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		files = append(files, goose.NamedFile{Path: name, Ast: f})
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	// use the same FFIs and exclusions as translating the module
+	dir := filepath.Dir(files[0].Path)
+	var tr goose.Translator
+	if _, err := tr.LoadConfig(dir); err != nil {
+		return nil, err
+	}
+	modulePath, err := goose.ModulePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	ctx := tr.TypedCtx(pass.Pkg, modulePath, pass.Fset, pass.TypesInfo)
+	_, _, errs := ctx.Decls(files...)
+	fixer := newFixer(pass)
+	for _, err := range errs {
+		cerr, ok := err.(*goose.ConversionError)
+		if !ok {
+			return nil, err
+		}
+		pass.Report(analysis.Diagnostic{
+			Pos:            cerr.Pos,
+			End:            endPos(pass.Fset, cerr),
+			Category:       cerr.Category,
+			Message:        fmt.Sprintf("%s: %s", cerr.Category, cerr.Message),
+			SuggestedFixes: fixer.suggestedFixes(fileOf(files, cerr.Pos), cerr),
+		})
+	}
+	return nil, nil
+}
+
+// endPos gives the end of the code responsible for an error
+func endPos(fset *token.FileSet, err *goose.ConversionError) token.Pos {
+	f := fset.File(err.Pos)
+	if f == nil || !err.End.IsValid() {
+		return token.NoPos
+	}
+	return f.Pos(err.End.Offset)
+}
+
+func fileOf(files []goose.NamedFile, pos token.Pos) *ast.File {
+	for _, f := range files {
+		if f.Ast.FileStart <= pos && pos <= f.Ast.FileEnd {
+			return f.Ast
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package analyzer
+
+import (
+	"go/ast"
+	"os"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/goose-lang/goose"
+	"github.com/goose-lang/goose/fix"
+)
+
+// fixer finds suggested fixes for errors, from the rewrites in package fix
+type fixer struct {
+	pass  *analysis.Pass
+	fixes map[*ast.File][]fix.Fix
+}
+
+func newFixer(pass *analysis.Pass) *fixer {
+	return &fixer{pass: pass, fixes: make(map[*ast.File][]fix.Fix)}
+}
+
+func (fr *fixer) fileFixes(f *ast.File) []fix.Fix {
+	if fixes, ok := fr.fixes[f]; ok {
+		return fixes
+	}
+	readFile := fr.pass.ReadFile
+	if readFile == nil {
+		readFile = os.ReadFile
+	}
+	var fixes []fix.Fix
+	src, err := readFile(fr.pass.Fset.File(f.Pos()).Name())
+	if err == nil {
+		fixes = fix.Fixes(fr.pass.Fset, fr.pass.TypesInfo, f, src)
+	}
+	fr.fixes[f] = fixes
+	return fixes
+}
+
+// suggestedFixes gives the rewrite of the construct responsible for err, if
+// it has one
+func (fr *fixer) suggestedFixes(f *ast.File, err *goose.ConversionError) []analysis.SuggestedFix {
+	if f == nil {
+		return nil
+	}
+	for _, fx := range fr.fileFixes(f) {
+		if fx.Pos != err.Pos {
+			continue
+		}
+		var edits []analysis.TextEdit
+		for _, e := range fx.Edits {
+			edits = append(edits, analysis.TextEdit{
+				Pos:     e.Pos,
+				End:     e.End,
+				NewText: []byte(e.NewText),
+			})
+		}
+		return []analysis.SuggestedFix{{Message: fx.Message, TextEdits: edits}}
+	}
+	return nil
+}
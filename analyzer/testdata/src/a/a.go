@@ -0,0 +1,29 @@
+package a
+
+func ifInit(x uint64) uint64 {
+	if y := x + 1; y > 2 { // want "unsupported: if statement initializations"
+		return y
+	}
+	return 0
+}
+
+func ifInitShadows(x uint64) uint64 {
+	y := x
+	if y := x + 1; y > 2 { // want "unsupported: if statement initializations"
+		return y
+	}
+	return y
+}
+
+func namedResult(x uint64) (y uint64) { // want "unsupported: named returned value"
+	return x + 1
+}
+
+func bareReturn(x uint64) (y uint64) { // want "unsupported: named returned value"
+	y = x
+	return
+}
+
+func selectStmt() {
+	select {} // want "unsupported: statement \\*ast.SelectStmt"
+}
@@ -0,0 +1,15 @@
+// goose-check reports Go code that is outside the subset supported by Goose.
+//
+// It can be run directly on packages, or as a vet tool with
+// go vet -vettool=$(which goose-check).
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/goose-lang/goose/analyzer"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/token"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/goose-lang/goose"
+	"github.com/goose-lang/goose/glang"
+)
+
+// A translationCache stores translated files, keyed on a hash of everything
+// the translation depends on: the package's source files, the export data of
+// its imports, the set of transitive dependencies (which determines the FFI),
+// the Translator configuration, the backend and the goose binary itself.
+//
+// Source maps are cached alongside the translation, in a file with the
+// extension .v.map.
+type translationCache struct {
+	dir  string
+	tr   goose.Translator
+	salt []byte
+	// sourceMaps is whether lookups need a source map
+	sourceMaps bool
+	// export data hashes, by export file
+	exportHashes map[string][]byte
+
+	hits, misses int
+}
+
+// cachedPackage is a package matched by the patterns, along with its cache key
+type cachedPackage struct {
+	pkgPath string
+	name    string
+	key     string
+}
+
+func newTranslationCache(dir string, tr goose.Translator, printer glang.Printer, sourceMaps bool) *translationCache {
+	h := sha256.New()
+	fmt.Fprintf(h, "goose translation cache v1\n")
+	if exe, err := os.Executable(); err == nil {
+		if err := hashFile(h, exe); err != nil {
+			fmt.Fprintf(h, "%s\n", exe)
+		}
+	}
+	fmt.Fprintf(h, "%+v\n", tr)
+	fmt.Fprintf(h, "backend %s\n", printer.Name())
+	return &translationCache{
+		dir:          dir,
+		tr:           tr,
+		salt:         h.Sum(nil),
+		sourceMaps:   sourceMaps,
+		exportHashes: make(map[string][]byte),
+	}
+}
+
+func hashFile(h hash.Hash, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(h, f)
+	return err
+}
+
+func (c *translationCache) exportHash(exportFile string) []byte {
+	if h, ok := c.exportHashes[exportFile]; ok {
+		return h
+	}
+	h := sha256.New()
+	if err := hashFile(h, exportFile); err != nil {
+		fmt.Fprintf(h, "missing %s\n", exportFile)
+	}
+	c.exportHashes[exportFile] = h.Sum(nil)
+	return c.exportHashes[exportFile]
+}
+
+// key computes the cache key for a package
+func (c *translationCache) key(pkg *packages.Package) (string, error) {
+	h := sha256.New()
+	h.Write(c.salt)
+	fmt.Fprintf(h, "package %s %s\n", pkg.PkgPath, pkg.Name)
+
+	files := append([]string{}, pkg.CompiledGoFiles...)
+	sort.Strings(files)
+	for _, file := range files {
+		fh := sha256.New()
+		if err := hashFile(fh, file); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "file %s %x\n", filepath.Base(file), fh.Sum(nil))
+	}
+
+	var imports []string
+	for path := range pkg.Imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	for _, path := range imports {
+		imp := pkg.Imports[path]
+		fmt.Fprintf(h, "import %s %s", path, imp.PkgPath)
+		if imp.ExportFile != "" {
+			fmt.Fprintf(h, " %x", c.exportHash(imp.ExportFile))
+		}
+		fmt.Fprintln(h)
+	}
+
+	var deps []string
+	packages.Visit([]*packages.Package{pkg}, nil, func(dep *packages.Package) {
+		deps = append(deps, dep.PkgPath)
+	})
+	sort.Strings(deps)
+	for _, dep := range deps {
+		fmt.Fprintf(h, "dep %s\n", dep)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// packages lists the packages matching patterns, with their cache keys.
+//
+// This only loads export data for dependencies, which is much cheaper than
+// type-checking the packages for translation.
+func (c *translationCache) packages(modDir string, patterns []string) ([]cachedPackage, error) {
+	mode := packages.NeedName | packages.NeedCompiledGoFiles
+	mode |= packages.NeedImports | packages.NeedDeps | packages.NeedExportFile
+	pkgs, err := packages.Load(&packages.Config{
+		Dir:        modDir,
+		Mode:       mode,
+		BuildFlags: []string{"-tags", "goose"},
+		Fset:       token.NewFileSet(),
+	}, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	var cached []cachedPackage
+	for _, pkg := range pkgs {
+		if c.tr.Excluded(pkg.PkgPath) {
+			continue
+		}
+		if len(pkg.Errors) > 0 {
+			return nil, fmt.Errorf("could not load package %v", pkg.PkgPath)
+		}
+		key, err := c.key(pkg)
+		if err != nil {
+			return nil, err
+		}
+		cached = append(cached, cachedPackage{
+			pkgPath: pkg.PkgPath,
+			name:    pkg.Name,
+			key:     key,
+		})
+	}
+	return cached, nil
+}
+
+func (c *translationCache) file(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".v")
+}
+
+// lookup returns the cached translation for a key, if there is one
+func (c *translationCache) lookup(key string) (translation, bool) {
+	var out translation
+	data, err := os.ReadFile(c.file(key))
+	if err == nil && c.sourceMaps {
+		out.sourceMap, err = os.ReadFile(c.file(key) + ".map")
+	}
+	if err != nil {
+		c.misses++
+		return translation{}, false
+	}
+	c.hits++
+	out.code = data
+	return out, true
+}
+
+// store saves a translation (atomically, so concurrent runs of goose can share
+// a cache)
+func (c *translationCache) store(key string, out translation) error {
+	file := c.file(key)
+	if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+		return err
+	}
+	// the source map is written first so a translation is never found
+	// without the map that goes with it
+	if out.sourceMap != nil {
+		if err := writeAtomic(file+".map", out.sourceMap); err != nil {
+			return err
+		}
+	}
+	return writeAtomic(file, out.code)
+}
+
+// writeAtomic writes data to file by renaming a temporary file into place
+func writeAtomic(file string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(file), "tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/goose-lang/goose"
+)
+
+// depsJSON is the -deps=json output
+type depsJSON struct {
+	*goose.DepGraph
+	Cycles [][]goose.DepNode `json:"cycles"`
+}
+
+// writeDeps prints the dependency graph of the declarations in the packages
+// matching pkgPatterns, in format (dot or json), and warns about cycles on
+// stderr. It returns false if any package has errors.
+func writeDeps(w io.Writer, pkgPatterns []string, opts options, format string) bool {
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+	g, errs, patternErr := opts.tr.DepGraph(opts.modDir, pkgPatterns...)
+	if patternErr != nil {
+		fmt.Fprintln(os.Stderr, red(patternErr.Error()))
+		os.Exit(1)
+	}
+	ok := true
+	for _, err := range errs {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, red(err.Error()))
+			ok = false
+		}
+	}
+
+	cycles := g.Cycles()
+	for _, c := range cycles {
+		var names []string
+		for _, n := range c {
+			names = append(names, n.String())
+		}
+		fmt.Fprintln(os.Stderr, yellow("cycle: "+strings.Join(names, ", ")))
+	}
+
+	var err error
+	switch format {
+	case "dot":
+		err = g.WriteDot(w)
+	case "json":
+		if cycles == nil {
+			cycles = [][]goose.DepNode{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(depsJSON{DepGraph: g, Cycles: cycles})
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, red(err.Error()))
+		os.Exit(1)
+	}
+	return ok
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/goose-lang/goose"
+)
+
+// A diagnostic is a translation error in a form suitable for editors and
+// other tools.
+type diagnostic struct {
+	Category  string `json:"category"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	GoCode    string `json:"goCode,omitempty"`
+	File      string `json:"file,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	EndLine   int    `json:"endLine,omitempty"`
+	EndColumn int    `json:"endColumn,omitempty"`
+}
+
+// newDiagnostics converts errors returned by translation to diagnostics.
+//
+// Errors that are not conversion errors (for example, a package that fails to
+// load) have no position and the category "error".
+func newDiagnostics(errs []error) []diagnostic {
+	diags := []diagnostic{}
+	for _, err := range errs {
+		cerrs, others := goose.ConversionErrors(err)
+		for _, e := range cerrs {
+			diags = append(diags, diagnostic{
+				Category:  e.Category,
+				Code:      e.Code(),
+				Message:   e.Message,
+				GoCode:    e.GoCode,
+				File:      e.Start.Filename,
+				Line:      e.Start.Line,
+				Column:    e.Start.Column,
+				EndLine:   e.End.Line,
+				EndColumn: e.End.Column,
+			})
+		}
+		for _, e := range others {
+			diags = append(diags, diagnostic{
+				Category: "error",
+				Code:     goose.CategoryCode("error"),
+				Message:  e.Error(),
+			})
+		}
+	}
+	return diags
+}
+
+// loadCategory is the category of an error loading the packages that match
+// the command line patterns, which is not specific to any one package
+const loadCategory = "load"
+
+// loadCode is the code for loadCategory, which is outside the range of
+// goose.CategoryCode
+const loadCode = "GOOSE100"
+
+// loadDiagnostics reports an error loading the packages to translate
+func loadDiagnostics(err error) []diagnostic {
+	return []diagnostic{{
+		Category: loadCategory,
+		Code:     loadCode,
+		Message:  err.Error(),
+	}}
+}
+
+func writeJSON(w io.Writer, diags []diagnostic) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(diags)
+}
+
+// The subset of SARIF 2.1.0 needed to report goose errors, see
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// sarifURI gives a file's path relative to the working directory where
+// possible, since code review tools expect paths relative to the repository
+func sarifURI(file string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	rel, err := filepath.Rel(wd, file)
+	if err != nil || !filepath.IsLocal(rel) {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// categoryDescriptions describes each error category, including the "error"
+// category for errors that are not conversion errors
+var categoryDescriptions = map[string]string{
+	"unsupported":             "Go feature that goose intentionally does not support",
+	"future":                  "Go feature that goose could support but probably will not",
+	"todo":                    "Go feature that goose does not yet support",
+	"impossible(go)":          "situation believed to be impossible in Go (a goose bug)",
+	"impossible(no-examples)": "situation believed to be impossible in practice (a goose bug)",
+	"error":                   "package could not be loaded",
+	loadCategory:              "packages matching the command line could not be loaded",
+}
+
+func writeSARIF(w io.Writer, diags []diagnostic) error {
+	var rules []sarifRule
+	categories := []string{"error"}
+	categories = append(categories, goose.ErrorCategories...)
+	for _, c := range categories {
+		rules = append(rules, sarifRule{
+			ID:               goose.CategoryCode(c),
+			Name:             c,
+			ShortDescription: sarifMessage{Text: categoryDescriptions[c]},
+		})
+	}
+	rules = append(rules, sarifRule{
+		ID:               loadCode,
+		Name:             loadCategory,
+		ShortDescription: sarifMessage{Text: categoryDescriptions[loadCategory]},
+	})
+	results := []sarifResult{}
+	for _, d := range diags {
+		r := sarifResult{
+			RuleID:  d.Code,
+			Level:   "error",
+			Message: sarifMessage{Text: d.Message},
+		}
+		if d.File != "" {
+			r.Locations = []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: sarifURI(d.File)},
+					Region: sarifRegion{
+						StartLine:   d.Line,
+						StartColumn: d.Column,
+						EndLine:     d.EndLine,
+						EndColumn:   d.EndColumn,
+					},
+				},
+			}}
+		}
+		results = append(results, r)
+	}
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "goose",
+				InformationURI: "https://github.com/goose-lang/goose",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+// writeDiagnostics reports diags in a machine-readable format
+func writeDiagnostics(w io.Writer, format string, diags []diagnostic) error {
+	switch format {
+	case "json":
+		return writeJSON(w, diags)
+	case "sarif":
+		return writeSARIF(w, diags)
+	}
+	return fmt.Errorf("unknown diagnostics format %q", format)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+
+	"github.com/goose-lang/goose/fix"
+)
+
+// runFix implements goose fix, which rewrites unsupported idioms into the
+// Goose subset
+func runFix(args []string) {
+	red := color.New(color.FgRed).SprintFunc()
+	flags := flag.NewFlagSet("goose fix", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: goose fix [options] <packages>")
+		fmt.Fprintln(flags.Output(), "Prints a diff of the rewrites for unsupported idioms.")
+		flags.PrintDefaults()
+	}
+	var write bool
+	flags.BoolVar(&write, "w", false, "write the rewritten files instead of printing a diff")
+	var modDir string
+	flags.StringVar(&modDir, "dir", ".", "directory containing necessary go.mod")
+	flags.Parse(args)
+
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	changes, err := fix.Packages(modDir, patterns...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, red(err.Error()))
+		os.Exit(1)
+	}
+	for _, c := range changes {
+		if !write {
+			fmt.Print(c.Diff())
+			continue
+		}
+		info, err := os.Stat(c.Path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, red(err.Error()))
+			os.Exit(1)
+		}
+		if err := os.WriteFile(c.Path, c.New, info.Mode()); err != nil {
+			fmt.Fprintln(os.Stderr, red(err.Error()))
+			os.Exit(1)
+		}
+	}
+}
@@ -2,14 +2,18 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/fatih/color"
+	"golang.org/x/tools/go/analysis/singlechecker"
 
 	"github.com/goose-lang/goose"
+	"github.com/goose-lang/goose/analyzer"
 	"github.com/goose-lang/goose/glang"
 )
 
@@ -29,77 +33,309 @@ func writeFileIfChanged(name string, data []byte, perm os.FileMode) error {
 	return os.WriteFile(name, data, perm)
 }
 
-func coqFileContents(f glang.File) []byte {
+// translation is the output for a package
+type translation struct {
+	code []byte
+	// sourceMap is the JSON source map for code, if requested
+	sourceMap []byte
+}
+
+func fileContents(opts options, f glang.File) (translation, error) {
 	var b bytes.Buffer
-	f.Write(&b)
-	return b.Bytes()
+	if !opts.sourceMap {
+		if err := opts.printer.WriteFile(&b, f); err != nil {
+			return translation{}, fmt.Errorf("%s: %w", f.PkgPath, err)
+		}
+		return translation{code: b.Bytes()}, nil
+	}
+	m := f.WriteWithSourceMap(&b)
+	m.File = path.Base(outputFile(opts, f.PkgPath, f.GoPackage))
+	sourceMap, err := json.Marshal(m)
+	if err != nil {
+		panic(err)
+	}
+	return translation{code: b.Bytes(), sourceMap: append(sourceMap, '\n')}, nil
+}
+
+// options configures how goose translates and writes packages
+type options struct {
+	tr goose.Translator
+	// printer is the backend that renders translations
+	printer      glang.Printer
+	outRootDir   string
+	modDir       string
+	ignoreErrors bool
+	diagnostics  string
+	// sourceMap writes a source map next to each (Coq) output file
+	sourceMap bool
+	// cacheDir is the translation cache ("" to disable caching)
+	cacheDir string
+	stats    bool
+}
+
+// outputFile is the file the translation of a package is written to
+func outputFile(opts options, pkgPath string, pkgName string) string {
+	outFile := path.Join(opts.outRootDir, opts.tr.OutputPath(pkgPath, pkgName))
+	return strings.TrimSuffix(outFile, ".v") + opts.printer.Extension()
 }
 
-func translate(pkgPatterns []string, outRootDir string, modDir string,
-	ignoreErrors bool, tr goose.Translator) {
+// writeOutput writes the translation of a package
+func writeOutput(opts options, pkgPath string, pkgName string, out translation) {
 	red := color.New(color.FgRed).SprintFunc()
-	fs, errs, patternError := tr.TranslatePackages(modDir, pkgPatterns...)
-	if patternError != nil {
-		fmt.Fprintln(os.Stderr, red(patternError.Error()))
+	outFile := outputFile(opts, pkgPath, pkgName)
+	outDir := path.Dir(outFile)
+	err := os.MkdirAll(outDir, 0777)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		fmt.Fprintln(os.Stderr, red("could not create output directory"))
+	}
+	err = writeFileIfChanged(outFile, out.code, 0666)
+	if err == nil && out.sourceMap != nil {
+		err = writeFileIfChanged(outFile+".map", out.sourceMap, 0666)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		fmt.Fprintln(os.Stderr, red("could not write output"))
 		os.Exit(1)
 	}
+}
+
+// translate translates the packages matching pkgPatterns and writes their
+// output, returning false if any package has errors
+func translate(pkgPatterns []string, opts options) bool {
+	red := color.New(color.FgRed).SprintFunc()
+
+	// with a cache, only translate packages that missed in the cache
+	var cache *translationCache
+	keys := make(map[string]string)
+	if opts.cacheDir != "" {
+		cache = newTranslationCache(opts.cacheDir, opts.tr, opts.printer, opts.sourceMap)
+		pkgs, err := cache.packages(opts.modDir, pkgPatterns)
+		// on an error, translate everything so the translator reports it
+		if err == nil && len(pkgs) > 0 {
+			pkgPatterns = nil
+			for _, pkg := range pkgs {
+				if out, ok := cache.lookup(pkg.key); ok {
+					writeOutput(opts, pkg.pkgPath, pkg.name, out)
+					continue
+				}
+				keys[pkg.pkgPath] = pkg.key
+				pkgPatterns = append(pkgPatterns, pkg.pkgPath)
+			}
+		}
+		if opts.stats {
+			defer func() {
+				fmt.Fprintf(os.Stderr, "cache: %d hits, %d misses\n", cache.hits, cache.misses)
+			}()
+		}
+	}
+
+	var fs []glang.File
+	var errs []error
+	if cache == nil || len(pkgPatterns) > 0 {
+		var patternError error
+		fs, errs, patternError = opts.tr.TranslatePackages(opts.modDir, pkgPatterns...)
+		if patternError != nil {
+			if opts.diagnostics == "text" {
+				fmt.Fprintln(os.Stderr, red(patternError.Error()))
+			} else if err := writeDiagnostics(os.Stdout, opts.diagnostics,
+				loadDiagnostics(patternError)); err != nil {
+				fmt.Fprintln(os.Stderr, red(err.Error()))
+			}
+			os.Exit(1)
+		}
+	}
 
 	someError := false
 	for i, f := range fs {
 		err := errs[i]
 		if err != nil {
-			fmt.Fprintln(os.Stderr, red(err.Error()))
+			if opts.diagnostics == "text" {
+				fmt.Fprintln(os.Stderr, red(err.Error()))
+			}
 			someError = true
-			if !ignoreErrors {
+			if !opts.ignoreErrors {
 				continue
 			}
 		}
-		outFile := path.Join(outRootDir,
-			glang.ImportToPath(f.PkgPath, f.GoPackage))
-		outDir := path.Dir(outFile)
-		err = os.MkdirAll(outDir, 0777)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err.Error())
-			fmt.Fprintln(os.Stderr, red("could not create output directory"))
+		out, printErr := fileContents(opts, f)
+		if printErr != nil {
+			fmt.Fprintln(os.Stderr, red(printErr.Error()))
+			someError = true
+			continue
+		}
+		writeOutput(opts, f.PkgPath, f.GoPackage, out)
+		if key, ok := keys[f.PkgPath]; ok && err == nil {
+			if err := cache.store(key, out); err != nil {
+				fmt.Fprintln(os.Stderr, red("could not write to cache: "+err.Error()))
+			}
 		}
-		err = writeFileIfChanged(outFile, coqFileContents(f), 0666)
+	}
+	if opts.diagnostics != "text" {
+		var translateErrs []error
+		for _, err := range errs {
+			if err != nil {
+				translateErrs = append(translateErrs, err)
+			}
+		}
+		err := writeDiagnostics(os.Stdout, opts.diagnostics, newDiagnostics(translateErrs))
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err.Error())
-			fmt.Fprintln(os.Stderr, red("could not write output"))
+			fmt.Fprintln(os.Stderr, red(err.Error()))
 			os.Exit(1)
 		}
 	}
-	if someError {
-		os.Exit(1)
-	}
+	return !someError
 }
 
 // noinspection GoUnhandledErrorResult
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		// goose check [flags] <packages>: report unsupported code without
+		// translating
+		os.Args = append(os.Args[:1:1], os.Args[2:]...)
+		singlechecker.Main(analyzer.Analyzer)
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "fix" {
+		runFix(os.Args[2:])
+		return
+	}
+
 	flag.Usage = func() {
 		fmt.Fprintln(flag.CommandLine.Output(), "Usage: goose [options] <path to go package>")
+		fmt.Fprintln(flag.CommandLine.Output(), "       goose check [options] <packages>")
+		fmt.Fprintln(flag.CommandLine.Output(), "       goose fix [options] <packages>")
 
 		flag.PrintDefaults()
 	}
-	var tr goose.Translator
+	var opts options
 
-	flag.BoolVar(&tr.AddSourceFileComments, "source-comments", false,
+	flag.BoolVar(&opts.tr.AddSourceFileComments, "source-comments", false,
 		"add comments indicating Go source code location for each top-level declaration")
-	flag.BoolVar(&tr.TypeCheck, "typecheck", false, "add type-checking theorems")
+	flag.BoolVar(&opts.tr.TypeCheck, "typecheck", false, "add type-checking theorems")
+	flag.IntVar(&opts.tr.MaxErrors, "max-errors", 10,
+		"stop translating a package after this many errors (0 for no limit)")
+	flag.IntVar(&opts.tr.Width, "width", glang.DefaultWidth,
+		"line width for Coq output")
 
-	var outRootDir string
-	flag.StringVar(&outRootDir, "out", ".",
+	flag.StringVar(&opts.outRootDir, "out", ".",
 		"root directory for output (default is current directory)")
 
-	var modDir string
-	flag.StringVar(&modDir, "dir", ".",
+	flag.StringVar(&opts.modDir, "dir", ".",
 		"directory containing necessary go.mod")
 
-	var ignoreErrors bool
-	flag.BoolVar(&ignoreErrors, "ignore-errors", false,
+	flag.BoolVar(&opts.ignoreErrors, "ignore-errors", false,
 		"output partial translation even if there are errors")
 
+	flag.StringVar(&opts.diagnostics, "diagnostics", "text",
+		"format for reporting errors: text, json or sarif (json and sarif are printed to stdout)")
+
+	flag.StringVar(&opts.cacheDir, "cache", "",
+		"directory for caching translations (disabled by default)")
+	flag.BoolVar(&opts.stats, "stats", false, "report translation cache hits and misses")
+
+	var deps string
+	flag.StringVar(&deps, "deps", "",
+		"print the dependency graph of declarations (dot or json) instead of translating")
+
+	flag.BoolVar(&opts.sourceMap, "source-map", false,
+		"write a source map from the output to Go positions to <output file>.map (coq backend only)")
+
+	var backend string
+	flag.StringVar(&backend, "backend", glang.CoqPrinter.Name(),
+		fmt.Sprintf("proof assistant to translate to (%s)",
+			strings.Join(glang.BackendNames(), " or ")))
+
+	var emit string
+	flag.StringVar(&emit, "emit", "code",
+		"what to output for each package: code (in the -backend language) or json (the translation as JSON)")
+
+	var watchMode bool
+	flag.BoolVar(&watchMode, "watch", false,
+		"retranslate packages whenever their files change")
+
 	flag.Parse()
 
-	translate(flag.Args(), outRootDir, modDir, ignoreErrors, tr)
+	// flags given explicitly take precedence over goose.json
+	flagTr := opts.tr
+	conf, err := opts.tr.LoadConfig(opts.modDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, color.New(color.FgRed).Sprint(err.Error()))
+		os.Exit(1)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "source-comments":
+			opts.tr.AddSourceFileComments = flagTr.AddSourceFileComments
+		case "typecheck":
+			opts.tr.TypeCheck = flagTr.TypeCheck
+		case "max-errors":
+			opts.tr.MaxErrors = flagTr.MaxErrors
+		case "width":
+			opts.tr.Width = flagTr.Width
+		case "out":
+			conf.Out = ""
+		}
+	})
+	if conf.Out != "" {
+		opts.outRootDir = conf.Out
+	}
+	pkgPatterns := flag.Args()
+	if len(pkgPatterns) == 0 {
+		pkgPatterns = conf.Patterns
+	}
+
+	switch opts.diagnostics {
+	case "text", "json", "sarif":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown -diagnostics format %q\n", opts.diagnostics)
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts.printer, err = glang.Backend(backend)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		flag.Usage()
+		os.Exit(2)
+	}
+	switch emit {
+	case "code":
+	case "json":
+		opts.printer = glang.JSONPrinter
+	default:
+		fmt.Fprintf(os.Stderr, "unknown -emit format %q\n", emit)
+		flag.Usage()
+		os.Exit(2)
+	}
+	if opts.sourceMap && opts.printer != glang.CoqPrinter {
+		fmt.Fprintln(os.Stderr, "-source-map is only supported for Coq output")
+		flag.Usage()
+		os.Exit(2)
+	}
+	switch deps {
+	case "", "dot", "json":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown -deps format %q\n", deps)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if deps != "" {
+		if !writeDeps(os.Stdout, pkgPatterns, opts, deps) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if watchMode {
+		if err := watch(pkgPatterns, opts); err != nil {
+			fmt.Fprintln(os.Stderr, color.New(color.FgRed).Sprint(err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
+	if !translate(pkgPatterns, opts) {
+		os.Exit(1)
+	}
 }
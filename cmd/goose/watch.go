@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/goose-lang/goose"
+)
+
+// A watcher reports changes to Go files in a set of directories
+type watcher interface {
+	// set monitors exactly dirs, starting to monitor new directories and
+	// stopping for those no longer listed (such as deleted packages)
+	set(dirs []string) error
+	// wait blocks until some Go files change, and returns the directories
+	// containing them
+	wait() ([]string, error)
+	close() error
+}
+
+// watchedPackage is a package matched by the watch patterns
+type watchedPackage struct {
+	pkgPath string
+	dir     string
+	// deps are the transitive dependencies of the package
+	deps map[string]bool
+}
+
+// loadWatched lists the packages matching patterns, along with their
+// directories and dependencies
+func loadWatched(tr goose.Translator, modDir string, patterns []string) ([]watchedPackage, error) {
+	mode := packages.NeedName | packages.NeedFiles
+	mode |= packages.NeedImports | packages.NeedDeps
+	pkgs, err := packages.Load(&packages.Config{
+		Dir:        modDir,
+		Mode:       mode,
+		BuildFlags: []string{"-tags", "goose"},
+		Fset:       token.NewFileSet(),
+	}, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	var watched []watchedPackage
+	for _, pkg := range pkgs {
+		if tr.Excluded(pkg.PkgPath) {
+			continue
+		}
+		files := append(append([]string{}, pkg.GoFiles...), pkg.IgnoredFiles...)
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no Go files in package %v", pkg.PkgPath)
+		}
+		deps := make(map[string]bool)
+		packages.Visit([]*packages.Package{pkg}, nil, func(dep *packages.Package) {
+			if dep != pkg {
+				deps[dep.PkgPath] = true
+			}
+		})
+		watched = append(watched, watchedPackage{
+			pkgPath: pkg.PkgPath,
+			dir:     filepath.Dir(files[0]),
+			deps:    deps,
+		})
+	}
+	return watched, nil
+}
+
+// affectedPackages returns the packages in changed directories, along with
+// the packages that depend on them
+func affectedPackages(pkgs []watchedPackage, changedDirs []string) []string {
+	changed := make(map[string]bool)
+	for _, dir := range changedDirs {
+		changed[dir] = true
+	}
+	changedPkgs := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if changed[pkg.dir] {
+			changedPkgs[pkg.pkgPath] = true
+		}
+	}
+	var affected []string
+	for _, pkg := range pkgs {
+		if changedPkgs[pkg.pkgPath] {
+			affected = append(affected, pkg.pkgPath)
+			continue
+		}
+		for dep := range pkg.deps {
+			if changedPkgs[dep] {
+				affected = append(affected, pkg.pkgPath)
+				break
+			}
+		}
+	}
+	sort.Strings(affected)
+	return affected
+}
+
+// retranslate translates pkgPaths and writes their output, printing one line
+// per package followed by its errors
+func retranslate(pkgPaths []string, opts options) {
+	red := color.New(color.FgRed).SprintFunc()
+	green := color.New(color.FgGreen).SprintFunc()
+	fs, errs, patternErr := opts.tr.TranslatePackages(opts.modDir, pkgPaths...)
+	if patternErr != nil {
+		fmt.Fprintln(os.Stderr, red(patternErr.Error()))
+		return
+	}
+	for i, f := range fs {
+		err := errs[i]
+		if err == nil {
+			fmt.Printf("%s %s\n", green("ok  "), f.PkgPath)
+		} else {
+			fmt.Printf("%s %s\n", red("FAIL"), f.PkgPath)
+			for _, d := range newDiagnostics([]error{err}) {
+				if d.File == "" {
+					fmt.Printf("\t%s\n", firstLine(d.Message))
+					continue
+				}
+				fmt.Printf("\t%s:%d:%d: %s: %s\n",
+					relPath(d.File), d.Line, d.Column, d.Category, d.Message)
+			}
+			if !opts.ignoreErrors {
+				continue
+			}
+		}
+		out, err := fileContents(opts, f)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, red(err.Error()))
+			continue
+		}
+		writeOutput(opts, f.PkgPath, f.GoPackage, out)
+	}
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// relPath shortens file to be relative to the current directory, if it is
+// within it
+func relPath(file string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return file
+	}
+	rel, err := filepath.Rel(wd, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return file
+	}
+	return rel
+}
+
+// watch translates the packages matching pkgPatterns, then retranslates them
+// whenever their Go files change. It only returns if watching fails.
+//
+// Packages that depend on a changed package are also retranslated, since
+// their translation can depend on its types.
+func watch(pkgPatterns []string, opts options) error {
+	w, err := newWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.close()
+
+	pkgs, err := loadWatched(opts.tr, opts.modDir, pkgPatterns)
+	if err != nil {
+		return err
+	}
+	if len(pkgs) == 0 {
+		return fmt.Errorf("patterns matched no packages")
+	}
+	var all []string
+	for _, pkg := range pkgs {
+		all = append(all, pkg.pkgPath)
+	}
+	retranslate(all, opts)
+
+	red := color.New(color.FgRed).SprintFunc()
+	for {
+		var dirs []string
+		for _, pkg := range pkgs {
+			dirs = append(dirs, pkg.dir)
+		}
+		if err := w.set(dirs); err != nil {
+			return err
+		}
+		changedDirs, err := w.wait()
+		if err != nil {
+			return err
+		}
+		// reload, since files may have been added or removed or imports
+		// changed
+		newPkgs, err := loadWatched(opts.tr, opts.modDir, pkgPatterns)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, red(err.Error()))
+			continue
+		}
+		pkgs = newPkgs
+		if affected := affectedPackages(pkgs, changedDirs); len(affected) > 0 {
+			retranslate(affected, opts)
+		}
+	}
+}
+
+// isGoFile reports whether changes to the file named name should trigger
+// retranslation
+func isGoFile(name string) bool {
+	return strings.HasSuffix(name, ".go") && !strings.HasPrefix(name, ".")
+}
@@ -0,0 +1,133 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// inotifyMask selects the events that can change a directory's Go files,
+// including deleting or moving the directory itself
+const inotifyMask = unix.IN_CLOSE_WRITE | unix.IN_CREATE | unix.IN_DELETE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF
+
+// debounceMillis is how long an inotifyWatcher waits for further events after
+// a change, so that saving several files retranslates once
+const debounceMillis = 100
+
+// An inotifyWatcher watches directories with Linux's inotify
+type inotifyWatcher struct {
+	fd int
+	// dirs maps watch descriptors to directories
+	dirs map[int]string
+}
+
+// newWatcher uses inotify, falling back to polling if it is unavailable (for
+// example, when the limit on inotify instances is reached)
+func newWatcher() (watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return newPollWatcher(), nil
+	}
+	return &inotifyWatcher{fd: fd, dirs: make(map[int]string)}, nil
+}
+
+func (w *inotifyWatcher) set(dirs []string) error {
+	watched := make(map[string]bool)
+	for _, dir := range dirs {
+		watched[dir] = true
+		// adding a directory again returns the same descriptor
+		wd, err := unix.InotifyAddWatch(w.fd, dir, inotifyMask)
+		if err != nil {
+			return &os.PathError{Op: "watch", Path: dir, Err: err}
+		}
+		w.dirs[wd] = dir
+	}
+	for wd, dir := range w.dirs {
+		if !watched[dir] {
+			w.remove(wd)
+		}
+	}
+	return nil
+}
+
+// remove stops watching the directory with watch descriptor wd
+func (w *inotifyWatcher) remove(wd int) {
+	// this fails if the kernel already removed the watch, which is fine
+	_, _ = unix.InotifyRmWatch(w.fd, uint32(wd))
+	delete(w.dirs, wd)
+}
+
+// read reads a batch of events, adding the directories of changed Go files to
+// changed
+func (w *inotifyWatcher) read(changed map[string]bool) error {
+	var buf [64 * (unix.SizeofInotifyEvent + unix.NAME_MAX + 1)]byte
+	n, err := unix.Read(w.fd, buf[:])
+	if err != nil {
+		if errors.Is(err, unix.EINTR) {
+			return nil
+		}
+		return err
+	}
+	for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+		ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		nameStart := offset + unix.SizeofInotifyEvent
+		offset = nameStart + int(ev.Len)
+		if ev.Mask&unix.IN_IGNORED != 0 {
+			// the kernel removed the watch
+			delete(w.dirs, int(ev.Wd))
+			continue
+		}
+		if ev.Mask&(unix.IN_DELETE_SELF|unix.IN_MOVE_SELF) != 0 {
+			// the directory's package is gone (or moved), which the caller
+			// finds by reloading
+			if dir, ok := w.dirs[int(ev.Wd)]; ok {
+				changed[dir] = true
+			}
+			w.remove(int(ev.Wd))
+			continue
+		}
+		name := strings.TrimRight(string(buf[nameStart:offset]), "\x00")
+		if dir, ok := w.dirs[int(ev.Wd)]; ok && isGoFile(name) {
+			changed[dir] = true
+		}
+	}
+	return nil
+}
+
+func (w *inotifyWatcher) wait() ([]string, error) {
+	changed := make(map[string]bool)
+	for len(changed) == 0 {
+		if err := w.read(changed); err != nil {
+			return nil, err
+		}
+	}
+	// collect events until things are quiet
+	for {
+		fds := []unix.PollFd{{Fd: int32(w.fd), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, debounceMillis)
+		if err != nil && !errors.Is(err, unix.EINTR) {
+			return nil, err
+		}
+		if n <= 0 {
+			break
+		}
+		if err := w.read(changed); err != nil {
+			return nil, err
+		}
+	}
+	var dirs []string
+	for dir := range changed {
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
+
+func (w *inotifyWatcher) close() error {
+	return unix.Close(w.fd)
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package main
+
+func newWatcher() (watcher, error) {
+	return newPollWatcher(), nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// pollInterval is how often a pollWatcher checks for changes
+const pollInterval = 500 * time.Millisecond
+
+// fileState is what a pollWatcher compares to detect a change to a file
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// A pollWatcher detects changes by periodically listing its directories. It
+// is the fallback on platforms without inotify.
+type pollWatcher struct {
+	// snapshots has the state of every Go file, by directory
+	snapshots map[string]map[string]fileState
+}
+
+func newPollWatcher() *pollWatcher {
+	return &pollWatcher{snapshots: make(map[string]map[string]fileState)}
+}
+
+func snapshotDir(dir string) map[string]fileState {
+	files := make(map[string]fileState)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		// a missing directory is a change from an existing one, but does
+		// not stop watching
+		return files
+	}
+	for _, e := range entries {
+		if e.IsDir() || !isGoFile(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files[filepath.Join(dir, e.Name())] = fileState{
+			modTime: info.ModTime(),
+			size:    info.Size(),
+		}
+	}
+	return files
+}
+
+func sameSnapshot(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, s := range a {
+		if t, ok := b[name]; !ok || t != s {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *pollWatcher) set(dirs []string) error {
+	watched := make(map[string]bool)
+	for _, dir := range dirs {
+		watched[dir] = true
+		if _, ok := w.snapshots[dir]; !ok {
+			w.snapshots[dir] = snapshotDir(dir)
+		}
+	}
+	for dir := range w.snapshots {
+		if !watched[dir] {
+			delete(w.snapshots, dir)
+		}
+	}
+	return nil
+}
+
+func (w *pollWatcher) wait() ([]string, error) {
+	for {
+		time.Sleep(pollInterval)
+		var changed []string
+		for dir, old := range w.snapshots {
+			s := snapshotDir(dir)
+			if !sameSnapshot(old, s) {
+				w.snapshots[dir] = s
+				changed = append(changed, dir)
+			}
+		}
+		if len(changed) > 0 {
+			return changed, nil
+		}
+	}
+}
+
+func (w *pollWatcher) close() error {
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAffectedPackages(t *testing.T) {
+	// app imports store, which imports index; tool is independent
+	pkgs := []watchedPackage{
+		{pkgPath: "example.com/m/app", dir: "/m/app",
+			deps: map[string]bool{"example.com/m/store": true, "example.com/m/store/index": true}},
+		{pkgPath: "example.com/m/store", dir: "/m/store",
+			deps: map[string]bool{"example.com/m/store/index": true}},
+		{pkgPath: "example.com/m/store/index", dir: "/m/store/index",
+			deps: map[string]bool{}},
+		{pkgPath: "example.com/m/tool", dir: "/m/tool",
+			deps: map[string]bool{"fmt": true}},
+	}
+	tests := []struct {
+		name     string
+		changed  []string
+		expected []string
+	}{
+		{
+			name:     "nothing changed",
+			changed:  nil,
+			expected: nil,
+		},
+		{
+			name:     "no dependents",
+			changed:  []string{"/m/app"},
+			expected: []string{"example.com/m/app"},
+		},
+		{
+			name:    "transitive dependents",
+			changed: []string{"/m/store/index"},
+			expected: []string{"example.com/m/app", "example.com/m/store",
+				"example.com/m/store/index"},
+		},
+		{
+			name:     "several directories",
+			changed:  []string{"/m/tool", "/m/store"},
+			expected: []string{"example.com/m/app", "example.com/m/store", "example.com/m/tool"},
+		},
+		{
+			name:     "directory without a watched package",
+			changed:  []string{"/m/deleted"},
+			expected: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, affectedPackages(pkgs, tt.changed))
+		})
+	}
+}
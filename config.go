@@ -0,0 +1,179 @@
+package goose
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+
+	"github.com/goose-lang/goose/glang"
+)
+
+// ConfigFileName is the name of the per-module configuration file, which goose
+// reads from the module root.
+const ConfigFileName = "goose.json"
+
+// Path mapping modes, which determine where the translation of a package is
+// written relative to the output directory.
+const (
+	// PathMappingImport places the translation of a package at its full
+	// import path (for example, github_com/goose_lang/goose/machine.v).
+	PathMappingImport = "import"
+	// PathMappingModule places translations relative to the directory
+	// containing the module, so the output for module example.com/m goes in
+	// m/. The Coq imports are unchanged, so the output directory should be
+	// mapped to the logical path of the module's parent (for example,
+	// with -Q out New.code.example_com).
+	PathMappingModule = "module"
+)
+
+// ModuleConfig is the contents of a goose.json file.
+//
+// Settings correspond to command-line flags, which take precedence. Relative
+// paths are relative to the module root.
+type ModuleConfig struct {
+	// Patterns are the packages to translate when none are given
+	Patterns []string `json:"patterns,omitempty"`
+	// Out is the root directory for output
+	Out                   string `json:"out,omitempty"`
+	AddSourceFileComments bool   `json:"sourceComments,omitempty"`
+	TypeCheck             bool   `json:"typecheck,omitempty"`
+	MaxErrors             *int   `json:"maxErrors,omitempty"`
+	// Ffi maps package paths to the FFI that implements them, for FFIs
+	// beyond the built-in ones
+	Ffi map[string]Ffi `json:"ffi,omitempty"`
+	// Exclude lists packages that are never translated, as import paths
+	// optionally ending in /... to exclude a package and those under it
+	Exclude     []string `json:"exclude,omitempty"`
+	PathMapping string   `json:"pathMapping,omitempty"`
+	Width       int      `json:"width,omitempty"`
+}
+
+// findModuleRoot returns the closest directory containing a go.mod, starting
+// from dir
+func findModuleRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no go.mod found")
+		}
+		dir = parent
+	}
+}
+
+// ModulePath gives the path of the module containing dir
+func ModulePath(dir string) (string, error) {
+	root, err := findModuleRoot(dir)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	return modfile.ModulePath(data), nil
+}
+
+// ReadModuleConfig reads the goose.json for the module containing dir,
+// returning the module root along with the configuration. A module without a
+// goose.json has an empty configuration.
+func ReadModuleConfig(dir string) (root string, conf ModuleConfig, err error) {
+	root, err = findModuleRoot(dir)
+	if err != nil {
+		return "", conf, err
+	}
+	data, err := os.ReadFile(filepath.Join(root, ConfigFileName))
+	if os.IsNotExist(err) {
+		return root, conf, nil
+	}
+	if err != nil {
+		return "", conf, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&conf); err != nil {
+		return "", conf, fmt.Errorf("%s: %v",
+			filepath.Join(root, ConfigFileName), err)
+	}
+	switch conf.PathMapping {
+	case "", PathMappingImport, PathMappingModule:
+	default:
+		return "", conf, fmt.Errorf("%s: unknown pathMapping %q",
+			filepath.Join(root, ConfigFileName), conf.PathMapping)
+	}
+	return root, conf, nil
+}
+
+// LoadConfig configures tr from the goose.json for the module containing dir
+// (if there is one), and returns the configuration so callers can use the
+// settings that are not part of translation, like Patterns and Out.
+func (tr *Translator) LoadConfig(dir string) (ModuleConfig, error) {
+	root, conf, err := ReadModuleConfig(dir)
+	if err != nil {
+		return conf, err
+	}
+	tr.AddSourceFileComments = tr.AddSourceFileComments || conf.AddSourceFileComments
+	tr.TypeCheck = tr.TypeCheck || conf.TypeCheck
+	if conf.MaxErrors != nil {
+		tr.MaxErrors = *conf.MaxErrors
+	}
+	for pkgPath, ffi := range conf.Ffi {
+		tr.RegisterFfi(pkgPath, ffi)
+	}
+	tr.Exclude = append(tr.Exclude, conf.Exclude...)
+	if conf.PathMapping != "" {
+		tr.PathMapping = conf.PathMapping
+	}
+	if conf.Width != 0 {
+		tr.Width = conf.Width
+	}
+	if tr.PathMapping == PathMappingModule {
+		modulePath, err := ModulePath(root)
+		if err != nil {
+			return conf, err
+		}
+		tr.modulePath = modulePath
+	}
+	if conf.Out != "" && !filepath.IsAbs(conf.Out) {
+		conf.Out = filepath.Join(root, conf.Out)
+	}
+	return conf, nil
+}
+
+// Excluded reports whether pkgPath is excluded from translation
+func (tr Translator) Excluded(pkgPath string) bool {
+	for _, pattern := range tr.Exclude {
+		if prefix, ok := strings.CutSuffix(pattern, "/..."); ok {
+			if pkgPath == prefix || strings.HasPrefix(pkgPath, prefix+"/") {
+				return true
+			}
+		} else if pkgPath == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// OutputPath gives the file (relative to the output directory) for the
+// translation of a package, according to the path mapping mode.
+func (tr Translator) OutputPath(pkgPath, pkgName string) string {
+	if tr.PathMapping == PathMappingModule && tr.modulePath != "" {
+		parent := path.Dir(tr.modulePath)
+		if rel, ok := strings.CutPrefix(pkgPath, parent+"/"); ok && parent != "." {
+			return glang.ImportToPath(rel, pkgName)
+		}
+	}
+	return glang.ImportToPath(pkgPath, pkgName)
+}
@@ -0,0 +1,90 @@
+package goose_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goose-lang/goose"
+)
+
+func writeModule(t *testing.T, config string) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"),
+		[]byte("module example.com/m\n\ngo 1.22\n"), 0666))
+	if config != "" {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, goose.ConfigFileName),
+			[]byte(config), 0666))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0777))
+	return dir
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := writeModule(t, `{
+  "patterns": ["./..."],
+  "out": "Goose",
+  "sourceComments": true,
+  "maxErrors": 3,
+  "ffi": {
+    "example.com/m/ffi": "myffi",
+    "example.com/m/log": {
+      "prelude": "log",
+      "functions": {"Append": "log.append"},
+      "compatible": ["myffi"]
+    }
+  },
+  "exclude": ["example.com/m/internal/..."],
+  "pathMapping": "module",
+  "width": 80
+}`)
+	var tr goose.Translator
+	// the configuration is found from a subdirectory of the module
+	conf, err := tr.LoadConfig(filepath.Join(dir, "sub"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"./..."}, conf.Patterns)
+	assert.Equal(t, filepath.Join(dir, "Goose"), conf.Out)
+	assert.True(t, tr.AddSourceFileComments)
+	assert.Equal(t, 3, tr.MaxErrors)
+	assert.Equal(t, 80, tr.Width)
+	assert.Equal(t, map[string]goose.Ffi{
+		"example.com/m/ffi": {Prelude: "myffi"},
+		"example.com/m/log": {
+			Prelude:    "log",
+			Functions:  map[string]string{"Append": "log.append"},
+			Compatible: []string{"myffi"},
+		},
+	}, tr.Ffi)
+
+	assert.True(t, tr.Excluded("example.com/m/internal"))
+	assert.True(t, tr.Excluded("example.com/m/internal/a"))
+	assert.False(t, tr.Excluded("example.com/m/internals"))
+	assert.False(t, tr.Excluded("example.com/m"))
+
+	assert.Equal(t, filepath.Join("m", "a.v"),
+		tr.OutputPath("example.com/m/a", "a"))
+	assert.Equal(t, filepath.Join("other_com", "b.v"),
+		tr.OutputPath("other.com/b", "b"))
+}
+
+func TestLoadConfigMissing(t *testing.T) {
+	dir := writeModule(t, "")
+	tr := goose.Translator{MaxErrors: 10}
+	conf, err := tr.LoadConfig(dir)
+	require.NoError(t, err)
+	assert.Equal(t, goose.ModuleConfig{}, conf)
+	assert.Equal(t, goose.Translator{MaxErrors: 10}, tr)
+	assert.Equal(t, filepath.Join("example_com", "m", "a.v"),
+		tr.OutputPath("example.com/m/a", "a"))
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	var tr goose.Translator
+	_, err := tr.LoadConfig(writeModule(t, `{"output": "Goose"}`))
+	assert.ErrorContains(t, err, "unknown field")
+	_, err = tr.LoadConfig(writeModule(t, `{"pathMapping": "flat"}`))
+	assert.ErrorContains(t, err, "unknown pathMapping")
+}
@@ -0,0 +1,262 @@
+package goose
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/goose-lang/goose/glang"
+	"golang.org/x/tools/go/packages"
+)
+
+// A DepNode is a translated declaration, identified by its package and Coq
+// name (methods are named Type__Method).
+type DepNode struct {
+	Package string `json:"package"`
+	Name    string `json:"name"`
+}
+
+func (n DepNode) String() string {
+	return n.Package + "." + n.Name
+}
+
+// A DepEdge records that the translation of From refers to To.
+type DepEdge struct {
+	From DepNode `json:"from"`
+	To   DepNode `json:"to"`
+}
+
+// DepGraph is the dependency graph of translated declarations. Nodes are the
+// declarations of the translated packages; edges can also point to
+// declarations in other packages.
+type DepGraph struct {
+	Nodes []DepNode `json:"nodes"`
+	Edges []DepEdge `json:"edges"`
+}
+
+// add merges another graph into g
+func (g *DepGraph) add(other DepGraph) {
+	g.Nodes = append(g.Nodes, other.Nodes...)
+	g.Edges = append(g.Edges, other.Edges...)
+}
+
+// recordDeps adds the dependencies gathered while translating a package to
+// ctx.depGraph.
+//
+// Dependencies on other packages are recorded by Coq name as pkg.Name, where
+// pkg is the package's glang.PackageQualifier; these are resolved using the
+// imports of fs.
+func (ctx Ctx) recordDeps(fs []NamedFile, names [][]string, deps [][]string) {
+	importPaths := make(map[string]string)
+	for _, f := range fs {
+		for _, spec := range f.Ast.Imports {
+			if pkgName := ctx.info.PkgNameOf(spec); pkgName != nil {
+				path := pkgName.Imported().Path()
+				importPaths[glang.PackageQualifier(path)] = path
+			}
+		}
+	}
+	local := make(map[string]bool)
+	for _, ns := range names {
+		for _, n := range ns {
+			local[n] = true
+		}
+	}
+
+	var g DepGraph
+	seen := make(map[DepEdge]bool)
+	for i, ns := range names {
+		for _, n := range ns {
+			from := DepNode{Package: ctx.pkgPath, Name: n}
+			g.Nodes = append(g.Nodes, from)
+			for _, dep := range deps[i] {
+				to := DepNode{Package: ctx.pkgPath, Name: dep}
+				if !local[dep] {
+					pkgName, name, ok := strings.Cut(dep, ".")
+					if !ok || importPaths[pkgName] == "" {
+						// a dependency on something not translated, such as a
+						// local variable or a built-in
+						continue
+					}
+					to = DepNode{Package: importPaths[pkgName], Name: name}
+				}
+				e := DepEdge{From: from, To: to}
+				if !seen[e] {
+					seen[e] = true
+					g.Edges = append(g.Edges, e)
+				}
+			}
+		}
+	}
+	ctx.depGraph.add(g)
+}
+
+// Cycles finds the cycles among distinct declarations in g (that is, strongly
+// connected components with more than one node). Recursive functions that only
+// call themselves are not cycles.
+//
+// Each cycle is sorted by name, and cycles are sorted by their first node.
+func (g DepGraph) Cycles() [][]DepNode {
+	succs := make(map[DepNode][]DepNode)
+	for _, e := range g.Edges {
+		succs[e.From] = append(succs[e.From], e.To)
+	}
+
+	var cycles [][]DepNode
+	for _, scc := range stronglyConnected(g.Nodes, func(n DepNode) []DepNode {
+		return succs[n]
+	}) {
+		if len(scc) > 1 {
+			sortNodes(scc)
+			cycles = append(cycles, scc)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0].String() < cycles[j][0].String()
+	})
+	return cycles
+}
+
+// stronglyConnected computes the strongly connected components of the graph
+// reachable from nodes, using Tarjan's algorithm. Components are returned in
+// reverse topological order (a component comes after those it reaches).
+func stronglyConnected[N comparable](nodes []N, succs func(N) []N) [][]N {
+	index := make(map[N]int)
+	lowlink := make(map[N]int)
+	onStack := make(map[N]bool)
+	var stack []N
+	var sccs [][]N
+	var visit func(n N)
+	visit = func(n N) {
+		index[n] = len(index)
+		lowlink[n] = index[n]
+		stack = append(stack, n)
+		onStack[n] = true
+		for _, m := range succs(n) {
+			if _, ok := index[m]; !ok {
+				visit(m)
+				lowlink[n] = min(lowlink[n], lowlink[m])
+			} else if onStack[m] {
+				lowlink[n] = min(lowlink[n], index[m])
+			}
+		}
+		if lowlink[n] != index[n] {
+			return
+		}
+		var scc []N
+		for {
+			m := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[m] = false
+			scc = append(scc, m)
+			if m == n {
+				break
+			}
+		}
+		sccs = append(sccs, scc)
+	}
+	for _, n := range nodes {
+		if _, ok := index[n]; !ok {
+			visit(n)
+		}
+	}
+	return sccs
+}
+
+func sortNodes(nodes []DepNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].String() < nodes[j].String()
+	})
+}
+
+// WriteDot writes g in the Graphviz dot format, with declarations grouped by
+// package and edges within cycles in red.
+func (g DepGraph) WriteDot(w io.Writer) error {
+	inCycle := make(map[DepNode]int)
+	for i, c := range g.Cycles() {
+		for _, n := range c {
+			inCycle[n] = i + 1
+		}
+	}
+	var pkgs []string
+	byPkg := make(map[string][]DepNode)
+	addNode := func(n DepNode) {
+		for _, m := range byPkg[n.Package] {
+			if m == n {
+				return
+			}
+		}
+		if byPkg[n.Package] == nil {
+			pkgs = append(pkgs, n.Package)
+		}
+		byPkg[n.Package] = append(byPkg[n.Package], n)
+	}
+	for _, n := range g.Nodes {
+		addNode(n)
+	}
+	for _, e := range g.Edges {
+		addNode(e.To)
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph goose {\n")
+	for i, pkg := range pkgs {
+		fmt.Fprintf(&b, "\tsubgraph cluster_%d {\n", i)
+		fmt.Fprintf(&b, "\t\tlabel=%q;\n", pkg)
+		for _, n := range byPkg[pkg] {
+			fmt.Fprintf(&b, "\t\t%q [label=%q];\n", n.String(), n.Name)
+		}
+		b.WriteString("\t}\n")
+	}
+	for _, e := range g.Edges {
+		attrs := ""
+		if c := inCycle[e.From]; c != 0 && inCycle[e.To] == c {
+			attrs = " [color=red]"
+		}
+		fmt.Fprintf(&b, "\t%q -> %q%s;\n", e.From.String(), e.To.String(), attrs)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// DepGraph loads packages by a list of patterns and translates them, returning
+// the dependency graph of their declarations.
+//
+// As with TranslatePackages, errs has the translation errors for each package;
+// the graph includes the declarations that translated successfully.
+func (tr Translator) DepGraph(modDir string,
+	pkgPattern ...string) (g *DepGraph, errs []error, patternErr error) {
+	loaded, err := packages.Load(newPackageConfig(modDir), pkgPattern...)
+	if err != nil {
+		return nil, nil, err
+	}
+	var pkgs []*packages.Package
+	for _, pkg := range loaded {
+		if !tr.Excluded(pkg.PkgPath) {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	if len(pkgs) == 0 {
+		return nil, nil, fmt.Errorf("patterns matched no packages")
+	}
+	tr.translated = translatedPkgs(pkgs)
+	graphs := make([]DepGraph, len(pkgs))
+	errs = make([]error, len(pkgs))
+	var wg sync.WaitGroup
+	wg.Add(len(pkgs))
+	for i, pkg := range pkgs {
+		go func(i int, pkg *packages.Package) {
+			defer wg.Done()
+			_, errs[i] = tr.translatePackageGraph(pkg, &graphs[i])
+		}(i, pkg)
+	}
+	wg.Wait()
+	g = &DepGraph{}
+	for _, pg := range graphs {
+		g.add(pg)
+	}
+	return g, errs, nil
+}
@@ -0,0 +1,89 @@
+package goose_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goose-lang/goose"
+)
+
+func TestDepGraph(t *testing.T) {
+	dir := writeModule(t, "")
+	files := map[string]string{
+		"lib/lib.go": `package lib
+
+type Counter struct {
+	N uint64
+}
+
+func Incr(c *Counter) {
+	c.N += 1
+}
+`,
+		"app/app.go": `package app
+
+import "example.com/m/lib"
+
+const start uint64 = 1
+
+func isEven(n uint64) bool {
+	if n == 0 {
+		return true
+	}
+	return isOdd(n - 1)
+}
+
+func isOdd(n uint64) bool {
+	if n == 0 {
+		return false
+	}
+	return isEven(n - 1)
+}
+
+func run(c *lib.Counter) bool {
+	lib.Incr(c)
+	return isEven(start)
+}
+`,
+	}
+	for name, contents := range files {
+		file := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0777))
+		require.NoError(t, os.WriteFile(file, []byte(contents), 0666))
+	}
+
+	var tr goose.Translator
+	g, errs, err := tr.DepGraph(dir, "./app")
+	require.NoError(t, err)
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	node := func(pkg, name string) goose.DepNode {
+		return goose.DepNode{Package: "example.com/m/" + pkg, Name: name}
+	}
+	assert.Equal(t, []goose.DepNode{
+		node("app", "start"),
+		node("app", "isEven"),
+		node("app", "isOdd"),
+		node("app", "run"),
+	}, g.Nodes)
+	assert.Contains(t, g.Edges, goose.DepEdge{From: node("app", "isEven"), To: node("app", "isOdd")})
+	assert.Contains(t, g.Edges, goose.DepEdge{From: node("app", "run"), To: node("app", "start")})
+	assert.Contains(t, g.Edges, goose.DepEdge{From: node("app", "run"), To: node("lib", "Incr")})
+
+	assert.Equal(t, [][]goose.DepNode{
+		{node("app", "isEven"), node("app", "isOdd")},
+	}, g.Cycles())
+
+	var b bytes.Buffer
+	require.NoError(t, g.WriteDot(&b))
+	assert.Contains(t, b.String(),
+		`"example.com/m/app.isEven" -> "example.com/m/app.isOdd" [color=red];`)
+	assert.Contains(t, b.String(), `label="example.com/m/lib";`)
+}
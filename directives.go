@@ -0,0 +1,156 @@
+package goose
+
+import (
+	"go/ast"
+	"strings"
+
+	"github.com/goose-lang/goose/glang"
+)
+
+// Directives are //goose: comments in the documentation of a declaration that
+// change how it is translated:
+//
+//	//goose:skip         omit the declaration
+//	//goose:opaque       declare the function as an axiom, without a body
+//	//goose:extern NAME  define the function to be the Coq definition NAME
+const (
+	skipDirective   = "skip"
+	opaqueDirective = "opaque"
+	externDirective = "extern"
+)
+
+const directivePrefix = "//goose:"
+
+// directive is a parsed //goose: comment
+type directive struct {
+	kind string
+	// arg is the Coq name for an extern directive
+	arg     string
+	comment *ast.Comment
+}
+
+// declDoc is the documentation of a top-level declaration
+func declDoc(d ast.Decl) *ast.CommentGroup {
+	switch d := d.(type) {
+	case *ast.FuncDecl:
+		return d.Doc
+	case *ast.GenDecl:
+		return d.Doc
+	}
+	return nil
+}
+
+// parseDirective parses the //goose: comment c, if it is one
+func parseDirective(c *ast.Comment) (d directive, fields []string, ok bool) {
+	text, ok := strings.CutPrefix(c.Text, directivePrefix)
+	if !ok {
+		return directive{}, nil, false
+	}
+	fields = strings.Fields(text)
+	d = directive{comment: c}
+	if len(fields) > 0 {
+		d.kind = fields[0]
+	}
+	if len(fields) > 1 {
+		d.arg = fields[1]
+	}
+	return d, fields, true
+}
+
+// declDirective finds the directive in a declaration's documentation, if
+// there is one
+//
+// The directive is not checked, so this never reports an error: directives are
+// checked once, by checkDirectives, when the declaration is translated.
+func declDirective(doc *ast.CommentGroup) (directive, bool) {
+	if doc == nil {
+		return directive{}, false
+	}
+	for _, c := range doc.List {
+		if d, _, ok := parseDirective(c); ok {
+			return d, true
+		}
+	}
+	return directive{}, false
+}
+
+// isSkipped reports whether a declaration is marked //goose:skip
+func isSkipped(d ast.Decl) bool {
+	dir, ok := declDirective(declDoc(d))
+	return ok && dir.kind == skipDirective
+}
+
+// checkDirectives reports malformed directives in a declaration's
+// documentation, and directives other than //goose:skip on declarations that
+// are not functions
+func (ctx Ctx) checkDirectives(d ast.Decl) {
+	doc := declDoc(d)
+	if doc == nil {
+		return
+	}
+	var found []directive
+	for _, c := range doc.List {
+		dir, fields, ok := parseDirective(c)
+		if !ok {
+			continue
+		}
+		switch dir.kind {
+		case "":
+			ctx.unsupported(c, "empty goose directive")
+		case skipDirective, opaqueDirective:
+			if len(fields) != 1 {
+				ctx.unsupported(c, "//goose:%s takes no arguments", dir.kind)
+			}
+		case externDirective:
+			if len(fields) != 2 {
+				ctx.unsupported(c, "//goose:extern needs exactly one Coq name")
+			}
+		default:
+			ctx.unsupported(c, "unknown goose directive %s", dir.kind)
+		}
+		found = append(found, dir)
+	}
+	if len(found) > 1 {
+		ctx.unsupported(found[1].comment, "multiple goose directives on one declaration")
+	}
+	if len(found) == 0 || found[0].kind == skipDirective {
+		return
+	}
+	if d, ok := d.(*ast.GenDecl); ok {
+		ctx.unsupported(d, "//goose:%s on %s declaration (only functions can be %s)",
+			found[0].kind, d.Tok, found[0].kind)
+	}
+}
+
+// funcSignature prints a function's Go signature, without its body
+func (ctx Ctx) funcSignature(d *ast.FuncDecl) string {
+	return ctx.printGo(&ast.FuncDecl{
+		Recv: d.Recv,
+		Name: d.Name,
+		Type: d.Type,
+	})
+}
+
+// directiveFuncDecl translates a function marked opaque or extern, whose name
+// and type parameters are already in fd
+func (ctx Ctx) directiveFuncDecl(d *ast.FuncDecl, dir directive, fd glang.FuncDecl) glang.Decl {
+	comment := fd.Comment
+	if comment != "" {
+		comment += "\n\n"
+	}
+	comment += dir.kind + ": " + ctx.funcSignature(d)
+	if dir.kind == externDirective {
+		return glang.ExternDecl{
+			Name:       fd.Name,
+			TypeParams: fd.TypeParams,
+			Extern:     dir.arg,
+			Comment:    comment,
+		}
+	}
+	return glang.OpaqueDecl{
+		Name:            fd.Name,
+		TypeParams:      fd.TypeParams,
+		Comment:         comment,
+		SectionVariable: len(ctx.Config.Ffis) == 0,
+	}
+}
@@ -7,7 +7,12 @@ import (
 	"go/printer"
 	"go/token"
 	"runtime"
+	"sort"
 	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/goose-lang/goose/glang"
 )
 
 type locatable interface {
@@ -18,10 +23,66 @@ type locatable interface {
 // issue was encountered in a uniform way.
 type errorReporter struct {
 	fset *token.FileSet
+	// errs collects errors that translation recovered from, or nil if the
+	// first error should abort translation
+	errs *errorList
 }
 
 func newErrorReporter(fset *token.FileSet) errorReporter {
-	return errorReporter{fset}
+	return errorReporter{fset: fset}
+}
+
+// errorList collects the errors encountered while translating a package, so
+// that a single run reports every unsupported construct
+type errorList struct {
+	errs []error
+	// max is the number of errors after which translation stops (0 for no
+	// limit)
+	max int
+}
+
+func (l *errorList) add(err error) {
+	l.errs = append(l.errs, err)
+}
+
+// full reports whether translation should stop
+func (l *errorList) full() bool {
+	return l.max > 0 && len(l.errs) >= l.max
+}
+
+// sortFrom sorts the errors starting at index start by source position.
+//
+// Statements are translated in reverse order, so the errors for a single
+// declaration are not collected in order.
+func (l *errorList) sortFrom(start int) {
+	errs := l.errs[start:]
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errorPos(errs[i]) < errorPos(errs[j])
+	})
+}
+
+func errorPos(err error) token.Pos {
+	if cerr, ok := err.(*ConversionError); ok {
+		return cerr.Pos
+	}
+	return token.NoPos
+}
+
+// recoverError handles a panic r from translating a statement or expression.
+//
+// A translation error is recorded and replaced with a placeholder, so that
+// translation continues and reports any further errors. Other panics (and the
+// error that reaches the limit) are propagated.
+func (r errorReporter) recoverError(p interface{}) glang.Expr {
+	gooseErr, ok := p.(gooseError)
+	if !ok || r.errs == nil ||
+		(r.errs.max > 0 && len(r.errs.errs)+1 >= r.errs.max) {
+		panic(p)
+	}
+	r.errs.add(gooseErr.err)
+	return glang.ErrorExpr{
+		Message: fmt.Sprintf("[%s]: %s", gooseErr.err.Category, gooseErr.err.Message),
+	}
 }
 
 // printField implements custom printing for fields, since printer.Fprint does
@@ -52,6 +113,9 @@ func (r errorReporter) printGo(n locatable) string {
 		}
 		return strings.Join(fields, "; ")
 	}
+	if c, ok := n.(*ast.Comment); ok {
+		return c.Text
+	}
 
 	if s, ok := n.(fmt.Stringer); ok {
 		return s.String()
@@ -103,6 +167,34 @@ type ConversionError struct {
 	GoSrcFile string
 	// (for systematic tests)
 	Pos token.Pos
+	// the span of GoCode in the source program
+	Start, End token.Position
+}
+
+// ErrorCategories lists the categories of ConversionError. The position of a
+// category determines its code, so new categories must be added at the end.
+var ErrorCategories = []string{
+	"unsupported",
+	"future",
+	"todo",
+	"impossible(go)",
+	"impossible(no-examples)",
+}
+
+// CategoryCode returns a stable code for an error category, for tools that
+// consume diagnostics.
+func CategoryCode(category string) string {
+	for i, c := range ErrorCategories {
+		if c == category {
+			return fmt.Sprintf("GOOSE%03d", i+1)
+		}
+	}
+	return "GOOSE000"
+}
+
+// Code is a stable identifier for the error's category.
+func (e *ConversionError) Code() string {
+	return CategoryCode(e.Category)
 }
 
 func (e *ConversionError) Error() string {
@@ -115,8 +207,35 @@ func (e *ConversionError) Error() string {
 	return strings.Join(lines, "\n")
 }
 
+// ConversionErrors extracts the conversion errors from an error returned by
+// translation.
+//
+// Other errors (for example, from loading the package) are returned
+// separately.
+func ConversionErrors(err error) (cerrs []*ConversionError, others []error) {
+	if err == nil {
+		return nil, nil
+	}
+	switch err := errors.Cause(err).(type) {
+	case *ConversionError:
+		return []*ConversionError{err}, nil
+	case MultipleErrors:
+		for _, e := range err {
+			c, o := ConversionErrors(e)
+			cerrs = append(cerrs, c...)
+			others = append(others, o...)
+		}
+		return cerrs, others
+	}
+	return nil, []error{err}
+}
+
 func (r errorReporter) prefixed(prefix string, n locatable, msg string, args ...interface{}) {
 	where := r.fset.Position(n.Pos())
+	end := where
+	if n, ok := n.(interface{ End() token.Pos }); ok {
+		end = r.fset.Position(n.End())
+	}
 	what := r.printGo(n)
 	formatted := fmt.Sprintf(msg, args...)
 
@@ -127,6 +246,8 @@ func (r errorReporter) prefixed(prefix string, n locatable, msg string, args ...
 		GooseCaller: getCaller(2),
 		GoSrcFile:   where.String(),
 		Pos:         n.Pos(),
+		Start:       where,
+		End:         end,
 	}
 
 	panic(gooseError{err: err})
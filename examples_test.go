@@ -8,25 +8,35 @@
 // changes that are expected to have no impact on existing working code,
 // and also conveniently are continuously-checked examples of goose output.
 //
+// Tests of several packages are in a directory with each package's gold file
+// placed where goose writes its output (see multiPackageTest).
+//
 // There are also negative examples in testdata/ that goose rejects due to
-// unsupported Go code. These are each run as a standalone package.
+// unsupported Go code. These are each run as a standalone package, and
+// declare the errors goose should report with comments (see errorExpectation).
 package goose_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/goose-lang/goose"
+	"github.com/goose-lang/goose/glang"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/packages"
 )
 
 var updateGold = flag.Bool("update-gold",
@@ -70,16 +80,25 @@ func (t test) isDir() bool {
 // with the expected Coq output.
 type positiveTest struct {
 	test
+	// ext is the extension of the output (v if empty)
+	ext string
+}
+
+func (t positiveTest) extension() string {
+	if t.ext == "" {
+		return "v"
+	}
+	return t.ext
 }
 
 // GoldFile returns the path to the test's gold Coq output
 func (t positiveTest) GoldFile() string {
-	return path.Join(t.path, t.name+".gold.v")
+	return path.Join(t.path, t.name+".gold."+t.extension())
 }
 
 // ActualFile returns the path to the test's actual output
 func (t positiveTest) ActualFile() string {
-	return path.Join(t.path, t.name+".actual.v")
+	return path.Join(t.path, t.name+".actual."+t.extension())
 }
 
 // Gold returns the contents of the gold Ast as a string
@@ -116,7 +135,7 @@ func testExample(testingT *testing.T, name string, tr goose.Translator) {
 	testingT.Parallel()
 	testingT.Helper()
 	assert := assert.New(testingT)
-	t := positiveTest{newTest("testdata/examples", name)}
+	t := positiveTest{test: newTest("testdata/examples", name)}
 	if !t.isDir() {
 		assert.FailNowf("not a test directory",
 			"path: %s",
@@ -141,8 +160,13 @@ func testExample(testingT *testing.T, name string, tr goose.Translator) {
 
 	var b bytes.Buffer
 	f.Write(&b)
-	actual := b.String()
+	t.checkGold(assert, b.String())
+}
 
+// checkGold compares actual against the gold output (or updates the gold
+// output with -update-gold), leaving the actual output next to the gold file
+// if they differ
+func (t positiveTest) checkGold(assert *assert.Assertions, actual string) {
 	if *updateGold {
 		expected := t.Gold()
 		if actual != expected {
@@ -161,7 +185,7 @@ func testExample(testingT *testing.T, name string, tr goose.Translator) {
 	}
 	if actual != expected {
 		t.PutActual(actual)
-		assert.FailNowf("actual Coq output != gold output",
+		assert.FailNowf("actual output != gold output",
 			"see %s",
 			t.ActualFile())
 		return
@@ -170,6 +194,135 @@ func testExample(testingT *testing.T, name string, tr goose.Translator) {
 	t.DeleteActual()
 }
 
+// A multiPackageTest is a directory with several packages that import each
+// other, translated together.
+//
+// Each package has a gold file at the path goose would write it to
+// (determined by glang.ImportToPath), relative to the test directory and with
+// the extension .gold.v: for example, the package in <dir>/a/b has the gold
+// file <dir>/a/b.gold.v.
+type multiPackageTest struct {
+	test
+	// root is the import path of the test directory
+	root string
+}
+
+func newMultiPackageTest(dir string, name string) (multiPackageTest, error) {
+	t := multiPackageTest{test: newTest(dir, name)}
+	abs, err := filepath.Abs(t.path)
+	if err != nil {
+		return t, err
+	}
+	pkgs, err := packages.Load(&packages.Config{
+		Dir:  t.path,
+		Mode: packages.NeedName | packages.NeedFiles,
+	}, "./...")
+	if err != nil {
+		return t, err
+	}
+	for _, pkg := range pkgs {
+		if len(pkg.GoFiles) == 0 {
+			continue
+		}
+		rel, err := filepath.Rel(abs, filepath.Dir(pkg.GoFiles[0]))
+		if err != nil {
+			return t, err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			t.root = pkg.PkgPath
+		} else {
+			t.root = strings.TrimSuffix(pkg.PkgPath, "/"+rel)
+		}
+		return t, nil
+	}
+	return t, fmt.Errorf("no packages in %s", t.path)
+}
+
+// GoldFiles returns the gold file of each package in the test, by package path
+func (t multiPackageTest) GoldFiles() map[string]string {
+	gold := make(map[string]string)
+	_ = filepath.WalkDir(t.path, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && strings.HasSuffix(p, ".gold.v") {
+			rel, _ := filepath.Rel(t.path, strings.TrimSuffix(p, ".gold.v"))
+			gold[t.pkgPath(filepath.ToSlash(rel))] = p
+		}
+		return nil
+	})
+	return gold
+}
+
+// pkgPath returns the import path of the package in the test directory rel
+func (t multiPackageTest) pkgPath(rel string) string {
+	return path.Join(t.root, rel)
+}
+
+// packageTest returns the test of a package's output, with its gold file
+// following the layout of glang.ImportToPath (ok is false if the output is
+// outside of the test directory)
+func (t multiPackageTest) packageTest(f glang.File) (pt positiveTest, ok bool) {
+	out := glang.ImportToPath(f.PkgPath, f.GoPackage)
+	root := strings.TrimSuffix(glang.ImportToPath(t.root, ""), ".v")
+	rel, err := filepath.Rel(root, out)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return positiveTest{}, false
+	}
+	rel = filepath.ToSlash(strings.TrimSuffix(rel, ".v"))
+	return positiveTest{test: test{
+		name: path.Base(rel),
+		path: path.Join(t.path, path.Dir(rel)),
+	}}, true
+}
+
+func testMultiPackageExample(testingT *testing.T, name string, tr goose.Translator) {
+	testingT.Parallel()
+	testingT.Helper()
+	assert := assert.New(testingT)
+	t, err := newMultiPackageTest("testdata/examples", name)
+	if err != nil {
+		assert.FailNowf("loading failed", "load error: %v", err)
+	}
+
+	files, errs, patternError := tr.TranslatePackages(t.path, "./...")
+	if patternError != nil {
+		assert.FailNowf("loading failed", "load error: %v", patternError)
+	}
+	translated := make(map[string]bool)
+	for _, f := range files {
+		translated[f.PkgPath] = true
+	}
+	for i, f := range files {
+		if errs[i] != nil {
+			fmt.Fprintln(os.Stderr, errs[i])
+			assert.FailNowf("translation failed", "package %s", f.PkgPath)
+		}
+		// imports within the test must be translated in the same call, so
+		// that the Coq they require is generated
+		for _, imp := range f.Imports {
+			if strings.HasPrefix(imp.Path, t.root+"/") {
+				assert.True(translated[imp.Path],
+					"%s imports %s, which was not translated", f.PkgPath, imp.Path)
+			}
+		}
+
+		pt, ok := t.packageTest(f)
+		if !ok {
+			assert.Failf("output outside of test directory",
+				"%s is written to %s", f.PkgPath, glang.ImportToPath(f.PkgPath, f.GoPackage))
+			continue
+		}
+		var b bytes.Buffer
+		f.Write(&b)
+		pt.checkGold(assert, b.String())
+	}
+	if !*updateGold {
+		for pkgPath, goldFile := range t.GoldFiles() {
+			assert.True(translated[pkgPath],
+				"%s has no translated package %s", goldFile, pkgPath)
+		}
+	}
+}
+
 func TestUnitTests(t *testing.T) {
 	testExample(t, "unittest", goose.Translator{})
 }
@@ -207,41 +360,65 @@ func TestComments(t *testing.T) {
 	testExample(t, "comments", goose.Translator{})
 }
 
+func TestMultiPackage(t *testing.T) {
+	testMultiPackageExample(t, "multipkg", goose.Translator{})
+}
+
+// An errorExpectation is an error that a negative test expects goose to
+// report, declared with a comment of the form
+//
+//	// ERROR category "message"
+//
+// on the line the error is reported at. The message is a Go string literal
+// that should be a substring of the error's message.
 type errorExpectation struct {
-	Line  int
-	Error string
+	Line     int
+	Category string
+	Message  string
 }
 
-type errorTestResult struct {
-	Err        *goose.ConversionError
-	ActualLine int
-	Expected   errorExpectation
+func (e errorExpectation) String() string {
+	return fmt.Sprintf("line %d: %s %q", e.Line, e.Category, e.Message)
 }
 
-func getExpectedError(fset *token.FileSet,
-	comments []*ast.CommentGroup) *errorExpectation {
-	errRegex := regexp.MustCompile(`ERROR ?(.*)`)
+var (
+	errorCommentRegex = regexp.MustCompile(`\bERROR\b(.*)`)
+	errorArgsRegex    = regexp.MustCompile(`^\s+(\w+)\s+("(?:[^"\\]|\\.)*")\s*$`)
+)
+
+// getExpectedErrors returns the errors declared in comments, in source order
+func getExpectedErrors(fset *token.FileSet,
+	comments []*ast.CommentGroup) ([]errorExpectation, error) {
+	var expected []errorExpectation
 	for _, cg := range comments {
 		for _, c := range cg.List {
-			ms := errRegex.FindStringSubmatch(c.Text)
+			ms := errorCommentRegex.FindStringSubmatch(c.Text)
 			if ms == nil {
 				continue
 			}
-			expected := &errorExpectation{
-				Line: fset.Position(c.Pos()).Line,
+			pos := fset.Position(c.Pos())
+			args := errorArgsRegex.FindStringSubmatch(ms[1])
+			if args == nil {
+				return nil, fmt.Errorf(`%v: expected // ERROR category "message"`, pos)
 			}
-			// found a match
-			if len(ms) > 1 {
-				expected.Error = ms[1]
+			msg, err := strconv.Unquote(args[2])
+			if err != nil {
+				return nil, fmt.Errorf("%v: bad message: %v", pos, err)
 			}
-			// only use the first ERROR
-			return expected
+			expected = append(expected, errorExpectation{
+				Line:     pos.Line,
+				Category: args[1],
+				Message:  msg,
+			})
 		}
 	}
-	return nil
+	return expected, nil
 }
 
-func translateErrorFile(assert *assert.Assertions, filePath string) *errorTestResult {
+// translateErrorFile translates the negative test in filePath, returning the
+// errors reported (with the full message) and the errors expected
+func translateErrorFile(assert *assert.Assertions,
+	filePath string) (actual, expected []errorExpectation) {
 	pkgName := "example"
 	ctx := goose.NewCtx(pkgName, goose.Config{})
 	fset := ctx.Fset
@@ -249,37 +426,45 @@ func translateErrorFile(assert *assert.Assertions, filePath string) *errorTestRe
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		assert.FailNowf("test code does not parse", "file: %s", filePath)
-		return nil
+		return
 	}
 
 	err = ctx.TypeCheck([]*ast.File{f})
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		assert.FailNowf("test code does not type check", "file: %s", filePath)
-		return nil
+		return
 	}
 
-	_, _, errs := ctx.Decls(goose.NamedFile{Path: filePath, Ast: f})
-	if len(errs) == 0 {
-		assert.FailNowf("expected error", "file: %s", filePath)
-		return nil
+	expected, err = getExpectedErrors(fset, f.Comments)
+	if err != nil {
+		assert.FailNowf("malformed error expectation", "%v", err)
+		return
 	}
-	cerr := errs[0].(*goose.ConversionError)
-
-	expectedErr := getExpectedError(fset, f.Comments)
-	if expectedErr == nil {
+	if len(expected) == 0 {
 		assert.FailNowf("test code does not have an error expectation",
 			"file: %s", filePath)
-		return nil
+		return
 	}
 
-	return &errorTestResult{
-		Err:        cerr,
-		ActualLine: fset.Position(cerr.Pos).Line,
-		Expected:   *expectedErr,
+	_, _, errs := ctx.Decls(goose.NamedFile{Path: filePath, Ast: f})
+	for _, err := range errs {
+		cerr, ok := err.(*goose.ConversionError)
+		if !ok {
+			assert.FailNowf("unexpected error", "%v", err)
+			return
+		}
+		actual = append(actual, errorExpectation{
+			Line:     fset.Position(cerr.Pos).Line,
+			Category: cerr.Category,
+			Message:  cerr.Message,
+		})
 	}
+	return actual, expected
 }
 
+// TestNegativeExamples checks that goose reports exactly the errors declared
+// in each file in testdata/negative-tests, in order.
 func TestNegativeExamples(testingT *testing.T) {
 	tests := loadTests("./testdata/negative-tests")
 	for _, t := range tests {
@@ -288,21 +473,124 @@ func TestNegativeExamples(testingT *testing.T) {
 		}
 		testingT.Run(t.name, func(testingT *testing.T) {
 			assert := assert.New(testingT)
-			tt := translateErrorFile(assert, t.path)
-			if tt == nil {
-				// this Ast has already failed
+			actual, expected := translateErrorFile(assert, t.path)
+			if len(actual) != len(expected) {
+				assert.FailNowf("wrong number of errors",
+					"%s: expected %d errors, got:\n%s",
+					t.name, len(expected), formatExpectations(actual))
+			}
+			for i, exp := range expected {
+				act := actual[i]
+				if act.Line != exp.Line || act.Category != exp.Category ||
+					!strings.Contains(act.Message, exp.Message) {
+					assert.Failf("incorrect error",
+						"%s: expected %v, got %v", t.name, exp, act)
+				}
+			}
+		})
+	}
+}
+
+func formatExpectations(es []errorExpectation) string {
+	var lines []string
+	for _, e := range es {
+		lines = append(lines, "  "+e.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func translateErrors(assert *assert.Assertions, filePath string,
+	maxErrors int) (fset *token.FileSet, errs []error) {
+	ctx := goose.NewCtx("example", goose.Config{MaxErrors: maxErrors})
+	f, err := parser.ParseFile(ctx.Fset, filePath, nil, parser.ParseComments)
+	assert.NoError(err)
+	assert.NoError(ctx.TypeCheck([]*ast.File{f}))
+	_, _, errs = ctx.Decls(goose.NamedFile{Path: filePath, Ast: f})
+	return ctx.Fset, errs
+}
+
+func TestMultipleErrors(testingT *testing.T) {
+	assert := assert.New(testingT)
+	path := "testdata/negative-tests/multipleerrors.go"
+
+	fset, errs := translateErrors(assert, path, 0)
+	var lines []int
+	for _, err := range errs {
+		cerr := err.(*goose.ConversionError)
+		assert.Equal("unsupported", cerr.Category)
+		assert.Equal("GOOSE001", cerr.Code())
+		lines = append(lines, fset.Position(cerr.Pos).Line)
+	}
+	assert.Equal([]int{5, 8, 16}, lines, "errors should be reported in order")
+
+	cerrs, others := goose.ConversionErrors(goose.MultipleErrors(errs))
+	assert.Len(cerrs, 3)
+	assert.Empty(others)
+	// the span of the select statement
+	assert.Equal(16, cerrs[2].Start.Line)
+	assert.Equal(16, cerrs[2].End.Line)
+	assert.Equal(3, cerrs[2].Start.Column)
+	assert.Equal(12, cerrs[2].End.Column)
+
+	_, errs = translateErrors(assert, path, 2)
+	assert.Len(errs, 2, "should stop after MaxErrors errors")
+}
+
+// testLeanExample checks the Lean output for an example against its gold file,
+// <name>.gold.lean
+func testLeanExample(testingT *testing.T, name string) {
+	testingT.Parallel()
+	assert := assert.New(testingT)
+	t := positiveTest{test: newTest("testdata/examples", name), ext: "lean"}
+	files, errs, err := goose.Translator{}.TranslatePackages(t.path, ".")
+	assert.NoError(err)
+	if !assert.Len(errs, 1) || !assert.NoError(errs[0]) {
+		return
+	}
+	var b bytes.Buffer
+	if !assert.NoError(glang.LeanPrinter.WriteFile(&b, files[0])) {
+		return
+	}
+	t.checkGold(assert, b.String())
+}
+
+func TestLeanUnitTests(t *testing.T) {
+	testLeanExample(t, "unittest")
+}
+
+// TestBackends checks that the other printers handle every construct in the
+// examples.
+func TestBackends(t *testing.T) {
+	for _, name := range []string{"unittest", "semantics", "simpledb", "wal",
+		"async", "logging2", "append_log", "comments"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			files, errs, err := goose.Translator{}.TranslatePackages(
+				path.Join("testdata/examples", name), ".")
+			assert.NoError(t, err)
+			if !assert.Len(t, errs, 1) || !assert.NoError(t, errs[0]) {
 				return
 			}
-			assert.Regexp(`(unsupported|future)`, tt.Err.Category)
-			if !strings.Contains(tt.Err.Message, tt.Expected.Error) {
-				assert.FailNowf("incorrect error message",
-					`%s: error message "%s" does not contain "%s"`,
-					t.name, tt.Err.Message, tt.Expected.Error)
+			var b bytes.Buffer
+			assert.NoError(t, glang.LeanPrinter.WriteFile(&b, files[0]))
+			assert.Contains(t, b.String(), "namespace "+files[0].GoPackage)
+
+			b.Reset()
+			assert.NoError(t, glang.JSONPrinter.WriteFile(&b, files[0]))
+			var decoded struct {
+				PkgPath string `json:"pkgPath"`
+				Decls   []struct {
+					Kind string          `json:"kind"`
+					Pos  *glang.Position `json:"pos"`
+				} `json:"decls"`
 			}
-			if tt.ActualLine > 0 && tt.ActualLine != tt.Expected.Line {
-				assert.FailNowf("incorrect error message line",
-					"%s: error is incorrectly attributed to %s",
-					t.name, tt.Err.GoSrcFile)
+			assert.NoError(t, json.Unmarshal(b.Bytes(), &decoded))
+			assert.Equal(t, files[0].PkgPath, decoded.PkgPath)
+			for _, d := range decoded.Decls {
+				if d.Kind != "CommentDecl" {
+					assert.NotNil(t, d.Pos, "%s has no position", d.Kind)
+				}
 			}
 		})
 	}
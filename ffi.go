@@ -0,0 +1,126 @@
+package goose
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Ffi describes a Go package that is implemented by a Coq FFI rather than
+// translated.
+//
+// In goose.json an Ffi can be written as just its prelude name.
+type Ffi struct {
+	// Prelude names the FFI; code that uses it imports New.<Prelude>_prelude
+	Prelude string `json:"prelude"`
+	// Functions maps functions in the package to their Coq translations.
+	// Other functions are referenced as <package name>.<function>.
+	Functions map[string]string `json:"functions,omitempty"`
+	// Compatible lists the preludes of other FFIs that can be used in the
+	// same package as this one (it is enough for either FFI to list the
+	// other)
+	Compatible []string `json:"compatible,omitempty"`
+}
+
+func (ffi *Ffi) UnmarshalJSON(data []byte) error {
+	var prelude string
+	if err := json.Unmarshal(data, &prelude); err == nil {
+		*ffi = Ffi{Prelude: prelude}
+		return nil
+	}
+	// a separate type so this does not recurse into UnmarshalJSON
+	type ffiFields Ffi
+	return json.Unmarshal(data, (*ffiFields)(ffi))
+}
+
+// builtinFfis are the FFIs for packages that have a prelude in Perennial
+var builtinFfis = map[string]Ffi{
+	"github.com/mit-pdos/gokv/grove_ffi":             {Prelude: "grove"},
+	"github.com/goose-lang/goose/machine/disk":       {Prelude: "disk"},
+	"github.com/goose-lang/goose/machine/async_disk": {Prelude: "async_disk"},
+}
+
+// RegisterFfi makes code that imports pkgPath use ffi. This overrides any
+// built-in FFI for the package.
+func (tr *Translator) RegisterFfi(pkgPath string, ffi Ffi) {
+	if tr.Ffi == nil {
+		tr.Ffi = make(map[string]Ffi)
+	}
+	tr.Ffi[pkgPath] = ffi
+}
+
+// ffis returns all the FFIs available to tr, by package path
+func (tr Translator) ffis() map[string]Ffi {
+	ffis := make(map[string]Ffi, len(builtinFfis)+len(tr.Ffi))
+	for pkgPath, ffi := range builtinFfis {
+		ffis[pkgPath] = ffi
+	}
+	for pkgPath, ffi := range tr.Ffi {
+		ffis[pkgPath] = ffi
+	}
+	return ffis
+}
+
+// compatibleFfis reports whether the FFIs with preludes a and b can be used
+// together
+func compatibleFfis(ffis map[string]Ffi, a, b string) bool {
+	for _, ffi := range ffis {
+		var other string
+		switch ffi.Prelude {
+		case a:
+			other = b
+		case b:
+			other = a
+		default:
+			continue
+		}
+		for _, c := range ffi.Compatible {
+			if c == other {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getFfis finds the FFIs a package uses (directly or through its
+// dependencies), returned as sorted prelude names
+func (tr Translator) getFfis(pkg *packages.Package) ([]string, error) {
+	ffis := tr.ffis()
+	// the package that first brought in each FFI, for reporting errors
+	seenFfis := make(map[string]string)
+	packages.Visit([]*packages.Package{pkg},
+		func(pkg *packages.Package) bool {
+			// the dependencies of an FFI are not considered as being used; this
+			// allows one FFI to be built on top of another
+			if _, ok := ffis[pkg.PkgPath]; ok {
+				return false
+			}
+			return true
+		},
+		func(pkg *packages.Package) {
+			if ffi, ok := ffis[pkg.PkgPath]; ok {
+				if _, ok := seenFfis[ffi.Prelude]; !ok {
+					seenFfis[ffi.Prelude] = pkg.PkgPath
+				}
+			}
+		},
+	)
+
+	var preludes []string
+	for prelude := range seenFfis {
+		preludes = append(preludes, prelude)
+	}
+	sort.Strings(preludes)
+	for i, a := range preludes {
+		for _, b := range preludes[i+1:] {
+			if !compatibleFfis(ffis, a, b) {
+				return nil, fmt.Errorf("%s uses incompatible FFIs %s (from %s) and %s (from %s)",
+					pkg.PkgPath, a, seenFfis[a], b, seenFfis[b])
+			}
+		}
+	}
+	return preludes, nil
+}
@@ -0,0 +1,77 @@
+package goose_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goose-lang/goose"
+)
+
+// ffiModule creates a module example.com/m with two FFI packages, a and b, and
+// a package app that uses both
+func ffiModule(t *testing.T) string {
+	dir := writeModule(t, "")
+	files := map[string]string{
+		"a/a.go": "package a\n\nfunc Read() uint64 { return 0 }\n\nfunc Reset() {}\n",
+		"b/b.go": "package b\n\nfunc Write(x uint64) {}\n",
+		"app/app.go": `package app
+
+import (
+	"example.com/m/a"
+	"example.com/m/b"
+)
+
+func Copy() {
+	a.Reset()
+	b.Write(a.Read())
+}
+`,
+	}
+	for name, contents := range files {
+		file := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0777))
+		require.NoError(t, os.WriteFile(file, []byte(contents), 0666))
+	}
+	return dir
+}
+
+func translateApp(t *testing.T, tr goose.Translator, dir string) (string, error) {
+	fs, errs, err := tr.TranslatePackages(dir, "./app")
+	require.NoError(t, err)
+	require.Len(t, fs, 1)
+	var b bytes.Buffer
+	fs[0].Write(&b)
+	return b.String(), errs[0]
+}
+
+func TestFfiExternals(t *testing.T) {
+	dir := ffiModule(t)
+	var tr goose.Translator
+	tr.RegisterFfi("example.com/m/a", goose.Ffi{
+		Prelude:    "a",
+		Functions:  map[string]string{"Read": "a_ffi.read"},
+		Compatible: []string{"b"},
+	})
+	tr.RegisterFfi("example.com/m/b", goose.Ffi{Prelude: "b"})
+	out, err := translateApp(t, tr, dir)
+	require.NoError(t, err)
+	assert.Contains(t, out, "From New Require Import a_prelude b_prelude.")
+	assert.Contains(t, out, "a_ffi.read")
+	// functions without an external translation are referenced as usual
+	assert.Contains(t, out, "a.Reset")
+	assert.Contains(t, out, "b.Write")
+}
+
+func TestFfiIncompatible(t *testing.T) {
+	dir := ffiModule(t)
+	var tr goose.Translator
+	tr.RegisterFfi("example.com/m/a", goose.Ffi{Prelude: "a"})
+	tr.RegisterFfi("example.com/m/b", goose.Ffi{Prelude: "b"})
+	_, err := translateApp(t, tr, dir)
+	assert.ErrorContains(t, err, "incompatible FFIs a (from example.com/m/a) and b (from example.com/m/b)")
+}
@@ -0,0 +1,355 @@
+// Package fix rewrites common Go idioms that Goose does not support into
+// equivalent code in the Goose subset.
+//
+// Each rewrite is a Fix, a set of textual edits computed from the type-checked
+// syntax tree; Apply applies fixes to a file's source and reformats it.
+package fix
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// An Edit replaces the source between Pos and End with NewText.
+type Edit struct {
+	Pos, End token.Pos
+	NewText  string
+}
+
+// A Fix rewrites a single construct, which starts at Pos.
+type Fix struct {
+	Pos     token.Pos
+	Message string
+	Edits   []Edit
+}
+
+type rewriter struct {
+	fset *token.FileSet
+	info *types.Info
+	file *token.File
+	src  []byte
+}
+
+// Fixes finds all the rewrites in a type-checked file, with source src.
+func Fixes(fset *token.FileSet, info *types.Info, f *ast.File, src []byte) []Fix {
+	r := rewriter{fset: fset, info: info, file: fset.File(f.Pos()), src: src}
+	var fixes []Fix
+	add := func(fix *Fix) {
+		if fix != nil {
+			fixes = append(fixes, *fix)
+		}
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			for _, s := range n.List {
+				if s, ok := s.(*ast.IfStmt); ok && s.Init != nil {
+					add(r.hoistIfInit(n, s))
+				}
+			}
+		case *ast.FuncDecl:
+			add(r.namedResults(n.Type, n.Body))
+		case *ast.FuncLit:
+			add(r.namedResults(n.Type, n.Body))
+		case *ast.StructType:
+			for _, field := range n.Fields.List {
+				add(r.splitField(field))
+			}
+		case *ast.IncDecStmt:
+			add(r.mapIncDec(n))
+		}
+		return true
+	})
+	sort.SliceStable(fixes, func(i, j int) bool {
+		return fixes[i].Pos < fixes[j].Pos
+	})
+	return fixes
+}
+
+func (r rewriter) render(n ast.Node) string {
+	var b bytes.Buffer
+	if err := format.Node(&b, r.fset, n); err != nil {
+		panic(err)
+	}
+	return b.String()
+}
+
+// indent gives the indentation of the line containing pos
+func (r rewriter) indent(pos token.Pos) string {
+	start := r.file.Offset(r.file.LineStart(r.file.Line(pos)))
+	end := start
+	for end < len(r.src) && (r.src[end] == ' ' || r.src[end] == '\t') {
+		end++
+	}
+	return string(r.src[start:end])
+}
+
+// hoistIfInit moves the initialization of an if statement before the if,
+//
+//	if x := f(); x > 0 {
+//
+// becomes
+//
+//	x := f()
+//	if x > 0 {
+//
+// which is only done if this doesn't change the meaning of x in the rest of
+// the enclosing block.
+func (r rewriter) hoistIfInit(block *ast.BlockStmt, s *ast.IfStmt) *Fix {
+	if !canHoist(r.info, block, s) {
+		return nil
+	}
+	return &Fix{
+		Pos:     s.Init.Pos(),
+		Message: "move initialization before the if statement",
+		Edits: []Edit{
+			{Pos: s.Pos(), End: s.Pos(), NewText: r.render(s.Init) + "\n" + r.indent(s.Pos())},
+			{Pos: s.Init.Pos(), End: s.Cond.Pos()},
+		},
+	}
+}
+
+// canHoist checks that the variables defined by the initialization of s can
+// be moved to the enclosing block: they must not conflict with other
+// declarations in the block, and must not shadow variables used later in the
+// block.
+func canHoist(info *types.Info, block *ast.BlockStmt, s *ast.IfStmt) bool {
+	scope := info.Scopes[s]
+	if scope == nil || scope.Parent() == nil {
+		return false
+	}
+	outer := scope.Parent()
+	names := make(map[string]bool)
+	for _, name := range scope.Names() {
+		if outer.Lookup(name) != nil {
+			return false
+		}
+		names[name] = true
+	}
+	ok := true
+	ast.Inspect(block, func(n ast.Node) bool {
+		id, isIdent := n.(*ast.Ident)
+		if !isIdent || id.Pos() < s.End() || !names[id.Name] {
+			return ok
+		}
+		if obj := info.Uses[id]; obj != nil && obj.Pos() < s.Pos() {
+			ok = false
+		}
+		return ok
+	})
+	return ok
+}
+
+// namedResults replaces named results with local variables,
+//
+//	func f() (n uint64) {
+//		n = 1
+//		return
+//	}
+//
+// becomes
+//
+//	func f() uint64 {
+//		var n uint64
+//		n = 1
+//		return n
+//	}
+//
+// If the body never refers to the results, the names are simply dropped.
+//
+// Functions that use defer are left alone, since deferred functions can
+// modify named results after the return statement (and goose supports named
+// results in this case).
+func (r rewriter) namedResults(fn *ast.FuncType, body *ast.BlockStmt) *Fix {
+	if fn.Results == nil || len(fn.Results.List[0].Names) == 0 || body == nil {
+		return nil
+	}
+	var names, resultTypes []string
+	results := make(map[types.Object]bool)
+	for _, f := range fn.Results.List {
+		ty := r.render(f.Type)
+		for _, name := range f.Names {
+			if name.Name == "_" {
+				return nil
+			}
+			names = append(names, name.Name)
+			resultTypes = append(resultTypes, ty)
+			results[r.info.Defs[name]] = true
+		}
+	}
+
+	var bareReturns []*ast.ReturnStmt
+	usesResults := false
+	hasDefer := false
+	var inspect func(n ast.Node, inFuncLit bool)
+	inspect = func(n ast.Node, inFuncLit bool) {
+		ast.Inspect(n, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				if !inFuncLit {
+					// a nested function can refer to the results, but its
+					// returns and defers are its own
+					inspect(n.Body, true)
+					return false
+				}
+			case *ast.DeferStmt:
+				hasDefer = hasDefer || !inFuncLit
+			case *ast.ReturnStmt:
+				if len(n.Results) == 0 && !inFuncLit {
+					bareReturns = append(bareReturns, n)
+				}
+			case *ast.Ident:
+				if results[r.info.Uses[n]] {
+					usesResults = true
+				}
+			}
+			return true
+		})
+	}
+	inspect(body, false)
+	if hasDefer {
+		return nil
+	}
+
+	newResults := strings.Join(resultTypes, ", ")
+	if len(resultTypes) > 1 {
+		newResults = "(" + newResults + ")"
+	}
+	fix := &Fix{
+		Pos:     fn.Results.List[0].Pos(),
+		Message: "remove result names",
+		Edits: []Edit{
+			{Pos: fn.Results.Pos(), End: fn.Results.End(), NewText: newResults},
+		},
+	}
+	if !usesResults && len(bareReturns) == 0 {
+		return fix
+	}
+
+	fix.Message = "replace named results with local variables"
+	var decls strings.Builder
+	first := body.List[0]
+	for i := range names {
+		fmt.Fprintf(&decls, "var %s %s\n%s", names[i], resultTypes[i], r.indent(first.Pos()))
+	}
+	fix.Edits = append(fix.Edits, Edit{Pos: first.Pos(), End: first.Pos(), NewText: decls.String()})
+	for _, ret := range bareReturns {
+		fix.Edits = append(fix.Edits, Edit{
+			Pos:     ret.Pos(),
+			End:     ret.End(),
+			NewText: "return " + strings.Join(names, ", "),
+		})
+	}
+	return fix
+}
+
+// splitField splits a struct field declaring multiple names,
+//
+//	a, b uint64
+//
+// into one field per name.
+func (r rewriter) splitField(field *ast.Field) *Fix {
+	if len(field.Names) < 2 {
+		return nil
+	}
+	suffix := " " + r.render(field.Type)
+	if field.Tag != nil {
+		suffix += " " + field.Tag.Value
+	}
+	var fields []string
+	for _, name := range field.Names {
+		fields = append(fields, name.Name+suffix)
+	}
+	return &Fix{
+		Pos:     field.Pos(),
+		Message: "split up fields with the same type",
+		Edits: []Edit{{
+			Pos:     field.Pos(),
+			End:     field.End(),
+			NewText: strings.Join(fields, "\n"+r.indent(field.Pos())),
+		}},
+	}
+}
+
+// isSimple checks that evaluating e twice is the same as evaluating it once
+func isSimple(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.Ident, *ast.BasicLit:
+		return true
+	case *ast.SelectorExpr:
+		return isSimple(e.X)
+	case *ast.ParenExpr:
+		return isSimple(e.X)
+	}
+	return false
+}
+
+// mapIncDec rewrites m[k]++ to m[k] = m[k] + 1, to make the read and write
+// of the map explicit
+func (r rewriter) mapIncDec(s *ast.IncDecStmt) *Fix {
+	index, ok := ast.Unparen(s.X).(*ast.IndexExpr)
+	if !ok {
+		return nil
+	}
+	if _, ok := r.info.TypeOf(index.X).Underlying().(*types.Map); !ok {
+		return nil
+	}
+	if !isSimple(index.X) || !isSimple(index.Index) {
+		return nil
+	}
+	op := "+"
+	if s.Tok == token.DEC {
+		op = "-"
+	}
+	x := r.render(index)
+	return &Fix{
+		Pos:     s.Pos(),
+		Message: fmt.Sprintf("replace %s with an assignment", s.Tok),
+		Edits: []Edit{{
+			Pos:     s.Pos(),
+			End:     s.End(),
+			NewText: fmt.Sprintf("%s = %s %s 1", x, x, op),
+		}},
+	}
+}
+
+func overlaps(a, b Edit) bool {
+	return a.Pos == b.Pos || (a.Pos < b.End && b.Pos < a.End)
+}
+
+// Apply applies fixes to src, the source of file, and formats the result.
+//
+// A fix that overlaps with an earlier fix is skipped; running the fixes again
+// on the result will apply it.
+func Apply(file *token.File, src []byte, fixes []Fix) ([]byte, error) {
+	var edits []Edit
+	for _, fix := range fixes {
+		conflict := false
+		for _, e := range fix.Edits {
+			for _, accepted := range edits {
+				conflict = conflict || overlaps(e, accepted)
+			}
+		}
+		if !conflict {
+			edits = append(edits, fix.Edits...)
+		}
+	}
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].Pos < edits[j].Pos
+	})
+	var out bytes.Buffer
+	last := 0
+	for _, e := range edits {
+		out.Write(src[last:file.Offset(e.Pos)])
+		out.WriteString(e.NewText)
+		last = file.Offset(e.End)
+	}
+	out.Write(src[last:])
+	return format.Source(out.Bytes())
+}
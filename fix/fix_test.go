@@ -0,0 +1,177 @@
+package fix
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fixSource(t *testing.T, src string) string {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "example.go", src, parser.ParseComments)
+	require.NoError(t, err)
+	info := &types.Info{
+		Defs:   make(map[*ast.Ident]types.Object),
+		Uses:   make(map[*ast.Ident]types.Object),
+		Types:  make(map[ast.Expr]types.TypeAndValue),
+		Scopes: make(map[ast.Node]*types.Scope),
+	}
+	_, err = (&types.Config{}).Check("example", fset, []*ast.File{f}, info)
+	require.NoError(t, err)
+	out, err := Apply(fset.File(f.Pos()), []byte(src), Fixes(fset, info, f, []byte(src)))
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestFixes(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{
+			name: "if initialization",
+			src: `package example
+
+func f(x uint64) uint64 {
+	if y := x + 1; y > 2 {
+		return y
+	}
+	return 0
+}
+`,
+			expected: `package example
+
+func f(x uint64) uint64 {
+	y := x + 1
+	if y > 2 {
+		return y
+	}
+	return 0
+}
+`,
+		},
+		{
+			name: "if initialization that would shadow",
+			src: `package example
+
+func f(x uint64) uint64 {
+	if x := x + 1; x > 2 {
+		return x
+	}
+	return x
+}
+`,
+		},
+		{
+			name: "named results",
+			src: `package example
+
+func f(x uint64) (y uint64, ok bool) {
+	if x > 0 {
+		y = x
+		ok = true
+		return
+	}
+	return 0, false
+}
+
+func g() (n uint64) {
+	return 1
+}
+`,
+			expected: `package example
+
+func f(x uint64) (uint64, bool) {
+	var y uint64
+	var ok bool
+	if x > 0 {
+		y = x
+		ok = true
+		return y, ok
+	}
+	return 0, false
+}
+
+func g() uint64 {
+	return 1
+}
+`,
+		},
+		{
+			name: "named results with defer",
+			src: `package example
+
+func f() (n uint64) {
+	defer func() { n = 2 }()
+	return 1
+}
+`,
+		},
+		{
+			name: "multiple fields",
+			src: `package example
+
+type S struct {
+	a, b uint64 ` + "`json:\"x\"`" + `
+	c    bool
+}
+`,
+			expected: `package example
+
+type S struct {
+	a uint64 ` + "`json:\"x\"`" + `
+	b uint64 ` + "`json:\"x\"`" + `
+	c bool
+}
+`,
+		},
+		{
+			name: "map increment",
+			src: `package example
+
+func f(m map[string]uint64, k string) {
+	m[k]++
+	m["x"]--
+}
+`,
+			expected: `package example
+
+func f(m map[string]uint64, k string) {
+	m[k] = m[k] + 1
+	m["x"] = m["x"] - 1
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := tt.expected
+			if expected == "" {
+				expected = tt.src
+			}
+			assert.Equal(t, expected, fixSource(t, tt.src))
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	c := Change{
+		Path: "example.go",
+		Old:  []byte("a\nb\nc\n"),
+		New:  []byte("a\nB\nc\n"),
+	}
+	assert.Equal(t, `--- example.go
++++ example.go
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`, c.Diff())
+}
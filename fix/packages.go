@@ -0,0 +1,81 @@
+package fix
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"golang.org/x/tools/go/packages"
+)
+
+// A Change is a rewritten file.
+type Change struct {
+	Path     string
+	Old, New []byte
+}
+
+// splitLines splits a file into lines, each with its trailing newline
+func splitLines(b []byte) []string {
+	lines := strings.SplitAfter(string(b), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Diff shows the change as a unified diff.
+func (c Change) Diff() string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(c.Old),
+		B:        splitLines(c.New),
+		FromFile: c.Path,
+		ToFile:   c.Path,
+		Context:  3,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return diff
+}
+
+// Packages loads packages by a list of patterns (relative to dir) and rewrites
+// all of their files, returning the files that changed.
+func Packages(dir string, patterns ...string) ([]Change, error) {
+	mode := packages.NeedName | packages.NeedCompiledGoFiles
+	mode |= packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo
+	pkgs, err := packages.Load(&packages.Config{
+		Dir:        dir,
+		Mode:       mode,
+		BuildFlags: []string{"-tags", "goose"},
+		Fset:       token.NewFileSet(),
+	}, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return nil, fmt.Errorf("could not load package %v: %v",
+				pkg.PkgPath, pkg.Errors[0])
+		}
+		for i, f := range pkg.Syntax {
+			path := pkg.CompiledGoFiles[i]
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			fixes := Fixes(pkg.Fset, pkg.TypesInfo, f, src)
+			if len(fixes) == 0 {
+				continue
+			}
+			out, err := Apply(pkg.Fset.File(f.Pos()), src, fixes)
+			if err != nil {
+				return nil, fmt.Errorf("%s: fixes produced invalid code: %v", path, err)
+			}
+			changes = append(changes, Change{Path: path, Old: src, New: out})
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,626 @@
+package goose_test
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/goose-lang/goose"
+	"github.com/goose-lang/goose/glang"
+)
+
+// progGen generates Go programs within the subset goose supports: structs,
+// slices, maps, loops and closures over uint64, bool and string values.
+//
+// Every choice consumes a byte of data (and once the data runs out, makes the
+// first choice, which is always the simplest), so that the fuzzer mutates
+// programs by mutating its input. Generated programs always type check.
+type progGen struct {
+	data    []byte
+	b       bytes.Buffer
+	indent  int
+	fresh   int
+	structs []genStruct
+	funcs   []genFunc
+}
+
+type genVar struct {
+	name string
+	ty   string
+}
+
+type genStruct struct {
+	name   string
+	fields []genVar
+}
+
+type genFunc struct {
+	name   string
+	params []string
+	ret    string
+}
+
+// genScope is the variables in scope in a block
+type genScope struct {
+	vars []genVar
+	// ret is the return type of the enclosing function ("" for none)
+	ret    string
+	inLoop bool
+}
+
+const closureType = "func(uint64) uint64"
+
+func (g *progGen) choose(n int) int {
+	if n <= 1 || len(g.data) == 0 {
+		return 0
+	}
+	c := int(g.data[0]) % n
+	g.data = g.data[1:]
+	return c
+}
+
+func (g *progGen) name(prefix string) string {
+	g.fresh++
+	return fmt.Sprintf("%s%d", prefix, g.fresh)
+}
+
+func (g *progGen) line(format string, args ...interface{}) {
+	g.b.WriteString(strings.Repeat("\t", g.indent))
+	fmt.Fprintf(&g.b, format, args...)
+	g.b.WriteString("\n")
+}
+
+func (g *progGen) typ(withFunc bool) string {
+	tys := []string{"uint64", "bool", "string", "[]uint64", "map[uint64]uint64"}
+	for _, s := range g.structs {
+		tys = append(tys, s.name, "*"+s.name)
+	}
+	if withFunc {
+		tys = append(tys, closureType)
+	}
+	return tys[g.choose(len(tys))]
+}
+
+// varsOf returns the variables in sc of type ty
+func (sc genScope) varsOf(ty string) []genVar {
+	var vs []genVar
+	for _, v := range sc.vars {
+		if v.ty == ty {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+// fieldsOf returns expressions for the fields of type ty of struct variables
+// in sc
+func (g *progGen) fieldsOf(sc genScope, ty string) []string {
+	var fs []string
+	for _, v := range sc.vars {
+		if s, ok := g.structOf(v.ty); ok {
+			for _, f := range s.fields {
+				if f.ty == ty {
+					fs = append(fs, v.name+"."+f.name)
+				}
+			}
+		}
+	}
+	return fs
+}
+
+func (g *progGen) structOf(ty string) (genStruct, bool) {
+	name := strings.TrimPrefix(ty, "*")
+	for _, s := range g.structs {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return genStruct{}, false
+}
+
+// literal is a constant or newly allocated value of type ty
+func (g *progGen) literal(ty string) string {
+	switch ty {
+	case "uint64":
+		return fmt.Sprint(g.choose(100))
+	case "bool":
+		return []string{"false", "true"}[g.choose(2)]
+	case "string":
+		return fmt.Sprintf("%q", []string{"", "a", "hello"}[g.choose(3)])
+	case "[]uint64":
+		return fmt.Sprintf("make([]uint64, %d)", g.choose(4))
+	case "map[uint64]uint64":
+		return "make(map[uint64]uint64)"
+	case closureType:
+		return "func(a uint64) uint64 { return a }"
+	}
+	if strings.HasPrefix(ty, "*") {
+		return "&" + strings.TrimPrefix(ty, "*") + "{}"
+	}
+	return ty + "{}"
+}
+
+func (g *progGen) structLit(sc genScope, s genStruct, depth int) string {
+	var fields []string
+	for _, f := range s.fields {
+		if g.choose(2) == 1 {
+			fields = append(fields, f.name+": "+g.expr(sc, f.ty, depth-1))
+		}
+	}
+	return s.name + "{" + strings.Join(fields, ", ") + "}"
+}
+
+// expr generates an expression of type ty
+func (g *progGen) expr(sc genScope, ty string, depth int) string {
+	opts := []func() string{func() string { return g.literal(ty) }}
+	add := func(f func() string) { opts = append(opts, f) }
+	for _, v := range sc.varsOf(ty) {
+		v := v
+		add(func() string { return v.name })
+	}
+	for _, f := range g.fieldsOf(sc, ty) {
+		f := f
+		add(func() string { return f })
+	}
+	if depth > 0 {
+		sub := func(ty string) string { return g.expr(sc, ty, depth-1) }
+		switch ty {
+		case "uint64":
+			add(func() string { return "(" + sub("uint64") + " + " + sub("uint64") + ")" })
+			add(func() string { return "(" + sub("uint64") + " * " + sub("uint64") + ")" })
+			add(func() string { return "uint64(len(" + sub("[]uint64") + "))" })
+			for _, v := range sc.varsOf("string") {
+				v := v
+				add(func() string { return "uint64(len(" + v.name + "))" })
+			}
+			// the left-hand side is a variable so that constants never
+			// underflow
+			for _, v := range sc.varsOf("uint64") {
+				v := v
+				add(func() string { return "(" + v.name + " - " + sub("uint64") + ")" })
+			}
+			for _, v := range sc.varsOf("[]uint64") {
+				v := v
+				add(func() string { return v.name + "[" + sub("uint64") + "]" })
+			}
+			for _, v := range sc.varsOf("map[uint64]uint64") {
+				v := v
+				add(func() string { return v.name + "[" + sub("uint64") + "]" })
+			}
+			for _, v := range sc.varsOf(closureType) {
+				v := v
+				add(func() string { return v.name + "(" + sub("uint64") + ")" })
+			}
+		case "bool":
+			add(func() string { return "(" + sub("uint64") + " < " + sub("uint64") + ")" })
+			add(func() string { return "(" + sub("uint64") + " == " + sub("uint64") + ")" })
+			add(func() string { return "(" + sub("string") + " == " + sub("string") + ")" })
+			add(func() string { return "!" + sub("bool") })
+			add(func() string { return "(" + sub("bool") + " && " + sub("bool") + ")" })
+			add(func() string { return "(" + sub("bool") + " || " + sub("bool") + ")" })
+		case "string":
+			add(func() string { return "(" + sub("string") + " + " + sub("string") + ")" })
+		case "[]uint64":
+			add(func() string { return "append(" + sub("[]uint64") + ", " + sub("uint64") + ")" })
+			add(func() string { return "[]uint64{" + sub("uint64") + ", " + sub("uint64") + "}" })
+			for _, v := range sc.varsOf("[]uint64") {
+				v := v
+				add(func() string { return v.name + "[" + sub("uint64") + ":]" })
+			}
+		case closureType:
+			add(func() string { return g.closure(sc, depth-1) })
+		}
+		if s, ok := g.structOf(ty); ok {
+			if strings.HasPrefix(ty, "*") {
+				add(func() string { return "&" + g.structLit(sc, s, depth) })
+				add(func() string { return "new(" + s.name + ")" })
+			} else {
+				add(func() string { return g.structLit(sc, s, depth) })
+				for _, v := range sc.varsOf("*" + s.name) {
+					v := v
+					add(func() string { return "*" + v.name })
+				}
+			}
+		}
+		for _, f := range g.funcs {
+			if f.ret == ty {
+				f := f
+				add(func() string { return g.call(sc, f, depth-1) })
+			}
+		}
+	}
+	return opts[g.choose(len(opts))]()
+}
+
+func (g *progGen) call(sc genScope, f genFunc, depth int) string {
+	var args []string
+	for _, ty := range f.params {
+		args = append(args, g.expr(sc, ty, depth))
+	}
+	return f.name + "(" + strings.Join(args, ", ") + ")"
+}
+
+// closure generates a function literal of closureType, with its body indented
+// one level from the current line
+func (g *progGen) closure(sc genScope, depth int) string {
+	param := g.name("a")
+	inner := genScope{
+		vars: append(append([]genVar{}, sc.vars...), genVar{param, "uint64"}),
+		ret:  "uint64",
+	}
+	start := g.b.Len()
+	g.indent++
+	g.block(inner, nil, depth, 1+g.choose(2))
+	g.line("return %s", g.expr(inner, "uint64", depth))
+	g.indent--
+	body := string(g.b.Bytes()[start:])
+	g.b.Truncate(start)
+	return fmt.Sprintf("func(%s uint64) uint64 {\n%s%s}",
+		param, body, strings.Repeat("\t", g.indent))
+}
+
+// block generates n statements in a new scope with the variables vars,
+// using those and the variables the statements declare at the end so they are
+// not unused
+func (g *progGen) block(sc genScope, vars []genVar, depth int, n int) {
+	outer := len(sc.vars)
+	sc.vars = append(append([]genVar{}, sc.vars...), vars...)
+	for i := 0; i < n; i++ {
+		g.stmt(&sc, depth)
+	}
+	for _, v := range sc.vars[outer:] {
+		g.line("_ = %s", v.name)
+	}
+}
+
+// nested generates a block within braces, starting on the current line
+func (g *progGen) nested(header string, sc genScope, vars []genVar, depth int) {
+	g.line("%s {", header)
+	g.indent++
+	g.block(sc, vars, depth-1, 1+g.choose(3))
+	g.indent--
+	g.line("}")
+}
+
+func (g *progGen) stmt(sc *genScope, depth int) {
+	declare := func(ty string) string {
+		v := genVar{g.name("x"), ty}
+		sc.vars = append(sc.vars, v)
+		return v.name
+	}
+	opts := []func(){func() {
+		ty := g.typ(depth > 0)
+		e := g.expr(*sc, ty, depth)
+		g.line("var %s %s = %s", declare(ty), ty, e)
+	}}
+	add := func(f func()) { opts = append(opts, f) }
+	for _, v := range sc.vars {
+		v := v
+		add(func() { g.line("%s = %s", v.name, g.expr(*sc, v.ty, depth)) })
+		switch v.ty {
+		case "[]uint64":
+			add(func() {
+				g.line("%s[%s] = %s", v.name, g.expr(*sc, "uint64", depth), g.expr(*sc, "uint64", depth))
+			})
+		case "map[uint64]uint64":
+			add(func() {
+				g.line("%s[%s] = %s", v.name, g.expr(*sc, "uint64", depth), g.expr(*sc, "uint64", depth))
+			})
+			add(func() {
+				k := g.expr(*sc, "uint64", depth)
+				g.line("%s, %s := %s[%s]", declare("uint64"), declare("bool"), v.name, k)
+			})
+		}
+	}
+	for _, f := range g.fieldsOf(*sc, "uint64") {
+		f := f
+		add(func() { g.line("%s = %s", f, g.expr(*sc, "uint64", depth)) })
+	}
+	for _, f := range g.funcs {
+		if f.ret == "" {
+			f := f
+			add(func() { g.line("%s", g.call(*sc, f, depth)) })
+		}
+	}
+	if sc.inLoop {
+		add(func() { g.line("%s", []string{"break", "continue"}[g.choose(2)]) })
+	}
+	if depth > 0 {
+		loop := *sc
+		loop.inLoop = true
+		add(func() {
+			cond := g.expr(*sc, "bool", depth-1)
+			g.nested("if "+cond, *sc, nil, depth)
+		})
+		add(func() {
+			cond := g.expr(*sc, "bool", depth-1)
+			g.line("if %s {", cond)
+			g.indent++
+			g.block(*sc, nil, depth-1, 1)
+			g.indent--
+			g.nested("} else", *sc, nil, depth)
+		})
+		if sc.ret != "" {
+			add(func() {
+				cond := g.expr(*sc, "bool", depth-1)
+				g.line("if %s {", cond)
+				g.line("\treturn %s", g.expr(*sc, sc.ret, depth-1))
+				g.line("}")
+			})
+		}
+		add(func() {
+			i := g.name("i")
+			g.nested(fmt.Sprintf("for %s := uint64(0); %s < %s; %s++",
+				i, i, g.expr(*sc, "uint64", depth-1), i),
+				loop, []genVar{{i, "uint64"}}, depth)
+		})
+		add(func() {
+			g.line("for {")
+			g.indent++
+			g.block(loop, nil, depth-1, 1+g.choose(2))
+			g.line("break")
+			g.indent--
+			g.line("}")
+		})
+		for _, v := range sc.varsOf("[]uint64") {
+			v := v
+			add(func() {
+				x := g.name("x")
+				g.nested(fmt.Sprintf("for _, %s := range %s", x, v.name),
+					loop, []genVar{{x, "uint64"}}, depth)
+			})
+		}
+		for _, v := range sc.varsOf("map[uint64]uint64") {
+			v := v
+			add(func() {
+				k, x := g.name("k"), g.name("x")
+				g.nested(fmt.Sprintf("for %s, %s := range %s", k, x, v.name),
+					loop, []genVar{{k, "uint64"}, {x, "uint64"}}, depth)
+			})
+		}
+		add(func() {
+			e := g.closure(*sc, depth-1)
+			g.line("var %s %s = %s", declare(closureType), closureType, e)
+		})
+	}
+	opts[g.choose(len(opts))]()
+}
+
+func (g *progGen) structDecl() {
+	s := genStruct{name: fmt.Sprintf("S%d", len(g.structs))}
+	for i := 0; i < 1+g.choose(3); i++ {
+		s.fields = append(s.fields, genVar{fmt.Sprintf("f%d", i), g.typ(false)})
+	}
+	g.line("type %s struct {", s.name)
+	for _, f := range s.fields {
+		g.line("\t%s %s", f.name, f.ty)
+	}
+	g.line("}")
+	g.line("")
+	g.structs = append(g.structs, s)
+}
+
+func (g *progGen) funcDecl() {
+	f := genFunc{name: fmt.Sprintf("F%d", len(g.funcs))}
+	sc := genScope{}
+	var params []string
+	for i := 0; i < g.choose(4); i++ {
+		p := genVar{g.name("p"), g.typ(false)}
+		f.params = append(f.params, p.ty)
+		sc.vars = append(sc.vars, p)
+		params = append(params, p.name+" "+p.ty)
+	}
+	if g.choose(3) > 0 {
+		f.ret = g.typ(false)
+		sc.ret = f.ret
+	}
+	g.line("func %s(%s) %s {", f.name, strings.Join(params, ", "), f.ret)
+	g.indent++
+	g.block(sc, nil, 2+g.choose(2), 1+g.choose(5))
+	if f.ret != "" {
+		g.line("return %s", g.expr(sc, f.ret, 2))
+	}
+	g.indent--
+	g.line("}")
+	g.line("")
+	// added after its body so that functions are not recursive
+	g.funcs = append(g.funcs, f)
+}
+
+// genProgram generates a Go package example from data
+func genProgram(data []byte) string {
+	g := &progGen{data: data}
+	g.line("package example")
+	g.line("")
+	for i := 0; i < g.choose(4); i++ {
+		g.structDecl()
+	}
+	for i := 0; i < 1+g.choose(5); i++ {
+		g.funcDecl()
+	}
+	return g.b.String()
+}
+
+// checkCoq checks that s is well-formed Coq, as far as matching delimiters,
+// strings and comments, and each sentence starting with a command.
+func checkCoq(s string) error {
+	if !utf8.ValidString(s) {
+		return fmt.Errorf("invalid UTF-8")
+	}
+	if strings.Contains(s, "%!") {
+		return fmt.Errorf("formatting error in output")
+	}
+	if strings.ContainsAny(s, "\uE000\uE001\uE002") {
+		return fmt.Errorf("source marker in output")
+	}
+	commands := []string{"From", "Section", "Context", "Local", "Definition",
+		"Axiom", "End"}
+	closing := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	var delims []rune
+	var sentence strings.Builder
+	line := 1
+	commentDepth := 0
+	inString := false
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		rest := s[i:]
+		i += size
+		if r == '\n' {
+			line++
+		}
+		switch {
+		case commentDepth > 0:
+			if strings.HasPrefix(rest, "(*") {
+				commentDepth++
+				i++
+			} else if strings.HasPrefix(rest, "*)") {
+				commentDepth--
+				i++
+			}
+			continue
+		case inString:
+			// quotes in Coq strings are escaped as ""
+			if r == '"' {
+				if strings.HasPrefix(s[i:], `"`) {
+					i++
+				} else {
+					inString = false
+				}
+			}
+		case strings.HasPrefix(rest, "(*"):
+			commentDepth++
+			i++
+			continue
+		case r == '"':
+			inString = true
+		case r == '(' || r == '[' || r == '{':
+			delims = append(delims, r)
+		case closing[r] != 0:
+			if len(delims) == 0 || delims[len(delims)-1] != closing[r] {
+				return fmt.Errorf("line %d: unmatched %c", line, r)
+			}
+			delims = delims[:len(delims)-1]
+		case r == '.' && len(delims) == 0 &&
+			(i == len(s) || unicode.IsSpace(rune(s[i]))):
+			fields := strings.Fields(sentence.String())
+			if len(fields) == 0 {
+				return fmt.Errorf("line %d: empty sentence", line)
+			}
+			known := false
+			for _, c := range commands {
+				known = known || fields[0] == c
+			}
+			if !known {
+				return fmt.Errorf("line %d: sentence starts with %s", line, fields[0])
+			}
+			sentence.Reset()
+			continue
+		}
+		sentence.WriteRune(r)
+	}
+	if commentDepth > 0 || inString || len(delims) > 0 {
+		return fmt.Errorf("unterminated comment, string or delimiter")
+	}
+	if strings.TrimSpace(sentence.String()) != "" {
+		return fmt.Errorf("unterminated sentence: %s", strings.TrimSpace(sentence.String()))
+	}
+	return nil
+}
+
+func TestCheckCoqGold(t *testing.T) {
+	for _, name := range []string{"unittest", "semantics", "wal", "comments"} {
+		gold := positiveTest{test: newTest("testdata/examples", name)}.Gold()
+		if err := checkCoq(gold); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	for _, bad := range []string{
+		"Definition x := (#().",
+		"Definition x := #()",
+		"Definition x := #(str \"a).",
+		"(* comment",
+		"Theorem x : True.",
+	} {
+		if checkCoq(bad) == nil {
+			t.Errorf("%q should be ill-formed", bad)
+		}
+	}
+}
+
+// translateProgram translates a single-file package with the source src,
+// returning the Coq output at the default width and at width.
+func translateProgram(t *testing.T, src string, width int) (coq, narrow string, errs []error) {
+	ctx := goose.NewCtx("example", goose.Config{})
+	f, err := parser.ParseFile(ctx.Fset, "example.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("generated program does not parse: %v\n%s", err, src)
+	}
+	if err := ctx.TypeCheck([]*ast.File{f}); err != nil {
+		t.Fatalf("generated program does not type check: %v\n%s", err, src)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("translation panicked: %v\n%s", r, src)
+		}
+	}()
+	imports, decls, errs := ctx.Decls(goose.NamedFile{Path: "example.go", Ast: f})
+	file := glang.File{
+		PkgPath:   "example",
+		GoPackage: "example",
+		Imports:   imports,
+		Decls:     decls,
+	}
+	var b bytes.Buffer
+	file.Write(&b)
+	coq = b.String()
+	b.Reset()
+	file.Width = width
+	file.Write(&b)
+	return coq, b.String(), errs
+}
+
+func removeSpace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
+// FuzzTranslate checks that goose translates random programs without
+// crashing. Goose may reject a program, but should do so with a translation
+// error; other panics are bugs. Successful translations should be well-formed
+// Coq, which the pretty-printer lays out the same (up to whitespace) at any
+// width.
+func FuzzTranslate(f *testing.F) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		data := make([]byte, 20+r.Intn(200))
+		r.Read(data)
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		src := genProgram(data)
+		width := 20
+		if len(data) > 0 {
+			width += int(data[len(data)-1])
+		}
+		coq, narrow, errs := translateProgram(t, src, width)
+		for _, err := range errs {
+			if _, ok := err.(*goose.ConversionError); !ok {
+				t.Fatalf("unexpected error: %v\n%s", err, src)
+			}
+		}
+		if len(errs) > 0 {
+			return
+		}
+		if err := checkCoq(coq); err != nil {
+			t.Fatalf("ill-formed Coq: %v\n%s\n%s", err, src, coq)
+		}
+		if removeSpace(coq) != removeSpace(narrow) {
+			t.Fatalf("output differs at width %d:\n%s\n%s", width, coq, narrow)
+		}
+	})
+}
@@ -120,19 +120,20 @@ type StructType struct {
 //
 // A struct declaration simply consists of the struct descriptor
 func (d StructType) Coq(needs_paren bool) string {
-	var pp buffer
-	pp.Add("structT [")
-	pp.Indent(2)
+	return renderText(d.coqDoc(needs_paren))
+}
+
+func (d StructType) coqDoc(needs_paren bool) doc {
+	var fields []doc
 	for i, fd := range d.Fields {
 		sep := ";"
 		if i == len(d.Fields)-1 {
 			sep = ""
 		}
-		pp.Add("%s :: %s%s", quote(fd.Name), fd.Type.Coq(false), sep)
+		fields = append(fields, hardLine, docText(quote(fd.Name)+" :: "),
+			exprDoc(fd.Type, false), docText(sep))
 	}
-	pp.Indent(-2)
-	pp.AddLine("]")
-	return pp.Build()
+	return concat(docText("structT ["), nest(2, fields...), hardLine, docText("]"))
 }
 
 type TypeDecl struct {
@@ -141,9 +142,12 @@ type TypeDecl struct {
 }
 
 func (d TypeDecl) CoqDecl() string {
-	var pp buffer
-	pp.Add("Definition %s : go_type := %s.", d.Name, d.Body.Coq(false))
-	return pp.Build()
+	return renderText(d.coqDeclDoc())
+}
+
+func (d TypeDecl) coqDeclDoc() doc {
+	return concat(docText("Definition "+d.Name+" : go_type := "),
+		exprDoc(d.Body, false), docText("."))
 }
 
 // Type represents some Coq type.
@@ -180,7 +184,11 @@ type MapType struct {
 }
 
 func (t MapType) Coq(needs_paren bool) string {
-	return NewCallExpr(GallinaIdent("mapT"), t.Key, t.Value).Coq(needs_paren)
+	return renderText(t.coqDoc(needs_paren))
+}
+
+func (t MapType) coqDoc(needs_paren bool) doc {
+	return NewCallExpr(GallinaIdent("mapT"), t.Key, t.Value).coqDoc(needs_paren)
 }
 
 type FuncType struct {
@@ -195,7 +203,11 @@ type SliceType struct {
 }
 
 func (t SliceType) Coq(needs_paren bool) string {
-	return NewCallExpr(GallinaIdent("sliceT"), t.Value).Coq(needs_paren)
+	return renderText(t.coqDoc(needs_paren))
+}
+
+func (t SliceType) coqDoc(needs_paren bool) doc {
+	return NewCallExpr(GallinaIdent("sliceT"), t.Value).coqDoc(needs_paren)
 }
 
 type ArrayType struct {
@@ -204,7 +216,11 @@ type ArrayType struct {
 }
 
 func (t ArrayType) Coq(needs_paren bool) string {
-	return NewCallExpr(GallinaIdent("arrayT"), t.Elt).Coq(needs_paren)
+	return renderText(t.coqDoc(needs_paren))
+}
+
+func (t ArrayType) coqDoc(needs_paren bool) doc {
+	return NewCallExpr(GallinaIdent("arrayT"), t.Elt).coqDoc(needs_paren)
 }
 
 type Expr interface {
@@ -212,6 +228,20 @@ type Expr interface {
 	Coq(needs_paren bool) string
 }
 
+// coqDocer is implemented by expressions and types whose Coq output is laid
+// out by the pretty-printer (rather than being fixed text).
+type coqDocer interface {
+	coqDoc(needs_paren bool) doc
+}
+
+// exprDoc is the doc for an Expr or Type
+func exprDoc(e Expr, needs_paren bool) doc {
+	if e, ok := e.(coqDocer); ok {
+		return e.coqDoc(needs_paren)
+	}
+	return textDoc(e.Coq(needs_paren))
+}
+
 // GallinaIdent is a identifier in Gallina (and not a variable)
 //
 // A GallinaIdent is translated literally to Coq.
@@ -249,7 +279,11 @@ type ParenExpr struct {
 }
 
 func (e ParenExpr) Coq(needs_paren bool) string {
-	return fmt.Sprintf("(%s)", e.Inner.Coq(needs_paren))
+	return renderText(e.coqDoc(needs_paren))
+}
+
+func (e ParenExpr) coqDoc(needs_paren bool) doc {
+	return concat(docText("("), exprDoc(e.Inner, needs_paren), docText(")"))
 }
 
 // IdentExpr is a go_lang-level variable
@@ -287,12 +321,18 @@ func NewCallExpr(name Expr, args ...Expr) CallExpr {
 }
 
 func (s CallExpr) Coq(needs_paren bool) string {
-	comps := []string{s.MethodName.Coq(true)}
+	return renderText(s.coqDoc(needs_paren))
+}
 
+// coqDoc for a call puts every argument on its own line if the call doesn't
+// fit on one line
+func (s CallExpr) coqDoc(needs_paren bool) doc {
+	var args []doc
 	for _, a := range s.Args {
-		comps = append(comps, a.Coq(true))
+		args = append(args, indentedLine(exprDoc(a, true)))
 	}
-	return addParens(needs_paren, strings.Join(comps, " "))
+	return parensDoc(needs_paren,
+		group(exprDoc(s.MethodName, true), concat(args...)))
 }
 
 func StructDesc(name string) Expr {
@@ -318,7 +358,11 @@ type ReturnExpr struct {
 }
 
 func (e ReturnExpr) Coq(needs_paren bool) string {
-	return fmt.Sprintf("return: %s", e.Value.Coq(needs_paren))
+	return renderText(e.coqDoc(needs_paren))
+}
+
+func (e ReturnExpr) coqDoc(needs_paren bool) doc {
+	return concat(docText("return: "), exprDoc(e.Value, needs_paren))
 }
 
 type DoExpr struct {
@@ -326,9 +370,11 @@ type DoExpr struct {
 }
 
 func (b DoExpr) Coq(needs_paren bool) string {
-	var pp buffer
-	pp.Add("do:  %s", b.Expr.Coq(false))
-	return addParens(needs_paren, pp.Build())
+	return renderText(b.coqDoc(needs_paren))
+}
+
+func (b DoExpr) coqDoc(needs_paren bool) doc {
+	return parensDoc(needs_paren, concat(docText("do:  "), exprDoc(b.Expr, false)))
 }
 
 type LetExpr struct {
@@ -349,36 +395,47 @@ func (e LetExpr) isAnonymous() bool {
 }
 
 func (b LetExpr) Coq(needs_paren bool) string {
-	var pp buffer
+	return renderText(b.coqDoc(needs_paren))
+}
+
+// letBinders is the pattern bound by a let
+func (b LetExpr) letBinders() string {
 	// Printing for anonymous and multiple return values
-	// TODO: exception monad sequencing should be its own thing, not an anonymous let binding.
-	if b.isAnonymous() {
-		pp.Add("%s;;;", b.ValExpr.Coq(false))
-	} else if len(b.Names) == 1 {
-		pp.Add("let: %s := %s in", binder(b.Names[0]), b.ValExpr.Coq(false))
-	} else if len(b.Names) == 2 {
-		pp.Add("let: (%s, %s) := %s in",
+	switch len(b.Names) {
+	case 1:
+		return binder(b.Names[0])
+	case 2:
+		return fmt.Sprintf("(%s, %s)",
 			binder(b.Names[0]),
-			binder(b.Names[1]),
-			b.ValExpr.Coq(false))
-	} else if len(b.Names) == 3 {
-		pp.Add("let: ((%s, %s), %s) := %s in",
+			binder(b.Names[1]))
+	case 3:
+		return fmt.Sprintf("((%s, %s), %s)",
 			binder(b.Names[0]),
 			binder(b.Names[1]),
-			binder(b.Names[2]),
-			b.ValExpr.Coq(false))
-	} else if len(b.Names) == 4 {
-		pp.Add("let: (((%s, %s), %s), %s) := %s in",
+			binder(b.Names[2]))
+	case 4:
+		return fmt.Sprintf("(((%s, %s), %s), %s)",
 			binder(b.Names[0]),
 			binder(b.Names[1]),
 			binder(b.Names[2]),
-			binder(b.Names[3]),
-			b.ValExpr.Coq(false))
+			binder(b.Names[3]))
+	}
+	panic("no support for destructuring more than 4 return values")
+}
+
+// coqDoc for a let moves the bound expression to the next line if the binding
+// doesn't fit on one line
+func (b LetExpr) coqDoc(needs_paren bool) doc {
+	var binding doc
+	// TODO: exception monad sequencing should be its own thing, not an anonymous let binding.
+	if b.isAnonymous() {
+		binding = concat(exprDoc(b.ValExpr, false), docText(";;;"))
 	} else {
-		panic("no support for destructuring more than 4 return values")
+		binding = group(docText("let: "+b.letBinders()+" :="),
+			indentedLine(exprDoc(b.ValExpr, false)), docText(" in"))
 	}
-	pp.Add(b.Cont.Coq(false))
-	return addParens(needs_paren, pp.Build())
+	return parensDoc(needs_paren,
+		concat(binding, hardLine, exprDoc(b.Cont, false)))
 }
 
 type fieldVal struct {
@@ -405,21 +462,34 @@ func (sl *StructLiteral) AddField(field string, value Expr) {
 	sl.elts = append(sl.elts, fieldVal{field, value})
 }
 
+// Fields returns the names of the literal's fields and their values, in the
+// order they were added.
+func (sl StructLiteral) Fields() (names []string, values []Expr) {
+	for _, f := range sl.elts {
+		names = append(names, f.Field)
+		values = append(values, f.Value)
+	}
+	return
+}
+
 func (sl StructLiteral) Coq(needs_paren bool) string {
-	var pp buffer
+	return renderText(sl.coqDoc(needs_paren))
+}
+
+func (sl StructLiteral) coqDoc(needs_paren bool) doc {
 	method := "struct.make"
-	pp.Add("%s %s [{", method, StructDesc(sl.StructName).Coq(true))
-	pp.Indent(2)
+	var fields []doc
 	for i, f := range sl.elts {
 		terminator := ";"
 		if i == len(sl.elts)-1 {
 			terminator = ""
 		}
-		pp.Add("%s ::= %s%s", quote(f.Field), f.Value.Coq(false), terminator)
+		fields = append(fields, hardLine, group(docText(quote(f.Field)+" ::="),
+			indentedLine(exprDoc(f.Value, false)), docText(terminator)))
 	}
-	pp.Indent(-2)
-	pp.Add("}]")
-	return addParens(needs_paren, pp.Build())
+	return parensDoc(needs_paren, concat(
+		docText(method+" "), exprDoc(StructDesc(sl.StructName), true), docText(" [{"),
+		nest(2, fields...), hardLine, docText("}]")))
 }
 
 type BoolLiteral bool
@@ -486,6 +556,21 @@ func (l nullLiteral) Coq(needs_paren bool) string {
 // Null represents a nil pointer in Go
 var Null = nullLiteral{}
 
+// ErrorExpr is a placeholder for code that failed to translate, which allows
+// translation to continue and find more errors. Output with an ErrorExpr is
+// never used.
+type ErrorExpr struct {
+	Message string
+}
+
+func (e ErrorExpr) Coq(needs_paren bool) string {
+	msg := strings.ReplaceAll(e.Message, `"`, `""`)
+	return addParens(needs_paren, fmt.Sprintf(`Panic "goose error: %s"`, msg))
+}
+
+// InterfaceNil represents a nil interface value in Go
+var InterfaceNil Expr = GallinaIdent("interface.nil")
+
 // BinOp is an enum for a Coq binary operator
 type BinOp int
 
@@ -512,6 +597,36 @@ const (
 	OpShr
 )
 
+var binOpNames = map[BinOp]string{
+	OpPlus:        "plus",
+	OpMinus:       "minus",
+	OpEquals:      "eq",
+	OpNotEquals:   "ne",
+	OpLessThan:    "lt",
+	OpGreaterThan: "gt",
+	OpLessEq:      "le",
+	OpGreaterEq:   "ge",
+	OpAppend:      "append",
+	OpMul:         "mul",
+	OpQuot:        "quot",
+	OpRem:         "rem",
+	OpAnd:         "and",
+	OpOr:          "or",
+	OpXor:         "xor",
+	OpLAnd:        "land",
+	OpLOr:         "lor",
+	OpShl:         "shl",
+	OpShr:         "shr",
+}
+
+// String gives a name for op, used by backends other than Coq
+func (op BinOp) String() string {
+	if name, ok := binOpNames[op]; ok {
+		return name
+	}
+	panic(fmt.Sprintf("unknown binop %d", op))
+}
+
 type BinaryExpr struct {
 	X  Expr
 	Op BinOp
@@ -519,6 +634,12 @@ type BinaryExpr struct {
 }
 
 func (be BinaryExpr) Coq(needs_paren bool) string {
+	return renderText(be.coqDoc(needs_paren))
+}
+
+// coqDoc for a binary expression breaks after the operator if the expression
+// doesn't fit on one line
+func (be BinaryExpr) coqDoc(needs_paren bool) doc {
 	coqBinOp := map[BinOp]string{
 		OpPlus:        "+",
 		OpMinus:       "-",
@@ -541,9 +662,8 @@ func (be BinaryExpr) Coq(needs_paren bool) string {
 		OpShr:         "≫",
 	}
 	if binop, ok := coqBinOp[be.Op]; ok {
-		expr := fmt.Sprintf("%s %s %s",
-			be.X.Coq(true), binop, be.Y.Coq(true))
-		return addParens(needs_paren, expr)
+		return parensDoc(needs_paren, group(exprDoc(be.X, true),
+			docText(" "+binop), indentedLine(exprDoc(be.Y, true))))
 	}
 
 	panic(fmt.Sprintf("unknown binop %d", be.Op))
@@ -554,18 +674,25 @@ type NotExpr struct {
 }
 
 func (e NotExpr) Coq(needs_paren bool) string {
-	return fmt.Sprintf("(~ %s)", e.X.Coq(true))
+	return renderText(e.coqDoc(needs_paren))
+}
+
+func (e NotExpr) coqDoc(needs_paren bool) doc {
+	return concat(docText("(~ "), exprDoc(e.X, true), docText(")"))
 }
 
 type TupleExpr []Expr
 
 func (te TupleExpr) Coq(needs_paren bool) string {
-	var comps []string
+	return renderText(te.coqDoc(needs_paren))
+}
+
+func (te TupleExpr) coqDoc(needs_paren bool) doc {
+	var comps []doc
 	for _, t := range te {
-		comps = append(comps, t.Coq(false))
+		comps = append(comps, exprDoc(t, false))
 	}
-	return fmt.Sprintf("(%s)",
-		indent(1, strings.Join(comps, ", ")))
+	return group(docText("("), alignBroken(join(comps, docText(","), softLine)), docText(")"))
 }
 
 // NewTuple is a smart constructor that wraps multiple expressions in a TupleExpr
@@ -579,18 +706,22 @@ func NewTuple(es []Expr) Expr {
 type ListExpr []Expr
 
 func (le ListExpr) Coq(needs_paren bool) string {
-	var comps []string
+	return renderText(le.coqDoc(needs_paren))
+}
+
+func (le ListExpr) coqDoc(needs_paren bool) doc {
+	var comps []doc
 	for _, t := range le {
-		comps = append(comps, t.Coq(false))
+		comps = append(comps, exprDoc(t, false))
 	}
-	elements := indent(1, strings.Join(comps, "; "))
-	if strings.HasPrefix(elements, "#") {
+	elements := join(comps, docText(";"), softLine)
+	if len(le) > 0 && strings.HasPrefix(le[0].Coq(false), "#") {
 		// [# ...] is a vector notation while we want the list notation [ ... ]
 		// (and `[#` is a single token even if the vector notation isn't in
 		// scope)
-		return fmt.Sprintf("[ %s ]", elements)
+		return group(docText("[ "), alignBroken(elements), docText(" ]"))
 	}
-	return fmt.Sprintf("[%s]", elements)
+	return group(docText("["), alignBroken(elements), docText("]"))
 }
 
 type DerefExpr struct {
@@ -599,8 +730,12 @@ type DerefExpr struct {
 }
 
 func (e DerefExpr) Coq(needs_paren bool) string {
-	expr := fmt.Sprintf("![%s] %s", e.Ty.Coq(false), e.X.Coq(true))
-	return addParens(needs_paren, expr)
+	return renderText(e.coqDoc(needs_paren))
+}
+
+func (e DerefExpr) coqDoc(needs_paren bool) doc {
+	return parensDoc(needs_paren, concat(docText("!["), exprDoc(e.Ty, false),
+		docText("] "), exprDoc(e.X, true)))
 }
 
 type RefExpr struct {
@@ -609,7 +744,11 @@ type RefExpr struct {
 }
 
 func (e RefExpr) Coq(needs_paren bool) string {
-	return NewCallExpr(GallinaIdent("ref_ty"), e.Ty, e.X).Coq(needs_paren)
+	return renderText(e.coqDoc(needs_paren))
+}
+
+func (e RefExpr) coqDoc(needs_paren bool) doc {
+	return NewCallExpr(GallinaIdent("ref_ty"), e.Ty, e.X).coqDoc(needs_paren)
 }
 
 type StoreStmt struct {
@@ -619,8 +758,13 @@ type StoreStmt struct {
 }
 
 func (e StoreStmt) Coq(needs_paren bool) string {
-	expr := fmt.Sprintf("%s <-[%s] %s", e.Dst.Coq(true), e.Ty.Coq(false), e.X.Coq(true))
-	return addParens(needs_paren, expr)
+	return renderText(e.coqDoc(needs_paren))
+}
+
+func (e StoreStmt) coqDoc(needs_paren bool) doc {
+	return parensDoc(needs_paren, group(exprDoc(e.Dst, true),
+		docText(" <-["), exprDoc(e.Ty, false), docText("]"),
+		indentedLine(exprDoc(e.X, true))))
 }
 
 type IfExpr struct {
@@ -629,28 +773,27 @@ type IfExpr struct {
 	Else Expr
 }
 
-func flowBranch(pp *buffer, prefix string, e Expr, suffix string) {
-	code := e.Coq(false) + suffix
-	if !strings.ContainsRune(code, '\n') {
-		// compact, single-line form
-		indent := pp.Block(prefix+" ", "%s", code)
-		pp.Indent(-indent)
-		return
+// flowBranch is a branch of an if, which is on the line of prefix if it fits
+// there and otherwise indented on the following lines
+func flowBranch(prefix string, e Expr, suffix string) doc {
+	code := concat(exprDoc(e, false), docText(suffix))
+	if hasHardLine(code) {
+		// full multiline, nicely indented form
+		return concat(docText(prefix), nest(2, hardLine, code))
 	}
-	// full multiline, nicely indented form
-	pp.AddLine(prefix)
-	pp.Indent(2)
-	pp.AddLine(code)
-	pp.Indent(-2)
+	// compact, single-line form if it fits
+	return group(docText(prefix), indentedLine(code))
 }
 
 func (ife IfExpr) Coq(needs_paren bool) string {
-	var pp buffer
+	return renderText(ife.coqDoc(needs_paren))
+}
+
+func (ife IfExpr) coqDoc(needs_paren bool) doc {
 	// Since we are parenthesesizing all if, we don't need to parenthesize the things inside the if
-	pp.Add("(if: %s", ife.Cond.Coq(false))
-	flowBranch(&pp, "then", ife.Then, "")
-	flowBranch(&pp, "else", ife.Else, ")")
-	return pp.Build()
+	return concat(docText("(if: "), exprDoc(ife.Cond, false),
+		hardLine, flowBranch("then", ife.Then, ""),
+		hardLine, flowBranch("else", ife.Else, ")"))
 }
 
 // The init statement must wrap the ForLoopExpr, so it can make use of bindings
@@ -663,11 +806,13 @@ type ForLoopExpr struct {
 }
 
 func (e ForLoopExpr) Coq(needs_paren bool) string {
-	var pp buffer
-	pp.Add("(for: (λ: <>, %s); (λ: <>, %s) := λ: <>,", e.Cond.Coq(false), e.Post.Coq(false))
-	pp.Indent(2)
-	pp.Add("%s)", e.Body.Coq(false))
-	return pp.Build()
+	return renderText(e.coqDoc(needs_paren))
+}
+
+func (e ForLoopExpr) coqDoc(needs_paren bool) doc {
+	return concat(docText("(for: (λ: <>, "), exprDoc(e.Cond, false),
+		docText("); (λ: <>, "), exprDoc(e.Post, false), docText(") := λ: <>,"),
+		nest(2, hardLine, exprDoc(e.Body, false), docText(")")))
 }
 
 type ForRangeSliceExpr struct {
@@ -679,22 +824,26 @@ type ForRangeSliceExpr struct {
 }
 
 func (e ForRangeSliceExpr) Coq(needs_paren bool) string {
-	var pp buffer
-	pp.Add("slice.for_range %s %s (λ: %s %s,",
-		e.Ty.Coq(true),
-		e.Slice.Coq(true),
-		binderToCoq(e.Key), binderToCoq(e.Val),
-	)
-	pp.Indent(2)
+	return renderText(e.coqDoc(needs_paren))
+}
+
+func (e ForRangeSliceExpr) coqDoc(needs_paren bool) doc {
+	var body []doc
 	if e.Key != nil && *e.Key != "_" {
-		pp.Add("let: %s := ref_ty uint64T %s in", binderToCoq(e.Key), binderToCoq(e.Key))
+		body = append(body, hardLine, docText(fmt.Sprintf("let: %s := ref_ty uint64T %s in",
+			binderToCoq(e.Key), binderToCoq(e.Key))))
 	}
 	if e.Val != nil && *e.Val != "_" {
-		pp.Add("let: %s := ref_ty %s %s in", binderToCoq(e.Val), e.Ty.Coq(true), binderToCoq(e.Val))
+		body = append(body, hardLine,
+			docText("let: "+binderToCoq(e.Val)+" := ref_ty "), exprDoc(e.Ty, true),
+			docText(" "+binderToCoq(e.Val)+" in"))
 	}
-	pp.Add("%s)", e.Body.Coq(false))
-	pp.Indent(-2)
-	return addParens(needs_paren, pp.Build())
+	body = append(body, hardLine, exprDoc(e.Body, false), docText(")"))
+	return parensDoc(needs_paren, concat(
+		docText("slice.for_range "), exprDoc(e.Ty, true),
+		docText(" "), exprDoc(e.Slice, true),
+		docText(fmt.Sprintf(" (λ: %s %s,", binderToCoq(e.Key), binderToCoq(e.Val))),
+		nest(2, body...)))
 }
 
 type Binder *IdentExpr
@@ -717,13 +866,14 @@ type ForRangeMapExpr struct {
 }
 
 func (e ForRangeMapExpr) Coq(needs_paren bool) string {
-	var pp buffer
-	pp.Add("MapIter %s (λ: %s %s,",
-		e.Map.Coq(true),
-		binder(e.KeyIdent), binder(e.ValueIdent))
-	pp.Indent(2)
-	pp.Add("%s)", e.Body.Coq(false))
-	return addParens(needs_paren, pp.Build())
+	return renderText(e.coqDoc(needs_paren))
+}
+
+func (e ForRangeMapExpr) coqDoc(needs_paren bool) doc {
+	return parensDoc(needs_paren, concat(
+		docText("MapIter "), exprDoc(e.Map, true),
+		docText(fmt.Sprintf(" (λ: %s %s,", binder(e.KeyIdent), binder(e.ValueIdent))),
+		nest(2, hardLine, exprDoc(e.Body, false), docText(")"))))
 }
 
 // SpawnExpr is a call to Spawn a thread running a procedure.
@@ -734,9 +884,37 @@ type SpawnExpr struct {
 }
 
 func (e SpawnExpr) Coq(needs_paren bool) string {
-	var pp buffer
-	pp.Block("Fork (", "%s)", e.Body.Coq(false))
-	return addParens(needs_paren, pp.Build())
+	return renderText(e.coqDoc(needs_paren))
+}
+
+func (e SpawnExpr) coqDoc(needs_paren bool) doc {
+	prefix := "Fork ("
+	return parensDoc(needs_paren, concat(docText(prefix),
+		nest(len(prefix), exprDoc(e.Body, false), docText(")"))))
+}
+
+// DeferExpr pushes a call onto the defer stack of the enclosing function.
+//
+// The defer stack is a single function "$defer" that runs all the deferred
+// calls in reverse order; it is set up by with_defer:, which calls it when the
+// function returns or panics.
+type DeferExpr struct {
+	// Body is the deferred call, whose function and arguments must already be
+	// evaluated
+	Body Expr
+}
+
+func (e DeferExpr) Coq(needs_paren bool) string {
+	return renderText(e.coqDoc(needs_paren))
+}
+
+func (e DeferExpr) coqDoc(needs_paren bool) doc {
+	return parensDoc(needs_paren, concat(
+		docText(`"$defer" <-[funcT] (let: "$oldf" := ![funcT] "$defer" in`),
+		nest(2, hardLine, docText("(λ: <>,"),
+			nest(2, hardLine, exprDoc(e.Body, false), docText(";;"),
+				hardLine, docText(`"$oldf" #()`)),
+			hardLine, docText("))"))))
 }
 
 // FuncLit is an unnamed function literal, consisting of its parameters and body.
@@ -748,8 +926,10 @@ type FuncLit struct {
 }
 
 func (e FuncLit) Coq(needs_paren bool) string {
-	var pp buffer
+	return renderText(e.coqDoc(needs_paren))
+}
 
+func (e FuncLit) coqDoc(needs_paren bool) doc {
 	var args []string
 	for _, a := range e.Args {
 		args = append(args, a.CoqBinder())
@@ -759,13 +939,8 @@ func (e FuncLit) Coq(needs_paren bool) string {
 	}
 	sig := strings.Join(args, " ")
 
-	pp.Add("(λ: %s,", sig)
-	pp.Indent(2)
-	defer pp.Indent(-2)
-	pp.AddLine(e.Body.Coq(false))
-	pp.Add(")")
-
-	return pp.Build()
+	return concat(docText("(λ: "+sig+","),
+		nest(2, hardLine, exprDoc(e.Body, false), hardLine, docText(")")))
 }
 
 // FuncDecl declares a function, including its parameters and body.
@@ -814,6 +989,10 @@ func (d FuncDecl) Type() string {
 // For FuncDecl this emits the Coq vernacular Definition that defines the whole
 // function.
 func (d FuncDecl) CoqDecl() string {
+	return renderText(d.coqDeclDoc())
+}
+
+func (d FuncDecl) coqDeclDoc() doc {
 	var pp buffer
 	pp.AddComment(d.Comment)
 
@@ -823,19 +1002,72 @@ func (d FuncDecl) CoqDecl() string {
 	}
 
 	pp.Add("Definition %s %s: val :=", d.Name, typeParams)
-	func() {
-		pp.Indent(2)
-		defer pp.Indent(-2)
-		pp.Add("rec: \"%s\" %s :=", d.Name, d.Signature())
-		pp.Indent(2)
-		defer pp.Indent(-2)
-		pp.AddLine(d.Body.Coq(false) + ".")
-	}()
+	decl := concat(textDoc(pp.Build()), nest(2,
+		hardLine, docText(fmt.Sprintf("rec: \"%s\" %s :=", d.Name, d.Signature())),
+		nest(2, hardLine, exprDoc(d.Body, false), docText("."))))
 	if d.AddTypes {
+		pp = buffer{}
 		pp.Add("Theorem %s_t: ⊢ %s : (%s).", d.Name, d.Name, d.Type())
 		pp.AddLine("Proof. typecheck. Qed.")
 		pp.Add("Hint Resolve %s_t : types.", d.Name)
+		decl = concat(decl, hardLine, textDoc(pp.Build()))
+	}
+	return decl
+}
+
+// OpaqueDecl declares a function without giving its definition, for Go
+// functions that should not be translated.
+type OpaqueDecl struct {
+	Name       string
+	TypeParams []TypeIdent
+	Comment    string
+	// SectionVariable is set when the declaration is in the code section of a
+	// package without an FFI, where val depends on the section's ffi_syntax
+	// and the function must be a variable of the section rather than an axiom
+	SectionVariable bool
+}
+
+// CoqDecl implements the Decl interface
+//
+// For OpaqueDecl this emits an Axiom for the function, or a Context variable
+// inside a section.
+func (d OpaqueDecl) CoqDecl() string {
+	var pp buffer
+	pp.AddComment(d.Comment)
+	ty := "val"
+	for range d.TypeParams {
+		ty = "go_type → " + ty
+	}
+	if d.SectionVariable {
+		pp.Add("Context (%s : %s).", d.Name, ty)
+	} else {
+		pp.Add("Axiom %s : %s.", d.Name, ty)
+	}
+	return pp.Build()
+}
+
+// ExternDecl defines a function to be an existing Coq definition.
+type ExternDecl struct {
+	Name       string
+	TypeParams []TypeIdent
+	Extern     string
+	Comment    string
+}
+
+// CoqDecl implements the Decl interface
+//
+// For ExternDecl this emits a Definition that refers to the extern (passing it
+// any type parameters).
+func (d ExternDecl) CoqDecl() string {
+	var pp buffer
+	pp.AddComment(d.Comment)
+	typeParams := ""
+	val := d.Extern
+	for _, t := range d.TypeParams {
+		typeParams += fmt.Sprintf("(%s: go_type) ", t.Coq(false))
+		val += " " + t.Coq(true)
 	}
+	pp.Add("Definition %s %s: val := %s.", d.Name, typeParams, val)
 	return pp.Build()
 }
 
@@ -868,24 +1100,117 @@ type ConstDecl struct {
 }
 
 func (d ConstDecl) CoqDecl() string {
-	var pp buffer
-	pp.AddComment(d.Comment)
-	indent := pp.Block("Definition ", "%s : expr := %s.",
-		d.Name, d.Val.Coq(false))
-	pp.Indent(-indent)
+	return renderText(d.coqDeclDoc())
+}
+
+func (d ConstDecl) coqDeclDoc() doc {
+	var decl []doc
+	if d.Comment != "" {
+		var pp buffer
+		pp.AddComment(d.Comment)
+		decl = append(decl, textDoc(pp.Build()), hardLine)
+	}
+	prefix := "Definition "
+	decl = append(decl, docText(prefix), nest(len(prefix),
+		docText(d.Name+" : expr := "), exprDoc(d.Val, false), docText(".")))
 	if d.AddTypes {
+		var pp buffer
 		pp.Add("Theorem %s_t Γ : Γ ⊢ %s : %s.",
 			d.Name, d.Name, d.Type.Coq(true))
 		pp.AddLine("Proof. typecheck. Qed.")
+		decl = append(decl, hardLine, textDoc(pp.Build()))
+	}
+	return concat(decl...)
+}
+
+// MethodSetDecl is the method table for a type, which interface values of
+// that dynamic type carry to look up their methods.
+type MethodSetDecl struct {
+	Name    string
+	Methods []MethodSetEntry
+}
+
+// MethodSetEntry is a method in a method table, which takes the receiver and
+// returns the method's closure.
+type MethodSetEntry struct {
+	Name   string
+	Method string
+	// Deref is the receiver type for a value method in the table for
+	// pointers, which loads the receiver from the pointer (nil otherwise)
+	Deref Type
+	// Ref refers to the method where its definition cannot be used, such as
+	// in a table built inside one of the type's own methods (nil otherwise)
+	Ref Expr
+}
+
+// ref is the expression that refers to the entry's method
+func (m MethodSetEntry) ref() Expr {
+	if m.Ref != nil {
+		return m.Ref
+	}
+	return GallinaIdent(m.Method)
+}
+
+// Fn is the function the entry maps the method's name to
+func (m MethodSetEntry) Fn() Expr {
+	if m.Deref == nil {
+		return m.ref()
+	}
+	return FuncLit{
+		Args: []FieldDecl{{Name: "$recvAddr"}},
+		Body: NewCallExpr(m.ref(),
+			DerefExpr{X: IdentExpr("$recvAddr"), Ty: m.Deref}),
 	}
-	return pp.Build()
 }
 
-// Decl is a FuncDecl, StructDecl, CommentDecl, or ConstDecl
+func (m MethodSetEntry) coqDoc() doc {
+	if m.Deref == nil {
+		return docText(m.Method + "%V")
+	}
+	return group(docText(`(λ: "$recvAddr",`), indentedLine(concat(
+		docText(m.Method+" "),
+		DerefExpr{X: IdentExpr("$recvAddr"), Ty: m.Deref}.coqDoc(true),
+		docText(")%V"))))
+}
+
+func (d MethodSetDecl) CoqDecl() string {
+	return renderText(d.coqDeclDoc())
+}
+
+func (d MethodSetDecl) coqDeclDoc() doc {
+	header := docText("Definition " + d.Name + " : list (string * val) := [")
+	if len(d.Methods) == 0 {
+		return concat(header, docText("]."))
+	}
+	var entries []doc
+	for _, m := range d.Methods {
+		entries = append(entries,
+			concat(docText("("+quote(m.Name)+", "), m.coqDoc(), docText(")")))
+	}
+	return concat(header,
+		nest(2, hardLine, join(entries, docText(";"), hardLine)),
+		hardLine, docText("]."))
+}
+
+// Decl is a FuncDecl, StructDecl, CommentDecl, ConstDecl, OpaqueDecl,
+// ExternDecl or MethodSetDecl
 type Decl interface {
 	CoqDecl() string
 }
 
+// coqDeclDocer is implemented by declarations laid out by the pretty-printer
+type coqDeclDocer interface {
+	coqDeclDoc() doc
+}
+
+// declDoc is the doc for a Decl
+func declDoc(d Decl) doc {
+	if d, ok := d.(coqDeclDocer); ok {
+		return d.coqDeclDoc()
+	}
+	return textDoc(d.CoqDecl())
+}
+
 type TupleType []Type
 
 // NewTupleType is a smart constructor that wraps multiple types in a TupleType
@@ -897,11 +1222,15 @@ func NewTupleType(types []Type) Type {
 }
 
 func (tt TupleType) Coq(needs_paren bool) string {
-	var comps []string
+	return renderText(tt.coqDoc(needs_paren))
+}
+
+func (tt TupleType) coqDoc(needs_paren bool) doc {
+	var comps []doc
 	for _, t := range tt {
-		comps = append(comps, t.Coq(true))
+		comps = append(comps, exprDoc(t, true))
 	}
-	return fmt.Sprintf("(%s)", strings.Join(comps, " * "))
+	return concat(docText("("), join(comps, docText(" * ")), docText(")"))
 }
 
 type PtrType struct {
@@ -915,6 +1244,15 @@ func TypeMethod(typeName string, methodName string) string {
 	return fmt.Sprintf("%s__%s", typeName, methodName)
 }
 
+// MethodSetName is the name of the method table of typeName, or of pointers
+// to typeName if ptr is set
+func MethodSetName(typeName string, ptr bool) string {
+	if ptr {
+		return typeName + "__mset_ptr"
+	}
+	return typeName + "__mset"
+}
+
 func InterfaceMethod(interfaceName string, methodName string) string {
 	return fmt.Sprintf("(struct.get %s \"%s\")", interfaceName, methodName)
 }
@@ -956,6 +1294,15 @@ func ImportToPath(pkgPath, pkgName string) string {
 	return filepath.Join(p, filename)
 }
 
+// PackageQualifier is the name used in Coq to refer to declarations of a
+// package: the last component of the Coq path the package is imported with.
+//
+// This is not always the Go package name, which may differ from the last
+// component of the import path (and a package can be imported under any name).
+func PackageQualifier(pkgPath string) string {
+	return path.Base(thisIsBadAndShouldBeDeprecatedGoPathToCoqPath(pkgPath))
+}
+
 func (decl ImportDecl) CoqDecl() string {
 	coqImportQualid := strings.ReplaceAll(thisIsBadAndShouldBeDeprecatedGoPathToCoqPath(decl.Path), "/", ".")
 	return fmt.Sprintf("From New.code Require %s.", coqImportQualid)
@@ -985,8 +1332,13 @@ type File struct {
 	Footer       string
 	PkgPath      string
 	GoPackage    string
-	Imports      ImportDecls
-	Decls        []Decl
+	// Ffis are the FFIs the package uses, which other backends import in
+	// place of ImportHeader
+	Ffis    []string
+	Imports ImportDecls
+	Decls   []Decl
+	// Width is the line width to print the file to (0 for DefaultWidth)
+	Width int
 }
 
 func (f File) autogeneratedNotice() CommentDecl {
@@ -1004,8 +1356,14 @@ func stringInSlice(a string, list []string) bool {
 }
 
 // Write outputs the Coq source for a File.
-// noinspection GoUnhandledErrorResult
 func (f File) Write(w io.Writer) {
+	io.WriteString(w, stripMarkers(f.coqSource()))
+}
+
+// coqSource is the Coq source for a File, including source markers.
+// noinspection GoUnhandledErrorResult
+func (f File) coqSource() string {
+	w := new(strings.Builder)
 	fmt.Fprintln(w, f.autogeneratedNotice().CoqDecl())
 	fmt.Fprintln(w, strings.Trim(importHeader, "\n"))
 	fmt.Fprintln(w, f.Imports.PrintImports())
@@ -1015,14 +1373,19 @@ func (f File) Write(w io.Writer) {
 	fmt.Fprintln(w, f.ImportHeader)
 	fmt.Fprintln(w)
 	decls := make(map[string]bool)
+	width := f.Width
+	if width == 0 {
+		width = DefaultWidth
+	}
 	for i, d := range f.Decls {
-		decl := d.CoqDecl()
+		decl := render(declDoc(d), width)
 		// don't translate the same thing twice (which the interface translation
 		// can currently do)
+		key := stripMarkers(decl)
 		_, isComment := d.(CommentDecl)
-		if isComment || !decls[decl] {
+		if isComment || !decls[key] {
 			fmt.Fprintln(w, decl)
-			decls[decl] = true
+			decls[key] = true
 
 			if i != len(f.Decls)-1 {
 				fmt.Fprintln(w)
@@ -1030,4 +1393,5 @@ func (f File) Write(w io.Writer) {
 		}
 	}
 	fmt.Fprint(w, f.Footer)
+	return w.String()
 }
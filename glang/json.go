@@ -0,0 +1,365 @@
+package glang
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// JSONVersion is the version of the JSON encoding of glang, which changes
+// whenever the encoding of an existing node changes.
+const JSONVersion = 1
+
+// The JSON encoding of glang represents each node as an object with a "kind"
+// field naming its glang type (such as "LetExpr" or "FuncDecl"), and the
+// node's fields under lowerCamelCase names. Nodes and declarations translated
+// from a specific piece of Go code also have a "pos" field with the Go
+// position as {"file", "line", "col"}. Missing optional children are null.
+//
+// The encoding of a File is an object with the fields "version", "pkgPath",
+// "goPackage", "ffis", "imports" (the import paths) and "decls".
+
+type jsonObject map[string]interface{}
+
+// MarshalJSON encodes o with "kind" first and the other fields sorted, so
+// the output is stable and easy to skim.
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var keys []string
+	for k := range o {
+		if k != "kind" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := o["kind"]; ok {
+		keys = append([]string{"kind"}, keys...)
+	}
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		kb, _ := json.Marshal(k)
+		b.Write(kb)
+		b.WriteByte(':')
+		vb, err := marshalValue(o[k])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(vb)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func marshalValue(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+func node(kind string, fields ...interface{}) jsonObject {
+	o := jsonObject{"kind": kind}
+	for i := 0; i+1 < len(fields); i += 2 {
+		o[fields[i].(string)] = fields[i+1]
+	}
+	return o
+}
+
+func jsonPos(p Position) jsonObject {
+	return jsonObject{"file": p.Filename, "line": p.Line, "col": p.Column}
+}
+
+func jsonExprs(es []Expr) []interface{} {
+	out := []interface{}{}
+	for _, e := range es {
+		out = append(out, jsonExpr(e))
+	}
+	return out
+}
+
+func jsonTypes(ts []Type) []interface{} {
+	out := []interface{}{}
+	for _, t := range ts {
+		out = append(out, jsonExpr(t))
+	}
+	return out
+}
+
+func jsonFields(fs []FieldDecl) []interface{} {
+	out := []interface{}{}
+	for _, f := range fs {
+		out = append(out, jsonExpr(f))
+	}
+	return out
+}
+
+func jsonTypeParams(ts []TypeIdent) []string {
+	out := []string{}
+	for _, t := range ts {
+		out = append(out, string(t))
+	}
+	return out
+}
+
+func jsonBinder(b Binder) interface{} {
+	if b == nil {
+		return nil
+	}
+	return string(*b)
+}
+
+// jsonExpr encodes a glang expression or type
+func jsonExpr(e Expr) interface{} {
+	switch e := e.(type) {
+	case nil:
+		return nil
+	case Located:
+		o, ok := jsonExpr(e.Expr).(jsonObject)
+		if ok {
+			o["pos"] = jsonPos(e.Pos)
+		}
+		return o
+
+	// types
+	case TypeIdent:
+		return node("TypeIdent", "name", string(e))
+	case StructName:
+		return node("StructName", "name", string(e))
+	case MapType:
+		return node("MapType", "key", jsonExpr(e.Key), "value", jsonExpr(e.Value))
+	case FuncType:
+		return node("FuncType")
+	case SliceType:
+		return node("SliceType", "elem", jsonExpr(e.Value))
+	case ArrayType:
+		return node("ArrayType", "len", e.Len, "elem", jsonExpr(e.Elt))
+	case PtrType:
+		return node("PtrType")
+	case TupleType:
+		return node("TupleType", "elems", jsonTypes(e))
+	case StructType:
+		return node("StructType", "fields", jsonFields(e.Fields))
+	case FieldDecl:
+		return node("FieldDecl", "name", e.Name, "type", jsonExpr(e.Type))
+
+	// expressions
+	case GallinaIdent:
+		return node("GallinaIdent", "name", string(e))
+	case PackageIdent:
+		return node("PackageIdent", "package", e.Package, "ident", e.Ident)
+	case LoggingStmt:
+		return node("LoggingStmt", "goCall", e.GoCall)
+	case ParenExpr:
+		return node("ParenExpr", "inner", jsonExpr(e.Inner))
+	case IdentExpr:
+		return node("IdentExpr", "name", string(e))
+	case GallinaString:
+		return node("GallinaString", "value", string(e))
+	case CallExpr:
+		return node("CallExpr", "func", jsonExpr(e.MethodName), "args", jsonExprs(e.Args))
+	case ContinueExpr:
+		return node("ContinueExpr")
+	case BreakExpr:
+		return node("BreakExpr")
+	case ReturnExpr:
+		return node("ReturnExpr", "value", jsonExpr(e.Value))
+	case DoExpr:
+		return node("DoExpr", "expr", jsonExpr(e.Expr))
+	case LetExpr:
+		names := []string{}
+		names = append(names, e.Names...)
+		return node("LetExpr", "names", names, "val", jsonExpr(e.ValExpr), "cont", jsonExpr(e.Cont))
+	case StructLiteral:
+		fields := []interface{}{}
+		for _, f := range e.elts {
+			fields = append(fields, jsonObject{"name": f.Field, "value": jsonExpr(f.Value)})
+		}
+		return node("StructLiteral", "struct", e.StructName, "fields", fields)
+	case BoolLiteral:
+		return node("BoolLiteral", "value", bool(e))
+	case UnitLiteral:
+		return node("UnitLiteral")
+	case IntLiteral:
+		return node("IntLiteral", "value", e.Value)
+	case Int32Literal:
+		return node("Int32Literal", "value", e.Value)
+	case ByteLiteral:
+		return node("ByteLiteral", "value", e.Value)
+	case StringLiteral:
+		return node("StringLiteral", "value", e.Value)
+	case nullLiteral:
+		return node("NullLiteral")
+	case ErrorExpr:
+		return node("ErrorExpr", "message", e.Message)
+	case BinaryExpr:
+		return node("BinaryExpr", "op", e.Op.String(), "x", jsonExpr(e.X), "y", jsonExpr(e.Y))
+	case NotExpr:
+		return node("NotExpr", "x", jsonExpr(e.X))
+	case TupleExpr:
+		return node("TupleExpr", "elems", jsonExprs(e))
+	case ListExpr:
+		return node("ListExpr", "elems", jsonExprs(e))
+	case DerefExpr:
+		return node("DerefExpr", "type", jsonExpr(e.Ty), "x", jsonExpr(e.X))
+	case RefExpr:
+		return node("RefExpr", "type", jsonExpr(e.Ty), "x", jsonExpr(e.X))
+	case StoreStmt:
+		return node("StoreStmt", "type", jsonExpr(e.Ty), "dst", jsonExpr(e.Dst), "x", jsonExpr(e.X))
+	case IfExpr:
+		return node("IfExpr", "cond", jsonExpr(e.Cond), "then", jsonExpr(e.Then), "else", jsonExpr(e.Else))
+	case ForLoopExpr:
+		return node("ForLoopExpr", "cond", jsonExpr(e.Cond), "post", jsonExpr(e.Post), "body", jsonExpr(e.Body))
+	case ForRangeSliceExpr:
+		return node("ForRangeSliceExpr",
+			"key", jsonBinder(e.Key), "val", jsonBinder(e.Val),
+			"type", jsonExpr(e.Ty), "slice", jsonExpr(e.Slice), "body", jsonExpr(e.Body))
+	case ForRangeMapExpr:
+		return node("ForRangeMapExpr",
+			"key", e.KeyIdent, "val", e.ValueIdent,
+			"map", jsonExpr(e.Map), "body", jsonExpr(e.Body))
+	case SpawnExpr:
+		return node("SpawnExpr", "body", jsonExpr(e.Body))
+	case DeferExpr:
+		return node("DeferExpr", "body", jsonExpr(e.Body))
+	case FuncLit:
+		return node("FuncLit", "args", jsonFields(e.Args), "body", jsonExpr(e.Body))
+	}
+	unprintable("json", e)
+	return nil
+}
+
+// jsonDecl encodes a glang declaration
+func jsonDecl(d Decl) interface{} {
+	switch d := d.(type) {
+	case LocatedDecl:
+		o, ok := jsonDecl(d.Decl).(jsonObject)
+		if ok {
+			o["pos"] = jsonPos(d.Pos)
+		}
+		return o
+	case TypeDecl:
+		return node("TypeDecl", "name", d.Name, "type", jsonExpr(d.Body))
+	case FuncDecl:
+		var recv interface{}
+		if d.RecvArg != nil {
+			recv = jsonExpr(*d.RecvArg)
+		}
+		return node("FuncDecl", "name", d.Name,
+			"typeParams", jsonTypeParams(d.TypeParams),
+			"recv", recv,
+			"args", jsonFields(d.Args),
+			"returnType", jsonExpr(d.ReturnType),
+			"body", jsonExpr(d.Body),
+			"comment", d.Comment)
+	case OpaqueDecl:
+		return node("OpaqueDecl", "name", d.Name,
+			"typeParams", jsonTypeParams(d.TypeParams),
+			"sectionVariable", d.SectionVariable,
+			"comment", d.Comment)
+	case ExternDecl:
+		return node("ExternDecl", "name", d.Name,
+			"typeParams", jsonTypeParams(d.TypeParams),
+			"extern", d.Extern,
+			"comment", d.Comment)
+	case CommentDecl:
+		return node("CommentDecl", "text", string(d))
+	case ConstDecl:
+		return node("ConstDecl", "name", d.Name,
+			"type", jsonExpr(d.Type),
+			"value", jsonExpr(d.Val),
+			"comment", d.Comment)
+	case MethodSetDecl:
+		methods := []interface{}{}
+		for _, m := range d.Methods {
+			methods = append(methods, jsonObject{"name": m.Name, "method": m.Method,
+				"deref": jsonExpr(m.Deref)})
+		}
+		return node("MethodSetDecl", "name", d.Name, "methods", methods)
+	}
+	unprintable("json", d)
+	return nil
+}
+
+func jsonFile(f File) jsonObject {
+	ffis := []string{}
+	ffis = append(ffis, f.Ffis...)
+	imports := []string{}
+	seen := make(map[string]bool)
+	for _, imp := range f.Imports {
+		if !seen[imp.Path] {
+			imports = append(imports, imp.Path)
+			seen[imp.Path] = true
+		}
+	}
+	sort.Strings(imports)
+	decls := []interface{}{}
+	for _, d := range f.Decls {
+		decls = append(decls, jsonDecl(d))
+	}
+	return jsonObject{
+		"version":   JSONVersion,
+		"pkgPath":   f.PkgPath,
+		"goPackage": f.GoPackage,
+		"ffis":      ffis,
+		"imports":   imports,
+		"decls":     decls,
+	}
+}
+
+func marshalJSON(v interface{}) (string, error) {
+	b, err := marshalValue(v)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	_ = json.Indent(&out, b, "", "  ")
+	return out.String(), nil
+}
+
+type jsonPrinter struct{}
+
+// JSONPrinter encodes glang as JSON, for tools that analyze translations.
+//
+// Object fields are sorted by name (after the kind), so the output is stable.
+var JSONPrinter Printer = jsonPrinter{}
+
+func (jsonPrinter) Name() string {
+	return "json"
+}
+
+func (jsonPrinter) Extension() string {
+	return ".json"
+}
+
+func (jsonPrinter) Expr(e Expr) (s string, err error) {
+	defer catchUnprintable(&err)
+	return marshalJSON(jsonExpr(e))
+}
+
+func (jsonPrinter) Type(t Type) (s string, err error) {
+	defer catchUnprintable(&err)
+	return marshalJSON(jsonExpr(t))
+}
+
+func (jsonPrinter) Decl(d Decl) (s string, err error) {
+	defer catchUnprintable(&err)
+	return marshalJSON(jsonDecl(d))
+}
+
+func (jsonPrinter) WriteFile(w io.Writer, f File) (err error) {
+	defer catchUnprintable(&err)
+	s, err := marshalJSON(jsonFile(f))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, s)
+	return err
+}
@@ -0,0 +1,73 @@
+package glang
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONExpr(t *testing.T) {
+	assert := assert.New(t)
+	e := Located{
+		Pos: Position{Filename: "a.go", Line: 3, Column: 9},
+		Expr: BinaryExpr{
+			X:  IdentExpr("x"),
+			Op: OpLessThan,
+			Y:  IntLiteral{4},
+		},
+	}
+	s, err := JSONPrinter.Expr(e)
+	assert.NoError(err)
+	assert.JSONEq(`{
+  "kind": "BinaryExpr",
+  "op": "lt",
+  "pos": {"file": "a.go", "line": 3, "col": 9},
+  "x": {"kind": "IdentExpr", "name": "x"},
+  "y": {"kind": "IntLiteral", "value": 4}
+}`, s)
+}
+
+func TestJSONDecl(t *testing.T) {
+	assert := assert.New(t)
+	d := LocatedDecl{
+		Pos: Position{Filename: "a.go", Line: 1, Column: 1},
+		Decl: FuncDecl{
+			Name:       "f",
+			Args:       []FieldDecl{{Name: "s", Type: SliceType{TypeIdent("byteT")}}},
+			ReturnType: TypeIdent("unitT"),
+			Body:       ReturnExpr{Value: Tt},
+		},
+	}
+	s, err := JSONPrinter.Decl(d)
+	assert.NoError(err)
+	assert.JSONEq(`{
+  "kind": "FuncDecl",
+  "name": "f",
+  "pos": {"file": "a.go", "line": 1, "col": 1},
+  "typeParams": [],
+  "recv": null,
+  "args": [
+    {"kind": "FieldDecl", "name": "s",
+     "type": {"kind": "SliceType", "elem": {"kind": "TypeIdent", "name": "byteT"}}}
+  ],
+  "returnType": {"kind": "TypeIdent", "name": "unitT"},
+  "body": {"kind": "ReturnExpr", "value": {"kind": "UnitLiteral"}},
+  "comment": ""
+}`, s)
+}
+
+func TestJSONUnknownNode(t *testing.T) {
+	assert := assert.New(t)
+	_, err := JSONPrinter.Expr(NewDoSeq(unknownExpr{}, ReturnExpr{Value: Tt}))
+	assert.EqualError(err, "json: cannot print glang.unknownExpr")
+
+	f := File{
+		PkgPath:   "example.com/a",
+		GoPackage: "a",
+		Decls:     []Decl{ConstDecl{Name: "C", Val: unknownExpr{}}},
+	}
+	var b bytes.Buffer
+	assert.Error(JSONPrinter.WriteFile(&b, f))
+	assert.Empty(b.String(), "nothing should be written for an unprintable file")
+}
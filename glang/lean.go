@@ -0,0 +1,453 @@
+package glang
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// leanPrinter prints Lean 4 against a GooseLang Lean library.
+//
+// The library is expected to mirror the Coq notations with ordinary
+// definitions: variables are (var "x"), binders are strings ("_" for an
+// anonymous binder), sequencing and let bindings are functions taking their
+// continuation (chained with <|), and Lean keywords get a trailing
+// underscore (if_, for_, return_, ...).
+type leanPrinter struct{}
+
+// LeanPrinter prints Lean 4.
+var LeanPrinter Printer = leanPrinter{}
+
+func (leanPrinter) Name() string {
+	return "lean"
+}
+
+func (leanPrinter) Extension() string {
+	return ".lean"
+}
+
+func (p leanPrinter) Expr(e Expr) (s string, err error) {
+	defer catchUnprintable(&err)
+	return p.term(e, false), nil
+}
+
+func (p leanPrinter) Type(t Type) (s string, err error) {
+	defer catchUnprintable(&err)
+	return p.term(t, false), nil
+}
+
+func leanString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\x%02x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// leanIdent converts a Gallina identifier to Lean
+//
+// Some GooseLang primitives are Coq notations ending in a colon (such as
+// with_defer:), which are plain functions in Lean.
+func leanIdent(s string) string {
+	return strings.TrimSuffix(s, ":")
+}
+
+func leanBinder(s string) string {
+	return leanString(s)
+}
+
+func leanBinders(names []string) string {
+	if len(names) == 0 {
+		names = []string{"_"}
+	}
+	var bs []string
+	for _, n := range names {
+		bs = append(bs, leanBinder(n))
+	}
+	return "[" + strings.Join(bs, ", ") + "]"
+}
+
+func leanComment(pp *buffer, c string) {
+	if c == "" {
+		return
+	}
+	// don't let Go comments end (or nest) Lean comments
+	c = strings.ReplaceAll(c, "/-", "/ -")
+	c = strings.ReplaceAll(c, "-/", "- /")
+	indent := pp.Block("/- ", "%s -/", c)
+	pp.Indent(-indent)
+}
+
+// call prints an application of f to args
+func (p leanPrinter) call(needs_paren bool, f string, args ...Expr) string {
+	comps := []string{f}
+	for _, a := range args {
+		comps = append(comps, p.term(a, true))
+	}
+	return addParens(needs_paren, strings.Join(comps, " "))
+}
+
+// lam prints a lambda with a multiline body
+func (p leanPrinter) lam(pp *buffer, prefix string, binders string, body Expr, suffix string) {
+	pp.Add("%slam %s <|", prefix, binders)
+	pp.Indent(2)
+	pp.AddLine(p.term(body, false) + suffix)
+	pp.Indent(-2)
+}
+
+func (p leanPrinter) branch(pp *buffer, e Expr, suffix string) {
+	pp.Indent(2)
+	pp.AddLine(p.term(e, true) + suffix)
+	pp.Indent(-2)
+}
+
+// term prints a Lean term for a glang expression or type
+func (p leanPrinter) term(e Expr, needs_paren bool) string {
+	switch e := e.(type) {
+	// types
+	case TypeIdent:
+		return leanIdent(string(e))
+	case StructName:
+		return leanIdent(string(e))
+	case MapType:
+		return p.call(needs_paren, "mapT", e.Key, e.Value)
+	case FuncType:
+		return "funcT"
+	case SliceType:
+		return p.call(needs_paren, "sliceT", e.Value)
+	case ArrayType:
+		return p.call(needs_paren, "arrayT", e.Elt)
+	case PtrType:
+		return "ptrT"
+	case TupleType:
+		var comps []string
+		for _, t := range e {
+			comps = append(comps, p.term(t, false))
+		}
+		return addParens(needs_paren, "tupleT ["+strings.Join(comps, ", ")+"]")
+	case StructType:
+		var pp buffer
+		pp.Add("structT [")
+		pp.Indent(2)
+		for i, fd := range e.Fields {
+			sep := ","
+			if i == len(e.Fields)-1 {
+				sep = ""
+			}
+			pp.Add("(%s, %s)%s", leanString(fd.Name), p.term(fd.Type, false), sep)
+		}
+		pp.Indent(-2)
+		pp.AddLine("]")
+		return addParens(needs_paren, pp.Build())
+
+	// expressions
+	case Located:
+		return p.term(e.Expr, needs_paren)
+	case GallinaIdent:
+		return leanIdent(string(e))
+	case PackageIdent:
+		return fmt.Sprintf("%s.%s", thisIsBadAndShouldBeDeprecatedGoPathToCoqPath(e.Package), e.Ident)
+	case LoggingStmt:
+		var pp buffer
+		leanComment(&pp, e.GoCall)
+		return addParens(needs_paren, "skip "+pp.Build())
+	case ParenExpr:
+		return fmt.Sprintf("(%s)", p.term(e.Inner, false))
+	case IdentExpr:
+		return addParens(needs_paren, "var "+leanString(string(e)))
+	case GallinaString:
+		return leanString(string(e))
+	case CallExpr:
+		return p.call(needs_paren, p.term(e.MethodName, true), e.Args...)
+	case ContinueExpr:
+		return "continue_"
+	case BreakExpr:
+		return "break_"
+	case ReturnExpr:
+		return p.call(needs_paren, "return_", e.Value)
+	case DoExpr:
+		return p.call(needs_paren, "do_", e.Expr)
+	case LetExpr:
+		var pp buffer
+		switch len(e.Names) {
+		case 0:
+			pp.Add("seq_ %s <|", p.term(e.ValExpr, true))
+		case 1:
+			pp.Add("let_ %s %s <|", leanBinder(e.Names[0]), p.term(e.ValExpr, true))
+		default:
+			pp.Add("lets_ %s %s <|", leanBinders(e.Names), p.term(e.ValExpr, true))
+		}
+		pp.AddLine(p.term(e.Cont, false))
+		return addParens(needs_paren, pp.Build())
+	case StructLiteral:
+		var pp buffer
+		pp.Add("struct.make %s [", leanIdent(e.StructName))
+		pp.Indent(2)
+		for i, f := range e.elts {
+			sep := ","
+			if i == len(e.elts)-1 {
+				sep = ""
+			}
+			pp.Add("(%s, %s)%s", leanString(f.Field), p.term(f.Value, false), sep)
+		}
+		pp.Indent(-2)
+		pp.Add("]")
+		return addParens(needs_paren, pp.Build())
+	case BoolLiteral:
+		return addParens(needs_paren, fmt.Sprintf("bool %t", bool(e)))
+	case UnitLiteral:
+		return "unit"
+	case IntLiteral:
+		return addParens(needs_paren, fmt.Sprintf("u64 %d", e.Value))
+	case Int32Literal:
+		return addParens(needs_paren, fmt.Sprintf("u32 %d", e.Value))
+	case ByteLiteral:
+		return addParens(needs_paren, fmt.Sprintf("u8 %d", e.Value))
+	case StringLiteral:
+		return addParens(needs_paren, "str "+leanString(e.Value))
+	case nullLiteral:
+		return "null"
+	case ErrorExpr:
+		return addParens(needs_paren, "Panic "+leanString("goose error: "+e.Message))
+	case BinaryExpr:
+		return p.call(needs_paren, "binop ."+e.Op.String(), e.X, e.Y)
+	case NotExpr:
+		return p.call(needs_paren, "not_", e.X)
+	case TupleExpr:
+		if len(e) == 1 {
+			return p.term(e[0], needs_paren)
+		}
+		var comps []string
+		for _, t := range e {
+			comps = append(comps, p.term(t, false))
+		}
+		return addParens(needs_paren, "tuple ["+indent(7, strings.Join(comps, ", "))+"]")
+	case ListExpr:
+		var comps []string
+		for _, t := range e {
+			comps = append(comps, p.term(t, false))
+		}
+		return "[" + indent(1, strings.Join(comps, ", ")) + "]"
+	case DerefExpr:
+		return p.call(needs_paren, "load", e.Ty, e.X)
+	case RefExpr:
+		return p.call(needs_paren, "ref_ty", e.Ty, e.X)
+	case StoreStmt:
+		return p.call(needs_paren, "store", e.Ty, e.Dst, e.X)
+	case IfExpr:
+		var pp buffer
+		pp.Add("(if_ %s", p.term(e.Cond, true))
+		p.branch(&pp, e.Then, "")
+		p.branch(&pp, e.Else, ")")
+		return pp.Build()
+	case ForLoopExpr:
+		var pp buffer
+		pp.Add("(for_ (lam [\"_\"] <| %s) (lam [\"_\"] <| %s) <|",
+			p.term(e.Cond, false), p.term(e.Post, false))
+		p.lam(&pp, "  ", `["_"]`, e.Body, ")")
+		return pp.Build()
+	case ForRangeSliceExpr:
+		var pp buffer
+		key, val := "_", "_"
+		if e.Key != nil {
+			key = string(*e.Key)
+		}
+		if e.Val != nil {
+			val = string(*e.Val)
+		}
+		pp.Add("slice.for_range %s %s <| lam %s <|",
+			p.term(e.Ty, true), p.term(e.Slice, true),
+			leanBinders([]string{key, val}))
+		pp.Indent(2)
+		if key != "_" {
+			pp.Add("let_ %s (ref_ty uint64T %s) <|", leanBinder(key), p.term(IdentExpr(key), true))
+		}
+		if val != "_" {
+			pp.Add("let_ %s (ref_ty %s %s) <|", leanBinder(val), p.term(e.Ty, true), p.term(IdentExpr(val), true))
+		}
+		pp.AddLine(p.term(e.Body, false))
+		pp.Indent(-2)
+		return addParens(needs_paren, pp.Build())
+	case ForRangeMapExpr:
+		var pp buffer
+		p.lam(&pp, fmt.Sprintf("MapIter %s <| ", p.term(e.Map, true)),
+			leanBinders([]string{e.KeyIdent, e.ValueIdent}), e.Body, "")
+		return addParens(needs_paren, pp.Build())
+	case SpawnExpr:
+		var pp buffer
+		pp.Block("fork (", "%s)", p.term(e.Body, false))
+		return addParens(needs_paren, pp.Build())
+	case DeferExpr:
+		var pp buffer
+		pp.Add(`store funcT (var "$defer") (let_ "$oldf" (load funcT (var "$defer")) <|`)
+		pp.Indent(2)
+		pp.Add(`lam ["_"] <|`)
+		pp.Indent(2)
+		pp.Add("seq_ %s <|", p.term(e.Body, true))
+		pp.Add(`(var "$oldf") unit)`)
+		pp.Indent(-4)
+		return addParens(needs_paren, pp.Build())
+	case FuncLit:
+		var names []string
+		for _, a := range e.Args {
+			names = append(names, a.Name)
+		}
+		var pp buffer
+		p.lam(&pp, "(", leanBinders(names), e.Body, ")")
+		return pp.Build()
+	case FieldDecl:
+		return leanBinder(e.Name)
+	}
+	unprintable("lean", e)
+	return ""
+}
+
+func leanTypeParams(params []TypeIdent) string {
+	s := ""
+	for _, t := range params {
+		s += fmt.Sprintf("(%s : GoType) ", leanIdent(string(t)))
+	}
+	return s
+}
+
+// Decl prints a top-level Lean declaration.
+//
+// Type-checking theorems are not supported and are omitted.
+func (p leanPrinter) Decl(d Decl) (s string, err error) {
+	defer catchUnprintable(&err)
+	return p.decl(d), nil
+}
+
+func (p leanPrinter) decl(d Decl) string {
+	var pp buffer
+	switch d := d.(type) {
+	case LocatedDecl:
+		return p.decl(d.Decl)
+	case TypeDecl:
+		indent := pp.Block("def ", "%s : GoType := %s", d.Name, p.term(d.Body, false))
+		pp.Indent(-indent)
+	case FuncDecl:
+		leanComment(&pp, d.Comment)
+		var names []string
+		if d.RecvArg != nil {
+			names = append(names, d.RecvArg.Name)
+		}
+		for _, a := range d.Args {
+			names = append(names, a.Name)
+		}
+		if len(d.Args) == 0 {
+			names = append(names, "_")
+		}
+		pp.Add("def %s %s: Val :=", d.Name, leanTypeParams(d.TypeParams))
+		pp.Indent(2)
+		pp.Add("rec_ %s %s <|", leanString(d.Name), leanBinders(names))
+		pp.Indent(2)
+		pp.AddLine(p.term(d.Body, false))
+		pp.Indent(-4)
+	case OpaqueDecl:
+		leanComment(&pp, d.Comment)
+		ty := "Val"
+		for range d.TypeParams {
+			ty = "GoType → " + ty
+		}
+		pp.Add("axiom %s : %s", d.Name, ty)
+	case ExternDecl:
+		leanComment(&pp, d.Comment)
+		val := leanIdent(d.Extern)
+		for _, t := range d.TypeParams {
+			val += " " + leanIdent(string(t))
+		}
+		pp.Add("def %s %s: Val := %s", d.Name, leanTypeParams(d.TypeParams), val)
+	case CommentDecl:
+		leanComment(&pp, string(d))
+	case ConstDecl:
+		leanComment(&pp, d.Comment)
+		indent := pp.Block("def ", "%s : Expr := %s", d.Name, p.term(d.Val, false))
+		pp.Indent(-indent)
+	case MethodSetDecl:
+		var entries []string
+		for _, m := range d.Methods {
+			entries = append(entries, "("+leanString(m.Name)+", "+p.term(m.Fn(), false)+")")
+		}
+		indent := pp.Block("def ", "%s : List (String × Val) := [%s]",
+			d.Name, strings.Join(entries, ", "))
+		pp.Indent(-indent)
+	default:
+		unprintable("lean", d)
+	}
+	return pp.Build()
+}
+
+// leanModule is the Lean module name for a Go import path
+func leanModule(pkgPath string) string {
+	return "New.code." + strings.ReplaceAll(thisIsBadAndShouldBeDeprecatedGoPathToCoqPath(pkgPath), "/", ".")
+}
+
+// WriteFile outputs the Lean source for a File.
+//
+// The file opens a namespace named after the Go package, so qualified
+// references from other packages (pkg.Ident) resolve as they do in Coq.
+// noinspection GoUnhandledErrorResult
+func (p leanPrinter) WriteFile(out io.Writer, f File) (err error) {
+	defer catchUnprintable(&err)
+	// buffer the output so nothing is written if a declaration is unprintable
+	var b bytes.Buffer
+	w := &b
+	fmt.Fprintf(w, "-- autogenerated from %s\n", f.PkgPath)
+	fmt.Fprintln(w, "import GooseLang")
+	for _, ffi := range f.Ffis {
+		fmt.Fprintf(w, "import New.%s_prelude\n", ffi)
+	}
+	seen := make(map[string]bool)
+	var imports []string
+	for _, imp := range f.Imports {
+		m := leanModule(imp.Path)
+		if !seen[m] {
+			imports = append(imports, m)
+			seen[m] = true
+		}
+	}
+	sort.Strings(imports)
+	for _, m := range imports {
+		fmt.Fprintf(w, "import %s\n", m)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "namespace %s\n", f.GoPackage)
+	fmt.Fprintln(w, "open GooseLang")
+	fmt.Fprintln(w)
+	decls := make(map[string]bool)
+	for _, d := range f.Decls {
+		decl := p.decl(d)
+		// same deduplication as the Coq output
+		_, isComment := d.(CommentDecl)
+		if isComment || !decls[decl] {
+			fmt.Fprintln(w, decl)
+			fmt.Fprintln(w)
+			decls[decl] = true
+		}
+	}
+	fmt.Fprintf(w, "end %s\n", f.GoPackage)
+	_, err = b.WriteTo(out)
+	return err
+}
@@ -0,0 +1,120 @@
+package glang
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeanExpr(t *testing.T) {
+	assert := assert.New(t)
+	e := LetExpr{
+		Names:   []string{"x"},
+		ValExpr: RefExpr{Ty: TypeIdent("uint64T"), X: IntLiteral{3}},
+		Cont: IfExpr{
+			Cond: BinaryExpr{
+				X:  DerefExpr{Ty: TypeIdent("uint64T"), X: IdentExpr("x")},
+				Op: OpLessThan,
+				Y:  IntLiteral{4},
+			},
+			Then: ReturnExpr{Value: StringLiteral{"a \"quote\""}},
+			Else: NewDoSeq(NewCallExpr(GallinaIdent("slice.len"), IdentExpr("s")),
+				ReturnExpr{Value: Tt}),
+		},
+	}
+	s, err := LeanPrinter.Expr(e)
+	assert.NoError(err)
+	assert.Equal(`let_ "x" (ref_ty uint64T (u64 3)) <|
+(if_ (binop .lt (load uint64T (var "x")) (u64 4))
+  (return_ (str "a \"quote\""))
+  (seq_ (do_ (slice.len (var "s"))) <|
+  return_ unit))`, s)
+}
+
+func TestLeanDefer(t *testing.T) {
+	e := DeferExpr{Body: NewCallExpr(IdentExpr("f"), IdentExpr("x"))}
+	s, err := LeanPrinter.Expr(e)
+	assert.NoError(t, err)
+	assert.Equal(t, `store funcT (var "$defer") (let_ "$oldf" (load funcT (var "$defer")) <|
+  lam ["_"] <|
+    seq_ ((var "f") (var "x")) <|
+    (var "$oldf") unit)`, s)
+}
+
+func TestLeanFuncDecl(t *testing.T) {
+	assert := assert.New(t)
+	d := FuncDecl{
+		Name:       "Id",
+		TypeParams: []TypeIdent{"T"},
+		Args:       []FieldDecl{{Name: "x", Type: TypeIdent("T")}},
+		Body: NewCallExpr(GallinaIdent("with_defer:"),
+			FuncLit{Body: ReturnExpr{Value: IdentExpr("x")}}),
+		Comment: "Id returns -/ its argument",
+	}
+	s, err := LeanPrinter.Decl(d)
+	assert.NoError(err)
+	assert.Equal(`/- Id returns - / its argument -/
+def Id (T : GoType) : Val :=
+  rec_ "Id" ["x"] <|
+    with_defer (lam ["_"] <|
+      return_ (var "x"))`, s)
+}
+
+func TestLeanFile(t *testing.T) {
+	assert := assert.New(t)
+	f := File{
+		PkgPath:   "example.com/a",
+		GoPackage: "a",
+		Ffis:      []string{"grove"},
+		Imports:   ImportDecls{{Path: "example.com/b"}, {Path: "example.com/b"}},
+		Decls: []Decl{
+			TypeDecl{Name: "S", Body: StructType{Fields: []FieldDecl{
+				{Name: "x", Type: TypeIdent("uint64T")},
+				{Name: "b", Type: PtrType{}},
+			}}},
+			ConstDecl{Name: "C", Val: PackageIdent{Package: "b", Ident: "C"}},
+		},
+	}
+	var b bytes.Buffer
+	assert.NoError(LeanPrinter.WriteFile(&b, f))
+	assert.Equal(`-- autogenerated from example.com/a
+import GooseLang
+import New.grove_prelude
+import New.code.example_com.b
+
+namespace a
+open GooseLang
+
+def S : GoType := structT [
+      ("x", uint64T),
+      ("b", ptrT)
+    ]
+
+def C : Expr := b.C
+
+end a
+`, b.String())
+}
+
+// unknownExpr is an Expr the Lean printer has no syntax for
+type unknownExpr struct{}
+
+func (unknownExpr) Coq(needs_paren bool) string {
+	return "unknown"
+}
+
+func TestLeanUnknownNode(t *testing.T) {
+	assert := assert.New(t)
+	_, err := LeanPrinter.Expr(NewDoSeq(unknownExpr{}, ReturnExpr{Value: Tt}))
+	assert.EqualError(err, "lean: cannot print glang.unknownExpr")
+
+	f := File{
+		PkgPath:   "example.com/a",
+		GoPackage: "a",
+		Decls:     []Decl{ConstDecl{Name: "C", Val: unknownExpr{}}},
+	}
+	var b bytes.Buffer
+	assert.Error(LeanPrinter.WriteFile(&b, f))
+	assert.Empty(b.String(), "nothing should be written for an unprintable file")
+}
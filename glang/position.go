@@ -0,0 +1,56 @@
+package glang
+
+import "fmt"
+
+// Position is the location in the Go source that glang was translated from.
+type Position struct {
+	// Filename is the base name of the Go file
+	Filename string
+	Line     int
+	Column   int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
+// IsValid reports whether p refers to a source location.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// Located is an expression annotated with its Go source position.
+//
+// Positions are not part of the Coq output: Located prints like Expr, between
+// source markers that printers remove (see File.WriteWithSourceMap).
+type Located struct {
+	Pos  Position
+	Expr Expr
+}
+
+func (e Located) Coq(needs_paren bool) string {
+	return renderText(e.coqDoc(needs_paren))
+}
+
+func (e Located) coqDoc(needs_paren bool) doc {
+	return markDoc(e.Pos, exprDoc(e.Expr, needs_paren))
+}
+
+// LocatedDecl is a declaration annotated with the position of the Go
+// declaration it was translated from.
+type LocatedDecl struct {
+	Pos  Position
+	Decl Decl
+}
+
+// CoqDecl implements the Decl interface
+//
+// A LocatedDecl is printed as its underlying declaration, between source
+// markers.
+func (d LocatedDecl) CoqDecl() string {
+	return renderText(d.coqDeclDoc())
+}
+
+func (d LocatedDecl) coqDeclDoc() doc {
+	return markDoc(d.Pos, declDoc(d.Decl))
+}
@@ -0,0 +1,303 @@
+package glang
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// The Coq printer lays out code with a pretty-printer in the style of Wadler's
+// "A prettier printer": each node produces a doc describing the ways it can be
+// printed, and the doc is rendered to fit within a line width where possible.
+//
+// A doc is one of the doc* types below. The main combinator is a group, which
+// is printed on a single line (flat) if that fits and otherwise breaks each of
+// its lines. Hard lines always break. A group containing a hard line keeps the
+// rest of its lines flat, so that for example a call whose last argument is a
+// multi-line function literal starts the function on the line of the call,
+// while groups nested within it are laid out on their own.
+
+// DefaultWidth is the line width Coq output is printed to by default.
+const DefaultWidth = 100
+
+type doc interface{}
+
+// docText is text without newlines
+type docText string
+
+// docMark is a source marker, which takes no space in the output
+type docMark string
+
+// docLine is a line break, which in a flat group is printed as flat
+type docLine struct {
+	flat string
+	hard bool
+}
+
+var (
+	// softLine breaks or is a space
+	softLine doc = docLine{flat: " "}
+	// hardLine always breaks
+	hardLine doc = docLine{hard: true}
+)
+
+type docConcat []doc
+
+// docNest increases the indentation of lines in d
+type docNest struct {
+	indent int
+	d      doc
+}
+
+// docAlign indents lines in d to the column where d starts
+type docAlign struct {
+	d doc
+}
+
+type docGroup struct {
+	d doc
+}
+
+// docIfBreak is broken or flat depending on whether the enclosing group is
+// broken
+type docIfBreak struct {
+	broken, flat doc
+}
+
+// indentedLine is d on the next line, indented, in a broken group and d after
+// a space in a flat group.
+//
+// Unlike nest(2, softLine, d), lines within d are only indented when the group
+// is broken.
+func indentedLine(d doc) doc {
+	return docIfBreak{
+		broken: nest(2, hardLine, d),
+		flat:   concat(docText(" "), d),
+	}
+}
+
+// alignBroken aligns the elements of a broken group with the first, and
+// otherwise indents lines within the elements by one space (which lines up
+// with a single character of opening punctuation when the group starts a
+// line).
+func alignBroken(d doc) doc {
+	return docIfBreak{broken: align(d), flat: nest(1, d)}
+}
+
+func concat(ds ...doc) doc {
+	return docConcat(ds)
+}
+
+func nest(indent int, ds ...doc) doc {
+	return docNest{indent: indent, d: docConcat(ds)}
+}
+
+func align(ds ...doc) doc {
+	return docAlign{d: docConcat(ds)}
+}
+
+func group(ds ...doc) doc {
+	return docGroup{d: docConcat(ds)}
+}
+
+// join concatenates ds with sep between each
+func join(ds []doc, sep ...doc) doc {
+	var out docConcat
+	for i, d := range ds {
+		if i > 0 {
+			out = append(out, sep...)
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// textDoc converts s (which may have multiple lines) to a doc, with each line
+// after the first indented to the current indentation
+func textDoc(s string) doc {
+	if !strings.ContainsRune(s, '\n') {
+		return docText(s)
+	}
+	var lines []doc
+	for _, l := range strings.Split(s, "\n") {
+		lines = append(lines, docText(l))
+	}
+	return join(lines, hardLine)
+}
+
+func parensDoc(needs_paren bool, d doc) doc {
+	if needs_paren {
+		return concat(docText("("), d, docText(")"))
+	}
+	return d
+}
+
+// hasHardLine reports whether d always spans multiple lines
+func hasHardLine(d doc) bool {
+	switch d := d.(type) {
+	case docLine:
+		return d.hard
+	case docConcat:
+		for _, d := range d {
+			if hasHardLine(d) {
+				return true
+			}
+		}
+	case docNest:
+		return hasHardLine(d.d)
+	case docAlign:
+		return hasHardLine(d.d)
+	case docGroup:
+		return hasHardLine(d.d)
+	case docIfBreak:
+		return hasHardLine(d.broken) && hasHardLine(d.flat)
+	}
+	return false
+}
+
+// docWidth is the number of columns s takes
+func docWidth(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+// A layoutCmd is a doc to be printed with an indentation and mode
+type layoutCmd struct {
+	indent int
+	flat   bool
+	d      doc
+}
+
+// fits reports whether next, followed by the rest of the commands (in stack
+// order), fits in width columns up to the first line break.
+//
+// Groups in the rest of the commands will be laid out on their own, so they
+// are assumed to break if they need to.
+func fits(next layoutCmd, rest []layoutCmd, width int) bool {
+	type fitCmd struct {
+		layoutCmd
+		rest bool
+	}
+	cmds := []fitCmd{{next, false}}
+	push := func(c fitCmd, d doc) {
+		cmds = append(cmds, fitCmd{layoutCmd{c.indent, c.flat, d}, c.rest})
+	}
+	for width >= 0 {
+		if len(cmds) == 0 {
+			if len(rest) == 0 {
+				return true
+			}
+			cmds = append(cmds, fitCmd{rest[len(rest)-1], true})
+			rest = rest[:len(rest)-1]
+		}
+		c := cmds[len(cmds)-1]
+		cmds = cmds[:len(cmds)-1]
+		switch d := c.d.(type) {
+		case docText:
+			width -= docWidth(string(d))
+		case docConcat:
+			for i := len(d) - 1; i >= 0; i-- {
+				push(c, d[i])
+			}
+		case docNest:
+			push(c, d.d)
+		case docAlign:
+			push(c, d.d)
+		case docGroup:
+			if c.rest {
+				c.flat = false
+			}
+			push(c, d.d)
+		case docIfBreak:
+			if c.flat {
+				push(c, d.flat)
+			} else {
+				push(c, d.broken)
+			}
+		case docLine:
+			if !c.flat || d.hard {
+				return true
+			}
+			width -= docWidth(d.flat)
+		}
+	}
+	return false
+}
+
+// renderText lays out d as plain text, at the default width.
+//
+// The Coq methods of glang nodes return plain text; only File keeps the source
+// markers, to produce a source map.
+func renderText(d doc) string {
+	return stripMarkers(render(d, DefaultWidth))
+}
+
+// render lays out d to fit in width columns where possible
+//
+// The output has source markers for docMarks, and text that happens to
+// contain marker characters is escaped (see escapeMarkers).
+func render(d doc, width int) string {
+	var out strings.Builder
+	col := 0
+	// indentation is written when the line has some text, so that lines
+	// are never indented only to be left empty
+	pending := 0
+	writeIndent := func() {
+		out.WriteString(strings.Repeat(" ", pending))
+		pending = 0
+	}
+	stack := []layoutCmd{{d: d}}
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch d := c.d.(type) {
+		case nil:
+		case docText:
+			if d == "" {
+				continue
+			}
+			writeIndent()
+			out.WriteString(escapeMarkers(string(d)))
+			col += docWidth(string(d))
+		case docMark:
+			// the start of a span comes before its text, so it goes after
+			// the indentation, while the end of a span stays where it is
+			if r, _ := utf8.DecodeRuneInString(string(d)); r == markStart {
+				writeIndent()
+			}
+			out.WriteString(string(d))
+		case docConcat:
+			for i := len(d) - 1; i >= 0; i-- {
+				stack = append(stack, layoutCmd{c.indent, c.flat, d[i]})
+			}
+		case docNest:
+			stack = append(stack, layoutCmd{c.indent + d.indent, c.flat, d.d})
+		case docAlign:
+			stack = append(stack, layoutCmd{col, c.flat, d.d})
+		case docGroup:
+			flat := layoutCmd{c.indent, true, d.d}
+			if hasHardLine(d.d) || fits(flat, stack, width-col) {
+				stack = append(stack, flat)
+			} else {
+				stack = append(stack, layoutCmd{c.indent, false, d.d})
+			}
+		case docIfBreak:
+			if c.flat {
+				stack = append(stack, layoutCmd{c.indent, c.flat, d.flat})
+			} else {
+				stack = append(stack, layoutCmd{c.indent, c.flat, d.broken})
+			}
+		case docLine:
+			if c.flat && !d.hard {
+				writeIndent()
+				out.WriteString(d.flat)
+				col += docWidth(d.flat)
+				continue
+			}
+			out.WriteString("\n")
+			col = c.indent
+			pending = c.indent
+		default:
+			panic("unknown doc")
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,64 @@
+package glang
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderGroup(t *testing.T) {
+	assert := assert.New(t)
+	d := group(docText("f"), indentedLine(docText("aaaa")), indentedLine(docText("bbbb")))
+	assert.Equal("f aaaa bbbb", render(d, 20))
+	assert.Equal("f\n  aaaa\n  bbbb", render(d, 8))
+	// the text after a group must also fit
+	d = concat(nest(4, d), docText(" in"), hardLine, docText("next"))
+	assert.Equal("f aaaa bbbb in\nnext", render(d, 14))
+	assert.Equal("f\n      aaaa\n      bbbb in\nnext", render(d, 13))
+}
+
+func TestRenderHardLine(t *testing.T) {
+	assert := assert.New(t)
+	body := concat(docText("(λ: <>,"), nest(2, hardLine, docText("x")), hardLine, docText(")"))
+	call := group(docText("f"), indentedLine(docText("a")), indentedLine(body))
+	// a multi-line argument doesn't break the call
+	assert.Equal("f a (λ: <>,\n  x\n)", render(call, 5))
+	// but groups within it are laid out to fit
+	inner := group(docText("g"), indentedLine(docText("bbbb")))
+	call = group(docText("f"), indentedLine(concat(docText("(λ: <>,"),
+		nest(2, hardLine, inner), docText(")"))))
+	assert.Equal("f (λ: <>,\n  g bbbb)", render(call, 10))
+	assert.Equal("f (λ: <>,\n  g\n    bbbb)", render(call, 7))
+}
+
+func TestRenderAlign(t *testing.T) {
+	d := concat(docText("x := "), group(docText("("),
+		alignBroken(join([]doc{docText("a"), docText("b")}, docText(","), softLine)),
+		docText(")")))
+	assert.Equal(t, "x := (a, b)", render(d, 20))
+	assert.Equal(t, "x := (a,\n      b)", render(d, 8))
+}
+
+func TestCoqWidth(t *testing.T) {
+	assert := assert.New(t)
+	call := NewCallExpr(GallinaIdent("slice.append"),
+		TypeIdent("byteT"),
+		DerefExpr{X: IdentExpr("xs"), Ty: SliceType{TypeIdent("byteT")}},
+		IdentExpr("x"))
+	e := LetExpr{Names: []string{"y"}, ValExpr: call, Cont: Tt}
+	assert.Equal(`let: "y" := slice.append byteT (![sliceT byteT] "xs") "x" in
+#()`, e.Coq(false))
+
+	f := File{Decls: []Decl{FuncDecl{Name: "f", Body: e}}, Width: 40}
+	var b strings.Builder
+	f.Write(&b)
+	assert.Contains(b.String(), `Definition f : val :=
+  rec: "f" <> :=
+    let: "y" :=
+      slice.append
+        byteT
+        (![sliceT byteT] "xs")
+        "x" in
+    #().`)
+}
@@ -0,0 +1,108 @@
+package glang
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A Printer renders glang in the syntax of a particular proof assistant.
+//
+// The Coq printer is the reference; other printers emit the same constructs
+// against an equivalent GooseLang library for their prover.
+type Printer interface {
+	// Name is the backend name, as given to goose -backend
+	Name() string
+	// Extension is the file extension for output files, such as ".v"
+	Extension() string
+	Expr(e Expr) (string, error)
+	Type(t Type) (string, error)
+	Decl(d Decl) (string, error)
+	// WriteFile outputs the complete source for a File.
+	//
+	// Nothing is written if f contains a node the printer cannot print.
+	WriteFile(w io.Writer, f File) error
+}
+
+// unprintableError reports a node that a printer has no syntax for.
+type unprintableError struct {
+	printer string
+	node    interface{}
+}
+
+func (e *unprintableError) Error() string {
+	return fmt.Sprintf("%s: cannot print %T", e.printer, e.node)
+}
+
+// unprintable aborts printing at a node the printer has no syntax for.
+//
+// The printer methods recover the abort with catchUnprintable and return it
+// as an error.
+func unprintable(printer string, node interface{}) {
+	panic(&unprintableError{printer: printer, node: node})
+}
+
+func catchUnprintable(err *error) {
+	if r := recover(); r != nil {
+		if e, ok := r.(*unprintableError); ok {
+			*err = e
+			return
+		}
+		panic(r)
+	}
+}
+
+// Backends maps backend names to their printers.
+var Backends = map[string]Printer{
+	CoqPrinter.Name():  CoqPrinter,
+	LeanPrinter.Name(): LeanPrinter,
+}
+
+// BackendNames returns the names of all backends, sorted.
+func BackendNames() []string {
+	var names []string
+	for name := range Backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Backend looks up a printer by name.
+func Backend(name string) (Printer, error) {
+	if p, ok := Backends[name]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("unknown backend %q (expected one of %v)",
+		name, BackendNames())
+}
+
+type coqPrinter struct{}
+
+// CoqPrinter prints Coq using the Coq methods of each node.
+var CoqPrinter Printer = coqPrinter{}
+
+func (coqPrinter) Name() string {
+	return "coq"
+}
+
+func (coqPrinter) Extension() string {
+	return ".v"
+}
+
+func (coqPrinter) Expr(e Expr) (string, error) {
+	return e.Coq(false), nil
+}
+
+func (coqPrinter) Type(t Type) (string, error) {
+	return t.Coq(false), nil
+}
+
+func (coqPrinter) Decl(d Decl) (string, error) {
+	return d.CoqDecl(), nil
+}
+
+func (coqPrinter) WriteFile(w io.Writer, f File) error {
+	f.Write(w)
+	return nil
+}
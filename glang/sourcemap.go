@@ -0,0 +1,236 @@
+package glang
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Located expressions and declarations are printed between source markers,
+// which record the Go position of the text in between. Markers use characters
+// from the Unicode private use area; they are removed before output is
+// written, either discarded by stripMarkers or converted to a SourceMap by
+// extractSourceMap.
+//
+// The markers around text with position pos are
+//
+//	markStart pos markPos text markEnd
+//
+// Marker characters that are part of the text itself (for example, in a Go
+// string literal or comment) are escaped by preceding them with markEsc.
+const (
+	markStart = '\uE000'
+	markPos   = '\uE001'
+	markEnd   = '\uE002'
+	markEsc   = '\uE003'
+)
+
+const markChars = string(markStart) + string(markPos) + string(markEnd) + string(markEsc)
+
+// escapeMarkers escapes the marker characters in s, so that it is output
+// as is
+func escapeMarkers(s string) string {
+	if !strings.ContainsAny(s, markChars) {
+		return s
+	}
+	var out strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(markChars, r) {
+			out.WriteRune(markEsc)
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// markDoc wraps d in source markers for pos
+func markDoc(pos Position, d doc) doc {
+	if !pos.IsValid() {
+		return d
+	}
+	return concat(docMark(string(markStart)+escapeMarkers(pos.String())+string(markPos)),
+		d, docMark(string(markEnd)))
+}
+
+// stripMarkers removes all source markers from s
+func stripMarkers(s string) string {
+	if !strings.ContainsAny(s, markChars) {
+		return s
+	}
+	s, _ = extractSourceMap(s)
+	return s
+}
+
+// SourceMap relates spans of a generated file to the Go code they were
+// translated from.
+//
+// Spans are given as 1-based lines and 0-based byte columns, which matches the
+// locations Coq reports in errors. The end of a span is exclusive.
+type SourceMap struct {
+	Version int `json:"version"`
+	// File is the generated file the spans refer to
+	File     string    `json:"file"`
+	Mappings []Mapping `json:"mappings"`
+}
+
+// SourceMapVersion is the version of the source map format.
+const SourceMapVersion = 1
+
+// Mapping is a span of the generated file along with the Go position it was
+// translated from.
+//
+// Spans nest, so a position in the output is generally covered by several
+// mappings. SourceMap.Mappings are sorted by their start, with enclosing spans
+// before the spans they contain.
+type Mapping struct {
+	StartLine int `json:"startLine"`
+	StartCol  int `json:"startCol"`
+	EndLine   int `json:"endLine"`
+	EndCol    int `json:"endCol"`
+	// File, Line and Col are the Go position of the span, with the same
+	// meaning as in a Position
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Col  int    `json:"col"`
+}
+
+// Source is the Go position of the span.
+func (m Mapping) Source() Position {
+	return Position{Filename: m.File, Line: m.Line, Column: m.Col}
+}
+
+func (m Mapping) contains(line, col int) bool {
+	return !before(line, col, m.StartLine, m.StartCol) &&
+		before(line, col, m.EndLine, m.EndCol)
+}
+
+// before reports whether line1:col1 comes before line2:col2
+func before(line1, col1, line2, col2 int) bool {
+	return line1 < line2 || (line1 == line2 && col1 < col2)
+}
+
+// Lookup finds the Go position of a location in the generated file, using the
+// innermost span that contains it.
+func (m SourceMap) Lookup(line, col int) (Position, bool) {
+	var found *Mapping
+	for i := range m.Mappings {
+		// later mappings that contain the location are nested inside
+		// earlier ones
+		if m.Mappings[i].contains(line, col) {
+			found = &m.Mappings[i]
+		}
+	}
+	if found == nil {
+		return Position{}, false
+	}
+	return found.Source(), true
+}
+
+// parsePosition parses the output of Position.String
+func parsePosition(s string) Position {
+	var p Position
+	rest, col, _ := cutLast(s, ":")
+	file, line, _ := cutLast(rest, ":")
+	p.Filename = file
+	p.Line, _ = strconv.Atoi(line)
+	p.Column, _ = strconv.Atoi(col)
+	return p
+}
+
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// extractSourceMap removes the source markers from s, returning the resulting
+// text and the mappings the markers described.
+//
+// It panics if the markers are malformed, which render never produces.
+func extractSourceMap(s string) (string, []Mapping) {
+	var out strings.Builder
+	var mappings []Mapping
+	// mappings are added when their span starts, so they are sorted by start
+	// with enclosing spans first
+	//
+	// open holds the indices in mappings of the spans that have not ended
+	var open []int
+	// pos is the position being read between markStart and markPos
+	var pos *strings.Builder
+	line, col := 1, 0
+	escaped := false
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		// the text is copied byte for byte, which preserves invalid UTF-8
+		text := s[i : i+size]
+		i += size
+		if escaped {
+			escaped = false
+		} else {
+			switch r {
+			case markEsc:
+				escaped = true
+				continue
+			case markStart:
+				if pos != nil {
+					panic("glang: nested source marker position")
+				}
+				pos = new(strings.Builder)
+				continue
+			case markPos:
+				if pos == nil {
+					panic("glang: source marker position without a start")
+				}
+				open = append(open, len(mappings))
+				p := parsePosition(pos.String())
+				pos = nil
+				mappings = append(mappings, Mapping{
+					StartLine: line,
+					StartCol:  col,
+					File:      p.Filename,
+					Line:      p.Line,
+					Col:       p.Column,
+				})
+				continue
+			case markEnd:
+				if len(open) == 0 || pos != nil {
+					panic("glang: unbalanced source markers")
+				}
+				m := &mappings[open[len(open)-1]]
+				open = open[:len(open)-1]
+				m.EndLine, m.EndCol = line, col
+				continue
+			}
+		}
+		if pos != nil {
+			pos.WriteString(text)
+			continue
+		}
+		out.WriteString(text)
+		if r == '\n' {
+			line, col = line+1, 0
+		} else {
+			col += size
+		}
+	}
+	if len(open) > 0 || pos != nil || escaped {
+		panic("glang: unbalanced source markers")
+	}
+	return out.String(), mappings
+}
+
+// WriteWithSourceMap outputs the Coq source for a File, like Write, and
+// returns a source map relating the output to the Go code.
+//
+// The File field of the source map is left for the caller to fill in, since
+// only the caller knows where the output goes.
+func (f File) WriteWithSourceMap(w io.Writer) SourceMap {
+	code, mappings := extractSourceMap(f.coqSource())
+	io.WriteString(w, code)
+	if mappings == nil {
+		mappings = []Mapping{}
+	}
+	return SourceMap{Version: SourceMapVersion, Mappings: mappings}
+}
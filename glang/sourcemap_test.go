@@ -0,0 +1,136 @@
+package glang
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sourceMapFile() File {
+	lt := Located{
+		Pos: Position{Filename: "a.go", Line: 4, Column: 9},
+		Expr: BinaryExpr{
+			X:  Located{Pos: Position{Filename: "a.go", Line: 4, Column: 9}, Expr: IdentExpr("x")},
+			Op: OpLessThan,
+			Y:  Located{Pos: Position{Filename: "a.go", Line: 4, Column: 13}, Expr: IntLiteral{4}},
+		},
+	}
+	return File{
+		PkgPath:   "example.com/a",
+		GoPackage: "a",
+		Decls: []Decl{
+			LocatedDecl{
+				Pos: Position{Filename: "a.go", Line: 3, Column: 1},
+				Decl: FuncDecl{
+					Name:       "f",
+					Args:       []FieldDecl{{Name: "x", Type: TypeIdent("uint64T")}},
+					ReturnType: TypeIdent("boolT"),
+					Body:       ReturnExpr{Value: lt},
+				},
+			},
+		},
+	}
+}
+
+// find returns the line and column of the first occurrence of s in text
+func find(text, s string) (line, col int) {
+	i := strings.Index(text, s)
+	before := text[:i]
+	line = strings.Count(before, "\n") + 1
+	col = i - (strings.LastIndex(before, "\n") + 1)
+	return
+}
+
+func TestSourceMap(t *testing.T) {
+	assert := assert.New(t)
+	f := sourceMapFile()
+	var plain, mapped bytes.Buffer
+	f.Write(&plain)
+	m := f.WriteWithSourceMap(&mapped)
+	assert.Equal(plain.String(), mapped.String(), "source maps should not change output")
+	assert.NotContains(plain.String(), string(markStart))
+	assert.Equal(SourceMapVersion, m.Version)
+	assert.Len(m.Mappings, 4)
+
+	text := plain.String()
+	line, col := find(text, "#4")
+	pos, ok := m.Lookup(line, col)
+	if assert.True(ok) {
+		assert.Equal(Position{Filename: "a.go", Line: 4, Column: 13}, pos)
+	}
+	line, col = find(text, " < ")
+	pos, ok = m.Lookup(line, col)
+	if assert.True(ok) {
+		assert.Equal(Position{Filename: "a.go", Line: 4, Column: 9}, pos)
+	}
+	line, col = find(text, "Definition f")
+	pos, ok = m.Lookup(line, col)
+	if assert.True(ok) {
+		assert.Equal(Position{Filename: "a.go", Line: 3, Column: 1}, pos)
+	}
+	_, ok = m.Lookup(1, 0)
+	assert.False(ok, "the file header has no Go position")
+}
+
+func TestPrinterStripsMarkers(t *testing.T) {
+	e := ListExpr{Located{
+		Pos:  Position{Filename: "a.go", Line: 1, Column: 1},
+		Expr: UnitLiteral{},
+	}}
+	s, err := CoqPrinter.Expr(e)
+	assert.NoError(t, err)
+	assert.Equal(t, "[ #() ]", s)
+}
+
+func TestMarkerCharactersInText(t *testing.T) {
+	assert := assert.New(t)
+	// text that happens to contain the marker characters (here in a comment
+	// and a string literal) is output as is
+	text := "a \uE000 b \uE001 c \uE002 d \uE003"
+	f := sourceMapFile()
+	f.Decls = append([]Decl{CommentDecl(text)}, f.Decls...)
+	f.Decls = append(f.Decls, LocatedDecl{
+		Pos: Position{Filename: "a.go", Line: 7, Column: 1},
+		Decl: ConstDecl{Name: "s", Val: Located{
+			Pos:  Position{Filename: "a.go", Line: 7, Column: 9},
+			Expr: StringLiteral{Value: text},
+		}},
+	})
+	var plain, mapped bytes.Buffer
+	f.Write(&plain)
+	m := f.WriteWithSourceMap(&mapped)
+	assert.Equal(plain.String(), mapped.String())
+	assert.Equal(2, strings.Count(plain.String(), text))
+	line, col := find(plain.String(), "#4")
+	pos, ok := m.Lookup(line, col)
+	if assert.True(ok) {
+		assert.Equal(Position{Filename: "a.go", Line: 4, Column: 13}, pos)
+	}
+	line, col = find(plain.String(), "Definition s")
+	pos, ok = m.Lookup(line, col)
+	if assert.True(ok) {
+		assert.Equal(Position{Filename: "a.go", Line: 7, Column: 1}, pos)
+	}
+}
+
+func TestCoqHasNoMarkers(t *testing.T) {
+	assert := assert.New(t)
+	f := sourceMapFile()
+	d := f.Decls[0]
+	assert.NotContains(d.CoqDecl(), string(markStart))
+	e := d.(LocatedDecl).Decl.(FuncDecl).Body
+	assert.Equal(`return: "x" < #4`, e.Coq(false))
+}
+
+func TestUnbalancedMarkers(t *testing.T) {
+	for _, s := range []string{
+		"\uE000a.go:1:1",
+		"\uE000a.go:1:1\uE001x",
+		"x\uE002",
+		"\uE001x",
+	} {
+		assert.Panics(t, func() { extractSourceMap(s) }, "%q", s)
+	}
+}
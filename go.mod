@@ -5,8 +5,10 @@ go 1.22
 require (
 	github.com/fatih/color v1.17.0
 	github.com/pkg/errors v0.9.1
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/stretchr/testify v1.9.0
 	github.com/tchajed/marshal v0.4.3
+	golang.org/x/mod v0.19.0
 	golang.org/x/sys v0.22.0
 	golang.org/x/tools v0.23.0
 )
@@ -16,8 +18,6 @@ require (
 	github.com/goose-lang/std v0.0.0-20220414201102-c41554454045 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/mod v0.19.0 // indirect
 	golang.org/x/sync v0.7.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -19,6 +19,7 @@ import (
 	"go/printer"
 	"go/token"
 	"go/types"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"unicode"
@@ -36,51 +37,75 @@ type Ctx struct {
 	Config
 
 	dep *depTracker
+	// recFuncs are the functions being defined recursively, which are
+	// referred to through the variable bound by rec:
+	recFuncs map[string]glang.Expr
+	// recTypes are the struct types being defined recursively
+	recTypes map[string]bool
+	// depGraph, if non-nil, collects the dependency graph of the package's
+	// declarations
+	depGraph *DepGraph
+
+	// sig is the signature of the enclosing function
+	sig *types.Signature
+	// namedResults are the result variables of the enclosing function, which
+	// are only supported in functions that use defer
+	namedResults []glang.FieldDecl
+	// inDeferredFunc is set while translating a function literal that is
+	// immediately deferred, the only place recover() is supported
+	inDeferredFunc bool
+	// recovers is set while translating a function that calls recover(),
+	// where panics carry their value so that it can be recovered
+	recovers bool
+	// inYield is set while translating the body of a range over a function,
+	// which becomes the yield closure passed to the iterator
+	inYield bool
+	// loopIsYield is set if break and continue in the innermost loop must
+	// return from a yield closure
+	loopIsYield bool
+	// label is the label of the loop being translated, and loopLabel that of
+	// the innermost loop (nil if they are unlabelled)
+	label, loopLabel types.Object
+	// sentinels are the package's global sentinel errors, which are
+	// translated as constants (see sentinelError)
+	sentinels map[types.Object]bool
 }
 
 // Config holds global configuration for Coq conversion
 type Config struct {
 	AddSourceFileComments bool
 	TypeCheck             bool
-	Ffi                   string
-}
-
-func getFfi(pkg *packages.Package) string {
-	seenFfis := make(map[string]struct{})
-	packages.Visit([]*packages.Package{pkg},
-		func(pkg *packages.Package) bool {
-			// the dependencies of an FFI are not considered as being used; this
-			// allows one FFI to be built on top of another
-			if _, ok := ffiMapping[pkg.PkgPath]; ok {
-				return false
-			}
-			return true
-		},
-		func(pkg *packages.Package) {
-			if ffi, ok := ffiMapping[pkg.PkgPath]; ok {
-				seenFfis[ffi] = struct{}{}
-			}
-		},
-	)
-
-	if len(seenFfis) > 1 {
-		panic(fmt.Sprintf("multiple ffis used %v", seenFfis))
-	}
-	for ffi := range seenFfis {
-		return ffi
-	}
-	return "none"
+	// Ffis are the preludes of the FFIs used by the package (none if empty)
+	Ffis []string
+	// MaxErrors is the number of errors after which translation of a package
+	// stops (0 for no limit)
+	MaxErrors int
+	// externals are the FFIs available, by package path, for translating
+	// references to their functions
+	externals map[string]Ffi
+	// translated are the other packages translated by goose, whose types have
+	// translated methods
+	translated map[string]bool
 }
 
 // NewPkgCtx initializes a context based on a properly loaded package
-func NewPkgCtx(pkg *packages.Package, tr Translator) Ctx {
+//
+// It fails if the package uses FFIs that cannot be used together.
+func NewPkgCtx(pkg *packages.Package, tr Translator) (Ctx, error) {
 	// Figure out which FFI we're using
 	var config Config
 	// TODO: this duplication is bad, Config should probably embed Translator or
 	//   some other cleanup is needed
 	config.TypeCheck = tr.TypeCheck
 	config.AddSourceFileComments = tr.AddSourceFileComments
-	config.Ffi = getFfi(pkg)
+	config.MaxErrors = tr.MaxErrors
+	ffis, err := tr.getFfis(pkg)
+	if err != nil {
+		return Ctx{}, err
+	}
+	config.Ffis = ffis
+	config.externals = tr.ffis()
+	config.translated = tr.translated
 
 	return Ctx{
 		info:          pkg.TypesInfo,
@@ -88,7 +113,7 @@ func NewPkgCtx(pkg *packages.Package, tr Translator) Ctx {
 		pkgPath:       pkg.PkgPath,
 		errorReporter: newErrorReporter(pkg.Fset),
 		Config:        config,
-	}
+	}, nil
 }
 
 // NewCtx loads a context for files passed directly,
@@ -113,6 +138,48 @@ func NewCtx(pkgPath string, conf Config) Ctx {
 	}
 }
 
+// NewTypedCtx creates a context for files that have already been type-checked,
+// for example by an analysis driver.
+func NewTypedCtx(pkgPath string, fset *token.FileSet, info *types.Info, conf Config) Ctx {
+	return Ctx{
+		info:          info,
+		Fset:          fset,
+		pkgPath:       pkgPath,
+		errorReporter: newErrorReporter(fset),
+		Config:        conf,
+	}
+}
+
+// TypedCtx creates a context for a package that has already been
+// type-checked, configured like tr (see LoadConfig).
+//
+// Without a list of the packages being translated, the translated packages are
+// taken to be those pkg imports from modulePath, other than excluded ones.
+func (tr Translator) TypedCtx(pkg *types.Package, modulePath string,
+	fset *token.FileSet, info *types.Info) Ctx {
+	conf := Config{
+		AddSourceFileComments: tr.AddSourceFileComments,
+		TypeCheck:             tr.TypeCheck,
+		MaxErrors:             tr.MaxErrors,
+		externals:             tr.ffis(),
+		translated:            make(map[string]bool),
+	}
+	var visit func(pkg *types.Package)
+	visit = func(pkg *types.Package) {
+		for _, imp := range pkg.Imports() {
+			path := imp.Path()
+			inModule := path == modulePath || strings.HasPrefix(path, modulePath+"/")
+			if conf.translated[path] || !inModule || tr.Excluded(path) {
+				continue
+			}
+			conf.translated[path] = true
+			visit(imp)
+		}
+	}
+	visit(pkg)
+	return NewTypedCtx(pkg.Path(), fset, info, conf)
+}
+
 // FIXME: this is currently never called
 // TypeCheck type-checks a set of files and stores the result in the Ctx
 //
@@ -128,6 +195,29 @@ func (ctx Ctx) where(node ast.Node) string {
 	return ctx.Fset.Position(node.Pos()).String()
 }
 
+// position gives the glang position of node, identifying its file by base
+// name so positions don't depend on where the package is
+func (ctx Ctx) position(node ast.Node) glang.Position {
+	p := ctx.Fset.Position(node.Pos())
+	return glang.Position{
+		Filename: filepath.Base(p.Filename),
+		Line:     p.Line,
+		Column:   p.Column,
+	}
+}
+
+// located annotates the translation of node with its position (unless it
+// already has a more precise one)
+func (ctx Ctx) located(node ast.Node, e glang.Expr) glang.Expr {
+	if e == nil {
+		return nil
+	}
+	if _, ok := e.(glang.Located); ok {
+		return e
+	}
+	return glang.Located{Pos: ctx.position(node), Expr: e}
+}
+
 func (ctx Ctx) printGo(node ast.Node) string {
 	var what bytes.Buffer
 	err := printer.Fprint(&what, ctx.Fset, node)
@@ -239,6 +329,52 @@ func (ctx Ctx) typeDecl(spec *ast.TypeSpec) glang.Decl {
 	}
 }
 
+// methodSetDecls declares the method tables for the type declared by spec and
+// for pointers to it, returning them along with the names of the methods they
+// refer to.
+//
+// The table for pointers has all of the type's methods while the table for
+// the type has those with a value receiver; methods with a value receiver take
+// the pointer and load the receiver through it in the pointer table.
+func (ctx Ctx) methodSetDecls(spec *ast.TypeSpec) (decls []glang.Decl, methods []string) {
+	obj, ok := ctx.info.Defs[spec.Name].(*types.TypeName)
+	if !ok || spec.TypeParams != nil {
+		return nil, nil
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok || named.NumMethods() == 0 {
+		return nil, nil
+	}
+	valueSet, ptrSet, methods := ctx.methodSetEntries(spec, named)
+	return []glang.Decl{
+		glang.MethodSetDecl{Name: glang.MethodSetName(spec.Name.Name, false), Methods: valueSet},
+		glang.MethodSetDecl{Name: glang.MethodSetName(spec.Name.Name, true), Methods: ptrSet},
+	}, methods
+}
+
+// methodSetEntries are the entries of the method tables for named and for
+// pointers to it, along with the names of the methods they refer to
+func (ctx Ctx) methodSetEntries(n locatable, named *types.Named) (valueSet, ptrSet []glang.MethodSetEntry, methods []string) {
+	for i := 0; i < named.NumMethods(); i++ {
+		m := named.Method(i)
+		name := glang.TypeMethod(named.Obj().Name(), m.Name())
+		methods = append(methods, name)
+		if _, ptrRecv := m.Type().(*types.Signature).Recv().Type().(*types.Pointer); ptrRecv {
+			ptrSet = append(ptrSet,
+				glang.MethodSetEntry{Name: m.Name(), Method: name})
+			continue
+		}
+		valueSet = append(valueSet,
+			glang.MethodSetEntry{Name: m.Name(), Method: name})
+		ptrSet = append(ptrSet, glang.MethodSetEntry{
+			Name:   m.Name(),
+			Method: name,
+			Deref:  ctx.glangType(n, named),
+		})
+	}
+	return
+}
+
 func toInitialLower(s string) string {
 	pastFirstLetter := false
 	return strings.Map(func(r rune) rune {
@@ -294,7 +430,23 @@ func (ctx Ctx) packageMethod(f *ast.SelectorExpr,
 	call *ast.CallExpr) glang.Expr {
 	args := call.Args
 	pkg := f.X.(*ast.Ident)
-	return ctx.newCoqCall(glang.PackageIdent{Package: pkg.Name, Ident: f.Sel.Name}, args)
+	return ctx.newCoqCall(ctx.packageIdent(pkg, f.Sel.Name), args)
+}
+
+// packageIdent translates a reference to name in the imported package pkg,
+// using the external translation if the package is an FFI that has one
+func (ctx Ctx) packageIdent(pkg *ast.Ident, name string) glang.Expr {
+	qualifier := pkg.Name
+	if pkgName, ok := ctx.info.Uses[pkg].(*types.PkgName); ok {
+		ffi := ctx.externals[pkgName.Imported().Path()]
+		if coq, ok := ffi.Functions[name]; ok {
+			return glang.GallinaIdent(coq)
+		}
+		// the package may be imported under a different name
+		qualifier = glang.PackageQualifier(pkgName.Imported().Path())
+	}
+	ctx.dep.addDep(qualifier + "." + name)
+	return glang.PackageIdent{Package: qualifier, Ident: name}
 }
 
 func (ctx Ctx) newCoqCall(method glang.Expr, es []ast.Expr) glang.CallExpr {
@@ -313,9 +465,96 @@ func (ctx Ctx) methodExpr(call *ast.CallExpr) glang.Expr {
 		}
 	}
 
+	if sig, ok := ctx.typeOf(call.Fun).(*types.Signature); ok &&
+		!sig.Variadic() && sig.Params().Len() == len(call.Args) {
+		var args []glang.Expr
+		for i, arg := range call.Args {
+			args = append(args, ctx.exprOfType(arg, sig.Params().At(i).Type()))
+		}
+		return glang.NewCallExpr(ctx.expr(call.Fun), args...)
+	}
 	return ctx.newCoqCall(ctx.expr(call.Fun), call.Args)
 }
 
+// exprOfType translates e where a value of type t is expected.
+//
+// The expected type determines the representation of an untyped nil and
+// whether e needs to be converted to an interface value.
+func (ctx Ctx) exprOfType(e ast.Expr, t types.Type) glang.Expr {
+	if t == nil || !ctx.isTranslatedInterface(t) {
+		return ctx.expr(e)
+	}
+	if ctx.info.Types[e].IsNil() {
+		return glang.InterfaceNil
+	}
+	if types.IsInterface(ctx.typeOf(e)) {
+		return ctx.expr(e)
+	}
+	return glang.NewCallExpr(glang.GallinaIdent("interface.make"),
+		glang.GallinaString(ctx.typeId(ctx.typeOf(e))),
+		ctx.methodSet(e, ctx.typeOf(e)),
+		ctx.expr(e))
+}
+
+// methodSet is the method table for interface values with dynamic type t
+//
+// Only named types declared in translated packages have methods in the
+// translation; the table for any other type is empty.
+func (ctx Ctx) methodSet(n locatable, t types.Type) glang.Expr {
+	ptr := false
+	if pt, ok := t.(*types.Pointer); ok {
+		ptr = true
+		t = pt.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.NumMethods() == 0 ||
+		named.Obj().Pkg() == nil || !ctx.isTranslatedPkg(named.Obj().Pkg().Path()) {
+		return glang.ListExpr{}
+	}
+	if named.TypeArgs().Len() > 0 {
+		ctx.unsupported(n, "converting generic type %v with methods to an interface", t)
+	}
+	if ctx.sig != nil && ctx.sig.Recv() != nil {
+		if recvType := ctx.sig.Recv().Type(); types.Identical(recvType, named) ||
+			types.Identical(recvType, types.NewPointer(named)) {
+			// The method table is defined after the methods, so a method of the
+			// type builds the table itself, referring to the methods in the
+			// same way as calls to them do.
+			valueSet, ptrSet, _ := ctx.methodSetEntries(n, named)
+			entries := valueSet
+			if ptr {
+				entries = ptrSet
+			}
+			var table glang.ListExpr
+			for _, m := range entries {
+				m.Ref = ctx.funcRef(m.Method)
+				table = append(table, glang.TupleExpr{glang.GallinaString(m.Name), m.Fn()})
+			}
+			return table
+		}
+	}
+	name := glang.MethodSetName(ctx.qualifiedName(named.Obj()), ptr)
+	ctx.dep.addDep(name)
+	return glang.GallinaIdent(name)
+}
+
+// typeId is the run-time identifier for the dynamic type of an interface
+// value, which determines its methods
+func (ctx Ctx) typeId(t types.Type) string {
+	switch t := t.(type) {
+	case *types.Named:
+		if t.Obj().Pkg() == nil {
+			return t.Obj().Name()
+		}
+		return t.Obj().Pkg().Path() + "." + t.Obj().Name()
+	case *types.Pointer:
+		return ctx.typeId(t.Elem()) + "'ptr"
+	case *types.Basic:
+		return types.Default(t).String()
+	}
+	return t.String()
+}
+
 func (ctx Ctx) makeSliceExpr(elt glang.Type, args []ast.Expr) glang.CallExpr {
 	if len(args) == 2 {
 		return glang.NewCallExpr(glang.GallinaIdent("slice.make2"), elt, ctx.expr(args[1]))
@@ -474,21 +713,149 @@ func (ctx Ctx) builtinCallExpr(s *ast.CallExpr) glang.Expr {
 		}
 		return glang.NewCallExpr(glang.GallinaIdent("MapDelete"), ctx.expr(s.Args[0]), ctx.expr(s.Args[1]))
 	case "panic":
-		msg := "oops"
-		if e, ok := s.Args[0].(*ast.BasicLit); ok {
-			if e.Kind == token.STRING {
-				v := ctx.info.Types[e].Value
-				msg = constant.StringVal(v)
-			}
+		// the panic value is observable through recover, so it has to be
+		// carried along unless it is a message that is never recovered
+		if v := ctx.info.Types[s.Args[0]].Value; v != nil &&
+			v.Kind() == constant.String && !ctx.recovers {
+			return glang.NewCallExpr(glang.GallinaIdent("Panic"),
+				glang.GallinaString(constant.StringVal(v)))
+		}
+		return glang.NewCallExpr(glang.GallinaIdent("PanicValue"),
+			ctx.exprOfType(s.Args[0], types.NewInterfaceType(nil, nil)))
+	case "recover":
+		if !ctx.inDeferredFunc {
+			ctx.unsupported(s, "recover outside of a deferred function literal")
 		}
-		return glang.NewCallExpr(glang.GallinaIdent("Panic"), glang.GallinaString(msg))
+		return glang.NewCallExpr(glang.GallinaIdent("recover"))
 	default:
 		ctx.unsupported(s, "builtin %s not supported", funName)
 		return nil
 	}
 }
 
+// isPackageFunc checks if e refers to the function name in the package
+// with path pkgPath
+func (ctx Ctx) isPackageFunc(e ast.Expr, pkgPath string, name string) bool {
+	sel, ok := e.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	f, ok := ctx.info.Uses[sel.Sel].(*types.Func)
+	return ok && f.Pkg() != nil && f.Pkg().Path() == pkgPath && f.Name() == name
+}
+
+// errorfExpr translates a restricted subset of fmt.Errorf: the format must be
+// a constant, the only verbs are %s and %v (for strings and errors) and %w, and
+// at most one error can be wrapped.
+//
+// The message is computed eagerly by concatenation, using the Error() method of
+// any errors in the arguments.
+func (ctx Ctx) errorfExpr(s *ast.CallExpr) glang.Expr {
+	if s.Ellipsis != token.NoPos {
+		ctx.unsupported(s, "fmt.Errorf with variadic arguments")
+	}
+	format := ctx.info.Types[s.Args[0]].Value
+	if format == nil || format.Kind() != constant.String {
+		ctx.unsupported(s.Args[0], "fmt.Errorf with a non-constant format")
+	}
+	args := s.Args[1:]
+	var wrapped glang.Expr
+	var parts []glang.Expr
+	lit := ""
+	addLit := func() {
+		if lit != "" {
+			if strings.ContainsRune(lit, '"') {
+				ctx.unsupported(s.Args[0], "string literals with quotes")
+			}
+			parts = append(parts, glang.StringLiteral{Value: lit})
+			lit = ""
+		}
+	}
+	errorMsg := func(e glang.Expr) glang.Expr {
+		return glang.NewCallExpr(glang.NewCallExpr(glang.GallinaIdent("interface.get"),
+			glang.GallinaString("Error"), e))
+	}
+	argIdx := 0
+	fmtStr := constant.StringVal(format)
+	for i := 0; i < len(fmtStr); i++ {
+		if fmtStr[i] != '%' {
+			lit += string(fmtStr[i])
+			continue
+		}
+		i++
+		if i == len(fmtStr) {
+			ctx.unsupported(s.Args[0], "fmt.Errorf format ends with %%")
+		}
+		verb := fmtStr[i]
+		if verb == '%' {
+			lit += "%"
+			continue
+		}
+		if argIdx >= len(args) {
+			ctx.unsupported(s, "fmt.Errorf with too few arguments")
+		}
+		arg := args[argIdx]
+		argTy := ctx.typeOf(arg)
+		v := glang.IdentExpr(fmt.Sprintf("$fmt%d", argIdx))
+		argIdx++
+		addLit()
+		switch {
+		case verb == 'w' && isErrorType(argTy):
+			if wrapped != nil {
+				ctx.unsupported(arg, "fmt.Errorf wrapping multiple errors")
+			}
+			wrapped = v
+			parts = append(parts, errorMsg(v))
+		case (verb == 's' || verb == 'v') && isErrorType(argTy):
+			parts = append(parts, errorMsg(v))
+		case (verb == 's' || verb == 'v') && isString(argTy.Underlying()):
+			parts = append(parts, v)
+		default:
+			ctx.unsupported(arg, "fmt.Errorf verb %%%c with argument of type %v "+
+				"(only %%s, %%v and %%w of strings and errors are supported)",
+				verb, argTy)
+		}
+	}
+	addLit()
+	if argIdx != len(args) {
+		ctx.unsupported(s, "fmt.Errorf with too many arguments")
+	}
+
+	var msg glang.Expr = glang.StringLiteral{Value: ""}
+	if len(parts) > 0 {
+		msg = parts[0]
+		for _, p := range parts[1:] {
+			msg = glang.BinaryExpr{X: msg, Op: glang.OpAppend, Y: p}
+		}
+	}
+	var e glang.Expr
+	if wrapped == nil {
+		e = glang.NewCallExpr(glang.GallinaIdent("errors.New"), msg)
+	} else {
+		e = glang.NewCallExpr(glang.GallinaIdent("errors.Wrap"), msg, wrapped)
+	}
+	for i := len(args); i > 0; i-- {
+		e = glang.LetExpr{
+			Names:   []string{fmt.Sprintf("$fmt%d", i-1)},
+			ValExpr: ctx.expr(args[i-1]),
+			Cont:    e,
+		}
+	}
+	return glang.ParenExpr{Inner: e}
+}
+
 func (ctx Ctx) callExpr(s *ast.CallExpr) glang.Expr {
+	if ctx.isPackageFunc(s.Fun, "fmt", "Errorf") {
+		return ctx.errorfExpr(s)
+	}
+	if f, ok := s.Fun.(*ast.SelectorExpr); ok {
+		if fn, ok := ctx.info.Uses[f.Sel].(*types.Func); ok &&
+			fn.Pkg() != nil && fn.Pkg().Path() == "sync/atomic" &&
+			fn.Type().(*types.Signature).Recv() == nil &&
+			!atomicFuncs[fn.Name()] {
+			ctx.unsupported(s, "function %s from sync/atomic", fn.Name())
+		}
+	}
 	if ctx.info.Types[s.Fun].IsType() {
 		return ctx.conversionExpr(s)
 	} else if ctx.info.Types[s.Fun].IsBuiltin() {
@@ -498,6 +865,8 @@ func (ctx Ctx) callExpr(s *ast.CallExpr) glang.Expr {
 	}
 }
 
+// qualifiedName is the Coq name for obj, which is qualified by its package if
+// it is imported
 func (ctx Ctx) qualifiedName(obj types.Object) string {
 	name := obj.Name()
 	if obj.Pkg() == nil {
@@ -506,7 +875,7 @@ func (ctx Ctx) qualifiedName(obj types.Object) string {
 		// no module name needed
 		return name
 	}
-	return fmt.Sprintf("%s.%s", obj.Pkg().Name(), name)
+	return fmt.Sprintf("%s.%s", glang.PackageQualifier(obj.Pkg().Path()), name)
 }
 
 func (ctx Ctx) selectorExpr(e *ast.SelectorExpr) glang.Expr {
@@ -518,13 +887,20 @@ func (ctx Ctx) selectorExpr(e *ast.SelectorExpr) glang.Expr {
 		if isIdent(e.X, "disk") {
 			return glang.GallinaIdent("disk." + e.Sel.Name)
 		}
-		if pkg, ok := getIdent(e.X); ok {
-			return glang.PackageIdent{
-				Package: pkg,
-				Ident:   e.Sel.Name,
-			}
+		if pkg, ok := e.X.(*ast.Ident); ok {
+			return ctx.packageIdent(pkg, e.Sel.Name)
 		}
 	}
+	if name, ok := syncPrimitive(selectorType); ok {
+		return ctx.syncMethod(name, e)
+	}
+	if isAtomicType(selectorType) {
+		ctx.unsupported(e, "type %v from sync/atomic", selectorType)
+	}
+	if selectorType != nil && ctx.isTranslatedInterface(selectorType) {
+		return glang.NewCallExpr(glang.GallinaIdent("interface.get"),
+			glang.GallinaString(e.Sel.Name), ctx.expr(e.X))
+	}
 	structInfo, ok := ctx.getStructInfo(selectorType)
 
 	if ok {
@@ -545,8 +921,32 @@ func (ctx Ctx) selectorExpr(e *ast.SelectorExpr) glang.Expr {
 	}
 	// must be method
 	m := glang.TypeMethod(structInfo.name, e.Sel.Name)
-	ctx.dep.addDep(m)
-	return glang.NewCallExpr(glang.GallinaIdent(m), ctx.expr(e.X))
+	return glang.NewCallExpr(ctx.funcRef(m), ctx.expr(e.X))
+}
+
+// syncMethod translates a method of a sync or sync/atomic type to its
+// dedicated GooseLang primitive.
+//
+// These methods all have pointer receivers, so the primitive gets the address
+// of the receiver even when it is stored by value (e.g., a mutex embedded in a
+// struct as a field).
+func (ctx Ctx) syncMethod(typeName string, e *ast.SelectorExpr) glang.Expr {
+	supported := false
+	for _, m := range syncMethods[typeName] {
+		if m == e.Sel.Name {
+			supported = true
+		}
+	}
+	if !supported {
+		ctx.unsupported(e, "method %s of %s", e.Sel.Name, typeName)
+	}
+	var recv glang.Expr
+	if _, ok := ctx.typeOf(e.X).(*types.Pointer); ok {
+		recv = ctx.expr(e.X)
+	} else {
+		recv = ctx.exprAddr(e.X)
+	}
+	return glang.NewCallExpr(glang.GallinaIdent(glang.TypeMethod(typeName, e.Sel.Name)), recv)
 }
 
 func (ctx Ctx) compositeLiteral(e *ast.CompositeLit) glang.Expr {
@@ -697,8 +1097,11 @@ func (ctx Ctx) binExpr(e *ast.BinaryExpr) glang.Expr {
 			Y:  ctx.expr(e.Y),
 		}
 		if ctx.isNilCompareExpr(e) {
-			if _, ok := ctx.typeOf(e.X).(*types.Pointer); ok {
+			switch ctx.typeOf(e.X).Underlying().(type) {
+			case *types.Pointer:
 				expr.Y = glang.Null
+			case *types.Interface:
+				expr.Y = glang.InterfaceNil
 			}
 		}
 		return expr
@@ -796,12 +1199,16 @@ func (ctx Ctx) variable(s *ast.Ident) glang.Expr {
 		ctx.dep.addDep(s.Name)
 		return glang.GallinaIdent(s.Name)
 	}
+	// sentinel errors are constants (see sentinelError)
+	if ctx.sentinels[ctx.info.Uses[s]] {
+		ctx.dep.addDep(s.Name)
+		return glang.GallinaIdent(s.Name)
+	}
 	return glang.DerefExpr{X: glang.IdentExpr(s.Name), Ty: ctx.glangType(s, ctx.typeOf(s))}
 }
 
 func (ctx Ctx) function(s *ast.Ident) glang.Expr {
-	ctx.dep.addDep(s.Name)
-	return glang.GallinaIdent(s.Name)
+	return ctx.funcRef(s.Name)
 }
 
 func (ctx Ctx) goBuiltin(e *ast.Ident) bool {
@@ -879,16 +1286,48 @@ func (ctx Ctx) expr(e ast.Expr) glang.Expr {
 	return ctx.exprSpecial(e, false)
 }
 
-func (ctx Ctx) funcLit(e *ast.FuncLit) glang.FuncLit {
+// funcLit translates a function literal; deferred should be set if the literal
+// is the function in a defer statement, which is where recover() works.
+func (ctx Ctx) funcLit(e *ast.FuncLit, deferred bool) glang.FuncLit {
 	fl := glang.FuncLit{}
+	ctx.sig, _ = ctx.typeOf(e).(*types.Signature)
+	ctx.namedResults = nil
+	ctx.inDeferredFunc = deferred
+	ctx.recovers = ctx.recovers || ctx.callsRecover(e.Body)
+	ctx.inYield = false
+	ctx.loopIsYield = false
+	ctx.loopLabel = nil
 
 	fl.Args = ctx.paramList(e.Type.Params)
 	// fl.ReturnType = ctx.returnType(d.Type.Results)
 	fl.Body = ctx.blockStmt(e.Body)
+	if containsDefer(e.Body) {
+		fl.Body = glang.NewCallExpr(glang.GallinaIdent("with_defer:"), fl.Body)
+	}
+	// like a function declaration, the parameters are variables and the body
+	// returns with return:
+	for i := len(fl.Args); i > 0; i-- {
+		arg := fl.Args[i-1]
+		if arg.Name == "_" {
+			continue
+		}
+		fl.Body = glang.LetExpr{
+			Names:   []string{arg.Name},
+			ValExpr: glang.RefExpr{Ty: arg.Type, X: glang.IdentExpr(arg.Name)},
+			Cont:    fl.Body,
+		}
+	}
+	fl.Body = glang.NewCallExpr(glang.GallinaIdent("exception_do"), fl.Body)
 	return fl
 }
 
-func (ctx Ctx) exprSpecial(e ast.Expr, isSpecial bool) glang.Expr {
+func (ctx Ctx) exprSpecial(e ast.Expr, isSpecial bool) (expr glang.Expr) {
+	defer func() {
+		if r := recover(); r != nil {
+			expr = ctx.recoverError(r)
+		}
+		expr = ctx.located(e, expr)
+	}()
 	switch e := e.(type) {
 	case *ast.CallExpr:
 		return ctx.callExpr(e)
@@ -916,7 +1355,7 @@ func (ctx Ctx) exprSpecial(e ast.Expr, isSpecial bool) glang.Expr {
 		// TODO: do something with the type
 		return ctx.expr(e.X)
 	case *ast.FuncLit:
-		return ctx.funcLit(e)
+		return ctx.funcLit(e, false)
 	default:
 		ctx.unsupported(e, "unexpected expr")
 	}
@@ -951,24 +1390,9 @@ func (ctx Ctx) ifStmt(s *ast.IfStmt, cont glang.Expr) glang.Expr {
 	return glang.LetExpr{ValExpr: ife, Cont: cont}
 }
 
-func (ctx Ctx) loopVar(s ast.Stmt) (ident *ast.Ident, init glang.Expr) {
-	initAssign, ok := s.(*ast.AssignStmt)
-	if !ok ||
-		len(initAssign.Lhs) > 1 ||
-		len(initAssign.Rhs) > 1 ||
-		initAssign.Tok != token.DEFINE {
-		ctx.unsupported(s, "loop initialization must be a single assignment")
-		return nil, nil
-	}
-	lhs, ok := initAssign.Lhs[0].(*ast.Ident)
-	if !ok {
-		ctx.nope(s, "initialization must define an identifier")
-	}
-	rhs := initAssign.Rhs[0]
-	return lhs, ctx.expr(rhs)
-}
-
 func (ctx Ctx) forStmt(s *ast.ForStmt, cont glang.Expr) glang.Expr {
+	ctx.loopIsYield = false
+	ctx.loopLabel, ctx.label = ctx.label, nil
 	var cond glang.Expr = glang.True
 	if s.Cond != nil {
 		cond = ctx.expr(s.Cond)
@@ -1032,11 +1456,11 @@ func (ctx Ctx) identBinder(id *ast.Ident) glang.Binder {
 }
 
 func (ctx Ctx) sliceRangeStmt(s *ast.RangeStmt) glang.Expr {
-	if s.Tok != token.DEFINE {
+	if s.Key != nil && s.Tok != token.DEFINE {
 		ctx.unsupported(s.Key, "range with pre-existing variables")
 	}
-	key, ok := s.Key.(*ast.Ident)
-	if !ok {
+	key := getIdentOrNil(s.Key)
+	if s.Key != nil && key == nil {
 		ctx.todo(s.Key, "range with non-identifier as iteration variable")
 	}
 	valExpr := glang.Binder(nil)
@@ -1062,18 +1486,211 @@ func (ctx Ctx) sliceRangeStmt(s *ast.RangeStmt) glang.Expr {
 	}
 }
 
+// intRangeStmt translates a range over an integer, for i := range n.
+//
+// This is translated to an ordinary for loop over a hidden counter. The
+// iteration variable is a fresh copy of the counter in each iteration, so the
+// body can modify (or capture) it without affecting the loop, as in Go 1.22.
+func (ctx Ctx) intRangeStmt(s *ast.RangeStmt) glang.Expr {
+	if s.Key != nil && s.Tok != token.DEFINE {
+		ctx.unsupported(s.Key, "range with pre-existing variables")
+	}
+	key := getIdentOrNil(s.Key)
+	if s.Key != nil && key == nil {
+		ctx.todo(s.Key, "range with non-identifier as iteration variable")
+	}
+	xTy := ctx.typeOf(s.X)
+	if info, _ := getIntegerType(xTy); info.isUntyped {
+		// the loop variable has the constant's default type, int
+		xTy = types.Default(xTy)
+	}
+	info, _ := getIntegerType(xTy)
+	ty := ctx.glangType(s.X, xTy)
+	counter := glang.IdentExpr("$i")
+	current := glang.DerefExpr{X: counter, Ty: ty}
+
+	body := ctx.blockStmt(s.Body)
+	if key != nil && key.Name != "_" {
+		body = glang.LetExpr{
+			Names:   []string{key.Name},
+			ValExpr: glang.RefExpr{X: current, Ty: ty},
+			Cont:    body,
+		}
+	}
+	var e glang.Expr = glang.ForLoopExpr{
+		Cond: glang.BinaryExpr{X: current, Op: glang.OpLessThan, Y: glang.IdentExpr("$range")},
+		Post: glang.StoreStmt{
+			Dst: counter,
+			X:   glang.BinaryExpr{X: current, Op: glang.OpPlus, Y: intLiteral(info, 1)},
+			Ty:  ty,
+		},
+		Body: body,
+	}
+	e = glang.LetExpr{
+		Names:   []string{"$i"},
+		ValExpr: glang.RefExpr{X: intLiteral(info, 0), Ty: ty},
+		Cont:    e,
+	}
+	return glang.LetExpr{
+		Names:   []string{"$range"},
+		ValExpr: ctx.expr(s.X),
+		Cont:    e,
+	}
+}
+
+// intLiteral creates a literal n of an integer type
+func intLiteral(info intTypeInfo, n uint64) glang.Expr {
+	switch {
+	case info.width == 32:
+		return glang.Int32Literal{Value: uint32(n)}
+	case info.width == 8:
+		return glang.ByteLiteral{Value: uint8(n)}
+	}
+	return glang.IntLiteral{Value: n}
+}
+
+// funcRangeStmt translates a range over an iterator function,
+// for k, v := range seq.
+//
+// The loop body becomes a yield closure passed to seq, where continue returns
+// #true and break returns #false to stop the iteration. A return in the body
+// saves its results, sets "$returned" and stops the iteration; once the
+// iterator finishes, the enclosing function returns the saved results.
+func (ctx Ctx) funcRangeStmt(s *ast.RangeStmt, yieldSig *types.Signature) glang.Expr {
+	if s.Key != nil && s.Tok != token.DEFINE {
+		ctx.unsupported(s.Key, "range with pre-existing variables")
+	}
+	if yieldSig.Results().Len() != 1 {
+		ctx.unsupported(s.X, "range over function whose yield does not return bool")
+	}
+	var vars []*ast.Ident
+	for _, e := range []ast.Expr{s.Key, s.Value} {
+		if e == nil {
+			continue
+		}
+		id := getIdentOrNil(e)
+		if id == nil {
+			ctx.todo(e, "range with non-identifier as iteration variable")
+		}
+		vars = append(vars, id)
+	}
+
+	bodyCtx := ctx
+	bodyCtx.inYield = true
+	bodyCtx.loopIsYield = true
+	var body glang.Expr = glang.ReturnExpr{Value: glang.True}
+	for i := len(s.Body.List); i > 0; i-- {
+		body = bodyCtx.stmt(s.Body.List[i-1], body)
+	}
+	yield := glang.FuncLit{}
+	for i := 0; i < yieldSig.Params().Len(); i++ {
+		arg := glang.FieldDecl{
+			Name: "_",
+			Type: ctx.glangType(s.X, yieldSig.Params().At(i).Type()),
+		}
+		if i < len(vars) {
+			arg.Name = vars[i].Name
+		}
+		yield.Args = append(yield.Args, arg)
+	}
+	for i := len(yield.Args); i > 0; i-- {
+		arg := yield.Args[i-1]
+		if arg.Name == "_" {
+			continue
+		}
+		body = glang.LetExpr{
+			Names:   []string{arg.Name},
+			ValExpr: glang.RefExpr{Ty: arg.Type, X: glang.IdentExpr(arg.Name)},
+			Cont:    body,
+		}
+	}
+	yield.Body = glang.NewCallExpr(glang.GallinaIdent("exception_do"), body)
+
+	var e glang.Expr = glang.NewCallExpr(ctx.expr(s.X), yield)
+	if !containsReturn(s.Body) {
+		return e
+	}
+	returned := glang.DerefExpr{X: glang.IdentExpr("$returned"), Ty: glang.TypeIdent("boolT")}
+	e = glang.ParenExpr{Inner: glang.NewDoSeq(e, glang.IfExpr{
+		Cond: returned,
+		Then: ctx.yieldReturn(),
+		Else: glang.DoExpr{Expr: glang.Tt},
+	})}
+	if ctx.inYield {
+		// an enclosing range over a function has already allocated the
+		// results
+		return e
+	}
+	if retType := ctx.yieldResultsType(s); retType != nil {
+		e = glang.LetExpr{
+			Names: []string{"$ret"},
+			ValExpr: glang.RefExpr{
+				X:  glang.NewCallExpr(glang.GallinaIdent("zero_val"), retType),
+				Ty: retType,
+			},
+			Cont: e,
+		}
+	}
+	return glang.LetExpr{
+		Names:   []string{"$returned"},
+		ValExpr: glang.RefExpr{X: glang.False, Ty: glang.TypeIdent("boolT")},
+		Cont:    e,
+	}
+}
+
+// yieldReturn is the code to run after an iterator finishes because the body
+// of the loop returned
+func (ctx Ctx) yieldReturn() glang.Expr {
+	if ctx.inYield {
+		// propagate the return through the enclosing iterator
+		return glang.ReturnExpr{Value: glang.False}
+	}
+	if len(ctx.namedResults) > 0 {
+		return glang.ReturnExpr{Value: glang.TupleExpr{glang.Tt}}
+	}
+	if ctx.sig == nil || ctx.sig.Results().Len() == 0 {
+		return glang.ReturnExpr{Value: glang.TupleExpr{glang.Tt}}
+	}
+	retType := ctx.yieldResultsType(nil)
+	return glang.ReturnExpr{Value: glang.DerefExpr{X: glang.IdentExpr("$ret"), Ty: retType}}
+}
+
+// yieldResultsType gives the type of "$ret", which holds the results of a
+// return from within the body of a range over a function, or nil if there are
+// no results to save
+func (ctx Ctx) yieldResultsType(n ast.Node) glang.Type {
+	if len(ctx.namedResults) > 0 || ctx.sig == nil || ctx.sig.Results().Len() == 0 {
+		return nil
+	}
+	var ts []glang.Type
+	for i := 0; i < ctx.sig.Results().Len(); i++ {
+		ts = append(ts, ctx.glangType(n, ctx.sig.Results().At(i).Type()))
+	}
+	return glang.NewTupleType(ts)
+}
+
 func (ctx Ctx) rangeStmt(s *ast.RangeStmt) glang.Expr {
-	switch ctx.typeOf(s.X).(type) {
+	ctx.loopIsYield = false
+	ctx.loopLabel, ctx.label = ctx.label, nil
+	switch t := ctx.typeOf(s.X).Underlying().(type) {
 	case *types.Map:
 		return ctx.mapRangeStmt(s)
 	case *types.Slice:
 		return ctx.sliceRangeStmt(s)
-	default:
-		ctx.unsupported(s,
-			"range over %v (only maps and slices are supported)",
-			ctx.typeOf(s.X))
-		return nil
+	case *types.Signature:
+		if t.Params().Len() == 1 && t.Results().Len() == 0 {
+			if yieldSig, ok := t.Params().At(0).Type().Underlying().(*types.Signature); ok {
+				return ctx.funcRangeStmt(s, yieldSig)
+			}
+		}
 	}
+	if _, ok := getIntegerType(ctx.typeOf(s.X)); ok {
+		return ctx.intRangeStmt(s)
+	}
+	ctx.unsupported(s,
+		"range over %v (only maps, slices, integers and iterator functions are supported)",
+		ctx.typeOf(s.X))
+	return nil
 }
 
 func (ctx Ctx) referenceTo(rhs ast.Expr) glang.Expr {
@@ -1118,6 +1735,11 @@ func (ctx Ctx) varSpec(s *ast.ValueSpec, cont glang.Expr) glang.Expr {
 		ty := ctx.glangTypeFromExpr(lhs)
 		rhs = glang.NewCallExpr(glang.GallinaIdent("ref_ty"), ty,
 			glang.NewCallExpr(glang.GallinaIdent("zero_val"), ty))
+	} else if s.Type != nil {
+		rhs = glang.RefExpr{
+			X:  ctx.exprOfType(s.Values[0], ctx.typeOf(lhs)),
+			Ty: ctx.glangTypeFromExpr(lhs),
+		}
 	} else {
 		rhs = ctx.referenceTo(s.Values[0])
 	}
@@ -1234,9 +1856,15 @@ func (ctx Ctx) assignStmt(s *ast.AssignStmt, cont glang.Expr) glang.Expr {
 	// compute values left-to-right
 	for i := len(s.Rhs); i > 0; i-- {
 		// NOTE: this handles the case that RHS = multiple-return function call
+		var rhs glang.Expr
+		if len(s.Lhs) == len(s.Rhs) {
+			rhs = ctx.exprOfType(s.Rhs[i-1], ctx.typeOf(s.Lhs[i-1]))
+		} else {
+			rhs = ctx.expr(s.Rhs[i-1])
+		}
 		e = glang.LetExpr{
 			Names:   intermediates[i-1:],
-			ValExpr: ctx.expr(s.Rhs[i-1]),
+			ValExpr: rhs,
 			Cont:    e,
 		}
 		intermediates = intermediates[:i-1]
@@ -1249,6 +1877,14 @@ func (ctx Ctx) assignOpStmt(s *ast.AssignStmt, cont glang.Expr) glang.Expr {
 	assignOps := map[token.Token]glang.BinOp{
 		token.ADD_ASSIGN: glang.OpPlus,
 		token.SUB_ASSIGN: glang.OpMinus,
+		token.MUL_ASSIGN: glang.OpMul,
+		token.QUO_ASSIGN: glang.OpQuot,
+		token.REM_ASSIGN: glang.OpRem,
+		token.AND_ASSIGN: glang.OpAnd,
+		token.OR_ASSIGN:  glang.OpOr,
+		token.XOR_ASSIGN: glang.OpXor,
+		token.SHL_ASSIGN: glang.OpShl,
+		token.SHR_ASSIGN: glang.OpShr,
 	}
 	op, ok := assignOps[s.Tok]
 	if !ok {
@@ -1275,6 +1911,21 @@ func (ctx Ctx) incDecStmt(stmt *ast.IncDecStmt, cont glang.Expr) glang.Expr {
 }
 
 func (ctx Ctx) branchStmt(s *ast.BranchStmt, cont glang.Expr) glang.Expr {
+	if s.Label != nil && ctx.info.Uses[s.Label] != ctx.loopLabel {
+		// in particular, the body of a range over a function is a yield
+		// closure, which cannot break out of the loops around the range
+		ctx.unsupported(s, "labelled %v to an outer loop", s.Tok)
+	}
+	if ctx.loopIsYield {
+		// the loop body is a yield closure, which reports whether to keep
+		// iterating
+		if s.Tok == token.CONTINUE {
+			return glang.LetExpr{ValExpr: glang.ReturnExpr{Value: glang.True}, Cont: cont}
+		}
+		if s.Tok == token.BREAK {
+			return glang.LetExpr{ValExpr: glang.ReturnExpr{Value: glang.False}, Cont: cont}
+		}
+	}
 	if s.Tok == token.CONTINUE {
 		return glang.LetExpr{ValExpr: glang.ContinueExpr{}, Cont: cont}
 	}
@@ -1314,10 +1965,58 @@ func (ctx Ctx) goStmt(e *ast.GoStmt, cont glang.Expr) glang.Expr {
 	return expr
 }
 
+// deferStmt evaluates the deferred function and its arguments immediately and
+// pushes a call to them onto the enclosing function's defer stack
+func (ctx Ctx) deferStmt(s *ast.DeferStmt, cont glang.Expr) glang.Expr {
+	if ctx.info.Types[s.Call.Fun].IsBuiltin() {
+		ctx.unsupported(s, "defer of a builtin")
+	}
+	if ctx.info.Types[s.Call.Fun].IsType() {
+		ctx.nope(s, "defer of a conversion")
+	}
+	args := make([]glang.Expr, 0, len(s.Call.Args))
+	for i := range len(s.Call.Args) {
+		args = append(args, glang.IdentExpr(fmt.Sprintf("$arg%d", i)))
+	}
+	var f glang.Expr
+	if lit, ok := s.Call.Fun.(*ast.FuncLit); ok {
+		f = ctx.funcLit(lit, true)
+	} else {
+		f = ctx.expr(s.Call.Fun)
+	}
+	var expr glang.Expr = glang.NewDoSeq(glang.DeferExpr{Body: glang.NewCallExpr(
+		glang.IdentExpr("$f"),
+		args...,
+	)}, cont)
+	expr = glang.LetExpr{
+		Names:   []string{"$f"},
+		ValExpr: f,
+		Cont:    expr,
+	}
+
+	// FIXME:(evaluation order)
+	// compute values left-to-right
+	for i := len(s.Call.Args); i > 0; i-- {
+		expr = glang.LetExpr{
+			Names:   []string{fmt.Sprintf("$arg%d", i-1)},
+			ValExpr: ctx.expr(s.Call.Args[i-1]),
+			Cont:    expr,
+		}
+	}
+
+	return expr
+}
+
 func (ctx Ctx) returnStmt(s *ast.ReturnStmt, cont glang.Expr) glang.Expr {
+	if ctx.inYield {
+		return ctx.yieldReturnStmt(s, cont)
+	}
+	if len(ctx.namedResults) > 0 {
+		return ctx.namedReturnStmt(s, cont)
+	}
 	exprs := make([]glang.Expr, 0, len(s.Results))
-	for _, result := range s.Results {
-		exprs = append(exprs, ctx.expr(result))
+	for i, result := range s.Results {
+		exprs = append(exprs, ctx.exprOfType(result, ctx.resultType(i, len(s.Results))))
 	}
 	if len(exprs) == 0 { // return #()
 		exprs = []glang.Expr{glang.Tt}
@@ -1326,7 +2025,93 @@ func (ctx Ctx) returnStmt(s *ast.ReturnStmt, cont glang.Expr) glang.Expr {
 	return glang.LetExpr{ValExpr: r, Cont: cont}
 }
 
-func (ctx Ctx) stmt(s ast.Stmt, cont glang.Expr) glang.Expr {
+// namedReturnStmt translates a return in a function with named results, which
+// stores into the result variables so deferred functions observe (and can
+// change) them before the function actually returns
+func (ctx Ctx) namedReturnStmt(s *ast.ReturnStmt, cont glang.Expr) glang.Expr {
+	return ctx.storeNamedResults(s, glang.LetExpr{
+		ValExpr: glang.ReturnExpr{Value: glang.TupleExpr{glang.Tt}},
+		Cont:    cont,
+	})
+}
+
+// yieldReturnStmt translates a return from within the body of a range over a
+// function, which saves the results and stops the iteration (see
+// funcRangeStmt)
+func (ctx Ctx) yieldReturnStmt(s *ast.ReturnStmt, cont glang.Expr) glang.Expr {
+	var e glang.Expr = glang.LetExpr{
+		ValExpr: glang.ReturnExpr{Value: glang.False},
+		Cont:    cont,
+	}
+	e = glang.NewDoSeq(glang.StoreStmt{
+		Dst: glang.IdentExpr("$returned"),
+		X:   glang.True,
+		Ty:  glang.TypeIdent("boolT"),
+	}, e)
+	if len(ctx.namedResults) > 0 {
+		return ctx.storeNamedResults(s, e)
+	}
+	if len(s.Results) == 0 {
+		return e
+	}
+	exprs := make([]glang.Expr, 0, len(s.Results))
+	for i, result := range s.Results {
+		exprs = append(exprs, ctx.exprOfType(result, ctx.resultType(i, len(s.Results))))
+	}
+	return glang.NewDoSeq(glang.StoreStmt{
+		Dst: glang.IdentExpr("$ret"),
+		X:   glang.TupleExpr(exprs),
+		Ty:  ctx.yieldResultsType(s),
+	}, e)
+}
+
+// storeNamedResults stores the results of a return statement into the named
+// result variables before running cont
+func (ctx Ctx) storeNamedResults(s *ast.ReturnStmt, cont glang.Expr) glang.Expr {
+	e := cont
+	if len(s.Results) == 0 {
+		return e
+	}
+	if len(s.Results) != len(ctx.namedResults) {
+		ctx.unsupported(s, "return of a multiple-value call with named results")
+	}
+	intermediates := make([]string, 0, len(s.Results))
+	for i := range s.Results {
+		intermediates = append(intermediates, fmt.Sprintf("$r%d", i))
+	}
+	for i := len(s.Results); i > 0; i-- {
+		r := ctx.namedResults[i-1]
+		e = glang.NewDoSeq(glang.StoreStmt{
+			Dst: glang.IdentExpr(r.Name),
+			X:   glang.IdentExpr(intermediates[i-1]),
+			Ty:  r.Type,
+		}, e)
+	}
+	for i := len(s.Results); i > 0; i-- {
+		e = glang.LetExpr{
+			Names:   []string{intermediates[i-1]},
+			ValExpr: ctx.exprOfType(s.Results[i-1], ctx.resultType(i-1, len(s.Results))),
+			Cont:    e,
+		}
+	}
+	return e
+}
+
+// resultType gives the type of the ith result of the enclosing function, if
+// known, for a return statement with n results
+func (ctx Ctx) resultType(i int, n int) types.Type {
+	if ctx.sig == nil || ctx.sig.Results().Len() != n {
+		return nil
+	}
+	return ctx.sig.Results().At(i).Type()
+}
+
+func (ctx Ctx) stmt(s ast.Stmt, cont glang.Expr) (e glang.Expr) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = glang.NewDoSeq(ctx.recoverError(r), cont)
+		}
+	}()
 	switch s := s.(type) {
 	case *ast.ReturnStmt:
 		return ctx.returnStmt(s, cont)
@@ -1336,6 +2121,8 @@ func (ctx Ctx) stmt(s ast.Stmt, cont glang.Expr) glang.Expr {
 		return ctx.ifStmt(s, cont)
 	case *ast.GoStmt:
 		return ctx.goStmt(s, cont)
+	case *ast.DeferStmt:
+		return ctx.deferStmt(s, cont)
 	case *ast.ExprStmt:
 		return glang.NewDoSeq(ctx.expr(s.X), cont)
 	case *ast.AssignStmt:
@@ -1358,6 +2145,15 @@ func (ctx Ctx) stmt(s ast.Stmt, cont glang.Expr) glang.Expr {
 		return glang.NewDoSeq(ctx.rangeStmt(s), cont)
 	case *ast.BlockStmt:
 		return ctx.blockStmt(s)
+	case *ast.LabeledStmt:
+		switch s.Stmt.(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+		default:
+			ctx.unsupported(s, "label on %T", s.Stmt)
+		}
+		// the label only matters to the branches that refer to it
+		ctx.label = ctx.info.Defs[s.Label]
+		return ctx.stmt(s.Stmt, cont)
 	case *ast.SwitchStmt:
 		ctx.todo(s, "switch statement")
 	case *ast.TypeSwitchStmt:
@@ -1387,23 +2183,121 @@ func (ctx Ctx) returnType(results *ast.FieldList) glang.Type {
 	}
 	rs := results.List
 	for _, r := range rs {
-		if len(r.Names) > 0 {
+		if len(r.Names) > 0 && ctx.namedResults == nil {
 			ctx.unsupported(r, "named returned value")
 			return glang.TypeIdent("<invalid>")
 		}
 	}
 	var ts []glang.Type
 	for _, r := range rs {
-		if len(r.Names) > 0 {
-			ctx.unsupported(r, "named returned value")
-			return glang.TypeIdent("<invalid>")
+		ty := ctx.glangTypeFromExpr(r.Type)
+		ts = append(ts, ty)
+		for range len(r.Names) - 1 {
+			ts = append(ts, ty)
 		}
-		ts = append(ts, ctx.glangTypeFromExpr(r.Type))
 	}
 	return glang.NewTupleType(ts)
 }
 
-func (ctx Ctx) funcDecl(d *ast.FuncDecl) glang.FuncDecl {
+// resultVars returns the named results of a function, naming blank results so
+// they can still be allocated
+func (ctx Ctx) resultVars(results *ast.FieldList) []glang.FieldDecl {
+	var decls []glang.FieldDecl
+	if results == nil {
+		return nil
+	}
+	for _, r := range results.List {
+		ty := ctx.glangTypeFromExpr(r.Type)
+		for _, name := range r.Names {
+			n := name.Name
+			if n == "_" {
+				n = fmt.Sprintf("$ret%d", len(decls))
+			}
+			decls = append(decls, glang.FieldDecl{Name: n, Type: ty})
+		}
+	}
+	return decls
+}
+
+// containsStmt checks if a function body has a statement matching isStmt
+// (outside of any nested function literals)
+func containsStmt(body *ast.BlockStmt, isStmt func(ast.Stmt) bool) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case ast.Stmt:
+			found = found || isStmt(n)
+		}
+		return !found
+	})
+	return found
+}
+
+// containsDefer checks if a function body has a defer statement
+func containsDefer(body *ast.BlockStmt) bool {
+	return containsStmt(body, func(s ast.Stmt) bool {
+		_, ok := s.(*ast.DeferStmt)
+		return ok
+	})
+}
+
+// callsRecover checks if a function body calls recover(), including in the
+// function literals it defers
+func (ctx Ctx) callsRecover(body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if f, ok := call.Fun.(*ast.Ident); ok {
+				_, builtin := ctx.info.Uses[f].(*types.Builtin)
+				found = found || builtin && f.Name == "recover"
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// containsReturn checks if a function (or loop) body has a return statement
+func containsReturn(body *ast.BlockStmt) bool {
+	return containsStmt(body, func(s ast.Stmt) bool {
+		_, ok := s.(*ast.ReturnStmt)
+		return ok
+	})
+}
+
+// deferBody wraps the body of a function that uses defer, so the defer stack
+// runs when the body returns or panics.
+//
+// Named results are allocated outside of the defer handler; the body only
+// assigns them, and they are read after deferred functions have had a chance
+// to modify them (for example, to turn a recovered panic into an error).
+func (ctx Ctx) deferBody(body glang.Expr) glang.Expr {
+	body = glang.NewCallExpr(glang.GallinaIdent("with_defer:"), body)
+	if len(ctx.namedResults) == 0 {
+		return body
+	}
+	var results []glang.Expr
+	for _, r := range ctx.namedResults {
+		results = append(results, glang.DerefExpr{X: glang.IdentExpr(r.Name), Ty: r.Type})
+	}
+	body = glang.NewDoSeq(body, glang.ReturnExpr{Value: glang.TupleExpr(results)})
+	for i := len(ctx.namedResults); i > 0; i-- {
+		r := ctx.namedResults[i-1]
+		body = glang.LetExpr{
+			Names: []string{r.Name},
+			ValExpr: glang.RefExpr{
+				X:  glang.NewCallExpr(glang.GallinaIdent("zero_val"), r.Type),
+				Ty: r.Type,
+			},
+			Cont: body,
+		}
+	}
+	return body
+}
+
+func (ctx Ctx) funcDecl(d *ast.FuncDecl) glang.Decl {
 	fd := glang.FuncDecl{Name: d.Name.Name, AddTypes: ctx.Config.TypeCheck}
 	addSourceDoc(d.Doc, &fd.Comment)
 	ctx.addSourceFile(d, &fd.Comment)
@@ -1428,11 +2322,29 @@ func (ctx Ctx) funcDecl(d *ast.FuncDecl) glang.FuncDecl {
 		f := ctx.field(receiver)
 		fd.RecvArg = &f
 	}
+	if dir, ok := declDirective(d.Doc); ok {
+		ctx.dep.addName(fd.Name)
+		return ctx.directiveFuncDecl(d, dir, fd)
+	}
+	if ctx.recFuncs == nil {
+		ctx.recFuncs = map[string]glang.Expr{fd.Name: glang.IdentExpr(fd.Name)}
+	}
 
 	fd.Args = append(fd.Args, ctx.paramList(d.Type.Params)...)
 
+	if f, ok := ctx.info.Defs[d.Name].(*types.Func); ok {
+		ctx.sig = f.Type().(*types.Signature)
+	}
+	ctx.recovers = ctx.callsRecover(d.Body)
+	hasDefer := containsDefer(d.Body)
+	if hasDefer {
+		ctx.namedResults = ctx.resultVars(d.Type.Results)
+	}
 	fd.ReturnType = ctx.returnType(d.Type.Results)
 	fd.Body = ctx.blockStmt(d.Body)
+	if hasDefer {
+		fd.Body = ctx.deferBody(fd.Body)
+	}
 	for _, arg := range fd.Args {
 		fd.Body = glang.LetExpr{
 			Names:   []string{arg.Name},
@@ -1492,11 +2404,79 @@ func (ctx Ctx) globalVarDecl(d *ast.GenDecl) []glang.Decl {
 	for _, spec := range d.Specs {
 		vs := spec.(*ast.ValueSpec)
 		ctx.dep.addName(vs.Names[0].Name)
+		if cd, ok := ctx.sentinelError(vs); ok {
+			specs = append(specs, cd)
+			continue
+		}
 		specs = append(specs, ctx.constSpec(vs))
 	}
 	return specs
 }
 
+// sentinelError translates a global error created with errors.New.
+//
+// Since globals are treated as constants, each use would otherwise create a
+// new error, and comparisons against the sentinel would always fail. Instead
+// the sentinel is identified by its fully-qualified name.
+func (ctx Ctx) sentinelError(spec *ast.ValueSpec) (glang.ConstDecl, bool) {
+	call, ok := ctx.sentinelCall(spec)
+	if !ok {
+		return glang.ConstDecl{}, false
+	}
+	msg := ctx.info.Types[call.Args[0]].Value
+	if msg == nil || msg.Kind() != constant.String {
+		ctx.unsupported(call.Args[0], "sentinel error with a non-constant message")
+	}
+	ident := spec.Names[0]
+	cd := glang.ConstDecl{
+		Name:     ident.Name,
+		Type:     glang.TypeIdent("error"),
+		AddTypes: ctx.Config.TypeCheck,
+	}
+	addSourceDoc(spec.Doc, &cd.Comment)
+	addSourceDoc(spec.Comment, &cd.Comment)
+	if strings.ContainsRune(constant.StringVal(msg), '"') {
+		ctx.unsupported(call.Args[0], "string literals with quotes")
+	}
+	cd.Val = glang.NewCallExpr(glang.GallinaIdent("errors.Sentinel"),
+		glang.StringLiteral{Value: ctx.pkgPath + "." + ident.Name},
+		glang.StringLiteral{Value: constant.StringVal(msg)})
+	return cd, true
+}
+
+// sentinelCall is the errors.New call creating the global declared by spec, if
+// it is a sentinel error
+func (ctx Ctx) sentinelCall(spec *ast.ValueSpec) (*ast.CallExpr, bool) {
+	if len(spec.Names) != 1 || len(spec.Values) != 1 {
+		return nil, false
+	}
+	call, ok := spec.Values[0].(*ast.CallExpr)
+	if !ok || !ctx.isPackageFunc(call.Fun, "errors", "New") {
+		return nil, false
+	}
+	return call, true
+}
+
+// sentinelErrors finds the globals in fs that are sentinel errors
+func (ctx Ctx) sentinelErrors(fs []NamedFile) map[types.Object]bool {
+	sentinels := make(map[types.Object]bool)
+	for _, f := range fs {
+		for _, d := range f.Ast.Decls {
+			d, ok := d.(*ast.GenDecl)
+			if !ok || d.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range d.Specs {
+				spec := spec.(*ast.ValueSpec)
+				if _, ok := ctx.sentinelCall(spec); ok {
+					sentinels[ctx.info.Defs[spec.Names[0]]] = true
+				}
+			}
+		}
+	}
+	return sentinels
+}
+
 func stringLitValue(lit *ast.BasicLit) string {
 	if lit.Kind != token.STRING {
 		panic("unexpected non-string literal")
@@ -1508,12 +2488,6 @@ func stringLitValue(lit *ast.BasicLit) string {
 	return s
 }
 
-var ffiMapping = map[string]string{
-	"github.com/mit-pdos/gokv/grove_ffi":             "grove",
-	"github.com/goose-lang/goose/machine/disk":       "disk",
-	"github.com/goose-lang/goose/machine/async_disk": "async_disk",
-}
-
 func (ctx Ctx) imports(d []ast.Spec) []glang.Decl {
 	var decls []glang.Decl
 	for _, s := range d {
@@ -1540,6 +2514,10 @@ func unqualifyName(name string) string {
 }
 
 func (ctx Ctx) maybeDecls(d ast.Decl) []glang.Decl {
+	ctx.checkDirectives(d)
+	if isSkipped(d) {
+		return nil
+	}
 	switch d := d.(type) {
 	case *ast.FuncDecl:
 		fd := ctx.funcDecl(d)
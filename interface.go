@@ -31,6 +31,23 @@ func (ctx Ctx) declsOrError(stmt ast.Decl) (decls []glang.Decl, err error) {
 	return ctx.maybeDecls(stmt), nil
 }
 
+// methodSetDeclsOrError declares the method tables for a type, returning
+// translation errors instead of panicking (see methodSetDecls)
+func (ctx Ctx) methodSetDeclsOrError(spec *ast.TypeSpec) (decls []glang.Decl, methods []string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if gooseErr, ok := r.(gooseError); ok {
+				err = gooseErr.err
+			} else {
+				// r is an error from a non-goose error, indicating a bug
+				panic(r)
+			}
+		}
+	}()
+	decls, methods = ctx.methodSetDecls(spec)
+	return decls, methods, nil
+}
+
 func filterImports(decls []glang.Decl) (nonImports []glang.Decl, imports glang.ImportDecls) {
 	for _, d := range decls {
 		switch d := d.(type) {
@@ -61,32 +78,148 @@ func (dt *depTracker) addDep(s string) {
 	dt.deps = append(dt.deps, s)
 }
 
+// lastDecl finds the declaration of names that comes last in the source, if
+// they all translated successfully
+func lastDecl(names []string, nameDecls map[string]declId, failed map[declId]bool) (last declId, ok bool) {
+	for _, n := range names {
+		id, found := nameDecls[n]
+		if !found || failed[id] {
+			return declId{}, false
+		}
+		if id.fileIdx > last.fileIdx ||
+			id.fileIdx == last.fileIdx && id.declIdx > last.declIdx {
+			last = id
+		}
+	}
+	return last, len(names) > 0
+}
+
 // Decls converts an entire package (possibly multiple files) to a list of decls
 func (ctx Ctx) Decls(fs ...NamedFile) (imports glang.ImportDecls, decls []glang.Decl, errs []error) {
 	declGroups := make(map[declId][]glang.Decl)
 	declDeps := make(map[declId][]string)
 	nameDecls := make(map[string]declId)
 	generated := make(map[declId]bool)
+	ctx.errs = &errorList{max: ctx.MaxErrors}
+	ctx.sentinels = ctx.sentinelErrors(fs)
+	declNames := make(map[declId][]string)
+	failed := make(map[declId]bool)
+	var ids []declId
 
 	// Translate every Go decl into a Glang decl and build up dependencies for
 	// each of them.
 	for fi, f := range fs {
 		for di, d := range f.Ast.Decls {
+			if ctx.errs.full() {
+				break
+			}
 			ctx.dep = &depTracker{}
 
 			id := declId{fi, di}
+			start := len(ctx.errs.errs)
 			newDecls, err := ctx.declsOrError(d)
 			if err != nil {
-				errs = append(errs, err)
+				ctx.errs.add(err)
 			}
+			failed[id] = len(ctx.errs.errs) > start
+			ctx.errs.sortFrom(start)
 
+			ids = append(ids, id)
 			declGroups[id] = newDecls
 			declDeps[id] = ctx.dep.deps
+			declNames[id] = ctx.dep.names
 			for _, n := range ctx.dep.names {
 				nameDecls[n] = id
 			}
 		}
 	}
+	if ctx.depGraph != nil {
+		var names, deps [][]string
+		for _, id := range ids {
+			names = append(names, declNames[id])
+			deps = append(deps, declDeps[id])
+		}
+		ctx.recordDeps(fs, names, deps)
+	}
+
+	// Translate recursive declarations again, as a group (see recursion.go)
+	for _, g := range recursiveGroups(fs, ids, declNames, declDeps, nameDecls) {
+		groupFailed := false
+		for _, id := range g.ids {
+			groupFailed = groupFailed || failed[id]
+		}
+		if groupFailed || ctx.errs.full() {
+			continue
+		}
+		switch {
+		case g.allDecls(isTypeDecl):
+			tctx := ctx
+			tctx.recTypes = make(map[string]bool)
+			for _, n := range g.names {
+				tctx.recTypes[n] = true
+			}
+			for i, id := range g.ids {
+				tctx.dep = &depTracker{}
+				newDecls, err := tctx.declsOrError(g.decls[i])
+				if err != nil {
+					ctx.errs.add(err)
+					continue
+				}
+				declGroups[id] = newDecls
+				declDeps[id] = tctx.dep.deps
+			}
+		default:
+			ctx.dep = &depTracker{}
+			newDecls, err := ctx.mutualFuncDeclsOrError(g)
+			if err != nil {
+				ctx.errs.add(err)
+				continue
+			}
+			inGroup := make(map[string]bool)
+			for _, n := range g.names {
+				inGroup[n] = true
+				nameDecls[n] = g.ids[0]
+			}
+			var deps []string
+			for _, dep := range ctx.dep.deps {
+				if !inGroup[dep] {
+					deps = append(deps, dep)
+				}
+			}
+			for _, id := range g.ids {
+				declGroups[id] = nil
+				declDeps[id] = nil
+			}
+			declGroups[g.ids[0]] = newDecls
+			declDeps[g.ids[0]] = deps
+		}
+	}
+
+	// Each type's method tables are emitted after the last of its methods
+	for _, id := range ids {
+		d, ok := fs[id.fileIdx].Ast.Decls[id.declIdx].(*ast.GenDecl)
+		// the first pass has reported any errors in the type declaration
+		if !ok || d.Tok != token.TYPE || failed[id] || isSkipped(d) {
+			continue
+		}
+		for _, spec := range d.Specs {
+			newDecls, methods, err := ctx.methodSetDeclsOrError(spec.(*ast.TypeSpec))
+			if err != nil {
+				ctx.errs.add(err)
+				continue
+			}
+			last, ok := lastDecl(methods, nameDecls, failed)
+			if !ok {
+				// some method was not translated
+				continue
+			}
+			declGroups[last] = append(declGroups[last], newDecls...)
+			declDeps[last] = append(declDeps[last], methods...)
+			for _, d := range newDecls {
+				nameDecls[d.(glang.MethodSetDecl).Name] = last
+			}
+		}
+	}
 
 	// Sort Glang decls based on dependencies
 	var lastFile int
@@ -113,7 +246,10 @@ func (ctx Ctx) Decls(fs ...NamedFile) (imports glang.ImportDecls, decls []glang.
 		}
 
 		newDecls, newImports := filterImports(declGroups[id])
-		decls = append(decls, newDecls...)
+		pos := ctx.position(fs[id.fileIdx].Ast.Decls[id.declIdx])
+		for _, d := range newDecls {
+			decls = append(decls, glang.LocatedDecl{Pos: pos, Decl: d})
+		}
 		imports = append(imports, newImports...)
 	}
 
@@ -130,6 +266,7 @@ func (ctx Ctx) Decls(fs ...NamedFile) (imports glang.ImportDecls, decls []glang.
 			processDecl(declId{fi, di}, "")
 		}
 	}
+	errs = ctx.errs.errs
 	return
 }
 
@@ -178,6 +315,31 @@ func sortedFiles(fileNames []string, fileAsts []*ast.File) []NamedFile {
 type Translator struct {
 	TypeCheck             bool
 	AddSourceFileComments bool
+	// MaxErrors is the number of errors after which translation of a package
+	// stops (0 for no limit)
+	MaxErrors int
+	// Ffi maps package paths to the FFI that implements them, in addition to
+	// the built-in FFIs (see RegisterFfi)
+	Ffi map[string]Ffi
+	// Exclude lists packages to skip in TranslatePackages (see Excluded)
+	Exclude []string
+	// PathMapping is the path mapping mode for output files (see OutputPath)
+	PathMapping string
+	// Width is the line width of the output (0 for glang.DefaultWidth)
+	Width int
+	// modulePath is the module being translated, for PathMappingModule
+	modulePath string
+	// translated are the packages translated together by TranslatePackages
+	translated map[string]bool
+}
+
+// translatedPkgs is the set of paths of pkgs, which are translated together
+func translatedPkgs(pkgs []*packages.Package) map[string]bool {
+	translated := make(map[string]bool, len(pkgs))
+	for _, pkg := range pkgs {
+		translated[pkg.PkgPath] = true
+	}
+	return translated
 }
 
 func pkgErrors(errors []packages.Error) error {
@@ -195,19 +357,31 @@ func pkgErrors(errors []packages.Error) error {
 // Coq code will be out-of-order. Sorting ensures the results are stable
 // and not dependent on map or directory iteration order.
 func (tr Translator) translatePackage(pkg *packages.Package) (glang.File, error) {
+	return tr.translatePackageGraph(pkg, nil)
+}
+
+// translatePackageGraph translates a package, also adding its declarations'
+// dependencies to graph if it is non-nil
+func (tr Translator) translatePackageGraph(pkg *packages.Package, graph *DepGraph) (glang.File, error) {
 	if len(pkg.Errors) > 0 {
 		return glang.File{}, errors.Errorf(
 			"could not load package %v:\n%v", pkg.PkgPath,
 			pkgErrors(pkg.Errors))
 	}
-	ctx := NewPkgCtx(pkg, tr)
+	ctx, err := NewPkgCtx(pkg, tr)
+	if err != nil {
+		return glang.File{}, err
+	}
+	ctx.depGraph = graph
 	files := sortedFiles(pkg.CompiledGoFiles, pkg.Syntax)
 
 	coqFile := glang.File{
 		PkgPath:   pkg.PkgPath,
 		GoPackage: pkg.Name,
+		Ffis:      ctx.Config.Ffis,
+		Width:     tr.Width,
 	}
-	coqFile.ImportHeader, coqFile.Footer = ffiHeaderFooter(ctx.Config.Ffi)
+	coqFile.ImportHeader, coqFile.Footer = ffiHeaderFooter(ctx.Config.Ffis)
 
 	imports, decls, errs := ctx.Decls(files...)
 	coqFile.Imports = imports
@@ -219,14 +393,19 @@ func (tr Translator) translatePackage(pkg *packages.Package) (glang.File, error)
 	return coqFile, nil
 }
 
-func ffiHeaderFooter(ffi string) (header string, footer string) {
-	if ffi == "none" {
+func ffiHeaderFooter(ffis []string) (header string, footer string) {
+	if len(ffis) == 0 {
 		header = "Section code.\n" +
 			"Context `{ffi_syntax}.\n" +
 			"Local Coercion Var' s: expr := Var s."
 		footer = "\nEnd code.\n"
 	} else {
-		header += fmt.Sprintf("From New Require Import %s_prelude.", ffi)
+		var preludes []string
+		for _, ffi := range ffis {
+			preludes = append(preludes, ffi+"_prelude")
+		}
+		header += fmt.Sprintf("From New Require Import %s.",
+			strings.Join(preludes, " "))
 	}
 	return
 }
@@ -253,15 +432,22 @@ func newPackageConfig(modDir string) *packages.Config {
 // a syntax error.
 func (tr Translator) TranslatePackages(modDir string,
 	pkgPattern ...string) (files []glang.File, errs []error, patternErr error) {
-	pkgs, err := packages.Load(newPackageConfig(modDir), pkgPattern...)
+	loaded, err := packages.Load(newPackageConfig(modDir), pkgPattern...)
 	if err != nil {
 		return nil, nil, err
 	}
+	var pkgs []*packages.Package
+	for _, pkg := range loaded {
+		if !tr.Excluded(pkg.PkgPath) {
+			pkgs = append(pkgs, pkg)
+		}
+	}
 	if len(pkgs) == 0 {
 		// consider matching nothing to be an error, unlike packages.Load
 		return nil, nil,
 			errors.New("patterns matched no packages")
 	}
+	tr.translated = translatedPkgs(pkgs)
 	files = make([]glang.File, len(pkgs))
 	errs = make([]error, len(pkgs))
 	var wg sync.WaitGroup
@@ -0,0 +1,819 @@
+package interp
+
+import (
+	"encoding/binary"
+	"strconv"
+
+	"github.com/goose-lang/goose/glang"
+)
+
+// primitiveTypes are the go_types defined by GooseLang and its libraries
+var primitiveTypes = map[string]*Type{
+	"uint64T":     uint64T,
+	"intT":        uint64T,
+	"uint32T":     uint32T,
+	"byteT":       byteT,
+	"boolT":       boolT,
+	"stringT":     stringT,
+	"unitT":       unitT,
+	"ptrT":        ptrT,
+	"funcT":       funcT,
+	"interfaceT":  interfaceT,
+	"error":       interfaceT,
+	"disk.Disk":   interfaceT,
+	"disk.blockT": {Kind: KindSlice, Elem: byteT},
+	// a mutex is a boolean that is true while it is locked
+	"sync.Mutex": {Kind: KindBool, Name: "sync.Mutex"},
+	// a read-write mutex is its number of readers, or rwLocked
+	"sync.RWMutex": {Kind: KindUint64, Name: "sync.RWMutex"},
+	// a Once is one of onceNotDone, onceRunning and onceDone
+	"sync.Once": {Kind: KindUint64, Name: "sync.Once"},
+	// the atomic types are their values, which primitives access atomically
+	"atomic.Uint64":  {Kind: KindUint64, Name: "atomic.Uint64"},
+	"atomic.Uint32":  {Kind: KindUint32, Name: "atomic.Uint32"},
+	"atomic.Bool":    {Kind: KindBool, Name: "atomic.Bool"},
+	"atomic.Pointer": {Kind: KindPtr, Name: "atomic.Pointer"},
+}
+
+// primitives are the GooseLang primitives and library functions used by
+// translated code, by their Gallina names
+var primitives map[string]Value
+
+// builtinMethods are the methods of the types of interface values created by
+// primitives, by their type identifiers
+var builtinMethods map[string]map[string]*Func
+
+// native creates a primitive taking arity arguments (functions without
+// arguments take a unit argument, as in GooseLang)
+func native(name string, arity int, f func(t *thread, args []Value) Value) *Func {
+	if arity == 0 {
+		arity = 1
+	}
+	params := make([]string, arity)
+	for i := range params {
+		params[i] = "_"
+	}
+	return &Func{name: name, params: params, native: f}
+}
+
+func init() {
+	primitives = map[string]Value{
+		"Skip":           Unit{},
+		"null":           Ptr{},
+		"slice.nil":      Slice{},
+		"interface.nil":  Interface{},
+		"disk.BlockSize": uint64(4096),
+
+		// heap
+		"ref_ty": native("ref_ty", 2, func(t *thread, args []Value) Value {
+			return Ptr{c: &cell{v: args[1]}}
+		}),
+		"zero_val": native("zero_val", 1, func(t *thread, args []Value) Value {
+			return asType(args[0]).zero()
+		}),
+		"struct.field_ref": native("struct.field_ref", 3, func(t *thread, args []Value) Value {
+			p := asPtr(args[2])
+			if p.IsNil() {
+				panic(t.goPanic("invalid memory address or nil pointer dereference"))
+			}
+			return p.field(asString(args[1]))
+		}),
+
+		// interfaces
+		"interface.make": native("interface.make", 3, func(t *thread, args []Value) Value {
+			id := asString(args[0])
+			// the table is the same for every conversion from a type, so the
+			// interface value only needs to carry the type identifier
+			t.s.methods[id] = asList(args[1])
+			return Interface{TypeId: id, V: args[2]}
+		}),
+		"interface.get": native("interface.get", 2, func(t *thread, args []Value) Value {
+			return t.method(asInterface(args[1]), asString(args[0]))
+		}),
+
+		// slices
+		"slice.make2": native("slice.make2", 2, func(t *thread, args []Value) Value {
+			n := t.length(args[1])
+			return newSlice(asType(args[0]), n, n)
+		}),
+		"slice.make3": native("slice.make3", 3, func(t *thread, args []Value) Value {
+			n, c := t.length(args[1]), t.length(args[2])
+			if n > c {
+				panic(t.goPanic("makeslice: cap out of range"))
+			}
+			return newSlice(asType(args[0]), n, c)
+		}),
+		"slice.len": native("slice.len", 1, func(t *thread, args []Value) Value {
+			return uint64(asSlice(args[0]).len)
+		}),
+		"slice.cap": native("slice.cap", 1, func(t *thread, args []Value) Value {
+			return uint64(asSlice(args[0]).cap)
+		}),
+		"slice.elem_ref": native("slice.elem_ref", 3, func(t *thread, args []Value) Value {
+			return t.elemRef(asSlice(args[1]), args[2])
+		}),
+		"SliceRef": native("SliceRef", 3, func(t *thread, args []Value) Value {
+			return t.elemRef(asSlice(args[1]), args[2])
+		}),
+		"slice.slice": native("slice.slice", 4, func(t *thread, args []Value) Value {
+			s := asSlice(args[1])
+			lo, hi := t.length(args[2]), t.length(args[3])
+			if lo > hi || hi > s.cap {
+				panic(t.goPanic("slice bounds out of range"))
+			}
+			if s.arr == nil {
+				return s
+			}
+			return Slice{arr: s.arr, off: s.off + lo, len: hi - lo, cap: s.cap - lo}
+		}),
+		"slice.literal": native("slice.literal", 2, func(t *thread, args []Value) Value {
+			return sliceOf(asType(args[0]), args[1].([]Value))
+		}),
+		"slice.append": native("slice.append", 3, func(t *thread, args []Value) Value {
+			return appendSlice(asType(args[0]), asSlice(args[1]), asSlice(args[2]).Elems())
+		}),
+		"slice.copy": native("slice.copy", 3, func(t *thread, args []Value) Value {
+			dst, src := asSlice(args[1]), asSlice(args[2])
+			vs := src.Elems()
+			n := len(vs)
+			if dst.len < n {
+				n = dst.len
+			}
+			for i := 0; i < n; i++ {
+				dst.elem(i).v = vs[i]
+			}
+			return uint64(n)
+		}),
+
+		// maps
+		"map.make": native("map.make", 3, func(t *thread, args []Value) Value {
+			return &Map{key: asType(args[0]), elem: asType(args[1]),
+				entries: make(map[Value]mapEntry)}
+		}),
+		"map.insert": native("map.insert", 3, func(t *thread, args []Value) Value {
+			m := asMap(args[0])
+			if m == nil {
+				panic(t.goPanic("assignment to entry in nil map"))
+			}
+			m.entries[hashKey(args[1])] = mapEntry{k: args[1], v: args[2]}
+			return Unit{}
+		}),
+		"map.get": native("map.get", 2, func(t *thread, args []Value) Value {
+			m := asMap(args[0])
+			if m == nil {
+				panic(errorf("lookup in a nil map (its element type is unknown)"))
+			}
+			if v, ok := m.get(args[1]); ok {
+				return Tuple{v, true}
+			}
+			return Tuple{m.elem.zero(), false}
+		}),
+		"MapDelete": native("MapDelete", 2, func(t *thread, args []Value) Value {
+			if m := asMap(args[0]); m != nil {
+				delete(m.entries, hashKey(args[1]))
+			}
+			return Unit{}
+		}),
+		"MapLen": native("MapLen", 1, func(t *thread, args []Value) Value {
+			return uint64(asMap(args[0]).Len())
+		}),
+		"Fst": native("Fst", 1, func(t *thread, args []Value) Value {
+			tuple, ok := args[0].(Tuple)
+			if !ok || len(tuple) != 2 {
+				panic(errorf("Fst of non-pair %s", Show(args[0])))
+			}
+			return tuple[0]
+		}),
+
+		// integers and strings
+		"to_u64": native("to_u64", 1, func(t *thread, args []Value) Value {
+			return toUint(args[0])
+		}),
+		"to_u32": native("to_u32", 1, func(t *thread, args []Value) Value {
+			return uint32(toUint(args[0]))
+		}),
+		"to_u8": native("to_u8", 1, func(t *thread, args []Value) Value {
+			return uint8(toUint(args[0]))
+		}),
+		"StringLength": native("StringLength", 1, func(t *thread, args []Value) Value {
+			return uint64(len(asString(args[0])))
+		}),
+		"StringToBytes": native("StringToBytes", 1, func(t *thread, args []Value) Value {
+			return bytesToSlice([]byte(asString(args[0])))
+		}),
+		"StringFromBytes": native("StringFromBytes", 1, func(t *thread, args []Value) Value {
+			return string(sliceToBytes(asSlice(args[0])))
+		}),
+
+		// panics and errors
+		//
+		// Go's panic is translated to PanicValue in functions that call
+		// recover, and to Panic with a constant message elsewhere (as well as
+		// in goose's placeholders for code that failed to translate).
+		"Panic": native("Panic", 1, func(t *thread, args []Value) Value {
+			panic(t.goPanic(asString(args[0])))
+		}),
+		"PanicValue": native("PanicValue", 1, func(t *thread, args []Value) Value {
+			panic(&PanicError{Pos: t.pos, Value: args[0]})
+		}),
+		"recover": native("recover", 0, func(t *thread, args []Value) Value {
+			p := t.panicking
+			if p == nil {
+				return Interface{}
+			}
+			t.panicking = nil
+			return p.Value
+		}),
+		"errors.New": native("errors.New", 1, func(t *thread, args []Value) Value {
+			return newError(args[0], Interface{})
+		}),
+		"errors.Sentinel": native("errors.Sentinel", 1, func(t *thread, args []Value) Value {
+			return newError(args[0], Interface{})
+		}),
+		"errors.Wrap": native("errors.Wrap", 2, func(t *thread, args []Value) Value {
+			return newError(args[0], args[1])
+		}),
+
+		// machine
+		"machine.UInt64Put": native("machine.UInt64Put", 2, func(t *thread, args []Value) Value {
+			var b [8]byte
+			binary.LittleEndian.PutUint64(b[:], toUint(args[1]))
+			t.putBytes(asSlice(args[0]), b[:])
+			return Unit{}
+		}),
+		"machine.UInt32Put": native("machine.UInt32Put", 2, func(t *thread, args []Value) Value {
+			var b [4]byte
+			binary.LittleEndian.PutUint32(b[:], uint32(toUint(args[1])))
+			t.putBytes(asSlice(args[0]), b[:])
+			return Unit{}
+		}),
+		"machine.UInt64Get": native("machine.UInt64Get", 1, func(t *thread, args []Value) Value {
+			return binary.LittleEndian.Uint64(t.getBytes(asSlice(args[0]), 8))
+		}),
+		"machine.UInt32Get": native("machine.UInt32Get", 1, func(t *thread, args []Value) Value {
+			return binary.LittleEndian.Uint32(t.getBytes(asSlice(args[0]), 4))
+		}),
+		"machine.UInt64ToString": native("machine.UInt64ToString", 1, func(t *thread, args []Value) Value {
+			return strconv.FormatUint(toUint(args[0]), 10)
+		}),
+		"machine.RandomUint64": native("machine.RandomUint64", 0, func(t *thread, args []Value) Value {
+			// deterministic, so failures can be reproduced
+			t.s.rand = t.s.rand*6364136223846793005 + 1442695040888963407
+			return t.s.rand
+		}),
+		"machine.Linearize": native("machine.Linearize", 0, func(t *thread, args []Value) Value {
+			return Unit{}
+		}),
+		"machine.Assert": native("machine.Assert", 1, func(t *thread, args []Value) Value {
+			if args[0] != true {
+				panic(t.goPanic("assertion failure"))
+			}
+			return Unit{}
+		}),
+		"machine.Assume": native("machine.Assume", 1, func(t *thread, args []Value) Value {
+			if args[0] != true {
+				panic(t.goPanic("assume failure"))
+			}
+			return Unit{}
+		}),
+		"machine.Sleep": native("machine.Sleep", 1, func(t *thread, args []Value) Value {
+			t.yield()
+			return Unit{}
+		}),
+		"machine.TimeNow": native("machine.TimeNow", 0, func(t *thread, args []Value) Value {
+			return uint64(t.s.steps)
+		}),
+
+		// sync
+		"sync.Mutex__Lock": native("sync.Mutex__Lock", 2, func(t *thread, args []Value) Value {
+			t.lock(asPtr(args[0]))
+			return Unit{}
+		}),
+		"sync.Mutex__Unlock": native("sync.Mutex__Unlock", 2, func(t *thread, args []Value) Value {
+			t.unlock(asPtr(args[0]))
+			return Unit{}
+		}),
+		"sync.RWMutex__Lock": native("sync.RWMutex__Lock", 2, func(t *thread, args []Value) Value {
+			m := asPtr(args[0])
+			t.yield()
+			t.waitFor(m, func(v Value) bool { return v == uint64(0) })
+			t.store(m, rwLocked)
+			return Unit{}
+		}),
+		"sync.RWMutex__Unlock": native("sync.RWMutex__Unlock", 2, func(t *thread, args []Value) Value {
+			m := asPtr(args[0])
+			if t.load(m) != rwLocked {
+				panic(t.goPanic("sync: Unlock of unlocked RWMutex"))
+			}
+			t.store(m, uint64(0))
+			t.wakeWaiters(m)
+			return Unit{}
+		}),
+		"sync.RWMutex__RLock": native("sync.RWMutex__RLock", 2, func(t *thread, args []Value) Value {
+			m := asPtr(args[0])
+			t.yield()
+			t.waitFor(m, func(v Value) bool { return v != rwLocked })
+			t.store(m, toUint(t.load(m))+1)
+			return Unit{}
+		}),
+		"sync.RWMutex__RUnlock": native("sync.RWMutex__RUnlock", 2, func(t *thread, args []Value) Value {
+			m := asPtr(args[0])
+			readers := toUint(t.load(m))
+			if readers == 0 || readers == rwLocked {
+				panic(t.goPanic("sync: RUnlock of unlocked RWMutex"))
+			}
+			t.store(m, readers-1)
+			if readers == 1 {
+				t.wakeWaiters(m)
+			}
+			return Unit{}
+		}),
+		"sync.Once__Do": native("sync.Once__Do", 2, func(t *thread, args []Value) Value {
+			t.onceDo(asPtr(args[0]), args[1])
+			return Unit{}
+		}),
+
+		// the disk package-level functions operate on the disk from disk.Get
+		"disk.Get": native("disk.Get", 0, func(t *thread, args []Value) Value {
+			return Interface{TypeId: diskTypeId, V: t.s.disk}
+		}),
+	}
+	builtinMethods = map[string]map[string]*Func{
+		errorTypeId: {
+			"Error": native("Error", 2, func(t *thread, args []Value) Value {
+				return args[0].(*goError).msg
+			}),
+			"Unwrap": native("Unwrap", 2, func(t *thread, args []Value) Value {
+				return args[0].(*goError).wrapped
+			}),
+		},
+		diskTypeId: diskMethods,
+	}
+	addAtomics()
+	for name := range diskMethods {
+		// disk.Disk is modeled by the FFI, so its methods are called as
+		// __Read d a rather than through interface.get
+		name := name
+		primitives["__"+name] = native("__"+name, 1,
+			func(t *thread, args []Value) Value {
+				return t.method(asInterface(args[0]), name)
+			})
+	}
+	for name, m := range diskMethods {
+		// disk.Read a is (disk.Get #()).Read a
+		m := m
+		primitives["disk."+name] = native("disk."+name, len(m.params)-1,
+			func(t *thread, args []Value) Value {
+				return t.apply(m, append([]Value{t.s.disk}, args...))
+			})
+	}
+}
+
+func (t *thread) goPanic(msg string) *PanicError {
+	return &PanicError{Pos: t.pos, Value: Interface{TypeId: "string", V: msg}}
+}
+
+func asType(v Value) *Type {
+	if t, ok := v.(*Type); ok {
+		return t
+	}
+	panic(errorf("expected a type, got %s", Show(v)))
+}
+
+func asPtr(v Value) Ptr {
+	if p, ok := v.(Ptr); ok {
+		return p
+	}
+	panic(errorf("expected a pointer, got %s", Show(v)))
+}
+
+func asString(v Value) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	panic(errorf("expected a string, got %s", Show(v)))
+}
+
+func asSlice(v Value) Slice {
+	if s, ok := v.(Slice); ok {
+		return s
+	}
+	panic(errorf("expected a slice, got %s", Show(v)))
+}
+
+func asMap(v Value) *Map {
+	if m, ok := v.(*Map); ok {
+		return m
+	}
+	panic(errorf("expected a map, got %s", Show(v)))
+}
+
+func asInterface(v Value) Interface {
+	if i, ok := v.(Interface); ok {
+		return i
+	}
+	panic(errorf("expected an interface value, got %s", Show(v)))
+}
+
+func asList(v Value) []Value {
+	if l, ok := v.([]Value); ok {
+		return l
+	}
+	panic(errorf("expected a list, got %s", Show(v)))
+}
+
+// toUint converts any integer to a uint64
+func toUint(v Value) uint64 {
+	switch v := v.(type) {
+	case uint64:
+		return v
+	case uint32:
+		return uint64(v)
+	case uint8:
+		return uint64(v)
+	}
+	panic(errorf("expected an integer, got %s", Show(v)))
+}
+
+// length converts an integer used as a length or index
+func (t *thread) length(v Value) int {
+	n := toUint(v)
+	if n > 1<<40 {
+		panic(t.goPanic("length out of range"))
+	}
+	return int(n)
+}
+
+func (t *thread) elemRef(s Slice, i Value) Ptr {
+	idx := toUint(i)
+	if idx >= uint64(s.len) {
+		panic(t.goPanic("index out of range [" +
+			strconv.FormatUint(idx, 10) + "] with length " + strconv.Itoa(s.len)))
+	}
+	return Ptr{c: s.elem(int(idx))}
+}
+
+func sliceOf(ty *Type, vs []Value) Slice {
+	s := newSlice(ty, len(vs), len(vs))
+	for i, v := range vs {
+		s.elem(i).v = v
+	}
+	return s
+}
+
+// appendSlice appends vs to s, with the same growth as Go for small slices
+// (so aliasing after an append is the same)
+func appendSlice(ty *Type, s Slice, vs []Value) Slice {
+	n := s.len + len(vs)
+	if n > s.cap {
+		newCap := 2 * s.cap
+		if newCap < n {
+			newCap = n
+		}
+		grown := newSlice(ty, s.len, newCap)
+		for i := 0; i < s.len; i++ {
+			grown.elem(i).v = s.elem(i).v
+		}
+		s = grown
+	}
+	s.len = n
+	for i, v := range vs {
+		s.elem(n - len(vs) + i).v = v
+	}
+	return s
+}
+
+func bytesToSlice(b []byte) Slice {
+	vs := make([]Value, len(b))
+	for i := range b {
+		vs[i] = b[i]
+	}
+	return sliceOf(byteT, vs)
+}
+
+func sliceToBytes(s Slice) []byte {
+	b := make([]byte, s.len)
+	for i := range b {
+		b[i] = s.elem(i).v.(uint8)
+	}
+	return b
+}
+
+func (t *thread) putBytes(s Slice, b []byte) {
+	if s.len < len(b) {
+		panic(t.goPanic("index out of range"))
+	}
+	for i := range b {
+		s.elem(i).v = b[i]
+	}
+}
+
+func (t *thread) getBytes(s Slice, n int) []byte {
+	if s.len < n {
+		panic(t.goPanic("index out of range"))
+	}
+	return sliceToBytes(Slice{arr: s.arr, off: s.off, len: n, cap: n})
+}
+
+// method looks up the method name of the dynamic type of i, with i as the
+// receiver
+func (t *thread) method(i Interface, name string) Value {
+	if i.TypeId == "" {
+		panic(t.goPanic("invalid memory address or nil pointer dereference"))
+	}
+	if ms, ok := builtinMethods[i.TypeId]; ok {
+		m, ok := ms[name]
+		if !ok {
+			panic(errorf("no method %s for %s", name, i.TypeId))
+		}
+		return t.apply(m, []Value{i.V})
+	}
+	for _, m := range t.s.methods[i.TypeId] {
+		if m := m.(Tuple); m[0] == name {
+			return t.apply(m[1], []Value{i.V})
+		}
+	}
+	panic(errorf("no method %s for %s", name, i.TypeId))
+}
+
+const (
+	errorTypeId = "errors.errorString'ptr"
+	diskTypeId  = "disk.MemDisk"
+)
+
+// goError is the dynamic value of errors created by the errors primitives
+type goError struct {
+	msg     Value
+	wrapped Value
+}
+
+func newError(msg Value, wrapped Value) Interface {
+	return Interface{TypeId: errorTypeId, V: &goError{msg: msg, wrapped: wrapped}}
+}
+
+// memDisk is the disk returned by disk.Get
+type memDisk struct {
+	blocks [][]byte
+}
+
+func newMemDisk(size uint64) *memDisk {
+	d := &memDisk{}
+	for i := uint64(0); i < size; i++ {
+		d.blocks = append(d.blocks, make([]byte, 4096))
+	}
+	return d
+}
+
+func (d *memDisk) block(t *thread, a Value) []byte {
+	addr := toUint(a)
+	if addr >= uint64(len(d.blocks)) {
+		panic(t.goPanic("out-of-bounds disk access at " + strconv.FormatUint(addr, 10)))
+	}
+	return d.blocks[addr]
+}
+
+var diskMethods = map[string]*Func{
+	"Read": native("Read", 2, func(t *thread, args []Value) Value {
+		return bytesToSlice(args[0].(*memDisk).block(t, args[1]))
+	}),
+	"ReadTo": native("ReadTo", 3, func(t *thread, args []Value) Value {
+		b := args[0].(*memDisk).block(t, args[1])
+		t.putBytes(asSlice(args[2]), b)
+		return Unit{}
+	}),
+	"Write": native("Write", 3, func(t *thread, args []Value) Value {
+		v := asSlice(args[2])
+		if v.len != 4096 {
+			panic(t.goPanic("v is not block sized (" + strconv.Itoa(v.len) + " bytes)"))
+		}
+		copy(args[0].(*memDisk).block(t, args[1]), sliceToBytes(v))
+		return Unit{}
+	}),
+	"Size": native("Size", 2, func(t *thread, args []Value) Value {
+		return uint64(len(args[0].(*memDisk).blocks))
+	}),
+	"Barrier": native("Barrier", 2, func(t *thread, args []Value) Value {
+		return Unit{}
+	}),
+	"Close": native("Close", 2, func(t *thread, args []Value) Value {
+		return Unit{}
+	}),
+}
+
+// withDefer runs body with a defer stack "$defer", then runs the deferred
+// calls even if body panics. Like exception_do, it turns a return into the
+// returned value.
+func (t *thread) withDefer(e *env, body glang.Expr) Value {
+	stack := Ptr{c: &cell{v: native("$defer", 0, func(t *thread, args []Value) Value {
+		return Unit{}
+	})}}
+	e = e.bind("$defer", stack)
+	var v Value = Unit{}
+	p := t.catchPanic(func() {
+		v = t.eval(e, body)
+	})
+	switch r := v.(type) {
+	case returnVal:
+		v = r.v
+	case execVal:
+		v = r.v
+	case breakVal, continueVal:
+		panic(errorf("break or continue outside of a loop"))
+	}
+	outer := t.panicking
+	t.panicking = p
+	func() {
+		defer func() { p, t.panicking = t.panicking, outer }()
+		t.apply(t.load(stack), []Value{Unit{}})
+	}()
+	if p != nil {
+		panic(p)
+	}
+	return v
+}
+
+// catchPanic runs f, returning the Go panic it raises
+func (t *thread) catchPanic(f func()) (p *PanicError) {
+	defer func() {
+		if r := recover(); r != nil {
+			if pe, ok := r.(*PanicError); ok {
+				p = pe
+				return
+			}
+			panic(r)
+		}
+	}()
+	f()
+	return nil
+}
+
+// deferCall pushes body onto the defer stack
+func (t *thread) deferCall(e *env, body glang.Expr) {
+	sv, ok := e.lookup("$defer")
+	if !ok {
+		panic(errorf("defer outside of with_defer:"))
+	}
+	stack := asPtr(sv)
+	old := t.load(stack)
+	pk := t.pkg
+	t.store(stack, native("$defer", 0, func(t *thread, args []Value) Value {
+		t.inPkg(pk, func() Value { return t.eval(e, body) })
+		return t.apply(old, []Value{Unit{}})
+	}))
+}
+
+func (t *thread) binary(e *env, x glang.BinaryExpr) Value {
+	switch x.Op {
+	case glang.OpLAnd:
+		return t.evalBool(e, x.X) && t.evalBool(e, x.Y)
+	case glang.OpLOr:
+		return t.evalBool(e, x.X) || t.evalBool(e, x.Y)
+	}
+	a := t.eval(e, x.X)
+	b := t.eval(e, x.Y)
+	switch x.Op {
+	case glang.OpEquals:
+		return Equal(a, b)
+	case glang.OpNotEquals:
+		return !Equal(a, b)
+	}
+	if a, ok := a.(string); ok {
+		b := asString(b)
+		switch x.Op {
+		case glang.OpPlus, glang.OpAppend:
+			return a + b
+		case glang.OpLessThan:
+			return a < b
+		case glang.OpGreaterThan:
+			return a > b
+		case glang.OpLessEq:
+			return a <= b
+		case glang.OpGreaterEq:
+			return a >= b
+		}
+		panic(errorf("operator %s on strings", x.Op))
+	}
+	width := 64
+	switch a.(type) {
+	case uint32:
+		width = 32
+	case uint8:
+		width = 8
+	}
+	n, m := toUint(a), toUint(b)
+	var r uint64
+	switch x.Op {
+	case glang.OpLessThan:
+		return n < m
+	case glang.OpGreaterThan:
+		return n > m
+	case glang.OpLessEq:
+		return n <= m
+	case glang.OpGreaterEq:
+		return n >= m
+	case glang.OpPlus:
+		r = n + m
+	case glang.OpMinus:
+		r = n - m
+	case glang.OpMul:
+		r = n * m
+	case glang.OpQuot, glang.OpRem:
+		if m == 0 {
+			panic(t.goPanic("integer divide by zero"))
+		}
+		if x.Op == glang.OpQuot {
+			r = n / m
+		} else {
+			r = n % m
+		}
+	case glang.OpAnd:
+		r = n & m
+	case glang.OpOr:
+		r = n | m
+	case glang.OpXor:
+		r = n ^ m
+	case glang.OpShl:
+		if m < uint64(width) {
+			r = n << m
+		}
+	case glang.OpShr:
+		if m < uint64(width) {
+			r = n >> m
+		}
+	default:
+		panic(errorf("operator %s on integers", x.Op))
+	}
+	switch width {
+	case 32:
+		return uint32(r)
+	case 8:
+		return uint8(r)
+	}
+	return r
+}
+
+// addAtomics adds the primitives for sync/atomic: the methods of its types,
+// such as atomic.Uint64__Add, and the functions on pointers to integers, such
+// as atomic.AddUint64. Primitives run without yielding, so they are atomic.
+func addAtomics() {
+	type op struct {
+		name  string
+		arity int
+		f     func(t *thread, p Ptr, args []Value) Value
+	}
+	load := op{"Load", 1, func(t *thread, p Ptr, args []Value) Value {
+		return t.load(p)
+	}}
+	store := op{"Store", 2, func(t *thread, p Ptr, args []Value) Value {
+		t.store(p, args[0])
+		return Unit{}
+	}}
+	swap := op{"Swap", 2, func(t *thread, p Ptr, args []Value) Value {
+		old := t.load(p)
+		t.store(p, args[0])
+		return old
+	}}
+	cas := op{"CompareAndSwap", 3, func(t *thread, p Ptr, args []Value) Value {
+		if !Equal(t.load(p), args[0]) {
+			return false
+		}
+		t.store(p, args[1])
+		return true
+	}}
+	add := op{"Add", 2, func(t *thread, p Ptr, args []Value) Value {
+		var v Value
+		switch old := t.load(p).(type) {
+		case uint64:
+			v = old + toUint(args[0])
+		case uint32:
+			v = old + uint32(toUint(args[0]))
+		default:
+			panic(errorf("atomic add to %s", Show(old)))
+		}
+		t.store(p, v)
+		return v
+	}}
+	prim := func(name string, o op) {
+		primitives[name] = native(name, o.arity, func(t *thread, args []Value) Value {
+			return o.f(t, asPtr(args[0]), args[1:])
+		})
+	}
+	for ty, ops := range map[string][]op{
+		"Uint64":  {load, store, swap, cas, add},
+		"Uint32":  {load, store, swap, cas, add},
+		"Bool":    {load, store, swap, cas},
+		"Pointer": {load, store, swap, cas},
+	} {
+		for _, o := range ops {
+			// methods without arguments take a unit argument
+			m := o
+			if m.arity == 1 {
+				m.arity = 2
+			}
+			prim(glang.TypeMethod("atomic."+ty, o.name), m)
+			if ty == "Uint64" || ty == "Uint32" {
+				prim("atomic."+o.name+ty, o)
+			}
+		}
+	}
+}
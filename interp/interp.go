@@ -0,0 +1,731 @@
+// Package interp is an interpreter for translated Go code, which runs the
+// glang output of goose directly rather than through Coq.
+//
+// The interpreter gives one execution of a GooseLang program, with a heap,
+// slices, maps, structs and interfaces, and goroutines that are run by a
+// deterministic scheduler. This makes it possible to run the same tests
+// against Go code and its translation, as a check that goose preserves the
+// meaning of programs (see docs/testing-proposal.md).
+//
+// The semantics follow the GooseLang primitives that goose emits; the
+// interpreter reports an error for anything it does not support, and for
+// executions that would be stuck in GooseLang (such as a nil dereference).
+package interp
+
+import (
+	"fmt"
+
+	"github.com/goose-lang/goose/glang"
+)
+
+// Error is a failure of the interpreted program that is not a Go panic: the
+// program reached a state with no GooseLang semantics, or used something the
+// interpreter does not implement.
+type Error struct {
+	// Pos is the Go position being executed when the error happened, if known
+	Pos glang.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+	}
+	return e.Msg
+}
+
+func errorf(format string, args ...interface{}) *Error {
+	return &Error{Msg: fmt.Sprintf(format, args...)}
+}
+
+// PanicError is a Go panic that was not recovered.
+type PanicError struct {
+	Pos   glang.Position
+	Value Value
+}
+
+func (e *PanicError) Error() string {
+	msg := "panic: " + Show(e.Value)
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s", e.Pos, msg)
+	}
+	return msg
+}
+
+// Options configure how a Program runs.
+type Options struct {
+	// DiskSize is the number of blocks in the disk returned by disk.Get
+	DiskSize uint64
+	// Preempt switches to the next goroutine after this many steps, to
+	// explore other interleavings (0 to switch only when a goroutine blocks
+	// or exits)
+	Preempt int
+	// MaxSteps limits the steps in each Call (0 for no limit)
+	MaxSteps int
+}
+
+type pkg struct {
+	name  string
+	path  string
+	decls map[string]glang.Decl
+	types map[string]*Type
+}
+
+// Program is a set of translated packages that can be run.
+type Program struct {
+	opts Options
+	main *pkg
+	// pkgs are the packages, by Go package name
+	pkgs map[string]*pkg
+	// byPath are the packages by import path
+	byPath map[string]*pkg
+}
+
+// NewProgram creates a Program from translated packages. The first file is
+// the main package, whose functions can be called with Call; the others are
+// packages it imports.
+func NewProgram(opts Options, files ...glang.File) *Program {
+	p := &Program{
+		opts:   opts,
+		pkgs:   make(map[string]*pkg),
+		byPath: make(map[string]*pkg),
+	}
+	for _, f := range files {
+		pk := &pkg{
+			name:  f.GoPackage,
+			path:  f.PkgPath,
+			decls: make(map[string]glang.Decl),
+			types: make(map[string]*Type),
+		}
+		for _, d := range f.Decls {
+			if ld, ok := d.(glang.LocatedDecl); ok {
+				d = ld.Decl
+			}
+			if name := declName(d); name != "" {
+				pk.decls[name] = d
+			}
+		}
+		if p.main == nil {
+			p.main = pk
+		}
+		p.pkgs[pk.name] = pk
+		p.byPath[pk.path] = pk
+	}
+	return p
+}
+
+func declName(d glang.Decl) string {
+	switch d := d.(type) {
+	case glang.FuncDecl:
+		return d.Name
+	case glang.TypeDecl:
+		return d.Name
+	case glang.ConstDecl:
+		return d.Name
+	case glang.OpaqueDecl:
+		return d.Name
+	case glang.ExternDecl:
+		return d.Name
+	case glang.MethodSetDecl:
+		return d.Name
+	}
+	return ""
+}
+
+// Functions returns the names of the functions in the main package.
+func (p *Program) Functions() []string {
+	var names []string
+	for name, d := range p.main.decls {
+		if _, ok := d.(glang.FuncDecl); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Call runs the function name from the main package with args (which can be
+// omitted for a function with no arguments), returning its result.
+//
+// Each call starts with a fresh disk. The call finishes when its goroutine
+// returns; other goroutines are abandoned.
+func (p *Program) Call(name string, args ...Value) (Value, error) {
+	d, ok := p.main.decls[name]
+	if !ok {
+		return nil, fmt.Errorf("no function %s in %s", name, p.main.path)
+	}
+	if _, ok := d.(glang.FuncDecl); !ok {
+		return nil, fmt.Errorf("%s is not a function", name)
+	}
+	if len(args) == 0 {
+		args = []Value{Unit{}}
+	}
+	s := newScheduler(p)
+	return s.run(func(t *thread) Value {
+		f := t.global(p.main, name)
+		return t.apply(f, args)
+	})
+}
+
+// env is an environment of GooseLang variables
+type env struct {
+	name string
+	v    Value
+	next *env
+}
+
+func (e *env) bind(name string, v Value) *env {
+	if name == "_" || name == "<>" {
+		return e
+	}
+	return &env{name: name, v: v, next: e}
+}
+
+func (e *env) lookup(name string) (Value, bool) {
+	for ; e != nil; e = e.next {
+		if e.name == name {
+			return e.v, true
+		}
+	}
+	return nil, false
+}
+
+// control values are the results of statements, which GooseLang represents
+// with an exception monad
+type (
+	execVal     struct{ v Value }
+	returnVal   struct{ v Value }
+	breakVal    struct{}
+	continueVal struct{}
+)
+
+func isControl(v Value) bool {
+	switch v.(type) {
+	case returnVal, breakVal, continueVal:
+		return true
+	}
+	return false
+}
+
+// global looks up a top-level name in pk
+func (t *thread) global(pk *pkg, name string) Value {
+	qualified := name
+	if pk != t.p.main {
+		qualified = pk.name + "." + name
+	}
+	d, ok := pk.decls[name]
+	if !ok {
+		panic(errorf("undefined: %s", qualified))
+	}
+	switch d := d.(type) {
+	case glang.FuncDecl:
+		return funcDecl(pk, d)
+	case glang.ConstDecl:
+		// constants are evaluated once per call, since they include global
+		// variables such as sentinel errors, which are compared by identity
+		if v, ok := t.s.globals[qualified]; ok {
+			return v
+		}
+		v := t.inPkg(pk, func() Value { return t.eval(nil, d.Val) })
+		t.s.globals[qualified] = v
+		return v
+	case glang.TypeDecl:
+		return t.declType(pk, d)
+	case glang.MethodSetDecl:
+		// a list of (name, method) pairs, as in GooseLang
+		return t.inPkg(pk, func() Value {
+			var methods []Value
+			for _, m := range d.Methods {
+				methods = append(methods, Tuple{m.Name, t.eval(nil, m.Fn())})
+			}
+			return methods
+		})
+	}
+	panic(errorf("%s has no definition that can be run (%T)", qualified, d))
+}
+
+// inPkg runs f with global names resolved in pk
+func (t *thread) inPkg(pk *pkg, f func() Value) Value {
+	saved := t.pkg
+	t.pkg = pk
+	defer func() { t.pkg = saved }()
+	return f()
+}
+
+func (t *thread) declType(pk *pkg, d glang.TypeDecl) *Type {
+	if ty, ok := pk.types[d.Name]; ok {
+		return ty
+	}
+	// the placeholder is only seen by recursive references, which goose
+	// erases to pointers
+	ty := &Type{Name: d.Name, Kind: KindStruct}
+	pk.types[d.Name] = ty
+	body := t.inPkg(pk, func() Value { return t.evalType(nil, d.Body) }).(*Type)
+	*ty = *body
+	ty.Name = d.Name
+	return ty
+}
+
+func funcDecl(pk *pkg, d glang.FuncDecl) *Func {
+	if len(d.TypeParams) > 0 {
+		panic(errorf("generic function %s", d.Name))
+	}
+	var params []string
+	if d.RecvArg != nil {
+		params = append(params, d.RecvArg.Name)
+	}
+	for _, a := range d.Args {
+		params = append(params, a.Name)
+	}
+	if len(d.Args) == 0 {
+		params = append(params, "_")
+	}
+	f := &Func{name: d.Name, params: params, body: d.Body, pkg: pk}
+	// rec: binds the function's own name, which recursive references use
+	f.env = f.env.bind(d.Name, f)
+	return f
+}
+
+// apply calls f with args, supporting partial and over-application
+func (t *thread) apply(fv Value, args []Value) Value {
+	f, ok := fv.(*Func)
+	if !ok {
+		panic(errorf("calling a non-function %s", Show(fv)))
+	}
+	if f == nil {
+		panic(t.goPanic("nil function call"))
+	}
+	all := append(append([]Value{}, f.applied...), args...)
+	if len(all) < len(f.params) {
+		partial := *f
+		partial.applied = all
+		return &partial
+	}
+	n := len(f.params)
+	var result Value
+	if f.native != nil {
+		result = f.native(t, all[:n])
+	} else {
+		e := f.env
+		for i, p := range f.params {
+			e = e.bind(p, all[i])
+		}
+		result = t.inPkg(f.pkg, func() Value { return t.eval(e, f.body) })
+	}
+	if len(all) > n {
+		return t.apply(result, all[n:])
+	}
+	return result
+}
+
+func (t *thread) step() {
+	t.s.steps++
+	if max := t.p.opts.MaxSteps; max > 0 && t.s.steps > max {
+		panic(errorf("exceeded %d steps", max))
+	}
+	if n := t.p.opts.Preempt; n > 0 && t.s.steps%n == 0 {
+		t.yield()
+	}
+}
+
+func (t *thread) evalBool(e *env, x glang.Expr) bool {
+	v := t.eval(e, x)
+	b, ok := v.(bool)
+	if !ok {
+		panic(errorf("expected a boolean, got %s", Show(v)))
+	}
+	return b
+}
+
+func (t *thread) evalType(e *env, x glang.Expr) *Type {
+	v := t.eval(e, x)
+	ty, ok := v.(*Type)
+	if !ok {
+		panic(errorf("expected a type, got %s", Show(v)))
+	}
+	return ty
+}
+
+func (t *thread) evalArgs(e *env, args []glang.Expr) []Value {
+	var vs []Value
+	for _, a := range args {
+		vs = append(vs, t.eval(e, a))
+	}
+	return vs
+}
+
+// special forms are primitives whose arguments are not evaluated first
+func (t *thread) special(e *env, name string, args []glang.Expr) (Value, bool) {
+	switch name {
+	case "exception_do":
+		v := t.eval(e, args[0])
+		switch v := v.(type) {
+		case returnVal:
+			return v.v, true
+		case execVal:
+			return v.v, true
+		case breakVal, continueVal:
+			panic(errorf("break or continue outside of a loop"))
+		}
+		return v, true
+	case "with_defer:":
+		return t.withDefer(e, args[0]), true
+	}
+	return nil, false
+}
+
+func (t *thread) eval(e *env, x glang.Expr) Value {
+	t.step()
+	switch x := x.(type) {
+	case glang.Located:
+		t.pos = x.Pos
+		return t.eval(e, x.Expr)
+	case glang.ParenExpr:
+		return t.eval(e, x.Inner)
+
+	// types
+	case glang.TypeIdent:
+		return t.typeIdent(string(x))
+	case glang.StructName:
+		return t.typeIdent(string(x))
+	case glang.MapType:
+		return &Type{Kind: KindMap, Key: t.evalType(e, x.Key), Elem: t.evalType(e, x.Value)}
+	case glang.FuncType:
+		return funcT
+	case glang.SliceType:
+		return &Type{Kind: KindSlice, Elem: t.evalType(e, x.Value)}
+	case glang.PtrType:
+		return ptrT
+	case glang.TupleType:
+		ty := &Type{Kind: KindTuple}
+		for _, elem := range x {
+			ty.Elems = append(ty.Elems, t.evalType(e, elem))
+		}
+		return ty
+	case glang.StructType:
+		ty := &Type{Kind: KindStruct}
+		for _, f := range x.Fields {
+			ty.Fields = append(ty.Fields, FieldType{Name: f.Name, Type: t.evalType(e, f.Type)})
+		}
+		return ty
+
+	// variables and names
+	case glang.IdentExpr:
+		v, ok := e.lookup(string(x))
+		if !ok {
+			panic(errorf("unbound variable %s", string(x)))
+		}
+		return v
+	case glang.GallinaIdent:
+		return t.ident(string(x))
+	case glang.PackageIdent:
+		return t.packageIdent(x.Package, x.Ident)
+	case glang.GallinaString:
+		return string(x)
+
+	// literals
+	case glang.BoolLiteral:
+		return bool(x)
+	case glang.UnitLiteral:
+		return Unit{}
+	case glang.IntLiteral:
+		return x.Value
+	case glang.Int32Literal:
+		return x.Value
+	case glang.ByteLiteral:
+		return x.Value
+	case glang.StringLiteral:
+		return x.Value
+	case glang.TupleExpr:
+		if len(x) == 1 {
+			// just parentheses
+			return t.eval(e, x[0])
+		}
+		return Tuple(t.evalArgs(e, x))
+	case glang.ListExpr:
+		return t.evalArgs(e, x)
+	case glang.StructLiteral:
+		ty := t.typeIdent(x.StructName)
+		s := ty.zero().(Struct)
+		names, values := x.Fields()
+		for i, name := range names {
+			s = s.with(name, t.eval(e, values[i]))
+		}
+		return s
+	case glang.ErrorExpr:
+		panic(errorf("goose could not translate this code: %s", x.Message))
+
+	// functions
+	case glang.CallExpr:
+		if f, ok := unlocated(x.MethodName).(glang.GallinaIdent); ok {
+			if v, ok := t.special(e, string(f), x.Args); ok {
+				return v
+			}
+		}
+		f := t.eval(e, x.MethodName)
+		return t.apply(f, t.evalArgs(e, x.Args))
+	case glang.FuncLit:
+		var params []string
+		for _, a := range x.Args {
+			params = append(params, a.Name)
+		}
+		if len(params) == 0 {
+			params = []string{"_"}
+		}
+		return &Func{params: params, body: x.Body, env: e, pkg: t.pkg}
+
+	// control flow
+	case glang.LetExpr:
+		v := t.eval(e, x.ValExpr)
+		switch len(x.Names) {
+		case 0:
+			// sequencing in the exception monad
+			if isControl(v) {
+				return v
+			}
+		case 1:
+			e = e.bind(x.Names[0], v)
+		default:
+			tuple, ok := v.(Tuple)
+			if !ok || len(tuple) != len(x.Names) {
+				panic(errorf("cannot destructure %s into %d values", Show(v), len(x.Names)))
+			}
+			for i, name := range x.Names {
+				e = e.bind(name, tuple[i])
+			}
+		}
+		return t.eval(e, x.Cont)
+	case glang.DoExpr:
+		v := t.eval(e, x.Expr)
+		if isControl(v) {
+			return v
+		}
+		return execVal{v}
+	case glang.ReturnExpr:
+		return returnVal{t.eval(e, x.Value)}
+	case glang.BreakExpr:
+		return breakVal{}
+	case glang.ContinueExpr:
+		return continueVal{}
+	case glang.IfExpr:
+		if t.evalBool(e, x.Cond) {
+			return t.eval(e, x.Then)
+		}
+		return t.eval(e, x.Else)
+	case glang.ForLoopExpr:
+		for t.evalBool(e, x.Cond) {
+			switch v := t.eval(e, x.Body).(type) {
+			case breakVal:
+				return execVal{Unit{}}
+			case returnVal:
+				return v
+			}
+			t.eval(e, x.Post)
+		}
+		return execVal{Unit{}}
+	case glang.ForRangeSliceExpr:
+		return t.rangeSlice(e, x)
+	case glang.ForRangeMapExpr:
+		return t.rangeMap(e, x)
+	case glang.SpawnExpr:
+		t.fork(e, x.Body)
+		return Unit{}
+	case glang.DeferExpr:
+		t.deferCall(e, x.Body)
+		return Unit{}
+
+	// heap
+	case glang.RefExpr:
+		t.evalType(e, x.Ty)
+		return Ptr{c: &cell{v: t.eval(e, x.X)}}
+	case glang.DerefExpr:
+		t.evalType(e, x.Ty)
+		return t.load(t.eval(e, x.X))
+	case glang.StoreStmt:
+		p := t.eval(e, x.Dst)
+		t.evalType(e, x.Ty)
+		t.store(p, t.eval(e, x.X))
+		return Unit{}
+
+	// operators
+	case glang.BinaryExpr:
+		return t.binary(e, x)
+	case glang.NotExpr:
+		// ~ is both negation and bitwise complement
+		switch v := t.eval(e, x.X).(type) {
+		case bool:
+			return !v
+		case uint64:
+			return ^v
+		case uint32:
+			return ^v
+		case uint8:
+			return ^v
+		default:
+			panic(errorf("~ of %s", Show(v)))
+		}
+	case glang.LoggingStmt:
+		return Unit{}
+	}
+	if x == glang.Null {
+		return Ptr{}
+	}
+	panic(errorf("cannot interpret %T", x))
+}
+
+func unlocated(x glang.Expr) glang.Expr {
+	if l, ok := x.(glang.Located); ok {
+		return unlocated(l.Expr)
+	}
+	return x
+}
+
+// typeIdent resolves a type name
+func (t *thread) typeIdent(name string) *Type {
+	if ty, ok := primitiveTypes[name]; ok {
+		return ty
+	}
+	v := t.ident(name)
+	ty, ok := v.(*Type)
+	if !ok {
+		panic(errorf("%s is not a type", name))
+	}
+	return ty
+}
+
+// ident resolves a Gallina identifier, which is either a primitive or a
+// declaration (possibly qualified with a package)
+func (t *thread) ident(name string) Value {
+	if ty, ok := primitiveTypes[name]; ok {
+		return ty
+	}
+	if v, ok := primitives[name]; ok {
+		return v
+	}
+	if _, ok := t.pkg.decls[name]; ok {
+		return t.global(t.pkg, name)
+	}
+	for i := len(name) - 1; i > 0; i-- {
+		if name[i] == '.' {
+			return t.packageIdent(name[:i], name[i+1:])
+		}
+	}
+	panic(errorf("undefined: %s", name))
+}
+
+func (t *thread) packageIdent(pkgName, name string) Value {
+	qualified := pkgName + "." + name
+	if ty, ok := primitiveTypes[qualified]; ok {
+		return ty
+	}
+	if v, ok := primitives[qualified]; ok {
+		return v
+	}
+	pk, ok := t.p.pkgs[pkgName]
+	if !ok {
+		panic(errorf("undefined: %s (package %s is not loaded)", qualified, pkgName))
+	}
+	return t.global(pk, name)
+}
+
+func (t *thread) load(pv Value) Value {
+	p, ok := pv.(Ptr)
+	if !ok {
+		panic(errorf("load from non-pointer %s", Show(pv)))
+	}
+	if p.IsNil() {
+		panic(t.goPanic("invalid memory address or nil pointer dereference"))
+	}
+	v := p.c.v
+	for _, f := range p.fields() {
+		s, ok := v.(Struct)
+		if !ok {
+			panic(errorf("field %s of non-struct %s", f, Show(v)))
+		}
+		v, ok = s.Get(f)
+		if !ok {
+			panic(errorf("no field %s in %s", f, Show(s)))
+		}
+	}
+	return v
+}
+
+func (t *thread) store(pv Value, v Value) {
+	p, ok := pv.(Ptr)
+	if !ok {
+		panic(errorf("store to non-pointer %s", Show(pv)))
+	}
+	if p.IsNil() {
+		panic(t.goPanic("invalid memory address or nil pointer dereference"))
+	}
+	p.c.v = storePath(p.c.v, p.fields(), v)
+}
+
+// storePath replaces the value at path within old
+func storePath(old Value, path []string, v Value) Value {
+	if len(path) == 0 {
+		return v
+	}
+	s, ok := old.(Struct)
+	if !ok {
+		panic(errorf("field %s of non-struct %s", path[0], Show(old)))
+	}
+	inner, ok := s.Get(path[0])
+	if !ok {
+		panic(errorf("no field %s in %s", path[0], Show(s)))
+	}
+	return s.with(path[0], storePath(inner, path[1:], v))
+}
+
+func (t *thread) rangeSlice(e *env, x glang.ForRangeSliceExpr) Value {
+	t.evalType(e, x.Ty)
+	sv := t.eval(e, x.Slice)
+	s, ok := sv.(Slice)
+	if !ok {
+		panic(errorf("range over non-slice %s", Show(sv)))
+	}
+	// the range is over the slice's length when the loop starts
+	n := s.len
+	for i := 0; i < n; i++ {
+		// like the Coq notation, the loop variables are references
+		be := e
+		if x.Key != nil {
+			be = be.bind(string(*x.Key), Ptr{c: &cell{v: uint64(i)}})
+		}
+		if x.Val != nil {
+			be = be.bind(string(*x.Val), Ptr{c: &cell{v: s.elem(i).v}})
+		}
+		switch v := t.eval(be, x.Body).(type) {
+		case breakVal:
+			return Unit{}
+		case returnVal:
+			return v
+		}
+	}
+	return Unit{}
+}
+
+func (t *thread) rangeMap(e *env, x glang.ForRangeMapExpr) Value {
+	mv := t.eval(e, x.Map)
+	m, ok := mv.(*Map)
+	if !ok {
+		panic(errorf("range over non-map %s", Show(mv)))
+	}
+	for _, k := range m.keys() {
+		v, ok := m.get(k)
+		if !ok {
+			// deleted during iteration
+			continue
+		}
+		// goose translates uses of the loop variables as loads, so they are
+		// references as in slice ranges
+		be := e.bind(x.KeyIdent, Ptr{c: &cell{v: k}})
+		be = be.bind(x.ValueIdent, Ptr{c: &cell{v: v}})
+		switch v := t.eval(be, x.Body).(type) {
+		case breakVal:
+			return Unit{}
+		case returnVal:
+			return v
+		}
+	}
+	return Unit{}
+}
@@ -0,0 +1,96 @@
+package interp_test
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goose-lang/goose"
+	"github.com/goose-lang/goose/glang"
+	"github.com/goose-lang/goose/interp"
+)
+
+func translate(t *testing.T, pkg string) glang.File {
+	files, errs, err := goose.Translator{}.TranslatePackages("../testdata/examples", pkg)
+	require.NoError(t, err)
+	require.Len(t, errs, 1)
+	require.NoError(t, errs[0])
+	return files[0]
+}
+
+// knownFailures are semantics tests whose translation is known to be wrong,
+// with the reason.
+//
+// The other failing_test* functions only fail in the Coq interpreter.
+var knownFailures = map[string]string{
+	"failing_testEncDec32": "untyped constant expressions are translated " +
+		"as uint64 even when used as uint32",
+	"failing_testFooBarMutation": "pointer methods called on an " +
+		"addressable struct get a copy of the struct",
+	"failing_testFunctionOrdering": "pointer methods called on an " +
+		"addressable struct get a copy of the struct",
+}
+
+// TestSemantics runs the test functions of testdata/examples/semantics on its
+// translation, which must return true like in the Go test suite generated by
+// cmd/test_gen.
+func TestSemantics(t *testing.T) {
+	f := translate(t, "./semantics")
+	for _, preempt := range []int{0, 7} {
+		prog := interp.NewProgram(interp.Options{
+			DiskSize: 30, Preempt: preempt, MaxSteps: 10_000_000,
+		}, f)
+		names := prog.Functions()
+		sort.Strings(names)
+		ran := 0
+		for _, name := range names {
+			if !strings.HasPrefix(name, "test") &&
+				!strings.HasPrefix(name, "failing_test") {
+				continue
+			}
+			ran++
+			v, err := prog.Call(name)
+			if reason, ok := knownFailures[name]; ok {
+				assert.False(t, err == nil && v == true,
+					"%s (preempt %d) passes but is a known failure: %s",
+					name, preempt, reason)
+				continue
+			}
+			if assert.NoError(t, err, "%s (preempt %d)", name, preempt) {
+				assert.Equal(t, true, v, "%s (preempt %d)", name, preempt)
+			}
+		}
+		assert.NotZero(t, ran)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	f := translate(t, "./unittest")
+	prog := interp.NewProgram(interp.Options{MaxSteps: 100_000}, f)
+
+	_, err := prog.Call("noSuchFunction")
+	assert.Error(t, err)
+
+	// panics are reported with the Go position of the panic
+	_, err = prog.Call("PanicAtTheDisco")
+	var panicErr *interp.PanicError
+	if assert.ErrorAs(t, err, &panicErr) {
+		assert.Equal(t, "panic.go", panicErr.Pos.Filename)
+		assert.Equal(t, 4, panicErr.Pos.Line)
+		assert.Contains(t, err.Error(), "disco")
+	}
+
+	v, err := prog.Call("recoverFromPanic")
+	assert.NoError(t, err)
+	assert.Equal(t, true, v)
+
+	v, err = prog.Call("recoverToValue", uint64(0))
+	assert.NoError(t, err)
+	assert.Equal(t, interp.Tuple{uint64(0), false}, v)
+	v, err = prog.Call("recoverToValue", uint64(3))
+	assert.NoError(t, err)
+	assert.Equal(t, interp.Tuple{uint64(3), true}, v)
+}
@@ -0,0 +1,243 @@
+package interp
+
+import (
+	"fmt"
+
+	"github.com/goose-lang/goose/glang"
+)
+
+// Goroutines are run by a deterministic scheduler: each goroutine is a Go
+// goroutine, but only one runs at a time, and control passes between them
+// when the running goroutine acquires a lock, blocks, exits, or is preempted
+// (see Options.Preempt). Runnable goroutines are run in FIFO order.
+
+// thread is the state of a single goroutine
+type thread struct {
+	p *Program
+	s *scheduler
+	// pkg is the package that global names refer to
+	pkg *pkg
+	// pos is the most recent position executed
+	pos glang.Position
+	// panicking is the panic being handled while running deferred functions,
+	// which recover clears
+	panicking *PanicError
+	// wake passes control to this thread
+	wake chan struct{}
+}
+
+type result struct {
+	v   Value
+	err error
+}
+
+type scheduler struct {
+	p        *Program
+	runnable []*thread
+	// blocked are the threads waiting to acquire a lock, in the order they
+	// blocked
+	blocked []waiter
+	steps   int
+	// globals caches the values of constants
+	globals map[string]Value
+	// methods are the method tables of the types converted to interfaces, by
+	// type identifier
+	methods map[string][]Value
+	disk    *memDisk
+	rand    uint64
+	// done receives the result of the main thread, or the first error
+	done chan result
+	// abort is closed when the call is finished, to stop all other threads
+	abort chan struct{}
+}
+
+type waiter struct {
+	t    *thread
+	lock Ptr
+}
+
+// abortThread unwinds a thread when the call it is part of is finished
+type abortThread struct{}
+
+func newScheduler(p *Program) *scheduler {
+	return &scheduler{
+		p:       p,
+		globals: make(map[string]Value),
+		methods: make(map[string][]Value),
+		disk:    newMemDisk(p.opts.DiskSize),
+		rand:    1,
+		done:    make(chan result, 1),
+		abort:   make(chan struct{}),
+	}
+}
+
+func (s *scheduler) newThread() *thread {
+	return &thread{p: s.p, s: s, pkg: s.p.main, wake: make(chan struct{})}
+}
+
+// finish reports the result of the call, if it is not already finished
+func (s *scheduler) finish(r result) {
+	select {
+	case s.done <- r:
+	default:
+	}
+}
+
+// run runs main on a new thread until it returns, and stops any remaining
+// threads.
+func (s *scheduler) run(main func(t *thread) Value) (Value, error) {
+	t := s.newThread()
+	s.start(t, func() {
+		s.finish(result{v: main(t)})
+	})
+	t.wake <- struct{}{}
+	r := <-s.done
+	close(s.abort)
+	return r.v, r.err
+}
+
+// start starts a thread running body once it is scheduled
+func (s *scheduler) start(t *thread, body func()) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(abortThread); ok {
+					return
+				}
+				s.finish(result{err: t.asError(r)})
+			}
+		}()
+		t.waitWake()
+		body()
+	}()
+}
+
+// asError converts a panic in the interpreter to an error
+func (t *thread) asError(r interface{}) error {
+	switch r := r.(type) {
+	case *Error:
+		if !r.Pos.IsValid() {
+			r.Pos = t.pos
+		}
+		return r
+	case *PanicError:
+		return r
+	}
+	// a bug in the interpreter
+	return fmt.Errorf("%s: interpreter failure: %v", t.pos, r)
+}
+
+func (t *thread) waitWake() {
+	select {
+	case <-t.wake:
+	case <-t.s.abort:
+		panic(abortThread{})
+	}
+}
+
+// switchThread passes control to the next runnable thread, reporting a
+// deadlock if there is none
+func (t *thread) switchThread() {
+	s := t.s
+	if len(s.runnable) == 0 {
+		s.finish(result{err: &Error{Pos: t.pos,
+			Msg: "all goroutines are asleep - deadlock!"}})
+		return
+	}
+	next := s.runnable[0]
+	s.runnable = s.runnable[1:]
+	next.wake <- struct{}{}
+}
+
+// yield lets other threads run
+func (t *thread) yield() {
+	if len(t.s.runnable) == 0 {
+		return
+	}
+	t.s.runnable = append(t.s.runnable, t)
+	t.switchThread()
+	t.waitWake()
+}
+
+// fork starts a new goroutine running body, which runs after the current
+// goroutine yields
+func (t *thread) fork(e *env, body glang.Expr) {
+	child := t.s.newThread()
+	child.pkg = t.pkg
+	child.pos = t.pos
+	t.s.start(child, func() {
+		child.eval(e, body)
+		child.switchThread()
+	})
+	t.s.runnable = append(t.s.runnable, child)
+}
+
+// lock acquires the mutex m points to, blocking while it is held.
+//
+// Acquiring a lock is also a yield point, so that goroutines that poll some
+// state under a lock make progress without preemption.
+func (t *thread) lock(m Ptr) {
+	t.yield()
+	t.waitFor(m, func(v Value) bool { return v == false })
+	t.store(m, true)
+}
+
+// unlock releases the mutex m points to, making the threads waiting for it
+// runnable
+func (t *thread) unlock(m Ptr) {
+	if t.load(m) != true {
+		panic(t.goPanic("sync: unlock of unlocked mutex"))
+	}
+	t.store(m, false)
+	t.wakeWaiters(m)
+}
+
+// waitFor blocks until the value m points to is ready, which other threads
+// signal by calling wakeWaiters after changing it
+func (t *thread) waitFor(m Ptr, ready func(v Value) bool) {
+	for !ready(t.load(m)) {
+		t.s.blocked = append(t.s.blocked, waiter{t: t, lock: m})
+		t.switchThread()
+		t.waitWake()
+	}
+}
+
+// wakeWaiters makes the threads waiting for the value m points to runnable
+func (t *thread) wakeWaiters(m Ptr) {
+	var blocked []waiter
+	for _, w := range t.s.blocked {
+		if w.lock == m {
+			t.s.runnable = append(t.s.runnable, w.t)
+		} else {
+			blocked = append(blocked, w)
+		}
+	}
+	t.s.blocked = blocked
+}
+
+// rwLocked is the state of a sync.RWMutex held by a writer, which otherwise
+// holds the number of readers
+const rwLocked = ^uint64(0)
+
+// the states of a sync.Once
+const (
+	onceNotDone uint64 = iota
+	onceRunning
+	onceDone
+)
+
+// onceDo runs f if it is the first call for the sync.Once o points to, and
+// otherwise waits for the first call to finish
+func (t *thread) onceDo(o Ptr, f Value) {
+	t.waitFor(o, func(v Value) bool { return v != onceRunning })
+	if t.load(o) == onceDone {
+		return
+	}
+	t.store(o, onceRunning)
+	defer func() {
+		// f is done even if it panics, as in Go
+		t.store(o, onceDone)
+		t.wakeWaiters(o)
+	}()
+	t.apply(f, []Value{Unit{}})
+}
@@ -0,0 +1,421 @@
+package interp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goose-lang/goose/glang"
+)
+
+// Value is a GooseLang value.
+//
+// Integers are uint64, uint32 and uint8, and booleans and strings are the
+// corresponding Go types. The other values are Unit, Tuple, Ptr, Slice, *Map,
+// Struct, Interface, *Func and *Type.
+type Value interface{}
+
+// Unit is the unit value #().
+type Unit struct{}
+
+// Tuple is a tuple of values, such as the results of a function.
+type Tuple []Value
+
+// A cell is a single heap location.
+type cell struct {
+	v Value
+}
+
+// Ptr is a pointer to a heap location, or to a (possibly nested) field of the
+// struct stored there.
+//
+// The zero Ptr is nil. Ptrs are comparable, with the same meaning as Go
+// pointer equality.
+type Ptr struct {
+	c *cell
+	// path is the sequence of field names, separated by '.'
+	path string
+}
+
+// IsNil reports whether p is the nil pointer.
+func (p Ptr) IsNil() bool {
+	return p.c == nil
+}
+
+func (p Ptr) field(name string) Ptr {
+	if p.path == "" {
+		return Ptr{c: p.c, path: name}
+	}
+	return Ptr{c: p.c, path: p.path + "." + name}
+}
+
+func (p Ptr) fields() []string {
+	if p.path == "" {
+		return nil
+	}
+	return strings.Split(p.path, ".")
+}
+
+// array is the backing store of slices
+type array struct {
+	cells []*cell
+}
+
+// Slice is a Go slice.
+//
+// The zero Slice is nil.
+type Slice struct {
+	arr      *array
+	off      int
+	len, cap int
+}
+
+// Len is the length of s.
+func (s Slice) Len() int {
+	return s.len
+}
+
+func (s Slice) elem(i int) *cell {
+	return s.arr.cells[s.off+i]
+}
+
+// Elems returns the current values in s.
+func (s Slice) Elems() []Value {
+	var vs []Value
+	for i := 0; i < s.len; i++ {
+		vs = append(vs, s.elem(i).v)
+	}
+	return vs
+}
+
+func newSlice(t *Type, len, cap int) Slice {
+	arr := &array{cells: make([]*cell, cap)}
+	for i := range arr.cells {
+		arr.cells[i] = &cell{v: t.zero()}
+	}
+	return Slice{arr: arr, len: len, cap: cap}
+}
+
+// Map is a Go map. A nil *Map is a nil map.
+type Map struct {
+	key, elem *Type
+	// entries are indexed by hashKey of the key
+	entries map[Value]mapEntry
+}
+
+type mapEntry struct {
+	k, v Value
+}
+
+// hashKey converts a map key to a value Go can hash (structs are represented
+// with slices, so they are hashed by their printed form)
+func hashKey(k Value) Value {
+	switch k := k.(type) {
+	case Struct, Tuple:
+		return Show(k)
+	case Interface:
+		switch k.V.(type) {
+		case Struct, Tuple:
+			return Show(k)
+		}
+	}
+	return k
+}
+
+// Len is the number of entries in m.
+func (m *Map) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.entries)
+}
+
+func (m *Map) get(k Value) (Value, bool) {
+	if m == nil {
+		return nil, false
+	}
+	e, ok := m.entries[hashKey(k)]
+	return e.v, ok
+}
+
+// keys returns the keys of m in a deterministic order
+func (m *Map) keys() []Value {
+	var keys []Value
+	if m == nil {
+		return keys
+	}
+	for _, e := range m.entries {
+		keys = append(keys, e.k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return less(keys[i], keys[j])
+	})
+	return keys
+}
+
+func less(x, y Value) bool {
+	switch x := x.(type) {
+	case uint64:
+		return x < y.(uint64)
+	case uint32:
+		return x < y.(uint32)
+	case uint8:
+		return x < y.(uint8)
+	case string:
+		return x < y.(string)
+	case bool:
+		return !x && y.(bool)
+	}
+	return fmt.Sprint(x) < fmt.Sprint(y)
+}
+
+// Field is a named struct field.
+type Field struct {
+	Name  string
+	Value Value
+}
+
+// Struct is a struct value. Struct values are immutable; storing to a field
+// through a pointer replaces the whole struct in the heap.
+type Struct []Field
+
+// Get returns the value of the field name.
+func (s Struct) Get(name string) (Value, bool) {
+	for _, f := range s {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (s Struct) with(name string, v Value) Struct {
+	s2 := make(Struct, len(s))
+	copy(s2, s)
+	for i := range s2 {
+		if s2[i].Name == name {
+			s2[i].Value = v
+			return s2
+		}
+	}
+	panic(errorf("no field %s in struct", name))
+}
+
+// Interface is an interface value: a value along with the identifier of its
+// dynamic type, which determines its methods.
+//
+// The zero Interface is nil.
+type Interface struct {
+	TypeId string
+	V      Value
+}
+
+// A Func is a function value, either a GooseLang closure or a primitive
+// implemented in Go.
+//
+// Functions are curried: applying a function to fewer arguments than it takes
+// produces a partial application.
+type Func struct {
+	name   string
+	params []string
+	// applied are the arguments of a partial application
+	applied []Value
+
+	// closures have a body, the environment it is evaluated in, and the
+	// package its global names refer to
+	body glang.Expr
+	env  *env
+	pkg  *pkg
+
+	// primitives are implemented by native
+	native func(t *thread, args []Value) Value
+}
+
+func (f *Func) String() string {
+	if f == nil {
+		return "nil func"
+	}
+	if f.name != "" {
+		return f.name
+	}
+	return "func"
+}
+
+// Kind is a kind of GooseLang type.
+type Kind int
+
+// The kinds of types
+const (
+	KindUint64 Kind = iota
+	KindUint32
+	KindByte
+	KindBool
+	KindString
+	KindUnit
+	KindPtr
+	KindFunc
+	KindSlice
+	KindMap
+	KindStruct
+	KindInterface
+	KindTuple
+)
+
+// Type is a GooseLang type (a go_type), which is also a value since types are
+// passed to primitives.
+type Type struct {
+	Kind Kind
+	// Name is the name of a declared type
+	Name string
+	// Elem is the element type of a slice or map, Key the key type of a map
+	Elem, Key *Type
+	// Fields are the fields of a struct
+	Fields []FieldType
+	// Elems are the components of a tuple
+	Elems []*Type
+}
+
+// FieldType is a struct field declaration.
+type FieldType struct {
+	Name string
+	Type *Type
+}
+
+var (
+	uint64T    = &Type{Kind: KindUint64}
+	uint32T    = &Type{Kind: KindUint32}
+	byteT      = &Type{Kind: KindByte}
+	boolT      = &Type{Kind: KindBool}
+	stringT    = &Type{Kind: KindString}
+	unitT      = &Type{Kind: KindUnit}
+	ptrT       = &Type{Kind: KindPtr}
+	funcT      = &Type{Kind: KindFunc}
+	interfaceT = &Type{Kind: KindInterface}
+)
+
+// zero is the zero value of a type
+func (t *Type) zero() Value {
+	switch t.Kind {
+	case KindUint64:
+		return uint64(0)
+	case KindUint32:
+		return uint32(0)
+	case KindByte:
+		return uint8(0)
+	case KindBool:
+		return false
+	case KindString:
+		return ""
+	case KindUnit:
+		return Unit{}
+	case KindPtr:
+		return Ptr{}
+	case KindFunc:
+		return (*Func)(nil)
+	case KindSlice:
+		return Slice{}
+	case KindMap:
+		return (*Map)(nil)
+	case KindStruct:
+		s := make(Struct, len(t.Fields))
+		for i, f := range t.Fields {
+			s[i] = Field{Name: f.Name, Value: f.Type.zero()}
+		}
+		return s
+	case KindInterface:
+		return Interface{}
+	case KindTuple:
+		var vs Tuple
+		for _, t := range t.Elems {
+			vs = append(vs, t.zero())
+		}
+		return vs
+	}
+	panic(errorf("zero value of unknown type kind %d", t.Kind))
+}
+
+// Equal is Go's == on values.
+func Equal(x, y Value) bool {
+	switch x := x.(type) {
+	case Struct:
+		y, ok := y.(Struct)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if x[i].Name != y[i].Name || !Equal(x[i].Value, y[i].Value) {
+				return false
+			}
+		}
+		return true
+	case Tuple:
+		y, ok := y.(Tuple)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !Equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case Interface:
+		y, ok := y.(Interface)
+		return ok && x.TypeId == y.TypeId && Equal(x.V, y.V)
+	case *Type:
+		return x == y
+	}
+	return x == y
+}
+
+// Show formats a value for error messages and test failures.
+func Show(v Value) string {
+	switch v := v.(type) {
+	case Unit:
+		return "#()"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case Ptr:
+		if v.IsNil() {
+			return "nil"
+		}
+		return fmt.Sprintf("ptr(%p%s)", v.c, v.path)
+	case Slice:
+		if v.arr == nil {
+			return "nil slice"
+		}
+		var elems []string
+		for _, e := range v.Elems() {
+			elems = append(elems, Show(e))
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case Struct:
+		var fields []string
+		for _, f := range v {
+			fields = append(fields, f.Name+": "+Show(f.Value))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case Tuple:
+		var elems []string
+		for _, e := range v {
+			elems = append(elems, Show(e))
+		}
+		return "(" + strings.Join(elems, ", ") + ")"
+	case *Map:
+		if v == nil {
+			return "nil map"
+		}
+		var entries []string
+		for _, k := range v.keys() {
+			val, _ := v.get(k)
+			entries = append(entries, Show(k)+": "+Show(val))
+		}
+		return "map[" + strings.Join(entries, ", ") + "]"
+	case Interface:
+		if v.TypeId == "" {
+			return "nil interface"
+		}
+		return fmt.Sprintf("%s(%s)", v.TypeId, Show(v.V))
+	}
+	return fmt.Sprint(v)
+}
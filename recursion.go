@@ -0,0 +1,203 @@
+package goose
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/goose-lang/goose/glang"
+)
+
+// Recursive declarations
+//
+// A function's own name is bound by rec:, so a recursive call refers to that
+// binding rather than to the Coq definition being defined. Mutually recursive
+// functions are combined into a single recursive function that takes an index
+// selecting which of the original functions to run; each original function is
+// then defined by calling it with its index.
+//
+// Struct types can refer to themselves (or each other) through pointers, which
+// are untyped, and through slices and maps. The element types of slices and
+// maps do not affect the layout of a struct, so recursive occurrences there are
+// erased to ptrT.
+
+// funcRef translates a reference to the function (or method) named name
+func (ctx Ctx) funcRef(name string) glang.Expr {
+	ctx.dep.addDep(name)
+	if e, ok := ctx.recFuncs[name]; ok {
+		return e
+	}
+	return glang.GallinaIdent(name)
+}
+
+// recursiveGroup is a strongly-connected component of declarations
+type recursiveGroup struct {
+	ids   []declId
+	decls []ast.Decl
+	// names are the names the declarations define, in order
+	names []string
+}
+
+func (g recursiveGroup) allDecls(isDecl func(d ast.Decl) bool) bool {
+	for _, d := range g.decls {
+		if !isDecl(d) {
+			return false
+		}
+	}
+	return true
+}
+
+func isFuncDecl(d ast.Decl) bool {
+	_, ok := d.(*ast.FuncDecl)
+	return ok
+}
+
+func isTypeDecl(d ast.Decl) bool {
+	d2, ok := d.(*ast.GenDecl)
+	return ok && d2.Tok == token.TYPE
+}
+
+// recursiveGroups finds the groups of declarations that depend on each other,
+// including single types that refer to themselves. Self-recursive functions
+// need no special treatment and are not included.
+func recursiveGroups(fs []NamedFile, ids []declId,
+	declNames map[declId][]string, declDeps map[declId][]string,
+	nameDecls map[string]declId) []recursiveGroup {
+	var groups []recursiveGroup
+	sccs := stronglyConnected(ids, func(id declId) []declId {
+		var succs []declId
+		for _, dep := range declDeps[id] {
+			if depId, ok := nameDecls[dep]; ok {
+				succs = append(succs, depId)
+			}
+		}
+		return succs
+	})
+	for _, scc := range sccs {
+		sort.Slice(scc, func(i, j int) bool {
+			return scc[i].fileIdx < scc[j].fileIdx ||
+				(scc[i].fileIdx == scc[j].fileIdx && scc[i].declIdx < scc[j].declIdx)
+		})
+		var g recursiveGroup
+		for _, id := range scc {
+			g.ids = append(g.ids, id)
+			g.decls = append(g.decls, fs[id.fileIdx].Ast.Decls[id.declIdx])
+			g.names = append(g.names, declNames[id]...)
+		}
+		if len(scc) == 1 {
+			selfRef := false
+			for _, dep := range declDeps[scc[0]] {
+				if depId, ok := nameDecls[dep]; ok && depId == scc[0] {
+					selfRef = true
+				}
+			}
+			if !selfRef || !g.allDecls(isTypeDecl) {
+				continue
+			}
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+// mutualFuncDeclsOrError translates a group of mutually recursive functions,
+// catching Goose translation errors and returning them as a regular Go error
+func (ctx Ctx) mutualFuncDeclsOrError(g recursiveGroup) (decls []glang.Decl, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if gooseErr, ok := r.(gooseError); ok {
+				err = gooseErr.err
+			} else {
+				// r is an error from a non-goose error, indicating a bug
+				panic(r)
+			}
+		}
+	}()
+	return ctx.mutualFuncDecls(g), nil
+}
+
+// mutualFuncDecls translates mutually recursive functions
+func (ctx Ctx) mutualFuncDecls(g recursiveGroup) []glang.Decl {
+	if !g.allDecls(isFuncDecl) {
+		ctx.unsupported(g.decls[0], "mutual recursion between functions and other declarations (%s)",
+			strings.Join(g.names, ", "))
+	}
+	// the prime keeps the name distinct from any Go identifier
+	group := g.names[0] + "'mutual"
+	ctx.recFuncs = make(map[string]glang.Expr)
+	for i, n := range g.names {
+		ctx.recFuncs[n] = glang.NewCallExpr(glang.IdentExpr(group),
+			glang.IntLiteral{Value: uint64(i)})
+	}
+	var fds []glang.FuncDecl
+	for _, d := range g.decls {
+		d := d.(*ast.FuncDecl)
+		if d.Type.TypeParams != nil {
+			ctx.futureWork(d, "mutually recursive generic functions")
+		}
+		fd, ok := ctx.funcDecl(d).(glang.FuncDecl)
+		if !ok {
+			ctx.nope(d, "directive on recursive function")
+		}
+		fds = append(fds, fd)
+	}
+
+	// the arguments of each function, including its receiver and a unit
+	// argument if it has no arguments (following FuncDecl.Signature)
+	args := func(fd glang.FuncDecl) []glang.FieldDecl {
+		var args []glang.FieldDecl
+		if fd.RecvArg != nil {
+			args = append(args, *fd.RecvArg)
+		}
+		args = append(args, fd.Args...)
+		if len(fd.Args) == 0 {
+			args = append(args, glang.FieldDecl{Name: "_"})
+		}
+		return args
+	}
+
+	var body glang.Expr
+	for i := len(fds) - 1; i >= 0; i-- {
+		lit := glang.FuncLit{Args: args(fds[i]), Body: fds[i].Body}
+		if body == nil {
+			body = lit
+			continue
+		}
+		body = glang.IfExpr{
+			Cond: glang.BinaryExpr{
+				X:  glang.IdentExpr("$fn"),
+				Op: glang.OpEquals,
+				Y:  glang.IntLiteral{Value: uint64(i)},
+			},
+			Then: lit,
+			Else: body,
+		}
+	}
+	decls := []glang.Decl{glang.FuncDecl{
+		Name:    group,
+		Args:    []glang.FieldDecl{{Name: "$fn", Type: glang.TypeIdent("uint64T")}},
+		Body:    body,
+		Comment: fmt.Sprintf("mutually recursive: %s", strings.Join(g.names, ", ")),
+	}}
+	for i, fd := range fds {
+		call := []glang.Expr{glang.IntLiteral{Value: uint64(i)}}
+		for _, a := range args(fd) {
+			if a.Name == "_" {
+				call = append(call, glang.Tt)
+			} else {
+				call = append(call, glang.IdentExpr(a.Name))
+			}
+		}
+		decls = append(decls, glang.FuncDecl{
+			Name:     fd.Name,
+			RecvArg:  fd.RecvArg,
+			Args:     fd.Args,
+			Body:     glang.NewCallExpr(glang.GallinaIdent(group), call...),
+			Comment:  fd.Comment,
+			AddTypes: fd.AddTypes,
+		})
+	}
+	return decls
+}
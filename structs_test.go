@@ -0,0 +1,65 @@
+package goose_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/goose-lang/goose"
+)
+
+// translateGooseTests translates packages in testdata/goose-tests, returning
+// the Coq output for each package by import path
+func translateGooseTests(t *testing.T, patterns ...string) map[string]string {
+	var tr goose.Translator
+	fs, errs, err := tr.TranslatePackages("testdata/goose-tests", patterns...)
+	require.NoError(t, err)
+	out := make(map[string]string)
+	for i, f := range fs {
+		require.NoError(t, errs[i])
+		var b bytes.Buffer
+		f.Write(&b)
+		out[f.PkgPath] = b.String()
+	}
+	return out
+}
+
+func TestCrossPackageStructs(t *testing.T) {
+	assert := assert.New(t)
+	out := translateGooseTests(t, "./structs", "./geometry", "./use_structs")
+	require.Len(t, out, 3)
+	code := out["example.com/goose-demo/m/use_structs"]
+
+	assert.Contains(code, "From New.code Require example_com.goose_demo.m.structs.")
+	assert.Contains(code, "From New.code Require example_com.goose_demo.m.geometry.")
+	// literals and field accesses use the descriptor of the exporting package
+	assert.Contains(code, "struct.make structs.Point [{")
+	assert.Contains(code, `struct.field_ref structs.Point "X" "p"`)
+	assert.Contains(code, `struct.field_ref structs.Segment "Start"`)
+	assert.Contains(code, `"P" :: structs.Point;`)
+	assert.Contains(code, "zero_val structs.Point")
+	assert.Contains(code, "structs.Point__Scale")
+	assert.Contains(code, "structs.NewSegment")
+	// package geom is in the directory geometry, which determines its Coq name
+	assert.Contains(code, "struct.make geometry.Rect [{")
+	assert.Contains(code, `struct.field_ref geometry.Rect "Width" "r"`)
+	assert.Contains(code, "geometry.Rect__Area")
+	assert.NotContains(code, "geom.")
+}
+
+func TestCrossPackageStructDeps(t *testing.T) {
+	var tr goose.Translator
+	g, errs, err := tr.DepGraph("testdata/goose-tests", "./use_structs")
+	require.NoError(t, err)
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	node := func(pkg, name string) goose.DepNode {
+		return goose.DepNode{Package: "example.com/goose-demo/m/" + pkg, Name: name}
+	}
+	assert.Contains(t, g.Edges, goose.DepEdge{From: node("use_structs", "MakePoint"), To: node("structs", "Point")})
+	assert.Contains(t, g.Edges, goose.DepEdge{From: node("use_structs", "Wrapper"), To: node("structs", "Point")})
+	assert.Contains(t, g.Edges, goose.DepEdge{From: node("use_structs", "Square"), To: node("geometry", "Rect")})
+}
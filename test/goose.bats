@@ -128,3 +128,176 @@ assert_file_not_exist() {
   assert_output --partial "ExampleFunc"
   sed -i~ 's/ExampleFunc/UseMarshal/' m.go
 }
+
+@test "goose reports all errors" {
+  run goose -out Goose ./errors/multiple
+  assert_failure
+  assert_output --partial "if statement initializations"
+  assert_output --partial "statement *ast.SelectStmt"
+  assert_output --partial "statement *ast.LabeledStmt"
+  assert_output --partial "3 errors"
+}
+
+@test "goose -max-errors" {
+  run goose -out Goose -max-errors 2 ./errors/multiple
+  assert_failure
+  assert_output --partial "2 errors"
+  refute_output --partial "statement *ast.LabeledStmt"
+}
+
+@test "goose -diagnostics=json" {
+  run goose -out Goose -diagnostics=json ./errors/multiple
+  assert_failure
+  assert_output --partial '"code": "GOOSE001"'
+  assert_output --partial '"message": "statement *ast.SelectStmt"'
+  assert_output --partial '"endLine": 18'
+}
+
+@test "goose -diagnostics=sarif" {
+  run goose -out Goose -diagnostics=sarif ./errors/multiple
+  assert_failure
+  assert_output --partial '"version": "2.1.0"'
+  assert_output --partial '"ruleId": "GOOSE001"'
+  assert_output --partial '"uri": "errors/multiple/multiple.go"'
+}
+
+@test "goose -diagnostics reports load errors" {
+  run goose -out Goose -diagnostics=json './errors/nothing...'
+  assert_failure
+  assert_output --partial '"category": "load"'
+  assert_output --partial '"code": "GOOSE100"'
+  assert_output --partial '"message": "patterns matched no packages"'
+  run goose -out Goose -diagnostics=sarif './errors/nothing...'
+  assert_failure
+  assert_output --partial '"ruleId": "GOOSE100"'
+}
+
+@test "goose check" {
+  run goose check ./errors/multiple
+  assert_failure
+  assert_output --partial "multiple.go:4:5: unsupported: if statement initializations"
+  assert_output --partial "unsupported: statement *ast.SelectStmt"
+  refute_output --partial "Definition"
+  assert_file_not_exist "$OUT"/m/errors/multiple.v
+}
+
+@test "goose fix" {
+  run goose fix ./errors/multiple
+  assert_success
+  assert_output --partial "-	if y := x + 1; y > 2 {"
+  assert_output --partial "+	y := x + 1"
+  # without -w, goose fix only prints a diff
+  run cat errors/multiple/multiple.go
+  assert_output --partial "if y := x + 1; y > 2 {"
+}
+
+@test "goose -stats reports cache hits" {
+  run goose -out Goose -cache Goose/cache -stats . ./use_disk
+  assert_success
+  assert_output --partial "cache: 0 hits, 2 misses"
+  rm "$OUT"/m.v
+  run goose -out Goose -cache Goose/cache -stats . ./use_disk
+  assert_success
+  assert_output --partial "cache: 2 hits, 0 misses"
+  # cache hits still write the output
+  assert_file_exists "$OUT"/m.v
+}
+
+@test "goose does not cache without -cache" {
+  run goose -out Goose -stats .
+  assert_success
+  refute_output --partial "cache:"
+}
+
+@test "goose cache is invalidated by changes" {
+  goose -out Goose -cache Goose/cache .
+  sed -i~ 's/UseMarshal/ExampleFunc/' m.go
+  run goose -out Goose -cache Goose/cache -stats .
+  sed -i~ 's/ExampleFunc/UseMarshal/' m.go
+  assert_output --partial "cache: 0 hits, 1 misses"
+  run cat "$OUT"/m.v
+  assert_output --partial "ExampleFunc"
+}
+
+@test "goose -watch retranslates changed packages" {
+  goose -out Goose -watch . ./use_disk > Goose.log 2>&1 &
+  pid=$!
+  sleep 3
+  sed -i~ 's/UseMarshal/ExampleFunc/' m.go
+  sleep 2
+  kill "$pid"
+  mv m.go~ m.go
+  run cat Goose.log
+  rm Goose.log
+  assert_line "ok   example.com/goose-demo/m/use_disk"
+  assert_line --index 2 "ok   example.com/goose-demo/m"
+  run cat "$OUT"/m.v
+  assert_output --partial "ExampleFunc"
+}
+
+@test "goose reads goose.json" {
+  cat > goose.json <<'JSON'
+{
+  "patterns": [".", "./use_disk"],
+  "out": "Goose/config",
+  "exclude": ["example.com/goose-demo/m/use_disk"],
+  "pathMapping": "module"
+}
+JSON
+  run goose
+  rm goose.json
+  assert_success
+  assert_file_exists Goose/config/m.v
+  assert_file_not_exist Goose/config/m/use_disk.v
+}
+
+@test "goose -deps" {
+  run goose -deps=dot .
+  assert_success
+  assert_output --partial '"example.com/goose-demo/m.UseMarshal" -> "github.com/tchajed/marshal.NewEnc";'
+  run goose -deps=json .
+  assert_success
+  assert_output --partial '"cycles": []'
+  assert_file_not_exist "$OUT"/m.v
+}
+
+@test "goose -backend=lean" {
+  run goose -out Goose -backend=lean .
+  assert_success
+  assert_file_exists "$OUT"/m.lean
+  assert_file_not_exist "$OUT"/m.v
+  run cat "$OUT"/m.lean
+  assert_line "namespace m"
+  assert_line "import GooseLang"
+}
+
+@test "goose -emit=json" {
+  run goose -out Goose -emit=json .
+  assert_success
+  assert_file_exists "$OUT"/m.json
+  assert_file_not_exist "$OUT"/m.v
+  run cat "$OUT"/m.json
+  assert_output --partial '"kind": "FuncDecl"'
+  assert_output --partial '"file": "m.go"'
+}
+
+@test "goose -source-map" {
+  run goose -out Goose -source-map .
+  assert_success
+  assert_file_exists "$OUT"/m.v
+  assert_file_exists "$OUT"/m.v.map
+  run cat "$OUT"/m.v.map
+  assert_output --partial '"file":"m.v"'
+  assert_output --partial '"file":"m.go"'
+  run goose -out Goose -backend=lean -source-map .
+  assert_failure
+}
+
+@test "goose translates structs from other packages" {
+  goose -out Goose ./structs ./geometry ./use_structs
+  assert_file_exists "$OUT"/m/structs.v
+  assert_file_exists "$OUT"/m/geometry.v
+  run cat "$OUT"/m/use_structs.v
+  assert_output --partial 'struct.field_ref structs.Point "X"'
+  assert_output --partial "struct.make geometry.Rect"
+}
@@ -24,8 +24,10 @@ Definition Log__mkHdr : val :=
     let: "enc" := ref_ty marshal.Enc (zero_val marshal.Enc) in
     let: "$a0" := marshal.NewEnc disk.BlockSize in
     do:  "enc" <-[marshal.Enc] "$a0";;;
-    do:  (marshal.Enc__PutInt (![marshal.Enc] "enc")) (![uint64T] (struct.field_ref Log "sz" (![ptrT] "log")));;;
-    do:  (marshal.Enc__PutInt (![marshal.Enc] "enc")) (![uint64T] (struct.field_ref Log "diskSz" (![ptrT] "log")));;;
+    do:  (marshal.Enc__PutInt (![marshal.Enc] "enc"))
+      (![uint64T] (struct.field_ref Log "sz" (![ptrT] "log")));;;
+    do:  (marshal.Enc__PutInt (![marshal.Enc] "enc"))
+      (![uint64T] (struct.field_ref Log "diskSz" (![ptrT] "log")));;;
     return: ((marshal.Enc__Finish (![marshal.Enc] "enc")) #());;;
     do:  #()).
 
@@ -127,13 +129,16 @@ Definition Log__append : val :=
     let: "sz" := ref_ty uint64T (zero_val uint64T) in
     let: "$a0" := ![uint64T] (struct.field_ref Log "sz" (![ptrT] "log")) in
     do:  "sz" <-[uint64T] "$a0";;;
-    (if: (slice.len (![sliceT (sliceT byteT)] "bks")) ≥ (((![uint64T] (struct.field_ref Log "diskSz" (![ptrT] "log"))) - #1) - (![uint64T] "sz"))
+    (if: (slice.len (![sliceT (sliceT byteT)] "bks")) ≥
+      (((![uint64T] (struct.field_ref Log "diskSz" (![ptrT] "log"))) - #1) - (![uint64T] "sz"))
     then
       return: (#false);;;
       do:  #()
     else do:  #());;;
     do:  writeAll (![sliceT (sliceT byteT)] "bks") (#1 + (![uint64T] "sz"));;;
-    do:  (struct.field_ref Log "sz" (![ptrT] "log")) <-[uint64T] ((![uint64T] (struct.field_ref Log "sz" (![ptrT] "log"))) + (slice.len (![sliceT (sliceT byteT)] "bks")));;;
+    do:  (struct.field_ref Log "sz" (![ptrT] "log")) <-[uint64T]
+      ((![uint64T] (struct.field_ref Log "sz" (![ptrT] "log"))) +
+        (slice.len (![sliceT (sliceT byteT)] "bks")));;;
     do:  (Log__writeHdr (![ptrT] "log")) #();;;
     return: (#true);;;
     do:  #()).
@@ -165,3 +170,16 @@ Definition Log__Reset : val :=
     do:  (Log__reset (![ptrT] "log")) #();;;
     do:  (sync.Mutex__Unlock (![ptrT] (struct.field_ref Log "m" (![ptrT] "log")))) #();;;
     do:  #()).
+
+Definition Log__mset : list (string * val) := [].
+
+Definition Log__mset_ptr : list (string * val) := [
+  ("mkHdr", Log__mkHdr%V);
+  ("writeHdr", Log__writeHdr%V);
+  ("get", Log__get%V);
+  ("Get", Log__Get%V);
+  ("append", Log__append%V);
+  ("Append", Log__Append%V);
+  ("reset", Log__reset%V);
+  ("Reset", Log__Reset%V)
+].
@@ -1,6 +1,6 @@
 module github.com/goose-lang/goose/testdata/examples
 
-go 1.22
+go 1.23
 
 require (
 	github.com/goose-lang/goose v0.6.1
@@ -75,12 +75,17 @@ Definition Log__readBlocks : val :=
     (let: "i" := ref_ty uint64T (zero_val uint64T) in
     let: "$a0" := #0 in
     do:  "i" <-[uint64T] "$a0";;;
-    (for: (λ: <>, (![uint64T] "i") < (![uint64T] "len")); (λ: <>, do:  "i" <-[uint64T] ((![uint64T] "i") + #1);;;
+    (for: (λ: <>, (![uint64T] "i") < (![uint64T] "len")); (λ: <>, do:  "i" <-[uint64T]
+      ((![uint64T] "i") + #1);;;
     #()) := λ: <>,
       let: "blk" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
       let: "$a0" := disk.Read (LOGSTART + (![uint64T] "i")) in
       do:  "blk" <-[sliceT byteT] "$a0";;;
-      let: "$a0" := slice.append (sliceT byteT) (![sliceT (sliceT byteT)] "blks") (slice.literal (sliceT byteT) [![sliceT byteT] "blk"]) in
+      let: "$a0" :=
+        slice.append
+          (sliceT byteT)
+          (![sliceT (sliceT byteT)] "blks")
+          (slice.literal (sliceT byteT) [![sliceT byteT] "blk"]) in
       do:  "blks" <-[sliceT (sliceT byteT)] "$a0";;;
       do:  #()));;;
     return: (![sliceT (sliceT byteT)] "blks");;;
@@ -110,9 +115,19 @@ Definition Log__memWrite : val :=
     (let: "i" := ref_ty uint64T (zero_val uint64T) in
     let: "$a0" := #0 in
     do:  "i" <-[uint64T] "$a0";;;
-    (for: (λ: <>, (![uint64T] "i") < (![uint64T] "n")); (λ: <>, do:  "i" <-[uint64T] ((![uint64T] "i") + #1);;;
+    (for: (λ: <>, (![uint64T] "i") < (![uint64T] "n")); (λ: <>, do:  "i" <-[uint64T]
+      ((![uint64T] "i") + #1);;;
     #()) := λ: <>,
-      let: "$a0" := slice.append (sliceT byteT) (![sliceT (sliceT byteT)] (![ptrT] (struct.field_ref Log "memLog" "log"))) (slice.literal (sliceT byteT) [![sliceT byteT] (slice.elem_ref (sliceT byteT) (![sliceT (sliceT byteT)] "l") (![uint64T] "i"))]) in
+      let: "$a0" :=
+        slice.append
+          (sliceT byteT)
+          (![sliceT (sliceT byteT)] (![ptrT] (struct.field_ref Log "memLog" "log")))
+          (slice.literal
+            (sliceT byteT)
+            [![sliceT byteT] (slice.elem_ref
+               (sliceT byteT)
+               (![sliceT (sliceT byteT)] "l")
+               (![uint64T] "i"))]) in
       do:  (![ptrT] (struct.field_ref Log "memLog" "log")) <-[sliceT (sliceT byteT)] "$a0";;;
       do:  #()));;;
     do:  #()).
@@ -122,7 +137,9 @@ Definition Log__memAppend : val :=
     exception_do (let: "log" := ref_ty Log "log" in
     let: "l" := ref_ty (sliceT (sliceT byteT)) "l" in
     do:  (sync.Mutex__Lock (![ptrT] (struct.field_ref Log "memLock" "log"))) #();;;
-    (if: ((![uint64T] (![ptrT] (struct.field_ref Log "memLen" "log"))) + (slice.len (![sliceT (sliceT byteT)] "l"))) ≥ (![uint64T] (struct.field_ref Log "logSz" "log"))
+    (if: ((![uint64T] (![ptrT] (struct.field_ref Log "memLen" "log"))) +
+      (slice.len (![sliceT (sliceT byteT)] "l"))) ≥
+      (![uint64T] (struct.field_ref Log "logSz" "log"))
     then
       do:  (sync.Mutex__Unlock (![ptrT] (struct.field_ref Log "memLock" "log"))) #();;;
       return: (#false, #0);;;
@@ -132,7 +149,9 @@ Definition Log__memAppend : val :=
     let: "$a0" := ![uint64T] (![ptrT] (struct.field_ref Log "memTxnNxt" "log")) in
     do:  "txn" <-[uint64T] "$a0";;;
     let: "n" := ref_ty uint64T (zero_val uint64T) in
-    let: "$a0" := (![uint64T] (![ptrT] (struct.field_ref Log "memLen" "log"))) + (slice.len (![sliceT (sliceT byteT)] "l")) in
+    let: "$a0" :=
+      (![uint64T] (![ptrT] (struct.field_ref Log "memLen" "log"))) +
+        (slice.len (![sliceT (sliceT byteT)] "l")) in
     do:  "n" <-[uint64T] "$a0";;;
     let: "$a0" := ![uint64T] "n" in
     do:  (![ptrT] (struct.field_ref Log "memLen" "log")) <-[uint64T] "$a0";;;
@@ -199,10 +218,15 @@ Definition Log__writeBlocks : val :=
     (let: "i" := ref_ty uint64T (zero_val uint64T) in
     let: "$a0" := #0 in
     do:  "i" <-[uint64T] "$a0";;;
-    (for: (λ: <>, (![uint64T] "i") < (![uint64T] "n")); (λ: <>, do:  "i" <-[uint64T] ((![uint64T] "i") + #1);;;
+    (for: (λ: <>, (![uint64T] "i") < (![uint64T] "n")); (λ: <>, do:  "i" <-[uint64T]
+      ((![uint64T] "i") + #1);;;
     #()) := λ: <>,
       let: "bk" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
-      let: "$a0" := ![sliceT byteT] (slice.elem_ref (sliceT byteT) (![sliceT (sliceT byteT)] "l") (![uint64T] "i")) in
+      let: "$a0" :=
+        ![sliceT byteT] (slice.elem_ref
+          (sliceT byteT)
+          (![sliceT (sliceT byteT)] "l")
+          (![uint64T] "i")) in
       do:  "bk" <-[sliceT byteT] "$a0";;;
       do:  disk.Write ((![uint64T] "pos") + (![uint64T] "i")) (![sliceT byteT] "bk");;;
       do:  #()));;;
@@ -230,7 +254,9 @@ Definition Log__diskAppend : val :=
     let: "$a0" := ![uint64T] (![ptrT] (struct.field_ref Log "memTxnNxt" "log")) in
     do:  "memnxt" <-[uint64T] "$a0";;;
     do:  (sync.Mutex__Unlock (![ptrT] (struct.field_ref Log "memLock" "log"))) #();;;
-    do:  (Log__writeBlocks (![Log] "log")) (![sliceT (sliceT byteT)] "blks") (![uint64T] "disklen");;;
+    do:  (Log__writeBlocks (![Log] "log"))
+      (![sliceT (sliceT byteT)] "blks")
+      (![uint64T] "disklen");;;
     do:  (Log__writeHdr (![Log] "log")) (![uint64T] "memlen");;;
     let: "$a0" := ![uint64T] "memnxt" in
     do:  (![ptrT] (struct.field_ref Log "logTxnNxt" "log")) <-[uint64T] "$a0";;;
@@ -245,6 +271,36 @@ Definition Log__Logger : val :=
       do:  #());;;
     do:  #()).
 
+Definition Log__mset : list (string * val) := [
+  ("writeHdr", Log__writeHdr%V);
+  ("readHdr", Log__readHdr%V);
+  ("readBlocks", Log__readBlocks%V);
+  ("Read", Log__Read%V);
+  ("memWrite", Log__memWrite%V);
+  ("memAppend", Log__memAppend%V);
+  ("readLogTxnNxt", Log__readLogTxnNxt%V);
+  ("diskAppendWait", Log__diskAppendWait%V);
+  ("Append", Log__Append%V);
+  ("writeBlocks", Log__writeBlocks%V);
+  ("diskAppend", Log__diskAppend%V);
+  ("Logger", Log__Logger%V)
+].
+
+Definition Log__mset_ptr : list (string * val) := [
+  ("writeHdr", (λ: "$recvAddr", Log__writeHdr (![Log] "$recvAddr"))%V);
+  ("readHdr", (λ: "$recvAddr", Log__readHdr (![Log] "$recvAddr"))%V);
+  ("readBlocks", (λ: "$recvAddr", Log__readBlocks (![Log] "$recvAddr"))%V);
+  ("Read", (λ: "$recvAddr", Log__Read (![Log] "$recvAddr"))%V);
+  ("memWrite", (λ: "$recvAddr", Log__memWrite (![Log] "$recvAddr"))%V);
+  ("memAppend", (λ: "$recvAddr", Log__memAppend (![Log] "$recvAddr"))%V);
+  ("readLogTxnNxt", (λ: "$recvAddr", Log__readLogTxnNxt (![Log] "$recvAddr"))%V);
+  ("diskAppendWait", (λ: "$recvAddr", Log__diskAppendWait (![Log] "$recvAddr"))%V);
+  ("Append", (λ: "$recvAddr", Log__Append (![Log] "$recvAddr"))%V);
+  ("writeBlocks", (λ: "$recvAddr", Log__writeBlocks (![Log] "$recvAddr"))%V);
+  ("diskAppend", (λ: "$recvAddr", Log__diskAppend (![Log] "$recvAddr"))%V);
+  ("Logger", (λ: "$recvAddr", Log__Logger (![Log] "$recvAddr"))%V)
+].
+
 (* txn.go *)
 
 Definition Txn : go_type := structT [
@@ -273,13 +329,20 @@ Definition Txn__Write : val :=
     let: "ret" := ref_ty boolT #true in
     let: "ok" := ref_ty boolT (zero_val boolT) in
     let: <> := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
-    let: ("$a0", "$a1") := Fst (map.get (![mapT uint64T (sliceT byteT)] (struct.field_ref Txn "blks" "txn")) (![uint64T] "addr")) in
+    let: ("$a0", "$a1") :=
+      Fst
+        (map.get
+          (![mapT uint64T (sliceT byteT)] (struct.field_ref Txn "blks" "txn"))
+          (![uint64T] "addr")) in
     do:  "ok" <-[boolT] "$a1";;;
     do:  "$a0";;;
     (if: ![boolT] "ok"
     then
       let: "$a0" := ![sliceT byteT] (![ptrT] "blk") in
-      do:  map.insert (![mapT uint64T (sliceT byteT)] (struct.field_ref Txn "blks" "txn")) (![uint64T] "addr") "$a0";;;
+      do:  map.insert
+        (![mapT uint64T (sliceT byteT)] (struct.field_ref Txn "blks" "txn"))
+        (![uint64T] "addr")
+        "$a0";;;
       do:  #()
     else do:  #());;;
     (if: (~ (![boolT] "ok"))
@@ -291,7 +354,10 @@ Definition Txn__Write : val :=
         do:  #()
       else
         let: "$a0" := ![sliceT byteT] (![ptrT] "blk") in
-        do:  map.insert (![mapT uint64T (sliceT byteT)] (struct.field_ref Txn "blks" "txn")) (![uint64T] "addr") "$a0";;;
+        do:  map.insert
+          (![mapT uint64T (sliceT byteT)] (struct.field_ref Txn "blks" "txn"))
+          (![uint64T] "addr")
+          "$a0";;;
         do:  #());;;
       do:  #()
     else do:  #());;;
@@ -304,7 +370,11 @@ Definition Txn__Read : val :=
     let: "addr" := ref_ty uint64T "addr" in
     let: "ok" := ref_ty boolT (zero_val boolT) in
     let: "v" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
-    let: ("$a0", "$a1") := Fst (map.get (![mapT uint64T (sliceT byteT)] (struct.field_ref Txn "blks" "txn")) (![uint64T] "addr")) in
+    let: ("$a0", "$a1") :=
+      Fst
+        (map.get
+          (![mapT uint64T (sliceT byteT)] (struct.field_ref Txn "blks" "txn"))
+          (![uint64T] "addr")) in
     do:  "ok" <-[boolT] "$a1";;;
     do:  "v" <-[sliceT byteT] "$a0";;;
     (if: ![boolT] "ok"
@@ -323,11 +393,29 @@ Definition Txn__Commit : val :=
     let: "$a0" := ref_ty (sliceT (sliceT byteT)) (zero_val (sliceT (sliceT byteT))) in
     do:  "blks" <-[ptrT] "$a0";;;
     do:  MapIter (![mapT uint64T (sliceT byteT)] (struct.field_ref Txn "blks" "txn")) (λ: <> "v",
-      let: "$a0" := slice.append (sliceT byteT) (![sliceT (sliceT byteT)] (![ptrT] "blks")) (slice.literal (sliceT byteT) [![sliceT byteT] "v"]) in
+      let: "$a0" :=
+        slice.append
+          (sliceT byteT)
+          (![sliceT (sliceT byteT)] (![ptrT] "blks"))
+          (slice.literal (sliceT byteT) [![sliceT byteT] "v"]) in
       do:  (![ptrT] "blks") <-[sliceT (sliceT byteT)] "$a0";;;
       do:  #());;;
     let: "ok" := ref_ty boolT (zero_val boolT) in
-    let: "$a0" := (Log__Append (![Log] (![ptrT] (struct.field_ref Txn "log" "txn")))) (![sliceT (sliceT byteT)] (![ptrT] "blks")) in
+    let: "$a0" :=
+      (Log__Append (![Log] (![ptrT] (struct.field_ref Txn "log" "txn"))))
+        (![sliceT (sliceT byteT)] (![ptrT] "blks")) in
     do:  "ok" <-[boolT] "$a0";;;
     return: (![boolT] "ok");;;
     do:  #()).
+
+Definition Txn__mset : list (string * val) := [
+  ("Write", Txn__Write%V);
+  ("Read", Txn__Read%V);
+  ("Commit", Txn__Commit%V)
+].
+
+Definition Txn__mset_ptr : list (string * val) := [
+  ("Write", (λ: "$recvAddr", Txn__Write (![Txn] "$recvAddr"))%V);
+  ("Read", (λ: "$recvAddr", Txn__Read (![Txn] "$recvAddr"))%V);
+  ("Commit", (λ: "$recvAddr", Txn__Commit (![Txn] "$recvAddr"))%V)
+].
@@ -0,0 +1,30 @@
+(* autogenerated from github.com/goose-lang/goose/testdata/examples/multipkg/app *)
+From New.golang Require Import defn.
+From New.code Require github_com.goose_lang.goose.testdata.examples.multipkg.entry.
+From New.code Require github_com.goose_lang.goose.testdata.examples.multipkg.store.
+
+Section code.
+Context `{ffi_syntax}.
+Local Coercion Var' s: expr := Var s.
+
+(* Package app uses a store through the entry and store packages. *)
+
+Definition PutGet : val :=
+  rec: "PutGet" "key" :=
+    exception_do (let: "key" := ref_ty uint64T "key" in
+    let: "s" := ref_ty ptrT (zero_val ptrT) in
+    let: "$a0" := store.New #() in
+    do:  "s" <-[ptrT] "$a0";;;
+    do:  (store.Store__Put (![ptrT] "s"))
+      (entry.New (![uint64T] "key") (StringToBytes #(str "v")));;;
+    let: "ok" := ref_ty boolT (zero_val boolT) in
+    let: "e" := ref_ty entry.Entry (zero_val entry.Entry) in
+    let: ("$a0", "$a1") := (store.Store__Get (![ptrT] "s")) (![uint64T] "key") in
+    do:  "ok" <-[boolT] "$a1";;;
+    do:  "e" <-[entry.Entry] "$a0";;;
+    return: (((![boolT] "ok") &&
+               ((![uint64T] (struct.field_ref entry.Entry "Key" "e")) = (![uint64T] "key"))) &&
+               ((![uint64T] "key") < entry.MaxKey));;;
+    do:  #()).
+
+End code.
@@ -0,0 +1,14 @@
+// Package app uses a store through the entry and store packages.
+package app
+
+import (
+	"github.com/goose-lang/goose/testdata/examples/multipkg/entry"
+	"github.com/goose-lang/goose/testdata/examples/multipkg/store"
+)
+
+func PutGet(key uint64) bool {
+	s := store.New()
+	s.Put(entry.New(key, []byte("v")))
+	e, ok := s.Get(key)
+	return ok && e.Key == key && key < entry.MaxKey
+}
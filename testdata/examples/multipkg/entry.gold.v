@@ -0,0 +1,46 @@
+(* autogenerated from github.com/goose-lang/goose/testdata/examples/multipkg/entry *)
+From New.golang Require Import defn.
+
+Section code.
+Context `{ffi_syntax}.
+Local Coercion Var' s: expr := Var s.
+
+(* Package entry defines the entries stored by package store. *)
+
+Definition MaxKey : expr := #1024.
+
+Definition Entry : go_type := structT [
+  "Key" :: uint64T;
+  "Value" :: sliceT byteT
+].
+
+Definition New : val :=
+  rec: "New" "key" "value" :=
+    exception_do (let: "value" := ref_ty (sliceT byteT) "value" in
+    let: "key" := ref_ty uint64T "key" in
+    return: (struct.make Entry [{
+       "Key" ::= ![uint64T] "key";
+       "Value" ::= ![sliceT byteT] "value"
+     }]);;;
+    do:  #()).
+
+Definition Entry__Valid : val :=
+  rec: "Entry__Valid" "e" <> :=
+    exception_do (let: "e" := ref_ty Entry "e" in
+    return: ((![uint64T] (struct.field_ref Entry "Key" "e")) < MaxKey);;;
+    do:  #()).
+
+Definition Entry__mset : list (string * val) := [
+  ("Valid", Entry__Valid%V)
+].
+
+Definition Entry__mset_ptr : list (string * val) := [
+  ("Valid", (λ: "$recvAddr", Entry__Valid (![Entry] "$recvAddr"))%V)
+].
+
+(* Checksum is trusted rather than translated.
+
+   opaque: func Checksum(e Entry) uint64 *)
+Context (Checksum : val).
+
+End code.
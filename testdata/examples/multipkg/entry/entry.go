@@ -0,0 +1,28 @@
+// Package entry defines the entries stored by package store.
+package entry
+
+const MaxKey uint64 = 1024
+
+type Entry struct {
+	Key   uint64
+	Value []byte
+}
+
+func New(key uint64, value []byte) Entry {
+	return Entry{Key: key, Value: value}
+}
+
+func (e Entry) Valid() bool {
+	return e.Key < MaxKey
+}
+
+// Checksum is trusted rather than translated.
+//
+//goose:opaque
+func Checksum(e Entry) uint64 {
+	var h uint64
+	for _, b := range e.Value {
+		h = h*31 + uint64(b)
+	}
+	return h
+}
@@ -0,0 +1,77 @@
+(* autogenerated from github.com/goose-lang/goose/testdata/examples/multipkg/store *)
+From New.golang Require Import defn.
+From New.code Require github_com.goose_lang.goose.testdata.examples.multipkg.entry.
+From New.code Require github_com.goose_lang.goose.testdata.examples.multipkg.store.index.
+
+Section code.
+Context `{ffi_syntax}.
+Local Coercion Var' s: expr := Var s.
+
+(* Package store is an append-only store of entries. *)
+
+Definition Store : go_type := structT [
+  "entries" :: sliceT entry.Entry;
+  "idx" :: ptrT
+].
+
+Definition New : val :=
+  rec: "New" <> :=
+    exception_do (return: (ref_ty Store (struct.make Store [{
+       "entries" ::= slice.nil;
+       "idx" ::= index.New #()
+     }]));;;
+    do:  #()).
+
+Definition Store__Put : val :=
+  rec: "Store__Put" "s" "e" :=
+    exception_do (let: "s" := ref_ty ptrT "s" in
+    let: "e" := ref_ty entry.Entry "e" in
+    (if: (~ ((entry.Entry__Valid (![entry.Entry] "e")) #()))
+    then
+      return: (#false);;;
+      do:  #()
+    else do:  #());;;
+    do:  (index.Index__Insert (![ptrT] (struct.field_ref Store "idx" (![ptrT] "s"))))
+      (![uint64T] (struct.field_ref entry.Entry "Key" "e"))
+      (slice.len (![sliceT entry.Entry] (struct.field_ref Store "entries" (![ptrT] "s"))));;;
+    let: "$a0" :=
+      slice.append
+        entry.Entry
+        (![sliceT entry.Entry] (struct.field_ref Store "entries" (![ptrT] "s")))
+        (slice.literal entry.Entry [![entry.Entry] "e"]) in
+    do:  (struct.field_ref Store "entries" (![ptrT] "s")) <-[sliceT entry.Entry] "$a0";;;
+    return: (#true);;;
+    do:  #()).
+
+Definition Store__Get : val :=
+  rec: "Store__Get" "s" "key" :=
+    exception_do (let: "s" := ref_ty ptrT "s" in
+    let: "key" := ref_ty uint64T "key" in
+    let: "ok" := ref_ty boolT (zero_val boolT) in
+    let: "pos" := ref_ty uint64T (zero_val uint64T) in
+    let: ("$a0", "$a1") :=
+      (index.Index__Lookup (![ptrT] (struct.field_ref Store "idx" (![ptrT] "s"))))
+        (![uint64T] "key") in
+    do:  "ok" <-[boolT] "$a1";;;
+    do:  "pos" <-[uint64T] "$a0";;;
+    (if: (~ (![boolT] "ok"))
+    then
+      return: (struct.make entry.Entry [{
+       }], #false);;;
+      do:  #()
+    else do:  #());;;
+    return: (![entry.Entry] (slice.elem_ref
+               entry.Entry
+               (![sliceT entry.Entry] (struct.field_ref Store "entries" (![ptrT] "s")))
+               (![uint64T] "pos")),
+             #true);;;
+    do:  #()).
+
+Definition Store__mset : list (string * val) := [].
+
+Definition Store__mset_ptr : list (string * val) := [
+  ("Put", Store__Put%V);
+  ("Get", Store__Get%V)
+].
+
+End code.
@@ -0,0 +1,56 @@
+(* autogenerated from github.com/goose-lang/goose/testdata/examples/multipkg/store/index *)
+From New.golang Require Import defn.
+
+Section code.
+Context `{ffi_syntax}.
+Local Coercion Var' s: expr := Var s.
+
+(* Package index maps keys to positions in a store. *)
+
+Definition Index : go_type := structT [
+  "m" :: mapT uint64T uint64T
+].
+
+Definition New : val :=
+  rec: "New" <> :=
+    exception_do (return: (ref_ty Index (struct.make Index [{
+       "m" ::= map.make uint64T uint64T #()
+     }]));;;
+    do:  #()).
+
+Definition Index__Insert : val :=
+  rec: "Index__Insert" "idx" "key" "pos" :=
+    exception_do (let: "idx" := ref_ty ptrT "idx" in
+    let: "pos" := ref_ty uint64T "pos" in
+    let: "key" := ref_ty uint64T "key" in
+    let: "$a0" := ![uint64T] "pos" in
+    do:  map.insert
+      (![mapT uint64T uint64T] (struct.field_ref Index "m" (![ptrT] "idx")))
+      (![uint64T] "key")
+      "$a0";;;
+    do:  #()).
+
+Definition Index__Lookup : val :=
+  rec: "Index__Lookup" "idx" "key" :=
+    exception_do (let: "idx" := ref_ty ptrT "idx" in
+    let: "key" := ref_ty uint64T "key" in
+    let: "ok" := ref_ty boolT (zero_val boolT) in
+    let: "pos" := ref_ty uint64T (zero_val uint64T) in
+    let: ("$a0", "$a1") :=
+      Fst
+        (map.get
+          (![mapT uint64T uint64T] (struct.field_ref Index "m" (![ptrT] "idx")))
+          (![uint64T] "key")) in
+    do:  "ok" <-[boolT] "$a1";;;
+    do:  "pos" <-[uint64T] "$a0";;;
+    return: (![uint64T] "pos", ![boolT] "ok");;;
+    do:  #()).
+
+Definition Index__mset : list (string * val) := [].
+
+Definition Index__mset_ptr : list (string * val) := [
+  ("Insert", Index__Insert%V);
+  ("Lookup", Index__Lookup%V)
+].
+
+End code.
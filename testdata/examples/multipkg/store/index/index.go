@@ -0,0 +1,19 @@
+// Package index maps keys to positions in a store.
+package index
+
+type Index struct {
+	m map[uint64]uint64
+}
+
+func New() *Index {
+	return &Index{m: make(map[uint64]uint64)}
+}
+
+func (idx *Index) Insert(key uint64, pos uint64) {
+	idx.m[key] = pos
+}
+
+func (idx *Index) Lookup(key uint64) (uint64, bool) {
+	pos, ok := idx.m[key]
+	return pos, ok
+}
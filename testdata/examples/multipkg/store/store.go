@@ -0,0 +1,33 @@
+// Package store is an append-only store of entries.
+package store
+
+import (
+	"github.com/goose-lang/goose/testdata/examples/multipkg/entry"
+	"github.com/goose-lang/goose/testdata/examples/multipkg/store/index"
+)
+
+type Store struct {
+	entries []entry.Entry
+	idx     *index.Index
+}
+
+func New() *Store {
+	return &Store{entries: nil, idx: index.New()}
+}
+
+func (s *Store) Put(e entry.Entry) bool {
+	if !e.Valid() {
+		return false
+	}
+	s.idx.Insert(e.Key, uint64(len(s.entries)))
+	s.entries = append(s.entries, e)
+	return true
+}
+
+func (s *Store) Get(key uint64) (entry.Entry, bool) {
+	pos, ok := s.idx.Lookup(key)
+	if !ok {
+		return entry.Entry{}, false
+	}
+	return s.entries[pos], true
+}
@@ -23,3 +23,23 @@ func testClosureBasic() bool {
 	}
 	return false
 }
+
+func testClosureParamAssign() bool {
+	double := func(x uint64) uint64 {
+		x = x * 2
+		return x
+	}
+	return double(3) == 6
+}
+
+func testClosureEarlyReturn() bool {
+	firstEven := func(xs []uint64) uint64 {
+		for _, x := range xs {
+			if x%2 == 0 {
+				return x
+			}
+		}
+		return 0
+	}
+	return firstEven([]uint64{1, 3, 4, 6}) == 4
+}
@@ -30,6 +30,18 @@ func (suite *GoTestSuite) TestClosureBasic() {
 	suite.Equal(true, testClosureBasic())
 }
 
+func (suite *GoTestSuite) TestClosureParamAssign() {
+	d := disk.NewMemDisk(30)
+	disk.Init(d)
+	suite.Equal(true, testClosureParamAssign())
+}
+
+func (suite *GoTestSuite) TestClosureEarlyReturn() {
+	d := disk.NewMemDisk(30)
+	disk.Init(d)
+	suite.Equal(true, testClosureEarlyReturn())
+}
+
 func (suite *GoTestSuite) TestCompareAll() {
 	d := disk.NewMemDisk(30)
 	disk.Init(d)
@@ -126,6 +138,18 @@ func (suite *GoTestSuite) TestArgumentOrder() {
 	suite.Equal(true, failing_testArgumentOrder())
 }
 
+func (suite *GoTestSuite) TestLockedCounter() {
+	d := disk.NewMemDisk(30)
+	disk.Init(d)
+	suite.Equal(true, testLockedCounter())
+}
+
+func (suite *GoTestSuite) TestRWLockedCounter() {
+	d := disk.NewMemDisk(30)
+	disk.Init(d)
+	suite.Equal(true, testRWLockedCounter())
+}
+
 func (suite *GoTestSuite) TestU64ToU32() {
 	d := disk.NewMemDisk(30)
 	disk.Init(d)
@@ -174,12 +198,42 @@ func (suite *GoTestSuite) TestIfStmtInterface() {
 	suite.Equal(true, testIfStmtInterface())
 }
 
+func (suite *GoTestSuite) TestPointerInterface() {
+	d := disk.NewMemDisk(30)
+	disk.Init(d)
+	suite.Equal(true, testPointerInterface())
+}
+
+func (suite *GoTestSuite) TestInterfaceInOwnMethod() {
+	d := disk.NewMemDisk(30)
+	disk.Init(d)
+	suite.Equal(true, testInterfaceInOwnMethod())
+}
+
 func (suite *GoTestSuite) TestsUseLocks() {
 	d := disk.NewMemDisk(30)
 	disk.Init(d)
 	suite.Equal(true, testsUseLocks())
 }
 
+func (suite *GoTestSuite) TestsUseRWLocks() {
+	d := disk.NewMemDisk(30)
+	disk.Init(d)
+	suite.Equal(true, testsUseRWLocks())
+}
+
+func (suite *GoTestSuite) TestOnce() {
+	d := disk.NewMemDisk(30)
+	disk.Init(d)
+	suite.Equal(true, testOnce())
+}
+
+func (suite *GoTestSuite) TestAtomics() {
+	d := disk.NewMemDisk(30)
+	disk.Init(d)
+	suite.Equal(true, testAtomics())
+}
+
 func (suite *GoTestSuite) TestStandardForLoop() {
 	d := disk.NewMemDisk(30)
 	disk.Init(d)
@@ -240,6 +294,12 @@ func (suite *GoTestSuite) TestNestedGoStyleLoopsNoComparison() {
 	suite.Equal(true, testNestedGoStyleLoopsNoComparison())
 }
 
+func (suite *GoTestSuite) TestRangeConstant() {
+	d := disk.NewMemDisk(30)
+	disk.Init(d)
+	suite.Equal(true, testRangeConstant())
+}
+
 func (suite *GoTestSuite) TestIterateMap() {
 	d := disk.NewMemDisk(30)
 	disk.Init(d)
@@ -390,6 +450,18 @@ func (suite *GoTestSuite) TestPlusTimes() {
 	suite.Equal(true, testPlusTimes())
 }
 
+func (suite *GoTestSuite) TestRecoverPanicValue() {
+	d := disk.NewMemDisk(30)
+	disk.Init(d)
+	suite.Equal(true, testRecoverPanicValue())
+}
+
+func (suite *GoTestSuite) TestRecoverNonNil() {
+	d := disk.NewMemDisk(30)
+	disk.Init(d)
+	suite.Equal(true, testRecoverNonNil())
+}
+
 func (suite *GoTestSuite) TestOrCompareSimple() {
 	d := disk.NewMemDisk(30)
 	disk.Init(d)
@@ -0,0 +1,48 @@
+package semantics
+
+import "sync"
+
+// testLockedCounter increments a counter from several goroutines and waits
+// for all of them by polling the counter.
+func testLockedCounter() bool {
+	m := new(sync.Mutex)
+	var n uint64
+	for i := uint64(0); i < 4; i++ {
+		go func() {
+			m.Lock()
+			n = n + 1
+			m.Unlock()
+		}()
+	}
+	for {
+		m.Lock()
+		done := n == 4
+		m.Unlock()
+		if done {
+			break
+		}
+	}
+	return n == 4
+}
+
+// testRWLockedCounter is testLockedCounter, polling under a read lock.
+func testRWLockedCounter() bool {
+	m := new(sync.RWMutex)
+	var n uint64
+	for i := uint64(0); i < 4; i++ {
+		go func() {
+			m.Lock()
+			n = n + 1
+			m.Unlock()
+		}()
+	}
+	for {
+		m.RLock()
+		done := n == 4
+		m.RUnlock()
+		if done {
+			break
+		}
+	}
+	return n == 4
+}
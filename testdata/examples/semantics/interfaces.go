@@ -33,6 +33,28 @@ func (t SquareStruct) Volume() uint64 {
 	return t.Side * t.Side * t.Side
 }
 
+// CubeStruct converts itself to an interface in its own methods.
+type CubeStruct struct {
+	Side uint64
+}
+
+func (t CubeStruct) Square() uint64 {
+	return t.Side * t.Side
+}
+
+func (t CubeStruct) Volume() uint64 {
+	return t.Square() * t.Side
+}
+
+func (t CubeStruct) asGeometry() geometryInterface {
+	return t
+}
+
+func (t *CubeStruct) grow() geometryInterface {
+	t.Side += 1
+	return t
+}
+
 // ----------------------------
 // TESTS
 // ----------------------------
@@ -79,3 +101,19 @@ func testIfStmtInterface() bool {
 	}
 	return false
 }
+
+func testPointerInterface() bool {
+	s := &SquareStruct{
+		Side: 3,
+	}
+	return measureArea(s) == 9 && measureVolume(s) == 27
+}
+
+func testInterfaceInOwnMethod() bool {
+	s := CubeStruct{
+		Side: 2,
+	}
+	g := s.asGeometry()
+	h := (&s).grow()
+	return g.Volume() == 8 && h.Square() == 9 && measureArea(s.asGeometry()) == 9
+}
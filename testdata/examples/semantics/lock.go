@@ -1,6 +1,9 @@
 package semantics
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+)
 
 // We can't interpret multithreaded code, so this just checks that
 // locks are correctly interpreted
@@ -10,3 +13,39 @@ func testsUseLocks() bool {
 	m.Unlock()
 	return true
 }
+
+func testsUseRWLocks() bool {
+	m := new(sync.RWMutex)
+	m.RLock()
+	m.RLock()
+	m.RUnlock()
+	m.RUnlock()
+	m.Lock()
+	m.Unlock()
+	return true
+}
+
+func testOnce() bool {
+	var once sync.Once
+	var n uint64
+	for i := uint64(0); i < 3; i++ {
+		once.Do(func() {
+			n = n + 1
+		})
+	}
+	return n == 1
+}
+
+func testAtomics() bool {
+	var n atomic.Uint64
+	n.Store(2)
+	n.Add(3)
+	old := n.Swap(7)
+	swapped := n.CompareAndSwap(7, 8)
+	var b atomic.Bool
+	b.Store(true)
+	var x uint64
+	atomic.AddUint64(&x, 4)
+	return old == 5 && swapped && n.Load() == 8 && b.Load() &&
+		atomic.LoadUint64(&x) == 4
+}
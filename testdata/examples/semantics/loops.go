@@ -160,3 +160,11 @@ func testNestedGoStyleLoopsNoComparison() bool {
 	}
 	return ok
 }
+
+func testRangeConstant() bool {
+	var sum uint64
+	for i := range 5 {
+		sum += uint64(i)
+	}
+	return sum == 10
+}
@@ -0,0 +1,32 @@
+package semantics
+
+// ----------------------------
+// SETUP
+// ----------------------------
+
+func recoverConstant() (r interface{}) {
+	defer func() {
+		r = recover()
+	}()
+	panic("message")
+}
+
+func recoverDynamic(s string) (r interface{}) {
+	defer func() {
+		r = recover()
+	}()
+	panic(s + "age")
+}
+
+// ----------------------------
+// TESTS
+// ----------------------------
+
+// constant and computed panic values are recovered the same way
+func testRecoverPanicValue() bool {
+	return recoverConstant() == recoverDynamic("mess")
+}
+
+func testRecoverNonNil() bool {
+	return recoverConstant() != nil
+}
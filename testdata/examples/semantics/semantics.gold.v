@@ -3,6 +3,7 @@ From New.golang Require Import defn.
 From New.code Require github_com.goose_lang.goose.machine.
 From New.code Require github_com.goose_lang.goose.machine.disk.
 From New.code Require sync.
+From New.code Require sync.atomic.
 
 From New Require Import disk_prelude.
 
@@ -50,7 +51,8 @@ Definition freeRange : val :=
     (let: "i" := ref_ty uint64T (zero_val uint64T) in
     let: "$a0" := #0 in
     do:  "i" <-[uint64T] "$a0";;;
-    (for: (λ: <>, (![uint64T] "i") < (![uint64T] "sz")); (λ: <>, do:  "i" <-[uint64T] ((![uint64T] "i") + #1);;;
+    (for: (λ: <>, (![uint64T] "i") < (![uint64T] "sz")); (λ: <>, do:  "i" <-[uint64T]
+      ((![uint64T] "i") + #1);;;
     #()) := λ: <>,
       let: "$a0" := struct.make unit [{
       }] in
@@ -110,9 +112,10 @@ Definition adder : val :=
   rec: "adder" <> :=
     exception_do (let: "sum" := ref_ty uint64T #0 in
     return: ((λ: "x",
+       exception_do (let: "x" := ref_ty uint64T "x" in
        do:  "sum" <-[uint64T] ((![uint64T] "sum") + (![uint64T] "x"));;;
        return: (![uint64T] "sum");;;
-       do:  #()
+       do:  #())
        ));;;
     do:  #()).
 
@@ -140,6 +143,41 @@ Definition testClosureBasic : val :=
     return: (#false);;;
     do:  #()).
 
+Definition testClosureParamAssign : val :=
+  rec: "testClosureParamAssign" <> :=
+    exception_do (let: "double" := ref_ty funcT (zero_val funcT) in
+    let: "$a0" := (λ: "x",
+      exception_do (let: "x" := ref_ty uint64T "x" in
+      let: "$a0" := (![uint64T] "x") * #2 in
+      do:  "x" <-[uint64T] "$a0";;;
+      return: (![uint64T] "x");;;
+      do:  #())
+      ) in
+    do:  "double" <-[funcT] "$a0";;;
+    return: (((![funcT] "double") #3) = #6);;;
+    do:  #()).
+
+Definition testClosureEarlyReturn : val :=
+  rec: "testClosureEarlyReturn" <> :=
+    exception_do (let: "firstEven" := ref_ty funcT (zero_val funcT) in
+    let: "$a0" := (λ: "xs",
+      exception_do (let: "xs" := ref_ty (sliceT uint64T) "xs" in
+      do:  let: "$range" := ![sliceT uint64T] "xs" in
+      slice.for_range uint64T "$range" (λ: <> "x",
+        let: "x" := ref_ty uint64T "x" in
+        (if: ((![uint64T] "x") `rem` #2) = #0
+        then
+          return: (![uint64T] "x");;;
+          do:  #()
+        else do:  #());;;
+        do:  #());;;
+      return: (#0);;;
+      do:  #())
+      ) in
+    do:  "firstEven" <-[funcT] "$a0";;;
+    return: (((![funcT] "firstEven") (slice.literal uint64T [ #1; #3; #4; #6 ])) = #4);;;
+    do:  #()).
+
 (* comparisons.go *)
 
 Definition testCompareAll : val :=
@@ -297,7 +335,8 @@ Definition testCopyShorterDst : val :=
     let: "n" := ref_ty uint64T (zero_val uint64T) in
     let: "$a0" := slice.copy byteT (![sliceT byteT] "y") (![sliceT byteT] "x") in
     do:  "n" <-[uint64T] "$a0";;;
-    return: (((![uint64T] "n") = #10) && ((![byteT] (slice.elem_ref byteT (![sliceT byteT] "y") #3)) = #(U8 1)));;;
+    return: (((![uint64T] "n") = #10) &&
+               ((![byteT] (slice.elem_ref byteT (![sliceT byteT] "y") #3)) = #(U8 1)));;;
     do:  #()).
 
 Definition testCopyShorterSrc : val :=
@@ -315,7 +354,9 @@ Definition testCopyShorterSrc : val :=
     let: "n" := ref_ty uint64T (zero_val uint64T) in
     let: "$a0" := slice.copy byteT (![sliceT byteT] "y") (![sliceT byteT] "x") in
     do:  "n" <-[uint64T] "$a0";;;
-    return: ((((![uint64T] "n") = #10) && ((![byteT] (slice.elem_ref byteT (![sliceT byteT] "y") #3)) = #(U8 1))) && ((![byteT] (slice.elem_ref byteT (![sliceT byteT] "y") #12)) = #(U8 2)));;;
+    return: ((((![uint64T] "n") = #10) &&
+               ((![byteT] (slice.elem_ref byteT (![sliceT byteT] "y") #3)) = #(U8 1))) &&
+               ((![byteT] (slice.elem_ref byteT (![sliceT byteT] "y") #12)) = #(U8 2)));;;
     do:  #()).
 
 (* encoding.go *)
@@ -338,6 +379,12 @@ Definition Enc__consume : val :=
     return: (![sliceT byteT] "b");;;
     do:  #()).
 
+Definition Enc__mset : list (string * val) := [].
+
+Definition Enc__mset_ptr : list (string * val) := [
+  ("consume", Enc__consume%V)
+].
+
 Definition Dec : go_type := structT [
   "p" :: sliceT byteT
 ].
@@ -356,6 +403,12 @@ Definition Dec__consume : val :=
     return: (![sliceT byteT] "b");;;
     do:  #()).
 
+Definition Dec__mset : list (string * val) := [].
+
+Definition Dec__mset_ptr : list (string * val) := [
+  ("consume", Dec__consume%V)
+].
+
 Definition roundtripEncDec32 : val :=
   rec: "roundtripEncDec32" "x" :=
     exception_do (let: "x" := ref_ty uint32T "x" in
@@ -442,7 +495,8 @@ Definition testEncDec64Simple : val :=
 Definition testEncDec64 : val :=
   rec: "testEncDec64" <> :=
     exception_do (let: "ok" := ref_ty boolT #true in
-    let: "$a0" := (![boolT] "ok") && ((roundtripEncDec64 #62206846038638762) = #62206846038638762) in
+    let: "$a0" :=
+      (![boolT] "ok") && ((roundtripEncDec64 #62206846038638762) = #62206846038638762) in
     do:  "ok" <-[boolT] "$a0";;;
     let: "$a0" := (![boolT] "ok") && ((roundtripEncDec64 (#1 ≪ #63)) = (#1 ≪ #63)) in
     do:  "ok" <-[boolT] "$a0";;;
@@ -496,7 +550,11 @@ Definition Editor__AdvanceReturn : val :=
     let: "next" := ref_ty uint64T "next" in
     let: "tmp" := ref_ty uint64T (![uint64T] (struct.field_ref Editor "next_val" (![ptrT] "e"))) in
     let: "$a0" := ![uint64T] "tmp" in
-    do:  (slice.elem_ref uint64T (![sliceT uint64T] (struct.field_ref Editor "s" (![ptrT] "e"))) #0) <-[uint64T] "$a0";;;
+    do:  (slice.elem_ref
+      uint64T
+      (![sliceT uint64T] (struct.field_ref Editor "s" (![ptrT] "e")))
+      #0) <-[uint64T]
+      "$a0";;;
     let: "$a0" := ![uint64T] "next" in
     do:  (struct.field_ref Editor "next_val" (![ptrT] "e")) <-[uint64T] "$a0";;;
     let: "$a0" := let: "$s" := ![sliceT uint64T] (struct.field_ref Editor "s" (![ptrT] "e")) in
@@ -505,6 +563,12 @@ Definition Editor__AdvanceReturn : val :=
     return: (![uint64T] "tmp");;;
     do:  #()).
 
+Definition Editor__mset : list (string * val) := [].
+
+Definition Editor__mset_ptr : list (string * val) := [
+  ("AdvanceReturn", Editor__AdvanceReturn%V)
+].
+
 (* we call this function with side-effectful function calls as arguments,
    its implementation is unimportant *)
 Definition addFour64 : val :=
@@ -539,7 +603,9 @@ Definition failing_testFunctionOrdering : val :=
       "next_val" ::= #101
     }] in
     do:  "e2" <-[Editor] "$a0";;;
-    (if: (((Editor__AdvanceReturn (![Editor] "e1")) #2) + ((Editor__AdvanceReturn (![Editor] "e2")) #102)) ≠ #102
+    (if: (((Editor__AdvanceReturn (![Editor] "e1")) #2) +
+      ((Editor__AdvanceReturn (![Editor] "e2")) #102)) ≠
+      #102
     then
       return: (#false);;;
       do:  #()
@@ -549,7 +615,12 @@ Definition failing_testFunctionOrdering : val :=
       return: (#false);;;
       do:  #()
     else do:  #());;;
-    (if: (addFour64 ((Editor__AdvanceReturn (![Editor] "e1")) #3) ((Editor__AdvanceReturn (![Editor] "e2")) #103) ((Editor__AdvanceReturn (![Editor] "e2")) #104) ((Editor__AdvanceReturn (![Editor] "e1")) #4)) ≠ #210
+    (if: (addFour64
+      ((Editor__AdvanceReturn (![Editor] "e1")) #3)
+      ((Editor__AdvanceReturn (![Editor] "e2")) #103)
+      ((Editor__AdvanceReturn (![Editor] "e2")) #104)
+      ((Editor__AdvanceReturn (![Editor] "e1")) #4)) ≠
+      #210
     then
       return: (#false);;;
       do:  #()
@@ -586,7 +657,9 @@ Definition failing_testFunctionOrdering : val :=
       return: (#false);;;
       do:  #()
     else do:  #());;;
-    return: (((![uint64T] (struct.field_ref Pair "x" "p")) + (![uint64T] (struct.field_ref Pair "x" "q"))) = #109);;;
+    return: (((![uint64T] (struct.field_ref Pair "x" "p")) +
+               (![uint64T] (struct.field_ref Pair "x" "q"))) =
+               #109);;;
     do:  #()).
 
 Definition storeAndReturn : val :=
@@ -603,13 +676,92 @@ Definition storeAndReturn : val :=
 Definition failing_testArgumentOrder : val :=
   rec: "failing_testArgumentOrder" <> :=
     exception_do (let: "x" := ref_ty uint64T #0 in
-    do:  addFour64 (storeAndReturn "x" #1) (storeAndReturn "x" #2) (storeAndReturn "x" #3) (storeAndReturn "x" #4);;;
+    do:  addFour64
+      (storeAndReturn "x" #1)
+      (storeAndReturn "x" #2)
+      (storeAndReturn "x" #3)
+      (storeAndReturn "x" #4);;;
     let: "ok" := ref_ty boolT (zero_val boolT) in
     let: "$a0" := (![uint64T] "x") = #4 in
     do:  "ok" <-[boolT] "$a0";;;
     return: (![boolT] "ok");;;
     do:  #()).
 
+(* goroutines.go *)
+
+(* testLockedCounter increments a counter from several goroutines and waits
+   for all of them by polling the counter. *)
+Definition testLockedCounter : val :=
+  rec: "testLockedCounter" <> :=
+    exception_do (let: "m" := ref_ty ptrT (zero_val ptrT) in
+    let: "$a0" := ref_ty sync.Mutex (zero_val sync.Mutex) in
+    do:  "m" <-[ptrT] "$a0";;;
+    let: "n" := ref_ty uint64T (zero_val uint64T) in
+    (let: "i" := ref_ty uint64T (zero_val uint64T) in
+    let: "$a0" := #0 in
+    do:  "i" <-[uint64T] "$a0";;;
+    (for: (λ: <>, (![uint64T] "i") < #4); (λ: <>, do:  "i" <-[uint64T] ((![uint64T] "i") + #1);;;
+    #()) := λ: <>,
+      let: "$go" := (λ: <>,
+        exception_do (do:  (sync.Mutex__Lock (![ptrT] "m")) #();;;
+        let: "$a0" := (![uint64T] "n") + #1 in
+        do:  "n" <-[uint64T] "$a0";;;
+        do:  (sync.Mutex__Unlock (![ptrT] "m")) #();;;
+        do:  #())
+        ) in
+      do:  Fork ("$go" #());;;
+      do:  #()));;;
+    (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+      do:  (sync.Mutex__Lock (![ptrT] "m")) #();;;
+      let: "done" := ref_ty boolT (zero_val boolT) in
+      let: "$a0" := (![uint64T] "n") = #4 in
+      do:  "done" <-[boolT] "$a0";;;
+      do:  (sync.Mutex__Unlock (![ptrT] "m")) #();;;
+      (if: ![boolT] "done"
+      then
+        break: #();;;
+        do:  #()
+      else do:  #());;;
+      do:  #());;;
+    return: ((![uint64T] "n") = #4);;;
+    do:  #()).
+
+(* testRWLockedCounter is testLockedCounter, polling under a read lock. *)
+Definition testRWLockedCounter : val :=
+  rec: "testRWLockedCounter" <> :=
+    exception_do (let: "m" := ref_ty ptrT (zero_val ptrT) in
+    let: "$a0" := ref_ty sync.RWMutex (zero_val sync.RWMutex) in
+    do:  "m" <-[ptrT] "$a0";;;
+    let: "n" := ref_ty uint64T (zero_val uint64T) in
+    (let: "i" := ref_ty uint64T (zero_val uint64T) in
+    let: "$a0" := #0 in
+    do:  "i" <-[uint64T] "$a0";;;
+    (for: (λ: <>, (![uint64T] "i") < #4); (λ: <>, do:  "i" <-[uint64T] ((![uint64T] "i") + #1);;;
+    #()) := λ: <>,
+      let: "$go" := (λ: <>,
+        exception_do (do:  (sync.RWMutex__Lock (![ptrT] "m")) #();;;
+        let: "$a0" := (![uint64T] "n") + #1 in
+        do:  "n" <-[uint64T] "$a0";;;
+        do:  (sync.RWMutex__Unlock (![ptrT] "m")) #();;;
+        do:  #())
+        ) in
+      do:  Fork ("$go" #());;;
+      do:  #()));;;
+    (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
+      do:  (sync.RWMutex__RLock (![ptrT] "m")) #();;;
+      let: "done" := ref_ty boolT (zero_val boolT) in
+      let: "$a0" := (![uint64T] "n") = #4 in
+      do:  "done" <-[boolT] "$a0";;;
+      do:  (sync.RWMutex__RUnlock (![ptrT] "m")) #();;;
+      (if: ![boolT] "done"
+      then
+        break: #();;;
+        do:  #()
+      else do:  #());;;
+      do:  #());;;
+    return: ((![uint64T] "n") = #4);;;
+    do:  #()).
+
 (* int_conversions.go *)
 
 Definition testU64ToU32 : val :=
@@ -654,7 +806,7 @@ Definition geometryInterface : go_type := interfaceT.
 Definition measureArea : val :=
   rec: "measureArea" "t" :=
     exception_do (let: "t" := ref_ty geometryInterface "t" in
-    return: ((__Square (![geometryInterface] "t")) #());;;
+    return: ((interface.get "Square" (![geometryInterface] "t")) #());;;
     do:  #()).
 
 Definition measureVolumePlusNM : val :=
@@ -662,13 +814,14 @@ Definition measureVolumePlusNM : val :=
     exception_do (let: "m" := ref_ty uint64T "m" in
     let: "n" := ref_ty uint64T "n" in
     let: "t" := ref_ty geometryInterface "t" in
-    return: ((((__Volume (![geometryInterface] "t")) #()) + (![uint64T] "n")) + (![uint64T] "m"));;;
+    return: ((((interface.get "Volume" (![geometryInterface] "t")) #()) + (![uint64T] "n")) +
+               (![uint64T] "m"));;;
     do:  #()).
 
 Definition measureVolume : val :=
   rec: "measureVolume" "t" :=
     exception_do (let: "t" := ref_ty geometryInterface "t" in
-    return: ((__Volume (![geometryInterface] "t")) #());;;
+    return: ((interface.get "Volume" (![geometryInterface] "t")) #());;;
     do:  #()).
 
 Definition SquareStruct : go_type := structT [
@@ -678,15 +831,84 @@ Definition SquareStruct : go_type := structT [
 Definition SquareStruct__Square : val :=
   rec: "SquareStruct__Square" "t" <> :=
     exception_do (let: "t" := ref_ty SquareStruct "t" in
-    return: ((![uint64T] (struct.field_ref SquareStruct "Side" "t")) * (![uint64T] (struct.field_ref SquareStruct "Side" "t")));;;
+    return: ((![uint64T] (struct.field_ref SquareStruct "Side" "t")) *
+               (![uint64T] (struct.field_ref SquareStruct "Side" "t")));;;
     do:  #()).
 
 Definition SquareStruct__Volume : val :=
   rec: "SquareStruct__Volume" "t" <> :=
     exception_do (let: "t" := ref_ty SquareStruct "t" in
-    return: (((![uint64T] (struct.field_ref SquareStruct "Side" "t")) * (![uint64T] (struct.field_ref SquareStruct "Side" "t"))) * (![uint64T] (struct.field_ref SquareStruct "Side" "t")));;;
+    return: (((![uint64T] (struct.field_ref SquareStruct "Side" "t")) *
+               (![uint64T] (struct.field_ref SquareStruct "Side" "t"))) *
+               (![uint64T] (struct.field_ref SquareStruct "Side" "t")));;;
     do:  #()).
 
+Definition SquareStruct__mset : list (string * val) := [
+  ("Square", SquareStruct__Square%V);
+  ("Volume", SquareStruct__Volume%V)
+].
+
+Definition SquareStruct__mset_ptr : list (string * val) := [
+  ("Square", (λ: "$recvAddr", SquareStruct__Square (![SquareStruct] "$recvAddr"))%V);
+  ("Volume", (λ: "$recvAddr", SquareStruct__Volume (![SquareStruct] "$recvAddr"))%V)
+].
+
+Definition CubeStruct : go_type := structT [
+  "Side" :: uint64T
+].
+
+Definition CubeStruct__Square : val :=
+  rec: "CubeStruct__Square" "t" <> :=
+    exception_do (let: "t" := ref_ty CubeStruct "t" in
+    return: ((![uint64T] (struct.field_ref CubeStruct "Side" "t")) *
+               (![uint64T] (struct.field_ref CubeStruct "Side" "t")));;;
+    do:  #()).
+
+Definition CubeStruct__Volume : val :=
+  rec: "CubeStruct__Volume" "t" <> :=
+    exception_do (let: "t" := ref_ty CubeStruct "t" in
+    return: (((CubeStruct__Square (![CubeStruct] "t")) #()) *
+               (![uint64T] (struct.field_ref CubeStruct "Side" "t")));;;
+    do:  #()).
+
+Definition CubeStruct__asGeometry : val :=
+  rec: "CubeStruct__asGeometry" "t" <> :=
+    exception_do (let: "t" := ref_ty CubeStruct "t" in
+    return: (interface.make
+               "github.com/goose-lang/goose/testdata/examples/semantics.CubeStruct"
+               [("Square", CubeStruct__Square);
+                ("Volume", CubeStruct__Volume);
+                ("asGeometry", "CubeStruct__asGeometry")]
+               (![CubeStruct] "t"));;;
+    do:  #()).
+
+Definition CubeStruct__grow : val :=
+  rec: "CubeStruct__grow" "t" <> :=
+    exception_do (let: "t" := ref_ty ptrT "t" in
+    do:  (struct.field_ref CubeStruct "Side" (![ptrT] "t")) <-[uint64T]
+      ((![uint64T] (struct.field_ref CubeStruct "Side" (![ptrT] "t"))) + #1);;;
+    return: (interface.make "github.com/goose-lang/goose/testdata/examples/semantics.CubeStruct'ptr" [("Square", (λ: "$recvAddr",
+         CubeStruct__Square (![CubeStruct] "$recvAddr")
+         )); ("Volume", (λ: "$recvAddr",
+         CubeStruct__Volume (![CubeStruct] "$recvAddr")
+         )); ("asGeometry", (λ: "$recvAddr",
+         CubeStruct__asGeometry (![CubeStruct] "$recvAddr")
+         )); ("grow", "CubeStruct__grow")] (![ptrT] "t"));;;
+    do:  #()).
+
+Definition CubeStruct__mset : list (string * val) := [
+  ("Square", CubeStruct__Square%V);
+  ("Volume", CubeStruct__Volume%V);
+  ("asGeometry", CubeStruct__asGeometry%V)
+].
+
+Definition CubeStruct__mset_ptr : list (string * val) := [
+  ("Square", (λ: "$recvAddr", CubeStruct__Square (![CubeStruct] "$recvAddr"))%V);
+  ("Volume", (λ: "$recvAddr", CubeStruct__Volume (![CubeStruct] "$recvAddr"))%V);
+  ("asGeometry", (λ: "$recvAddr", CubeStruct__asGeometry (![CubeStruct] "$recvAddr"))%V);
+  ("grow", CubeStruct__grow%V)
+].
+
 Definition testBasicInterface : val :=
   rec: "testBasicInterface" <> :=
     exception_do (let: "s" := ref_ty SquareStruct (zero_val SquareStruct) in
@@ -694,7 +916,12 @@ Definition testBasicInterface : val :=
       "Side" ::= #2
     }] in
     do:  "s" <-[SquareStruct] "$a0";;;
-    return: ((measureArea (![SquareStruct] "s")) = #4);;;
+    return: ((measureArea
+               (interface.make
+                 "github.com/goose-lang/goose/testdata/examples/semantics.SquareStruct"
+                 SquareStruct__mset
+                 (![SquareStruct] "s"))) =
+               #4);;;
     do:  #()).
 
 Definition testAssignInterface : val :=
@@ -705,7 +932,12 @@ Definition testAssignInterface : val :=
     }] in
     do:  "s" <-[SquareStruct] "$a0";;;
     let: "area" := ref_ty uint64T (zero_val uint64T) in
-    let: "$a0" := measureArea (![SquareStruct] "s") in
+    let: "$a0" :=
+      measureArea
+        (interface.make
+          "github.com/goose-lang/goose/testdata/examples/semantics.SquareStruct"
+          SquareStruct__mset
+          (![SquareStruct] "s")) in
     do:  "area" <-[uint64T] "$a0";;;
     return: ((![uint64T] "area") = #9);;;
     do:  #()).
@@ -718,10 +950,20 @@ Definition testMultipleInterface : val :=
     }] in
     do:  "s" <-[SquareStruct] "$a0";;;
     let: "square1" := ref_ty uint64T (zero_val uint64T) in
-    let: "$a0" := measureArea (![SquareStruct] "s") in
+    let: "$a0" :=
+      measureArea
+        (interface.make
+          "github.com/goose-lang/goose/testdata/examples/semantics.SquareStruct"
+          SquareStruct__mset
+          (![SquareStruct] "s")) in
     do:  "square1" <-[uint64T] "$a0";;;
     let: "square2" := ref_ty uint64T (zero_val uint64T) in
-    let: "$a0" := measureArea (![SquareStruct] "s") in
+    let: "$a0" :=
+      measureArea
+        (interface.make
+          "github.com/goose-lang/goose/testdata/examples/semantics.SquareStruct"
+          SquareStruct__mset
+          (![SquareStruct] "s")) in
     do:  "square2" <-[uint64T] "$a0";;;
     return: ((![uint64T] "square1") = (![uint64T] "square2"));;;
     do:  #()).
@@ -734,12 +976,33 @@ Definition testBinaryExprInterface : val :=
     }] in
     do:  "s" <-[SquareStruct] "$a0";;;
     let: "square1" := ref_ty uint64T (zero_val uint64T) in
-    let: "$a0" := measureArea (![SquareStruct] "s") in
+    let: "$a0" :=
+      measureArea
+        (interface.make
+          "github.com/goose-lang/goose/testdata/examples/semantics.SquareStruct"
+          SquareStruct__mset
+          (![SquareStruct] "s")) in
     do:  "square1" <-[uint64T] "$a0";;;
     let: "square2" := ref_ty uint64T (zero_val uint64T) in
-    let: "$a0" := measureVolume (![SquareStruct] "s") in
+    let: "$a0" :=
+      measureVolume
+        (interface.make
+          "github.com/goose-lang/goose/testdata/examples/semantics.SquareStruct"
+          SquareStruct__mset
+          (![SquareStruct] "s")) in
     do:  "square2" <-[uint64T] "$a0";;;
-    return: (((![uint64T] "square1") = (measureArea (![SquareStruct] "s"))) && ((![uint64T] "square2") = (measureVolume (![SquareStruct] "s"))));;;
+    return: (((![uint64T] "square1") =
+               (measureArea
+                 (interface.make
+                   "github.com/goose-lang/goose/testdata/examples/semantics.SquareStruct"
+                   SquareStruct__mset
+                   (![SquareStruct] "s")))) &&
+               ((![uint64T] "square2") =
+                 (measureVolume
+                   (interface.make
+                     "github.com/goose-lang/goose/testdata/examples/semantics.SquareStruct"
+                     SquareStruct__mset
+                     (![SquareStruct] "s")))));;;
     do:  #()).
 
 Definition testIfStmtInterface : val :=
@@ -749,7 +1012,12 @@ Definition testIfStmtInterface : val :=
       "Side" ::= #3
     }] in
     do:  "s" <-[SquareStruct] "$a0";;;
-    (if: (measureArea (![SquareStruct] "s")) = #9
+    (if: (measureArea
+      (interface.make
+        "github.com/goose-lang/goose/testdata/examples/semantics.SquareStruct"
+        SquareStruct__mset
+        (![SquareStruct] "s"))) =
+      #9
     then
       return: (#true);;;
       do:  #()
@@ -757,6 +1025,45 @@ Definition testIfStmtInterface : val :=
     return: (#false);;;
     do:  #()).
 
+Definition testPointerInterface : val :=
+  rec: "testPointerInterface" <> :=
+    exception_do (let: "s" := ref_ty ptrT (zero_val ptrT) in
+    let: "$a0" := ref_ty SquareStruct (struct.make SquareStruct [{
+      "Side" ::= #3
+    }]) in
+    do:  "s" <-[ptrT] "$a0";;;
+    return: (((measureArea
+               (interface.make
+                 "github.com/goose-lang/goose/testdata/examples/semantics.SquareStruct'ptr"
+                 SquareStruct__mset_ptr
+                 (![ptrT] "s"))) =
+               #9) &&
+               ((measureVolume
+                 (interface.make
+                   "github.com/goose-lang/goose/testdata/examples/semantics.SquareStruct'ptr"
+                   SquareStruct__mset_ptr
+                   (![ptrT] "s"))) =
+                 #27));;;
+    do:  #()).
+
+Definition testInterfaceInOwnMethod : val :=
+  rec: "testInterfaceInOwnMethod" <> :=
+    exception_do (let: "s" := ref_ty CubeStruct (zero_val CubeStruct) in
+    let: "$a0" := struct.make CubeStruct [{
+      "Side" ::= #2
+    }] in
+    do:  "s" <-[CubeStruct] "$a0";;;
+    let: "g" := ref_ty geometryInterface (zero_val geometryInterface) in
+    let: "$a0" := (CubeStruct__asGeometry (![CubeStruct] "s")) #() in
+    do:  "g" <-[geometryInterface] "$a0";;;
+    let: "h" := ref_ty geometryInterface (zero_val geometryInterface) in
+    let: "$a0" := (CubeStruct__grow "s") #() in
+    do:  "h" <-[geometryInterface] "$a0";;;
+    return: (((((interface.get "Volume" (![geometryInterface] "g")) #()) = #8) &&
+               (((interface.get "Square" (![geometryInterface] "h")) #()) = #9)) &&
+               ((measureArea ((CubeStruct__asGeometry (![CubeStruct] "s")) #())) = #9));;;
+    do:  #()).
+
 (* interfaces_failing.go *)
 
 (* lock.go *)
@@ -773,6 +1080,59 @@ Definition testsUseLocks : val :=
     return: (#true);;;
     do:  #()).
 
+Definition testsUseRWLocks : val :=
+  rec: "testsUseRWLocks" <> :=
+    exception_do (let: "m" := ref_ty ptrT (zero_val ptrT) in
+    let: "$a0" := ref_ty sync.RWMutex (zero_val sync.RWMutex) in
+    do:  "m" <-[ptrT] "$a0";;;
+    do:  (sync.RWMutex__RLock (![ptrT] "m")) #();;;
+    do:  (sync.RWMutex__RLock (![ptrT] "m")) #();;;
+    do:  (sync.RWMutex__RUnlock (![ptrT] "m")) #();;;
+    do:  (sync.RWMutex__RUnlock (![ptrT] "m")) #();;;
+    do:  (sync.RWMutex__Lock (![ptrT] "m")) #();;;
+    do:  (sync.RWMutex__Unlock (![ptrT] "m")) #();;;
+    return: (#true);;;
+    do:  #()).
+
+Definition testOnce : val :=
+  rec: "testOnce" <> :=
+    exception_do (let: "once" := ref_ty sync.Once (zero_val sync.Once) in
+    let: "n" := ref_ty uint64T (zero_val uint64T) in
+    (let: "i" := ref_ty uint64T (zero_val uint64T) in
+    let: "$a0" := #0 in
+    do:  "i" <-[uint64T] "$a0";;;
+    (for: (λ: <>, (![uint64T] "i") < #3); (λ: <>, do:  "i" <-[uint64T] ((![uint64T] "i") + #1);;;
+    #()) := λ: <>,
+      do:  (sync.Once__Do "once") (λ: <>,
+        exception_do (let: "$a0" := (![uint64T] "n") + #1 in
+        do:  "n" <-[uint64T] "$a0";;;
+        do:  #())
+        );;;
+      do:  #()));;;
+    return: ((![uint64T] "n") = #1);;;
+    do:  #()).
+
+Definition testAtomics : val :=
+  rec: "testAtomics" <> :=
+    exception_do (let: "n" := ref_ty atomic.Uint64 (zero_val atomic.Uint64) in
+    do:  (atomic.Uint64__Store "n") #2;;;
+    do:  (atomic.Uint64__Add "n") #3;;;
+    let: "old" := ref_ty uint64T (zero_val uint64T) in
+    let: "$a0" := (atomic.Uint64__Swap "n") #7 in
+    do:  "old" <-[uint64T] "$a0";;;
+    let: "swapped" := ref_ty boolT (zero_val boolT) in
+    let: "$a0" := (atomic.Uint64__CompareAndSwap "n") #7 #8 in
+    do:  "swapped" <-[boolT] "$a0";;;
+    let: "b" := ref_ty atomic.Bool (zero_val atomic.Bool) in
+    do:  (atomic.Bool__Store "b") #true;;;
+    let: "x" := ref_ty uint64T (zero_val uint64T) in
+    do:  atomic.AddUint64 "x" #4;;;
+    return: ((((((![uint64T] "old") = #5) && (![boolT] "swapped")) &&
+               (((atomic.Uint64__Load "n") #()) = #8)) &&
+               ((atomic.Bool__Load "b") #())) &&
+               ((atomic.LoadUint64 "x") = #4));;;
+    do:  #()).
+
 (* loops.go *)
 
 (* helpers *)
@@ -792,7 +1152,8 @@ Definition standardForLoop : val :=
         let: "$a0" := ![uint64T] (![ptrT] "sumPtr") in
         do:  "sum" <-[uint64T] "$a0";;;
         let: "x" := ref_ty uint64T (zero_val uint64T) in
-        let: "$a0" := ![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "s") (![uint64T] "i")) in
+        let: "$a0" :=
+          ![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "s") (![uint64T] "i")) in
         do:  "x" <-[uint64T] "$a0";;;
         let: "$a0" := (![uint64T] "sum") + (![uint64T] "x") in
         do:  (![ptrT] "sumPtr") <-[uint64T] "$a0";;;
@@ -832,14 +1193,26 @@ Definition LoopStruct__forLoopWait : val :=
       do:  #());;;
     do:  #()).
 
+Definition LoopStruct__mset : list (string * val) := [
+  ("forLoopWait", LoopStruct__forLoopWait%V)
+].
+
+Definition LoopStruct__mset_ptr : list (string * val) := [
+  ("forLoopWait", (λ: "$recvAddr", LoopStruct__forLoopWait (![LoopStruct] "$recvAddr"))%V)
+].
+
 (* tests *)
 Definition testStandardForLoop : val :=
   rec: "testStandardForLoop" <> :=
     exception_do (let: "arr" := ref_ty (sliceT uint64T) (slice.make2 uint64T #4) in
-    do:  (slice.elem_ref uint64T (![sliceT uint64T] "arr") #0) <-[uint64T] ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #0)) + #1);;;
-    do:  (slice.elem_ref uint64T (![sliceT uint64T] "arr") #1) <-[uint64T] ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #1)) + #3);;;
-    do:  (slice.elem_ref uint64T (![sliceT uint64T] "arr") #2) <-[uint64T] ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #2)) + #5);;;
-    do:  (slice.elem_ref uint64T (![sliceT uint64T] "arr") #3) <-[uint64T] ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #3)) + #7);;;
+    do:  (slice.elem_ref uint64T (![sliceT uint64T] "arr") #0) <-[uint64T]
+      ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #0)) + #1);;;
+    do:  (slice.elem_ref uint64T (![sliceT uint64T] "arr") #1) <-[uint64T]
+      ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #1)) + #3);;;
+    do:  (slice.elem_ref uint64T (![sliceT uint64T] "arr") #2) <-[uint64T]
+      ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #2)) + #5);;;
+    do:  (slice.elem_ref uint64T (![sliceT uint64T] "arr") #3) <-[uint64T]
+      ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #3)) + #7);;;
     return: ((standardForLoop (![sliceT uint64T] "arr")) = #16);;;
     do:  #()).
 
@@ -977,7 +1350,8 @@ Definition testNestedGoStyleLoops : val :=
       (let: "j" := ref_ty uint64T (zero_val uint64T) in
       let: "$a0" := #0 in
       do:  "j" <-[uint64T] "$a0";;;
-      (for: (λ: <>, (![uint64T] "j") < (![uint64T] "i")); (λ: <>, do:  "j" <-[uint64T] ((![uint64T] "j") + #1);;;
+      (for: (λ: <>, (![uint64T] "j") < (![uint64T] "i")); (λ: <>, do:  "j" <-[uint64T]
+        ((![uint64T] "j") + #1);;;
       #()) := λ: <>,
         (if: #true
         then
@@ -1003,7 +1377,8 @@ Definition testNestedGoStyleLoopsNoComparison : val :=
       (let: "j" := ref_ty uint64T (zero_val uint64T) in
       let: "$a0" := #0 in
       do:  "j" <-[uint64T] "$a0";;;
-      (for: (λ: <>, (![uint64T] "j") < (![uint64T] "i")); (λ: <>, do:  "j" <-[uint64T] ((![uint64T] "j") + #1);;;
+      (for: (λ: <>, (![uint64T] "j") < (![uint64T] "i")); (λ: <>, do:  "j" <-[uint64T]
+        ((![uint64T] "j") + #1);;;
       #()) := λ: <>,
         (if: #true
         then
@@ -1018,6 +1393,18 @@ Definition testNestedGoStyleLoopsNoComparison : val :=
     return: (![boolT] "ok");;;
     do:  #()).
 
+Definition testRangeConstant : val :=
+  rec: "testRangeConstant" <> :=
+    exception_do (let: "sum" := ref_ty uint64T (zero_val uint64T) in
+    do:  let: "$range" := #5 in
+    let: "$i" := ref_ty intT #0 in
+    (for: (λ: <>, (![intT] "$i") < "$range"); (λ: <>, "$i" <-[intT] ((![intT] "$i") + #1)) := λ: <>,
+      let: "i" := ref_ty intT (![intT] "$i") in
+      do:  "sum" <-[uint64T] ((![uint64T] "sum") + (![intT] "i"));;;
+      do:  #());;;
+    return: ((![uint64T] "sum") = #10);;;
+    do:  #()).
+
 (* maps.go *)
 
 Definition IterateMapKeys : val :=
@@ -1111,7 +1498,8 @@ Definition testAssignThree : val :=
     do:  "z" <-[uint32T] "$a2";;;
     do:  "y" <-[boolT] "$a1";;;
     do:  "x" <-[uint64T] "$a0";;;
-    return: ((((![uint64T] "x") = #2) && ((![boolT] "y") = #true)) && ((![uint32T] "z") = #(U32 1)));;;
+    return: ((((![uint64T] "x") = #2) && ((![boolT] "y") = #true)) &&
+               ((![uint32T] "z") = #(U32 1)));;;
     do:  #()).
 
 Definition testMultipleAssignToMap : val :=
@@ -1165,7 +1553,8 @@ Definition testReturnThree : val :=
     do:  "z" <-[uint32T] "$a2";;;
     do:  "y" <-[boolT] "$a1";;;
     do:  "x" <-[uint64T] "$a0";;;
-    return: ((((![uint64T] "x") = #2) && ((![boolT] "y") = #true)) && ((![uint32T] "z") = #(U32 1)));;;
+    return: ((((![uint64T] "x") = #2) && ((![boolT] "y") = #true)) &&
+               ((![uint32T] "z") = #(U32 1)));;;
     do:  #()).
 
 Definition returnFour : val :=
@@ -1184,7 +1573,9 @@ Definition testReturnFour : val :=
     do:  "z" <-[uint32T] "$a2";;;
     do:  "y" <-[boolT] "$a1";;;
     do:  "x" <-[uint64T] "$a0";;;
-    return: (((((![uint64T] "x") = #2) && ((![boolT] "y") = #true)) && ((![uint32T] "z") = #(U32 1))) && ((![uint64T] "w") = #7));;;
+    return: (((((![uint64T] "x") = #2) && ((![boolT] "y") = #true)) &&
+               ((![uint32T] "z") = #(U32 1))) &&
+               ((![uint64T] "w") = #7));;;
     do:  #()).
 
 (* nil.go *)
@@ -1423,6 +1814,54 @@ Definition testPlusTimes : val :=
     exception_do (return: (((#2 + #5) * #2) = #14);;;
     do:  #()).
 
+(* panics.go *)
+
+Definition recoverConstant : val :=
+  rec: "recoverConstant" <> :=
+    exception_do (let: "r" := ref_ty interfaceT (zero_val interfaceT) in
+    do:  with_defer: (let: "$f" := (λ: <>,
+      exception_do (let: "$a0" := recover #() in
+      do:  "r" <-[interfaceT] "$a0";;;
+      do:  #())
+      ) in
+    do:  "$defer" <-[funcT] (let: "$oldf" := ![funcT] "$defer" in
+      (λ: <>,
+        "$f" #();;
+        "$oldf" #()
+      ));;;
+    do:  PanicValue (interface.make "string" [] #(str "message"));;;
+    do:  #());;;
+    return: (![interfaceT] "r")).
+
+Definition recoverDynamic : val :=
+  rec: "recoverDynamic" "s" :=
+    exception_do (let: "s" := ref_ty stringT "s" in
+    let: "r" := ref_ty interfaceT (zero_val interfaceT) in
+    do:  with_defer: (let: "$f" := (λ: <>,
+      exception_do (let: "$a0" := recover #() in
+      do:  "r" <-[interfaceT] "$a0";;;
+      do:  #())
+      ) in
+    do:  "$defer" <-[funcT] (let: "$oldf" := ![funcT] "$defer" in
+      (λ: <>,
+        "$f" #();;
+        "$oldf" #()
+      ));;;
+    do:  PanicValue (interface.make "string" [] ((![stringT] "s") + #(str "age")));;;
+    do:  #());;;
+    return: (![interfaceT] "r")).
+
+(* constant and computed panic values are recovered the same way *)
+Definition testRecoverPanicValue : val :=
+  rec: "testRecoverPanicValue" <> :=
+    exception_do (return: ((recoverConstant #()) = (recoverDynamic #(str "mess")));;;
+    do:  #()).
+
+Definition testRecoverNonNil : val :=
+  rec: "testRecoverNonNil" <> :=
+    exception_do (return: ((recoverConstant #()) ≠ interface.nil);;;
+    do:  #()).
+
 (* precedence.go *)
 
 Definition testOrCompareSimple : val :=
@@ -1501,14 +1940,16 @@ Definition BoolTest : go_type := structT [
 Definition CheckTrue : val :=
   rec: "CheckTrue" "b" :=
     exception_do (let: "b" := ref_ty ptrT "b" in
-    do:  (struct.field_ref BoolTest "tc" (![ptrT] "b")) <-[uint64T] ((![uint64T] (struct.field_ref BoolTest "tc" (![ptrT] "b"))) + #1);;;
+    do:  (struct.field_ref BoolTest "tc" (![ptrT] "b")) <-[uint64T]
+      ((![uint64T] (struct.field_ref BoolTest "tc" (![ptrT] "b"))) + #1);;;
     return: (![boolT] (struct.field_ref BoolTest "t" (![ptrT] "b")));;;
     do:  #()).
 
 Definition CheckFalse : val :=
   rec: "CheckFalse" "b" :=
     exception_do (let: "b" := ref_ty ptrT "b" in
-    do:  (struct.field_ref BoolTest "fc" (![ptrT] "b")) <-[uint64T] ((![uint64T] (struct.field_ref BoolTest "fc" (![ptrT] "b"))) + #1);;;
+    do:  (struct.field_ref BoolTest "fc" (![ptrT] "b")) <-[uint64T]
+      ((![uint64T] (struct.field_ref BoolTest "fc" (![ptrT] "b"))) + #1);;;
     return: (![boolT] (struct.field_ref BoolTest "f" (![ptrT] "b")));;;
     do:  #()).
 
@@ -1528,7 +1969,8 @@ Definition testShortcircuitAndTF : val :=
       return: (#false);;;
       do:  #()
     else do:  #());;;
-    return: (((![uint64T] (struct.field_ref BoolTest "tc" (![ptrT] "b"))) = #1) && ((![uint64T] (struct.field_ref BoolTest "fc" (![ptrT] "b"))) = #1));;;
+    return: (((![uint64T] (struct.field_ref BoolTest "tc" (![ptrT] "b"))) = #1) &&
+               ((![uint64T] (struct.field_ref BoolTest "fc" (![ptrT] "b"))) = #1));;;
     do:  #()).
 
 Definition testShortcircuitAndFT : val :=
@@ -1546,7 +1988,8 @@ Definition testShortcircuitAndFT : val :=
       return: (#false);;;
       do:  #()
     else do:  #());;;
-    return: (((![uint64T] (struct.field_ref BoolTest "tc" (![ptrT] "b"))) = #0) && ((![uint64T] (struct.field_ref BoolTest "fc" (![ptrT] "b"))) = #1));;;
+    return: (((![uint64T] (struct.field_ref BoolTest "tc" (![ptrT] "b"))) = #0) &&
+               ((![uint64T] (struct.field_ref BoolTest "fc" (![ptrT] "b"))) = #1));;;
     do:  #()).
 
 Definition testShortcircuitOrTF : val :=
@@ -1561,7 +2004,8 @@ Definition testShortcircuitOrTF : val :=
     do:  "b" <-[ptrT] "$a0";;;
     (if: (CheckTrue (![ptrT] "b")) || (CheckFalse (![ptrT] "b"))
     then
-      return: (((![uint64T] (struct.field_ref BoolTest "tc" (![ptrT] "b"))) = #1) && ((![uint64T] (struct.field_ref BoolTest "fc" (![ptrT] "b"))) = #0));;;
+      return: (((![uint64T] (struct.field_ref BoolTest "tc" (![ptrT] "b"))) = #1) &&
+                 ((![uint64T] (struct.field_ref BoolTest "fc" (![ptrT] "b"))) = #0));;;
       do:  #()
     else do:  #());;;
     return: (#false);;;
@@ -1579,7 +2023,8 @@ Definition testShortcircuitOrFT : val :=
     do:  "b" <-[ptrT] "$a0";;;
     (if: (CheckFalse (![ptrT] "b")) || (CheckTrue (![ptrT] "b"))
     then
-      return: (((![uint64T] (struct.field_ref BoolTest "tc" (![ptrT] "b"))) = #1) && ((![uint64T] (struct.field_ref BoolTest "fc" (![ptrT] "b"))) = #1));;;
+      return: (((![uint64T] (struct.field_ref BoolTest "tc" (![ptrT] "b"))) = #1) &&
+                 ((![uint64T] (struct.field_ref BoolTest "fc" (![ptrT] "b"))) = #1));;;
       do:  #()
     else do:  #());;;
     return: (#false);;;
@@ -1597,16 +2042,28 @@ Definition ArrayEditor__Advance : val :=
     exception_do (let: "ae" := ref_ty ptrT "ae" in
     let: "next" := ref_ty uint64T "next" in
     let: "arr" := ref_ty (sliceT uint64T) "arr" in
-    do:  (slice.elem_ref uint64T (![sliceT uint64T] "arr") #0) <-[uint64T] ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #0)) + #1);;;
+    do:  (slice.elem_ref uint64T (![sliceT uint64T] "arr") #0) <-[uint64T]
+      ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #0)) + #1);;;
     let: "$a0" := ![uint64T] (struct.field_ref ArrayEditor "next_val" (![ptrT] "ae")) in
-    do:  (slice.elem_ref uint64T (![sliceT uint64T] (struct.field_ref ArrayEditor "s" (![ptrT] "ae"))) #0) <-[uint64T] "$a0";;;
+    do:  (slice.elem_ref
+      uint64T
+      (![sliceT uint64T] (struct.field_ref ArrayEditor "s" (![ptrT] "ae")))
+      #0) <-[uint64T]
+      "$a0";;;
     let: "$a0" := ![uint64T] "next" in
     do:  (struct.field_ref ArrayEditor "next_val" (![ptrT] "ae")) <-[uint64T] "$a0";;;
-    let: "$a0" := let: "$s" := ![sliceT uint64T] (struct.field_ref ArrayEditor "s" (![ptrT] "ae")) in
+    let: "$a0" := let: "$s" :=
+      ![sliceT uint64T] (struct.field_ref ArrayEditor "s" (![ptrT] "ae")) in
     slice.slice uint64T "$s" #1 (slice.len "$s") in
     do:  (struct.field_ref ArrayEditor "s" (![ptrT] "ae")) <-[sliceT uint64T] "$a0";;;
     do:  #()).
 
+Definition ArrayEditor__mset : list (string * val) := [].
+
+Definition ArrayEditor__mset_ptr : list (string * val) := [
+  ("Advance", ArrayEditor__Advance%V)
+].
+
 (* tests *)
 Definition testSliceOps : val :=
   rec: "testSliceOps" <> :=
@@ -1638,13 +2095,18 @@ Definition testSliceOps : val :=
     let: "ok" := ref_ty boolT #true in
     let: "$a0" := (![boolT] "ok") && ((![uint64T] "v1") = #10) in
     do:  "ok" <-[boolT] "$a0";;;
-    let: "$a0" := (![boolT] "ok") && ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "v2") #0)) = #10) in
+    let: "$a0" :=
+      (![boolT] "ok") &&
+        ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "v2") #0)) = #10) in
     do:  "ok" <-[boolT] "$a0";;;
     let: "$a0" := (![boolT] "ok") && ((slice.len (![sliceT uint64T] "v2")) = #1) in
     do:  "ok" <-[boolT] "$a0";;;
-    let: "$a0" := (![boolT] "ok") && ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "v3") #1)) = #5) in
+    let: "$a0" :=
+      (![boolT] "ok") && ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "v3") #1)) = #5) in
     do:  "ok" <-[boolT] "$a0";;;
-    let: "$a0" := (![boolT] "ok") && ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "v3") #2)) = #10) in
+    let: "$a0" :=
+      (![boolT] "ok") &&
+        ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "v3") #2)) = #10) in
     do:  "ok" <-[boolT] "$a0";;;
     let: "$a0" := (![boolT] "ok") && ((slice.len (![sliceT uint64T] "v3")) = #3) in
     do:  "ok" <-[boolT] "$a0";;;
@@ -1675,14 +2137,16 @@ Definition testSliceCapacityOps : val :=
     do:  "ok" <-[boolT] "$a0";;;
     let: "$a0" := (![boolT] "ok") && ((slice.cap (![sliceT uint64T] "sub1")) = #10) in
     do:  "ok" <-[boolT] "$a0";;;
-    let: "$a0" := (![boolT] "ok") && ((![uint64T] (slice.elem_ref uint64T (let: "$s" := ![sliceT uint64T] "x" in
+    let: "$a0" := (![boolT] "ok") && ((![uint64T] (slice.elem_ref uint64T (let: "$s" :=
+      ![sliceT uint64T] "x" in
     slice.slice uint64T "$s" #0 #10) #0)) = #1) in
     do:  "ok" <-[boolT] "$a0";;;
     let: "$a0" := (![boolT] "ok") && ((slice.len (![sliceT uint64T] "sub2")) = #2) in
     do:  "ok" <-[boolT] "$a0";;;
     let: "$a0" := (![boolT] "ok") && ((slice.cap (![sliceT uint64T] "sub2")) = #8) in
     do:  "ok" <-[boolT] "$a0";;;
-    let: "$a0" := (![boolT] "ok") && ((![uint64T] (slice.elem_ref uint64T (let: "$s" := ![sliceT uint64T] "x" in
+    let: "$a0" := (![boolT] "ok") && ((![uint64T] (slice.elem_ref uint64T (let: "$s" :=
+      ![sliceT uint64T] "x" in
     slice.slice uint64T "$s" #0 #10) #2)) = #2) in
     do:  "ok" <-[boolT] "$a0";;;
     return: (![boolT] "ok");;;
@@ -1712,12 +2176,17 @@ Definition testOverwriteArray : val :=
     do:  (ArrayEditor__Advance (![ptrT] "ae1")) (![sliceT uint64T] "arr") #3;;;
     do:  (ArrayEditor__Advance (![ptrT] "ae1")) (![sliceT uint64T] "arr") #4;;;
     do:  (ArrayEditor__Advance (![ptrT] "ae1")) (![sliceT uint64T] "arr") #5;;;
-    (if: ((((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #0)) + (![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #1))) + (![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #2))) + (![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #3))) ≥ #100
+    (if: ((((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #0)) +
+      (![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #1))) +
+      (![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #2))) +
+      (![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #3))) ≥
+      #100
     then
       return: (#false);;;
       do:  #()
     else do:  #());;;
-    return: (((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #3)) = #4) && ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #0)) = #4));;;
+    return: (((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #3)) = #4) &&
+               ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "arr") #0)) = #4));;;
     do:  #()).
 
 Definition testSliceLiteral : val :=
@@ -1726,12 +2195,16 @@ Definition testSliceLiteral : val :=
     let: "$a0" := slice.literal byteT [ #(U8 1); #(U8 2) ] in
     do:  "bytes" <-[sliceT byteT] "$a0";;;
     let: "ok" := ref_ty boolT #true in
-    let: "$a0" := (![boolT] "ok") && ((![byteT] (slice.elem_ref byteT (![sliceT byteT] "bytes") #0)) = #(U8 1)) in
+    let: "$a0" :=
+      (![boolT] "ok") &&
+        ((![byteT] (slice.elem_ref byteT (![sliceT byteT] "bytes") #0)) = #(U8 1)) in
     do:  "ok" <-[boolT] "$a0";;;
     let: "ints" := ref_ty (sliceT uint64T) (zero_val (sliceT uint64T)) in
     let: "$a0" := slice.literal uint64T [ #1; #2; #3 ] in
     do:  "ints" <-[sliceT uint64T] "$a0";;;
-    let: "$a0" := (![boolT] "ok") && ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "ints") #1)) = #2) in
+    let: "$a0" :=
+      (![boolT] "ok") &&
+        ((![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "ints") #1)) = #2) in
     do:  "ok" <-[boolT] "$a0";;;
     return: (![boolT] "ok");;;
     do:  #()).
@@ -1796,12 +2269,24 @@ Definition Bar__mutate : val :=
     do:  (struct.field_ref Bar "b" (![ptrT] "bar")) <-[uint64T] "$a0";;;
     do:  #()).
 
+Definition Bar__mset : list (string * val) := [].
+
+Definition Bar__mset_ptr : list (string * val) := [
+  ("mutate", Bar__mutate%V)
+].
+
 Definition Foo__mutateBar : val :=
   rec: "Foo__mutateBar" "foo" <> :=
     exception_do (let: "foo" := ref_ty ptrT "foo" in
     do:  (Bar__mutate (![Bar] (struct.field_ref Foo "bar" (![ptrT] "foo")))) #();;;
     do:  #()).
 
+Definition Foo__mset : list (string * val) := [].
+
+Definition Foo__mset_ptr : list (string * val) := [
+  ("mutateBar", Foo__mutateBar%V)
+].
+
 Definition failing_testFooBarMutation : val :=
   rec: "failing_testFooBarMutation" <> :=
     exception_do (let: "x" := ref_ty Foo (zero_val Foo) in
@@ -1874,6 +2359,18 @@ Definition S__negateC : val :=
     do:  (struct.field_ref S "c" (![ptrT] "s")) <-[boolT] "$a0";;;
     do:  #()).
 
+Definition S__mset : list (string * val) := [
+  ("readBVal", S__readBVal%V)
+].
+
+Definition S__mset_ptr : list (string * val) := [
+  ("readA", S__readA%V);
+  ("readB", S__readB%V);
+  ("readBVal", (λ: "$recvAddr", S__readBVal (![S] "$recvAddr"))%V);
+  ("updateBValX", S__updateBValX%V);
+  ("negateC", S__negateC%V)
+].
+
 Definition failing_testStructUpdates : val :=
   rec: "failing_testStructUpdates" <> :=
     exception_do (let: "ok" := ref_ty boolT #true in
@@ -1886,7 +2383,8 @@ Definition failing_testStructUpdates : val :=
     let: "$a0" := (![boolT] "ok") && ((![uint64T] (struct.field_ref TwoInts "x" "b1")) = #1) in
     do:  "ok" <-[boolT] "$a0";;;
     do:  (S__negateC (![ptrT] "ns")) #();;;
-    let: "$a0" := (![boolT] "ok") && ((![boolT] (struct.field_ref S "c" (![ptrT] "ns"))) = #false) in
+    let: "$a0" :=
+      (![boolT] "ok") && ((![boolT] (struct.field_ref S "c" (![ptrT] "ns"))) = #false) in
     do:  "ok" <-[boolT] "$a0";;;
     let: "$a0" := #3 in
     do:  (struct.field_ref TwoInts "x" "b1") <-[uint64T] "$a0";;;
@@ -1894,7 +2392,8 @@ Definition failing_testStructUpdates : val :=
     let: "$a0" := (![boolT] "ok") && ((![uint64T] (struct.field_ref TwoInts "x" "b2")) = #1) in
     do:  "ok" <-[boolT] "$a0";;;
     let: "b3" := ref_ty ptrT (struct.field_ref S "b" (![ptrT] "ns")) in
-    let: "$a0" := (![boolT] "ok") && ((![uint64T] (struct.field_ref TwoInts "x" (![ptrT] "b3"))) = #1) in
+    let: "$a0" :=
+      (![boolT] "ok") && ((![uint64T] (struct.field_ref TwoInts "x" (![ptrT] "b3"))) = #1) in
     do:  "ok" <-[boolT] "$a0";;;
     do:  (S__updateBValX (![ptrT] "ns")) #4;;;
     return: (![boolT] "ok");;;
@@ -1906,14 +2405,20 @@ Definition testNestedStructUpdates : val :=
     let: "ns" := ref_ty ptrT (NewS #()) in
     let: "$a0" := #5 in
     do:  (struct.field_ref TwoInts "x" (struct.field_ref S "b" (![ptrT] "ns"))) <-[uint64T] "$a0";;;
-    let: "$a0" := (![boolT] "ok") && ((![uint64T] (struct.field_ref TwoInts "x" (struct.field_ref S "b" (![ptrT] "ns")))) = #5) in
+    let: "$a0" :=
+      (![boolT] "ok") &&
+        ((![uint64T] (struct.field_ref TwoInts "x" (struct.field_ref S "b" (![ptrT] "ns")))) =
+          #5) in
     do:  "ok" <-[boolT] "$a0";;;
     let: "$a0" := NewS #() in
     do:  "ns" <-[ptrT] "$a0";;;
     let: "p" := ref_ty ptrT (struct.field_ref S "b" (![ptrT] "ns")) in
     let: "$a0" := #5 in
     do:  (struct.field_ref TwoInts "x" (![ptrT] "p")) <-[uint64T] "$a0";;;
-    let: "$a0" := (![boolT] "ok") && ((![uint64T] (struct.field_ref TwoInts "x" (struct.field_ref S "b" (![ptrT] "ns")))) = #5) in
+    let: "$a0" :=
+      (![boolT] "ok") &&
+        ((![uint64T] (struct.field_ref TwoInts "x" (struct.field_ref S "b" (![ptrT] "ns")))) =
+          #5) in
     do:  "ok" <-[boolT] "$a0";;;
     let: "$a0" := NewS #() in
     do:  "ns" <-[ptrT] "$a0";;;
@@ -1921,7 +2426,8 @@ Definition testNestedStructUpdates : val :=
     do:  "p" <-[ptrT] "$a0";;;
     let: "$a0" := #5 in
     do:  (struct.field_ref TwoInts "x" (struct.field_ref S "b" (![ptrT] "ns"))) <-[uint64T] "$a0";;;
-    let: "$a0" := (![boolT] "ok") && ((![uint64T] (struct.field_ref TwoInts "x" (![ptrT] "p"))) = #5) in
+    let: "$a0" :=
+      (![boolT] "ok") && ((![uint64T] (struct.field_ref TwoInts "x" (![ptrT] "p"))) = #5) in
     do:  "ok" <-[boolT] "$a0";;;
     let: "$a0" := NewS #() in
     do:  "ns" <-[ptrT] "$a0";;;
@@ -1929,7 +2435,8 @@ Definition testNestedStructUpdates : val :=
     do:  "p" <-[ptrT] "$a0";;;
     let: "$a0" := #5 in
     do:  (struct.field_ref TwoInts "x" (struct.field_ref S "b" (![ptrT] "ns"))) <-[uint64T] "$a0";;;
-    let: "$a0" := (![boolT] "ok") && ((![uint64T] (struct.field_ref TwoInts "x" (![ptrT] "p"))) = #5) in
+    let: "$a0" :=
+      (![boolT] "ok") && ((![uint64T] (struct.field_ref TwoInts "x" (![ptrT] "p"))) = #5) in
     do:  "ok" <-[boolT] "$a0";;;
     return: (![boolT] "ok");;;
     do:  #()).
@@ -1981,7 +2488,9 @@ Definition testIncompleteStruct : val :=
       "a" ::= #2
     }] in
     do:  "p2" <-[S] "$a0";;;
-    let: "$a0" := (![boolT] "ok") && ((![uint64T] (struct.field_ref TwoInts "x" (struct.field_ref S "b" "p2"))) = #0) in
+    let: "$a0" :=
+      (![boolT] "ok") &&
+        ((![uint64T] (struct.field_ref TwoInts "x" (struct.field_ref S "b" "p2"))) = #0) in
     do:  "ok" <-[boolT] "$a0";;;
     let: "$a0" := (![boolT] "ok") && ((![boolT] (struct.field_ref S "c" "p2")) = #false) in
     do:  "ok" <-[boolT] "$a0";;;
@@ -2046,8 +2555,9 @@ Definition testStructFieldFunc : val :=
     let: "$a0" := ref_ty StructWithFunc (zero_val StructWithFunc) in
     do:  "a" <-[ptrT] "$a0";;;
     let: "$a0" := (λ: "arg",
+      exception_do (let: "arg" := ref_ty uint64T "arg" in
       return: ((![uint64T] "arg") * #2);;;
-      do:  #()
+      do:  #())
       ) in
     do:  (struct.field_ref StructWithFunc "fn" (![ptrT] "a")) <-[funcT] "$a0";;;
     return: (((![funcT] (struct.field_ref StructWithFunc "fn" (![ptrT] "a"))) #10) = #20);;;
@@ -2193,7 +2703,11 @@ Definition Log__Read : val :=
     do:  (Log__lock (![Log] "l")) #();;;
     let: "ok" := ref_ty boolT (zero_val boolT) in
     let: "v" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
-    let: ("$a0", "$a1") := Fst (map.get (![mapT uint64T (sliceT byteT)] (struct.field_ref Log "cache" "l")) (![uint64T] "a")) in
+    let: ("$a0", "$a1") :=
+      Fst
+        (map.get
+          (![mapT uint64T (sliceT byteT)] (struct.field_ref Log "cache" "l"))
+          (![uint64T] "a")) in
     do:  "ok" <-[boolT] "$a1";;;
     do:  "v" <-[sliceT byteT] "$a0";;;
     (if: ![boolT] "ok"
@@ -2204,7 +2718,8 @@ Definition Log__Read : val :=
     else do:  #());;;
     do:  (Log__unlock (![Log] "l")) #();;;
     let: "dv" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
-    let: "$a0" := (__Read (![disk.Disk] (struct.field_ref Log "d" "l"))) (logLength + (![uint64T] "a")) in
+    let: "$a0" :=
+      (__Read (![disk.Disk] (struct.field_ref Log "d" "l"))) (logLength + (![uint64T] "a")) in
     do:  "dv" <-[sliceT byteT] "$a0";;;
     return: (![sliceT byteT] "dv");;;
     do:  #()).
@@ -2239,10 +2754,17 @@ Definition Log__Write : val :=
     let: "nextAddr" := ref_ty uint64T (zero_val uint64T) in
     let: "$a0" := #1 + (#2 * (![uint64T] "length")) in
     do:  "nextAddr" <-[uint64T] "$a0";;;
-    do:  (__Write (![disk.Disk] (struct.field_ref Log "d" "l"))) (![uint64T] "nextAddr") (![sliceT byteT] "aBlock");;;
-    do:  (__Write (![disk.Disk] (struct.field_ref Log "d" "l"))) ((![uint64T] "nextAddr") + #1) (![sliceT byteT] "v");;;
+    do:  (__Write (![disk.Disk] (struct.field_ref Log "d" "l")))
+      (![uint64T] "nextAddr")
+      (![sliceT byteT] "aBlock");;;
+    do:  (__Write (![disk.Disk] (struct.field_ref Log "d" "l")))
+      ((![uint64T] "nextAddr") + #1)
+      (![sliceT byteT] "v");;;
     let: "$a0" := ![sliceT byteT] "v" in
-    do:  map.insert (![mapT uint64T (sliceT byteT)] (struct.field_ref Log "cache" "l")) (![uint64T] "a") "$a0";;;
+    do:  map.insert
+      (![mapT uint64T (sliceT byteT)] (struct.field_ref Log "cache" "l"))
+      (![uint64T] "a")
+      "$a0";;;
     let: "$a0" := (![uint64T] "length") + #1 in
     do:  (![ptrT] (struct.field_ref Log "length" "l")) <-[uint64T] "$a0";;;
     do:  (Log__unlock (![Log] "l")) #();;;
@@ -2334,6 +2856,28 @@ Definition Log__Apply : val :=
     do:  (Log__unlock (![Log] "l")) #();;;
     do:  #()).
 
+Definition Log__mset : list (string * val) := [
+  ("lock", Log__lock%V);
+  ("unlock", Log__unlock%V);
+  ("BeginTxn", Log__BeginTxn%V);
+  ("Read", Log__Read%V);
+  ("Size", Log__Size%V);
+  ("Write", Log__Write%V);
+  ("Commit", Log__Commit%V);
+  ("Apply", Log__Apply%V)
+].
+
+Definition Log__mset_ptr : list (string * val) := [
+  ("lock", (λ: "$recvAddr", Log__lock (![Log] "$recvAddr"))%V);
+  ("unlock", (λ: "$recvAddr", Log__unlock (![Log] "$recvAddr"))%V);
+  ("BeginTxn", (λ: "$recvAddr", Log__BeginTxn (![Log] "$recvAddr"))%V);
+  ("Read", (λ: "$recvAddr", Log__Read (![Log] "$recvAddr"))%V);
+  ("Size", (λ: "$recvAddr", Log__Size (![Log] "$recvAddr"))%V);
+  ("Write", (λ: "$recvAddr", Log__Write (![Log] "$recvAddr"))%V);
+  ("Commit", (λ: "$recvAddr", Log__Commit (![Log] "$recvAddr"))%V);
+  ("Apply", (λ: "$recvAddr", Log__Apply (![Log] "$recvAddr"))%V)
+].
+
 (* Open recovers the log following a crash or shutdown *)
 Definition Open : val :=
   rec: "Open" <> :=
@@ -2381,13 +2925,18 @@ Definition disabled_testWal : val :=
     else do:  #());;;
     let: "$a0" := (![boolT] "ok") && ((blockToInt ((Log__Read (![Log] "lg")) #2)) = #11) in
     do:  "ok" <-[boolT] "$a0";;;
-    let: "$a0" := (![boolT] "ok") && ((blockToInt ((__Read (![disk.Disk] (struct.field_ref Log "d" "lg"))) #0)) = #0) in
+    let: "$a0" :=
+      (![boolT] "ok") &&
+        ((blockToInt ((__Read (![disk.Disk] (struct.field_ref Log "d" "lg"))) #0)) = #0) in
     do:  "ok" <-[boolT] "$a0";;;
     do:  (Log__Commit (![Log] "lg")) #();;;
-    let: "$a0" := (![boolT] "ok") && ((blockToInt ((__Read (![disk.Disk] (struct.field_ref Log "d" "lg"))) #0)) = #1) in
+    let: "$a0" :=
+      (![boolT] "ok") &&
+        ((blockToInt ((__Read (![disk.Disk] (struct.field_ref Log "d" "lg"))) #0)) = #1) in
     do:  "ok" <-[boolT] "$a0";;;
     do:  (Log__Apply (![Log] "lg")) #();;;
-    let: "$a0" := (![boolT] "ok") && ((![uint64T] (![ptrT] (struct.field_ref Log "length" "lg"))) = #0) in
+    let: "$a0" :=
+      (![boolT] "ok") && ((![uint64T] (![ptrT] (struct.field_ref Log "length" "lg"))) = #0) in
     do:  "ok" <-[boolT] "$a0";;;
     return: (![boolT] "ok");;;
     do:  #()).
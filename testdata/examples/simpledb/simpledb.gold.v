@@ -104,7 +104,8 @@ Definition DecodeEntry : val :=
        }], #0);;;
       do:  #()
     else do:  #());;;
-    (if: (slice.len (![sliceT byteT] "data")) < (((![uint64T] "l1") + (![uint64T] "l2")) + (![uint64T] "valueLen"))
+    (if: (slice.len (![sliceT byteT] "data")) <
+      (((![uint64T] "l1") + (![uint64T] "l2")) + (![uint64T] "valueLen"))
     then
       return: (struct.make Entry [{
          "Key" ::= #0;
@@ -114,7 +115,11 @@ Definition DecodeEntry : val :=
     else do:  #());;;
     let: "value" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
     let: "$a0" := let: "$s" := ![sliceT byteT] "data" in
-    slice.slice byteT "$s" ((![uint64T] "l1") + (![uint64T] "l2")) (((![uint64T] "l1") + (![uint64T] "l2")) + (![uint64T] "valueLen")) in
+    slice.slice
+      byteT
+      "$s"
+      ((![uint64T] "l1") + (![uint64T] "l2"))
+      (((![uint64T] "l1") + (![uint64T] "l2")) + (![uint64T] "valueLen")) in
     do:  "value" <-[sliceT byteT] "$a0";;;
     return: (struct.make Entry [{
        "Key" ::= ![uint64T] "key";
@@ -141,15 +146,20 @@ Definition readTableIndex : val :=
     (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
       let: "l" := ref_ty uint64T (zero_val uint64T) in
       let: "e" := ref_ty Entry (zero_val Entry) in
-      let: ("$a0", "$a1") := DecodeEntry (![sliceT byteT] (struct.field_ref lazyFileBuf "next" "buf")) in
+      let: ("$a0", "$a1") :=
+        DecodeEntry (![sliceT byteT] (struct.field_ref lazyFileBuf "next" "buf")) in
       do:  "l" <-[uint64T] "$a1";;;
       do:  "e" <-[Entry] "$a0";;;
       (if: (![uint64T] "l") > #0
       then
         let: "$a0" := #8 + (![uint64T] (struct.field_ref lazyFileBuf "offset" "buf")) in
-        do:  map.insert (![mapT uint64T uint64T] "index") (![uint64T] (struct.field_ref Entry "Key" "e")) "$a0";;;
+        do:  map.insert
+          (![mapT uint64T uint64T] "index")
+          (![uint64T] (struct.field_ref Entry "Key" "e"))
+          "$a0";;;
         let: "$a0" := struct.make lazyFileBuf [{
-          "offset" ::= (![uint64T] (struct.field_ref lazyFileBuf "offset" "buf")) + (![uint64T] "l");
+          "offset" ::=
+            (![uint64T] (struct.field_ref lazyFileBuf "offset" "buf")) + (![uint64T] "l");
           "next" ::= let: "$s" := ![sliceT byteT] (struct.field_ref lazyFileBuf "next" "buf") in
           slice.slice byteT "$s" (![uint64T] "l") (slice.len "$s")
         }] in
@@ -158,7 +168,12 @@ Definition readTableIndex : val :=
         do:  #()
       else
         let: "p" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
-        let: "$a0" := FS.ReadAt (![fileT] "f") ((![uint64T] (struct.field_ref lazyFileBuf "offset" "buf")) + (slice.len (![sliceT byteT] (struct.field_ref lazyFileBuf "next" "buf")))) #4096 in
+        let: "$a0" :=
+          FS.ReadAt
+            (![fileT] "f")
+            ((![uint64T] (struct.field_ref lazyFileBuf "offset" "buf")) +
+              (slice.len (![sliceT byteT] (struct.field_ref lazyFileBuf "next" "buf"))))
+            #4096 in
         do:  "p" <-[sliceT byteT] "$a0";;;
         (if: (slice.len (![sliceT byteT] "p")) = #0
         then
@@ -166,7 +181,11 @@ Definition readTableIndex : val :=
           do:  #()
         else
           let: "newBuf" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
-          let: "$a0" := slice.append byteT (![sliceT byteT] (struct.field_ref lazyFileBuf "next" "buf")) (![sliceT byteT] "p") in
+          let: "$a0" :=
+            slice.append
+              byteT
+              (![sliceT byteT] (struct.field_ref lazyFileBuf "next" "buf"))
+              (![sliceT byteT] "p") in
           do:  "newBuf" <-[sliceT byteT] "$a0";;;
           let: "$a0" := struct.make lazyFileBuf [{
             "offset" ::= ![uint64T] (struct.field_ref lazyFileBuf "offset" "buf");
@@ -223,7 +242,11 @@ Definition readValue : val :=
     (if: (![uint64T] "haveBytes") < (![uint64T] "totalBytes")
     then
       let: "buf2" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
-      let: "$a0" := FS.ReadAt (![fileT] "f") ((![uint64T] "off") + #512) ((![uint64T] "totalBytes") - (![uint64T] "haveBytes")) in
+      let: "$a0" :=
+        FS.ReadAt
+          (![fileT] "f")
+          ((![uint64T] "off") + #512)
+          ((![uint64T] "totalBytes") - (![uint64T] "haveBytes")) in
       do:  "buf2" <-[sliceT byteT] "$a0";;;
       let: "newBuf" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
       let: "$a0" := slice.append byteT (![sliceT byteT] "buf") (![sliceT byteT] "buf2") in
@@ -241,7 +264,9 @@ Definition tableRead : val :=
     let: "t" := ref_ty Table "t" in
     let: "ok" := ref_ty boolT (zero_val boolT) in
     let: "off" := ref_ty uint64T (zero_val uint64T) in
-    let: ("$a0", "$a1") := Fst (map.get (![mapT uint64T uint64T] (struct.field_ref Table "Index" "t")) (![uint64T] "k")) in
+    let: ("$a0", "$a1") :=
+      Fst
+        (map.get (![mapT uint64T uint64T] (struct.field_ref Table "Index" "t")) (![uint64T] "k")) in
     do:  "ok" <-[boolT] "$a1";;;
     do:  "off" <-[uint64T] "$a0";;;
     (if: (~ (![boolT] "ok"))
@@ -413,7 +438,10 @@ Definition tablePut : val :=
     let: "$a0" := ![uint64T] (![ptrT] (struct.field_ref tableWriter "offset" "w")) in
     do:  "off" <-[uint64T] "$a0";;;
     let: "$a0" := (![uint64T] "off") + (slice.len (![sliceT byteT] "tmp2")) in
-    do:  map.insert (![mapT uint64T uint64T] (struct.field_ref tableWriter "index" "w")) (![uint64T] "k") "$a0";;;
+    do:  map.insert
+      (![mapT uint64T uint64T] (struct.field_ref tableWriter "index" "w"))
+      (![uint64T] "k")
+      "$a0";;;
     do:  tableWriterAppend (![tableWriter] "w") (![sliceT byteT] "tmp3");;;
     do:  #()).
 
@@ -429,7 +457,8 @@ Definition Database : go_type := structT [
 
 Definition makeValueBuffer : val :=
   rec: "makeValueBuffer" <> :=
-    exception_do (let: "buf" := ref_ty (mapT uint64T (sliceT byteT)) (zero_val (mapT uint64T (sliceT byteT))) in
+    exception_do (let: "buf" :=
+      ref_ty (mapT uint64T (sliceT byteT)) (zero_val (mapT uint64T (sliceT byteT))) in
     let: "$a0" := map.make uint64T (sliceT byteT) #() in
     do:  "buf" <-[mapT uint64T (sliceT byteT)] "$a0";;;
     let: "bufPtr" := ref_ty ptrT (zero_val ptrT) in
@@ -497,7 +526,8 @@ Definition Read : val :=
     let: "db" := ref_ty Database "db" in
     do:  (sync.Mutex__Lock (![ptrT] (struct.field_ref Database "bufferL" "db"))) #();;;
     let: "buf" := ref_ty (mapT uint64T (sliceT byteT)) (zero_val (mapT uint64T (sliceT byteT))) in
-    let: "$a0" := ![mapT uint64T (sliceT byteT)] (![ptrT] (struct.field_ref Database "wbuffer" "db")) in
+    let: "$a0" :=
+      ![mapT uint64T (sliceT byteT)] (![ptrT] (struct.field_ref Database "wbuffer" "db")) in
     do:  "buf" <-[mapT uint64T (sliceT byteT)] "$a0";;;
     let: "ok" := ref_ty boolT (zero_val boolT) in
     let: "v" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
@@ -511,7 +541,8 @@ Definition Read : val :=
       do:  #()
     else do:  #());;;
     let: "rbuf" := ref_ty (mapT uint64T (sliceT byteT)) (zero_val (mapT uint64T (sliceT byteT))) in
-    let: "$a0" := ![mapT uint64T (sliceT byteT)] (![ptrT] (struct.field_ref Database "rbuffer" "db")) in
+    let: "$a0" :=
+      ![mapT uint64T (sliceT byteT)] (![ptrT] (struct.field_ref Database "rbuffer" "db")) in
     do:  "rbuf" <-[mapT uint64T (sliceT byteT)] "$a0";;;
     let: "v2" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
     let: ("$a0", "$a1") := Fst (map.get (![mapT uint64T (sliceT byteT)] "rbuf") (![uint64T] "k")) in
@@ -549,7 +580,8 @@ Definition Write : val :=
     let: "db" := ref_ty Database "db" in
     do:  (sync.Mutex__Lock (![ptrT] (struct.field_ref Database "bufferL" "db"))) #();;;
     let: "buf" := ref_ty (mapT uint64T (sliceT byteT)) (zero_val (mapT uint64T (sliceT byteT))) in
-    let: "$a0" := ![mapT uint64T (sliceT byteT)] (![ptrT] (struct.field_ref Database "wbuffer" "db")) in
+    let: "$a0" :=
+      ![mapT uint64T (sliceT byteT)] (![ptrT] (struct.field_ref Database "wbuffer" "db")) in
     do:  "buf" <-[mapT uint64T (sliceT byteT)] "$a0";;;
     let: "$a0" := ![sliceT byteT] "v" in
     do:  map.insert (![mapT uint64T (sliceT byteT)] "buf") (![uint64T] "k") "$a0";;;
@@ -597,23 +629,32 @@ Definition tablePutOldTable : val :=
     (for: (λ: <>, #true); (λ: <>, Skip) := λ: <>,
       let: "l" := ref_ty uint64T (zero_val uint64T) in
       let: "e" := ref_ty Entry (zero_val Entry) in
-      let: ("$a0", "$a1") := DecodeEntry (![sliceT byteT] (struct.field_ref lazyFileBuf "next" "buf")) in
+      let: ("$a0", "$a1") :=
+        DecodeEntry (![sliceT byteT] (struct.field_ref lazyFileBuf "next" "buf")) in
       do:  "l" <-[uint64T] "$a1";;;
       do:  "e" <-[Entry] "$a0";;;
       (if: (![uint64T] "l") > #0
       then
         let: "ok" := ref_ty boolT (zero_val boolT) in
         let: <> := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
-        let: ("$a0", "$a1") := Fst (map.get (![mapT uint64T (sliceT byteT)] "b") (![uint64T] (struct.field_ref Entry "Key" "e"))) in
+        let: ("$a0", "$a1") :=
+          Fst
+            (map.get
+              (![mapT uint64T (sliceT byteT)] "b")
+              (![uint64T] (struct.field_ref Entry "Key" "e"))) in
         do:  "ok" <-[boolT] "$a1";;;
         do:  "$a0";;;
         (if: (~ (![boolT] "ok"))
         then
-          do:  tablePut (![tableWriter] "w") (![uint64T] (struct.field_ref Entry "Key" "e")) (![sliceT byteT] (struct.field_ref Entry "Value" "e"));;;
+          do:  tablePut
+            (![tableWriter] "w")
+            (![uint64T] (struct.field_ref Entry "Key" "e"))
+            (![sliceT byteT] (struct.field_ref Entry "Value" "e"));;;
           do:  #()
         else do:  #());;;
         let: "$a0" := struct.make lazyFileBuf [{
-          "offset" ::= (![uint64T] (struct.field_ref lazyFileBuf "offset" "buf")) + (![uint64T] "l");
+          "offset" ::=
+            (![uint64T] (struct.field_ref lazyFileBuf "offset" "buf")) + (![uint64T] "l");
           "next" ::= let: "$s" := ![sliceT byteT] (struct.field_ref lazyFileBuf "next" "buf") in
           slice.slice byteT "$s" (![uint64T] "l") (slice.len "$s")
         }] in
@@ -622,7 +663,12 @@ Definition tablePutOldTable : val :=
         do:  #()
       else
         let: "p" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
-        let: "$a0" := FS.ReadAt (![fileT] (struct.field_ref Table "File" "t")) ((![uint64T] (struct.field_ref lazyFileBuf "offset" "buf")) + (slice.len (![sliceT byteT] (struct.field_ref lazyFileBuf "next" "buf")))) #4096 in
+        let: "$a0" :=
+          FS.ReadAt
+            (![fileT] (struct.field_ref Table "File" "t"))
+            ((![uint64T] (struct.field_ref lazyFileBuf "offset" "buf")) +
+              (slice.len (![sliceT byteT] (struct.field_ref lazyFileBuf "next" "buf"))))
+            #4096 in
         do:  "p" <-[sliceT byteT] "$a0";;;
         (if: (slice.len (![sliceT byteT] "p")) = #0
         then
@@ -630,7 +676,11 @@ Definition tablePutOldTable : val :=
           do:  #()
         else
           let: "newBuf" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
-          let: "$a0" := slice.append byteT (![sliceT byteT] (struct.field_ref lazyFileBuf "next" "buf")) (![sliceT byteT] "p") in
+          let: "$a0" :=
+            slice.append
+              byteT
+              (![sliceT byteT] (struct.field_ref lazyFileBuf "next" "buf"))
+              (![sliceT byteT] "p") in
           do:  "newBuf" <-[sliceT byteT] "$a0";;;
           let: "$a0" := struct.make lazyFileBuf [{
             "offset" ::= ![uint64T] (struct.field_ref lazyFileBuf "offset" "buf");
@@ -665,7 +715,10 @@ Definition constructNewTable : val :=
     let: "oldTable" := ref_ty Table (zero_val Table) in
     let: "$a0" := ![Table] (![ptrT] (struct.field_ref Database "table" "db")) in
     do:  "oldTable" <-[Table] "$a0";;;
-    do:  tablePutOldTable (![tableWriter] "w") (![Table] "oldTable") (![mapT uint64T (sliceT byteT)] "wbuf");;;
+    do:  tablePutOldTable
+      (![tableWriter] "w")
+      (![Table] "oldTable")
+      (![mapT uint64T (sliceT byteT)] "wbuf");;;
     do:  tablePutBuffer (![tableWriter] "w") (![mapT uint64T (sliceT byteT)] "wbuf");;;
     let: "newTable" := ref_ty Table (zero_val Table) in
     let: "$a0" := tableWriterClose (![tableWriter] "w") in
@@ -683,15 +736,19 @@ Definition Compact : val :=
     do:  (sync.Mutex__Lock (![ptrT] (struct.field_ref Database "compactionL" "db"))) #();;;
     do:  (sync.Mutex__Lock (![ptrT] (struct.field_ref Database "bufferL" "db"))) #();;;
     let: "buf" := ref_ty (mapT uint64T (sliceT byteT)) (zero_val (mapT uint64T (sliceT byteT))) in
-    let: "$a0" := ![mapT uint64T (sliceT byteT)] (![ptrT] (struct.field_ref Database "wbuffer" "db")) in
+    let: "$a0" :=
+      ![mapT uint64T (sliceT byteT)] (![ptrT] (struct.field_ref Database "wbuffer" "db")) in
     do:  "buf" <-[mapT uint64T (sliceT byteT)] "$a0";;;
-    let: "emptyWbuffer" := ref_ty (mapT uint64T (sliceT byteT)) (zero_val (mapT uint64T (sliceT byteT))) in
+    let: "emptyWbuffer" :=
+      ref_ty (mapT uint64T (sliceT byteT)) (zero_val (mapT uint64T (sliceT byteT))) in
     let: "$a0" := map.make uint64T (sliceT byteT) #() in
     do:  "emptyWbuffer" <-[mapT uint64T (sliceT byteT)] "$a0";;;
     let: "$a0" := ![mapT uint64T (sliceT byteT)] "emptyWbuffer" in
-    do:  (![ptrT] (struct.field_ref Database "wbuffer" "db")) <-[mapT uint64T (sliceT byteT)] "$a0";;;
+    do:  (![ptrT] (struct.field_ref Database "wbuffer" "db")) <-[mapT uint64T (sliceT byteT)]
+      "$a0";;;
     let: "$a0" := ![mapT uint64T (sliceT byteT)] "buf" in
-    do:  (![ptrT] (struct.field_ref Database "rbuffer" "db")) <-[mapT uint64T (sliceT byteT)] "$a0";;;
+    do:  (![ptrT] (struct.field_ref Database "rbuffer" "db")) <-[mapT uint64T (sliceT byteT)]
+      "$a0";;;
     do:  (sync.Mutex__Unlock (![ptrT] (struct.field_ref Database "bufferL" "db"))) #();;;
     do:  (sync.Mutex__Lock (![ptrT] (struct.field_ref Database "tableL" "db"))) #();;;
     let: "oldTableName" := ref_ty stringT (zero_val stringT) in
@@ -699,7 +756,8 @@ Definition Compact : val :=
     do:  "oldTableName" <-[stringT] "$a0";;;
     let: "t" := ref_ty Table (zero_val Table) in
     let: "oldTable" := ref_ty Table (zero_val Table) in
-    let: ("$a0", "$a1") := constructNewTable (![Database] "db") (![mapT uint64T (sliceT byteT)] "buf") in
+    let: ("$a0", "$a1") :=
+      constructNewTable (![Database] "db") (![mapT uint64T (sliceT byteT)] "buf") in
     do:  "t" <-[Table] "$a1";;;
     do:  "oldTable" <-[Table] "$a0";;;
     let: "newTable" := ref_ty stringT (zero_val stringT) in
@@ -771,7 +829,8 @@ Definition deleteOtherFiles : val :=
         do:  #()
       else do:  #());;;
       let: "name" := ref_ty stringT (zero_val stringT) in
-      let: "$a0" := ![stringT] (slice.elem_ref stringT (![sliceT stringT] "files") (![uint64T] "i")) in
+      let: "$a0" :=
+        ![stringT] (slice.elem_ref stringT (![sliceT stringT] "files") (![uint64T] "i")) in
       do:  "name" <-[stringT] "$a0";;;
       do:  deleteOtherFile (![stringT] "name") (![stringT] "tableName");;;
       let: "$a0" := (![uint64T] "i") + #1 in
@@ -0,0 +1,21 @@
+package unittest
+
+func closureParams() uint64 {
+	add := func(x uint64, y uint64) uint64 {
+		x += y
+		return x
+	}
+	return add(1, 2)
+}
+
+func closureEarlyReturn(xs []uint64) bool {
+	contains := func(y uint64) bool {
+		for _, x := range xs {
+			if x == y {
+				return true
+			}
+		}
+		return false
+	}
+	return contains(3)
+}
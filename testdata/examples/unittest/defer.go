@@ -0,0 +1,39 @@
+package unittest
+
+import "sync"
+
+func deferUnlock(m *sync.Mutex) uint64 {
+	m.Lock()
+	defer m.Unlock()
+	return 1
+}
+
+func deferWithArgs(s []uint64) {
+	defer consumeSlice(s)
+	s = nil
+}
+
+func consumeSlice(s []uint64) {}
+
+func recoverFromPanic() (recovered bool) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			recovered = true
+		}
+	}()
+	panic("oops")
+}
+
+func recoverToValue(x uint64) (y uint64, ok bool) {
+	defer func() {
+		if recover() != nil {
+			y = 0
+			ok = false
+		}
+	}()
+	if x == 0 {
+		panic(x)
+	}
+	return x, true
+}
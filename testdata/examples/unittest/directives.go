@@ -0,0 +1,39 @@
+package unittest
+
+// The implementation of this function is trusted rather than translated.
+//
+//goose:opaque
+func trustedHash(data []byte) uint64 {
+	var h uint64
+	for _, b := range data {
+		h = h*31 + uint64(b)
+	}
+	return h
+}
+
+//goose:extern Prelude.checksum
+func checksum(data []byte) uint64 {
+	return trustedHash(data)
+}
+
+type counter struct {
+	n uint64
+}
+
+//goose:opaque
+func (c *counter) incrementAtomically() {
+	c.n += 1
+}
+
+//goose:skip
+func debugDump(c *counter) {
+	println(c.n)
+}
+
+//goose:skip
+const debugLevel uint64 = 3
+
+func useDirectives(c *counter) uint64 {
+	c.incrementAtomically()
+	return checksum(nil)
+}
@@ -0,0 +1,54 @@
+package unittest
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// parseMessage is an ordinary global rather than a sentinel error
+var parseMessage = "parse error"
+
+type parseError struct {
+	line uint64
+}
+
+func (e *parseError) Error() string {
+	return parseMessage
+}
+
+func findKey(m map[string]uint64, k string) (uint64, error) {
+	v, ok := m[k]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return v, nil
+}
+
+func parse(line uint64) error {
+	if line == 0 {
+		return &parseError{line: line}
+	}
+	return nil
+}
+
+func errorMessage(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func wrapError(op string, err error) error {
+	return fmt.Errorf("%s failed: %w", op, err)
+}
+
+func isNotFound(err error) bool {
+	return err == ErrNotFound || errors.Is(err, ErrNotFound)
+}
+
+func asParseError(err error) bool {
+	var pe *parseError
+	return errors.As(err, &pe)
+}
@@ -0,0 +1,69 @@
+package unittest
+
+type orderedSet struct {
+	keys []uint64
+}
+
+func (s *orderedSet) All() func(yield func(uint64) bool) {
+	return func(yield func(uint64) bool) {
+		for _, k := range s.keys {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+func (s *orderedSet) Enumerate() func(yield func(uint64, uint64) bool) {
+	return func(yield func(uint64, uint64) bool) {
+		var i uint64
+		for _, k := range s.keys {
+			if !yield(i, k) {
+				return
+			}
+			i++
+		}
+	}
+}
+
+func sumBelow(s *orderedSet, max uint64) uint64 {
+	var sum uint64
+	for k := range s.All() {
+		if k >= max {
+			break
+		}
+		if k == 0 {
+			continue
+		}
+		sum += k
+	}
+	return sum
+}
+
+func countElements(s *orderedSet) uint64 {
+	var n uint64
+	for range s.All() {
+		n++
+	}
+	return n
+}
+
+func findIndex(s *orderedSet, key uint64) (uint64, bool) {
+	for i, k := range s.Enumerate() {
+		if k == key {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func findPair(s *orderedSet, t *orderedSet, sum uint64) bool {
+	for a := range s.All() {
+		for b := range t.All() {
+			if a+b == sum {
+				return true
+			}
+		}
+	}
+	return false
+}
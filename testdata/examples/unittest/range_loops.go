@@ -0,0 +1,69 @@
+package unittest
+
+func rangeInt(n uint64) uint64 {
+	var sum uint64
+	for i := range n {
+		sum += i
+	}
+	return sum
+}
+
+func rangeConstant() uint64 {
+	var sum uint64
+	for i := range 10 {
+		sum += uint64(i)
+	}
+	return sum
+}
+
+func rangeLabelled(s []uint64) uint64 {
+	var sum uint64
+loop:
+	for _, x := range s {
+		if x == 0 {
+			continue loop
+		}
+		sum += x
+	}
+	return sum
+}
+
+func rangeIntNoVar(n uint32) uint64 {
+	var count uint64
+	for range n {
+		count++
+	}
+	return count
+}
+
+func rangeSliceNoVars(s []uint64) uint64 {
+	var count uint64
+	for range s {
+		count++
+	}
+	return count
+}
+
+func rangeMapNoVars(m map[uint64]bool) uint64 {
+	var count uint64
+	for range m {
+		count++
+	}
+	return count
+}
+
+func loopCompoundPost(n uint64) uint64 {
+	var steps uint64
+	for i := uint64(1); i < n; i *= 2 {
+		steps++
+	}
+	return steps
+}
+
+func loopMultipleAssign(s []byte) {
+	for i, j := uint64(0), uint64(len(s)); i < j; i, j = i+1, j-1 {
+		x := s[i]
+		s[i] = s[j-1]
+		s[j-1] = x
+	}
+}
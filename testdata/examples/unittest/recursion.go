@@ -0,0 +1,56 @@
+package unittest
+
+func sumTo(n uint64) uint64 {
+	if n == 0 {
+		return 0
+	}
+	return n + sumTo(n-1)
+}
+
+func isEven(n uint64) bool {
+	if n == 0 {
+		return true
+	}
+	return isOdd(n - 1)
+}
+
+func isOdd(n uint64) bool {
+	if n == 0 {
+		return false
+	}
+	return isEven(n - 1)
+}
+
+type listNode struct {
+	val  uint64
+	next *listNode
+}
+
+func (l *listNode) length() uint64 {
+	if l == nil {
+		return 0
+	}
+	return 1 + l.next.length()
+}
+
+type treeNode struct {
+	children []treeNode
+	byName   map[string]*treeNode
+}
+
+func (t treeNode) size() uint64 {
+	var n uint64 = 1
+	for _, c := range t.children {
+		n += c.size()
+	}
+	return n
+}
+
+type forest struct {
+	trees []tree
+}
+
+type tree struct {
+	label uint64
+	sub   forest
+}
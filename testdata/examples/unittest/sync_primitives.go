@@ -0,0 +1,52 @@
+package unittest
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type cache struct {
+	mu      sync.RWMutex
+	entries map[uint64]uint64
+	hits    atomic.Uint64
+	init    sync.Once
+}
+
+func (c *cache) get(k uint64) (uint64, bool) {
+	c.mu.RLock()
+	v, ok := c.entries[k]
+	c.mu.RUnlock()
+	if ok {
+		c.hits.Add(1)
+	}
+	return v, ok
+}
+
+func (c *cache) put(k uint64, v uint64) {
+	c.init.Do(func() {
+		c.entries = make(map[uint64]uint64)
+	})
+	c.mu.Lock()
+	c.entries[k] = v
+	c.mu.Unlock()
+}
+
+func (c *cache) numHits() uint64 {
+	return c.hits.Load()
+}
+
+func atomicCounter(counter *uint64) uint64 {
+	atomic.AddUint64(counter, 1)
+	return atomic.LoadUint64(counter)
+}
+
+func waitForWorkers(n uint64) {
+	var wg sync.WaitGroup
+	for i := uint64(0); i < n; i++ {
+		wg.Add(1)
+		go func() {
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,2158 @@
+-- autogenerated from github.com/goose-lang/goose/testdata/examples/unittest
+import GooseLang
+import New.disk_prelude
+import New.code.errors
+import New.code.fmt
+import New.code.github_com.goose_lang.goose.machine
+import New.code.github_com.goose_lang.goose.machine.disk
+import New.code.github_com.tchajed.marshal
+import New.code.log
+import New.code.sync
+import New.code.sync.atomic
+
+namespace unittest
+open GooseLang
+
+/- closures.go -/
+
+def closureParams : Val :=
+  rec_ "closureParams" ["_"] <|
+    exception_do (let_ "add" (ref_ty funcT (zero_val funcT)) <|
+    let_ "$a0" (lam ["x", "y"] <|
+      exception_do (let_ "x" (ref_ty uint64T (var "x")) <|
+      let_ "y" (ref_ty uint64T (var "y")) <|
+      seq_ (do_ (store uint64T (var "x") (binop .plus (load uint64T (var "x")) (load uint64T (var "y"))))) <|
+      seq_ (return_ (load uint64T (var "x"))) <|
+      do_ unit)) <|
+    seq_ (do_ (store funcT (var "add") (var "$a0"))) <|
+    seq_ (return_ ((load funcT (var "add")) (u64 1) (u64 2))) <|
+    do_ unit)
+
+def closureEarlyReturn : Val :=
+  rec_ "closureEarlyReturn" ["xs"] <|
+    exception_do (let_ "xs" (ref_ty (sliceT uint64T) (var "xs")) <|
+    let_ "contains" (ref_ty funcT (zero_val funcT)) <|
+    let_ "$a0" (lam ["y"] <|
+      exception_do (let_ "y" (ref_ty uint64T (var "y")) <|
+      seq_ (do_ (let_ "$range" (load (sliceT uint64T) (var "xs")) <|
+      slice.for_range uint64T (var "$range") <| lam ["_", "x"] <|
+        let_ "x" (ref_ty uint64T (var "x")) <|
+        seq_ (if_ (binop .eq (load uint64T (var "x")) (load uint64T (var "y")))
+          (seq_ (return_ (bool true)) <|
+          do_ unit)
+          (do_ unit)) <|
+        do_ unit)) <|
+      seq_ (return_ (bool false)) <|
+      do_ unit)) <|
+    seq_ (do_ (store funcT (var "contains") (var "$a0"))) <|
+    seq_ (return_ ((load funcT (var "contains")) (u64 3))) <|
+    do_ unit)
+
+/- comments.go -/
+
+/- unittest is a package full of many independent and small translation examples -/
+
+def importantStruct : GoType := structT [
+    ]
+
+/- doSubtleThings does a number of subtle things:
+
+   (actually, it does nothing) -/
+def doSubtleThings : Val :=
+  rec_ "doSubtleThings" ["_"] <|
+    exception_do (do_ unit)
+
+/- This comment starts a Coq comment (* -/
+def hasStartComment : Val :=
+  rec_ "hasStartComment" ["_"] <|
+    exception_do (do_ unit)
+
+/- This comment *) ends a Coq comment -/
+def hasEndComment : Val :=
+  rec_ "hasEndComment" ["_"] <|
+    exception_do (do_ unit)
+
+/- condvar.go -/
+
+def condvarWrapping : Val :=
+  rec_ "condvarWrapping" ["_"] <|
+    exception_do (let_ "mu" (ref_ty ptrT (zero_val ptrT)) <|
+    let_ "$a0" (ref_ty sync.Mutex (zero_val sync.Mutex)) <|
+    seq_ (do_ (store ptrT (var "mu") (var "$a0"))) <|
+    let_ "cond1" (ref_ty ptrT (zero_val ptrT)) <|
+    let_ "$a0" (sync.NewCond (load ptrT (var "mu"))) <|
+    seq_ (do_ (store ptrT (var "cond1") (var "$a0"))) <|
+    let_ "$a0" (ref_ty sync.Mutex (zero_val sync.Mutex)) <|
+    seq_ (do_ (store ptrT (var "mu") (var "$a0"))) <|
+    seq_ (do_ ((sync.Cond__Wait (load ptrT (var "cond1"))) unit)) <|
+    do_ unit)
+
+/- const.go -/
+
+def GlobalConstant : Expr := str "foo"
+
+/- an untyped string -/
+def UntypedStringConstant : Expr := str "bar"
+
+def TypedInt : Expr := u64 32
+
+def ConstWithArith : Expr := binop .plus (u64 4) (binop .mul (u64 3) TypedInt)
+
+def TypedInt32 : Expr := u32 3
+
+def DivisionInConst : Expr := binop .quot (binop .minus (u64 4096) (u64 8)) (u64 8)
+
+/- 517 -/
+def ModInConst : Expr := binop .plus (u64 513) (binop .rem (u64 12) (u64 8))
+
+/- 5 -/
+def ModInConstParens : Expr := binop .rem (binop .plus (u64 513) (u64 12)) (u64 8)
+
+/- control_flow.go -/
+
+def conditionalReturn : Val :=
+  rec_ "conditionalReturn" ["x"] <|
+    exception_do (let_ "x" (ref_ty boolT (var "x")) <|
+    seq_ (if_ (load boolT (var "x"))
+      (seq_ (return_ (u64 0)) <|
+      do_ unit)
+      (do_ unit)) <|
+    seq_ (return_ (u64 1)) <|
+    do_ unit)
+
+def alwaysReturn : Val :=
+  rec_ "alwaysReturn" ["x"] <|
+    exception_do (let_ "x" (ref_ty boolT (var "x")) <|
+    seq_ (if_ (load boolT (var "x"))
+      (seq_ (return_ (u64 0)) <|
+      do_ unit)
+      (seq_ (return_ (u64 1)) <|
+      do_ unit)) <|
+    do_ unit)
+
+def alwaysReturnInNestedBranches : Val :=
+  rec_ "alwaysReturnInNestedBranches" ["x"] <|
+    exception_do (let_ "x" (ref_ty boolT (var "x")) <|
+    seq_ (if_ (not_ (load boolT (var "x")))
+      (seq_ (if_ (load boolT (var "x"))
+        (seq_ (return_ (u64 0)) <|
+        do_ unit)
+        (seq_ (return_ (u64 1)) <|
+        do_ unit)) <|
+      do_ unit)
+      (do_ unit)) <|
+    let_ "y" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 14) <|
+    seq_ (do_ (store uint64T (var "y") (var "$a0"))) <|
+    seq_ (return_ (load uint64T (var "y"))) <|
+    do_ unit)
+
+def earlyReturn : Val :=
+  rec_ "earlyReturn" ["x"] <|
+    exception_do (let_ "x" (ref_ty boolT (var "x")) <|
+    seq_ (if_ (load boolT (var "x"))
+      (seq_ (return_ unit) <|
+      do_ unit)
+      (do_ unit)) <|
+    do_ unit)
+
+def conditionalAssign : Val :=
+  rec_ "conditionalAssign" ["x"] <|
+    exception_do (let_ "x" (ref_ty boolT (var "x")) <|
+    let_ "y" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (if_ (load boolT (var "x"))
+      (let_ "$a0" (u64 1) <|
+      seq_ (do_ (store uint64T (var "y") (var "$a0"))) <|
+      do_ unit)
+      (let_ "$a0" (u64 2) <|
+      seq_ (do_ (store uint64T (var "y") (var "$a0"))) <|
+      do_ unit)) <|
+    seq_ (do_ (store uint64T (var "y") (binop .plus (load uint64T (var "y")) (u64 1)))) <|
+    seq_ (return_ (load uint64T (var "y"))) <|
+    do_ unit)
+
+def elseIf : Val :=
+  rec_ "elseIf" ["x", "y"] <|
+    exception_do (let_ "y" (ref_ty boolT (var "y")) <|
+    let_ "x" (ref_ty boolT (var "x")) <|
+    seq_ (if_ (load boolT (var "x"))
+      (seq_ (return_ (u64 0)) <|
+      do_ unit)
+      (seq_ (if_ (load boolT (var "y"))
+        (seq_ (return_ (u64 1)) <|
+        do_ unit)
+        (seq_ (return_ (u64 2)) <|
+        do_ unit)) <|
+      unit)) <|
+    do_ unit)
+
+/- conversions.go -/
+
+def stringWrapper : GoType := stringT
+
+def typedLiteral : Val :=
+  rec_ "typedLiteral" ["_"] <|
+    exception_do (seq_ (return_ (u64 3)) <|
+    do_ unit)
+
+def literalCast : Val :=
+  rec_ "literalCast" ["_"] <|
+    exception_do (let_ "x" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 2) <|
+    seq_ (do_ (store uint64T (var "x") (var "$a0"))) <|
+    seq_ (return_ (binop .plus (load uint64T (var "x")) (u64 2))) <|
+    do_ unit)
+
+def castInt : Val :=
+  rec_ "castInt" ["p"] <|
+    exception_do (let_ "p" (ref_ty (sliceT byteT) (var "p")) <|
+    seq_ (return_ (slice.len (load (sliceT byteT) (var "p")))) <|
+    do_ unit)
+
+def stringToByteSlice : Val :=
+  rec_ "stringToByteSlice" ["s"] <|
+    exception_do (let_ "s" (ref_ty stringT (var "s")) <|
+    let_ "p" (ref_ty (sliceT byteT) (zero_val (sliceT byteT))) <|
+    let_ "$a0" (StringToBytes (load stringT (var "s"))) <|
+    seq_ (do_ (store (sliceT byteT) (var "p") (var "$a0"))) <|
+    seq_ (return_ (load (sliceT byteT) (var "p"))) <|
+    do_ unit)
+
+def byteSliceToString : Val :=
+  rec_ "byteSliceToString" ["p"] <|
+    exception_do (let_ "p" (ref_ty (sliceT byteT) (var "p")) <|
+    let_ "s" (ref_ty stringT (zero_val stringT)) <|
+    let_ "$a0" (StringFromBytes (load (sliceT byteT) (var "p"))) <|
+    seq_ (do_ (store stringT (var "s") (var "$a0"))) <|
+    seq_ (return_ (load stringT (var "s"))) <|
+    do_ unit)
+
+def stringToStringWrapper : Val :=
+  rec_ "stringToStringWrapper" ["s"] <|
+    exception_do (let_ "s" (ref_ty stringT (var "s")) <|
+    seq_ (return_ (load stringT (var "s"))) <|
+    do_ unit)
+
+def stringWrapperToString : Val :=
+  rec_ "stringWrapperToString" ["s"] <|
+    exception_do (let_ "s" (ref_ty stringWrapper (var "s")) <|
+    seq_ (return_ (load stringWrapper (var "s"))) <|
+    do_ unit)
+
+/- copy.go -/
+
+def testCopySimple : Val :=
+  rec_ "testCopySimple" ["_"] <|
+    exception_do (let_ "x" (ref_ty (sliceT byteT) (zero_val (sliceT byteT))) <|
+    let_ "$a0" (slice.make2 byteT (u64 10)) <|
+    seq_ (do_ (store (sliceT byteT) (var "x") (var "$a0"))) <|
+    let_ "$a0" (u8 1) <|
+    seq_ (do_ (store byteT (slice.elem_ref byteT (load (sliceT byteT) (var "x")) (u64 3)) (var "$a0"))) <|
+    let_ "y" (ref_ty (sliceT byteT) (zero_val (sliceT byteT))) <|
+    let_ "$a0" (slice.make2 byteT (u64 10)) <|
+    seq_ (do_ (store (sliceT byteT) (var "y") (var "$a0"))) <|
+    seq_ (do_ (slice.copy byteT (load (sliceT byteT) (var "y")) (load (sliceT byteT) (var "x")))) <|
+    seq_ (return_ (binop .eq (load byteT (slice.elem_ref byteT (load (sliceT byteT) (var "y")) (u64 3))) (u8 1))) <|
+    do_ unit)
+
+def testCopyDifferentLengths : Val :=
+  rec_ "testCopyDifferentLengths" ["_"] <|
+    exception_do (let_ "x" (ref_ty (sliceT byteT) (zero_val (sliceT byteT))) <|
+    let_ "$a0" (slice.make2 byteT (u64 15)) <|
+    seq_ (do_ (store (sliceT byteT) (var "x") (var "$a0"))) <|
+    let_ "$a0" (u8 1) <|
+    seq_ (do_ (store byteT (slice.elem_ref byteT (load (sliceT byteT) (var "x")) (u64 3)) (var "$a0"))) <|
+    let_ "$a0" (u8 2) <|
+    seq_ (do_ (store byteT (slice.elem_ref byteT (load (sliceT byteT) (var "x")) (u64 12)) (var "$a0"))) <|
+    let_ "y" (ref_ty (sliceT byteT) (zero_val (sliceT byteT))) <|
+    let_ "$a0" (slice.make2 byteT (u64 10)) <|
+    seq_ (do_ (store (sliceT byteT) (var "y") (var "$a0"))) <|
+    let_ "n" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (slice.copy byteT (load (sliceT byteT) (var "y")) (load (sliceT byteT) (var "x"))) <|
+    seq_ (do_ (store uint64T (var "n") (var "$a0"))) <|
+    seq_ (return_ (binop .land (binop .eq (load uint64T (var "n")) (u64 10)) (binop .eq (load byteT (slice.elem_ref byteT (load (sliceT byteT) (var "y")) (u64 3))) (u8 1)))) <|
+    do_ unit)
+
+/- data_structures.go -/
+
+def atomicCreateStub : Val :=
+  rec_ "atomicCreateStub" ["dir", "fname", "data"] <|
+    exception_do (let_ "data" (ref_ty (sliceT byteT) (var "data")) <|
+    let_ "fname" (ref_ty stringT (var "fname")) <|
+    let_ "dir" (ref_ty stringT (var "dir")) <|
+    do_ unit)
+
+def useSlice : Val :=
+  rec_ "useSlice" ["_"] <|
+    exception_do (let_ "s" (ref_ty (sliceT byteT) (zero_val (sliceT byteT))) <|
+    let_ "$a0" (slice.make2 byteT (u64 1)) <|
+    seq_ (do_ (store (sliceT byteT) (var "s") (var "$a0"))) <|
+    let_ "s1" (ref_ty (sliceT byteT) (zero_val (sliceT byteT))) <|
+    let_ "$a0" (slice.append byteT (load (sliceT byteT) (var "s")) (load (sliceT byteT) (var "s"))) <|
+    seq_ (do_ (store (sliceT byteT) (var "s1") (var "$a0"))) <|
+    seq_ (do_ (atomicCreateStub (str "dir") (str "file") (load (sliceT byteT) (var "s1")))) <|
+    do_ unit)
+
+def useSliceIndexing : Val :=
+  rec_ "useSliceIndexing" ["_"] <|
+    exception_do (let_ "s" (ref_ty (sliceT uint64T) (zero_val (sliceT uint64T))) <|
+    let_ "$a0" (slice.make2 uint64T (u64 2)) <|
+    seq_ (do_ (store (sliceT uint64T) (var "s") (var "$a0"))) <|
+    let_ "$a0" (u64 2) <|
+    seq_ (do_ (store uint64T (slice.elem_ref uint64T (load (sliceT uint64T) (var "s")) (u64 1)) (var "$a0"))) <|
+    let_ "x" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (load uint64T (slice.elem_ref uint64T (load (sliceT uint64T) (var "s")) (u64 0))) <|
+    seq_ (do_ (store uint64T (var "x") (var "$a0"))) <|
+    seq_ (return_ (load uint64T (var "x"))) <|
+    do_ unit)
+
+def useMap : Val :=
+  rec_ "useMap" ["_"] <|
+    exception_do (let_ "m" (ref_ty (mapT uint64T (sliceT byteT)) (zero_val (mapT uint64T (sliceT byteT)))) <|
+    let_ "$a0" (map.make uint64T (sliceT byteT) unit) <|
+    seq_ (do_ (store (mapT uint64T (sliceT byteT)) (var "m") (var "$a0"))) <|
+    let_ "$a0" slice.nil <|
+    seq_ (do_ (map.insert (load (mapT uint64T (sliceT byteT)) (var "m")) (u64 1) (var "$a0"))) <|
+    let_ "ok" (ref_ty boolT (zero_val boolT)) <|
+    let_ "x" (ref_ty (sliceT byteT) (zero_val (sliceT byteT))) <|
+    lets_ ["$a0", "$a1"] (Fst (map.get (load (mapT uint64T (sliceT byteT)) (var "m")) (u64 2))) <|
+    seq_ (do_ (store boolT (var "ok") (var "$a1"))) <|
+    seq_ (do_ (store (sliceT byteT) (var "x") (var "$a0"))) <|
+    seq_ (if_ (load boolT (var "ok"))
+      (seq_ (return_ unit) <|
+      do_ unit)
+      (do_ unit)) <|
+    let_ "$a0" (load (sliceT byteT) (var "x")) <|
+    seq_ (do_ (map.insert (load (mapT uint64T (sliceT byteT)) (var "m")) (u64 3) (var "$a0"))) <|
+    do_ unit)
+
+def usePtr : Val :=
+  rec_ "usePtr" ["_"] <|
+    exception_do (let_ "p" (ref_ty ptrT (zero_val ptrT)) <|
+    let_ "$a0" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (do_ (store ptrT (var "p") (var "$a0"))) <|
+    let_ "$a0" (u64 1) <|
+    seq_ (do_ (store uint64T (load ptrT (var "p")) (var "$a0"))) <|
+    let_ "x" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (load uint64T (load ptrT (var "p"))) <|
+    seq_ (do_ (store uint64T (var "x") (var "$a0"))) <|
+    let_ "$a0" (load uint64T (var "x")) <|
+    seq_ (do_ (store uint64T (load ptrT (var "p")) (var "$a0"))) <|
+    do_ unit)
+
+def iterMapKeysAndValues : Val :=
+  rec_ "iterMapKeysAndValues" ["m"] <|
+    exception_do (let_ "m" (ref_ty (mapT uint64T uint64T) (var "m")) <|
+    let_ "sumPtr" (ref_ty ptrT (zero_val ptrT)) <|
+    let_ "$a0" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (do_ (store ptrT (var "sumPtr") (var "$a0"))) <|
+    seq_ (do_ (MapIter (load (mapT uint64T uint64T) (var "m")) <| lam ["k", "v"] <|
+      let_ "sum" (ref_ty uint64T (zero_val uint64T)) <|
+      let_ "$a0" (load uint64T (load ptrT (var "sumPtr"))) <|
+      seq_ (do_ (store uint64T (var "sum") (var "$a0"))) <|
+      let_ "$a0" (binop .plus (binop .plus (load uint64T (var "sum")) (load uint64T (var "k"))) (load uint64T (var "v"))) <|
+      seq_ (do_ (store uint64T (load ptrT (var "sumPtr")) (var "$a0"))) <|
+      do_ unit)) <|
+    let_ "sum" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (load uint64T (load ptrT (var "sumPtr"))) <|
+    seq_ (do_ (store uint64T (var "sum") (var "$a0"))) <|
+    seq_ (return_ (load uint64T (var "sum"))) <|
+    do_ unit)
+
+def iterMapKeys : Val :=
+  rec_ "iterMapKeys" ["m"] <|
+    exception_do (let_ "m" (ref_ty (mapT uint64T uint64T) (var "m")) <|
+    let_ "keysSlice" (ref_ty (sliceT uint64T) (zero_val (sliceT uint64T))) <|
+    let_ "$a0" (slice.make2 uint64T (u64 0)) <|
+    seq_ (do_ (store (sliceT uint64T) (var "keysSlice") (var "$a0"))) <|
+    let_ "keysRef" (ref_ty ptrT (zero_val ptrT)) <|
+    let_ "$a0" (ref_ty (sliceT uint64T) (zero_val (sliceT uint64T))) <|
+    seq_ (do_ (store ptrT (var "keysRef") (var "$a0"))) <|
+    let_ "$a0" (load (sliceT uint64T) (var "keysSlice")) <|
+    seq_ (do_ (store (sliceT uint64T) (load ptrT (var "keysRef")) (var "$a0"))) <|
+    seq_ (do_ (MapIter (load (mapT uint64T uint64T) (var "m")) <| lam ["k", "_"] <|
+      let_ "keys" (ref_ty (sliceT uint64T) (zero_val (sliceT uint64T))) <|
+      let_ "$a0" (load (sliceT uint64T) (load ptrT (var "keysRef"))) <|
+      seq_ (do_ (store (sliceT uint64T) (var "keys") (var "$a0"))) <|
+      let_ "newKeys" (ref_ty (sliceT uint64T) (zero_val (sliceT uint64T))) <|
+      let_ "$a0" (slice.append uint64T (load (sliceT uint64T) (var "keys")) (slice.literal uint64T [load uint64T (var "k")])) <|
+      seq_ (do_ (store (sliceT uint64T) (var "newKeys") (var "$a0"))) <|
+      let_ "$a0" (load (sliceT uint64T) (var "newKeys")) <|
+      seq_ (do_ (store (sliceT uint64T) (load ptrT (var "keysRef")) (var "$a0"))) <|
+      do_ unit)) <|
+    let_ "keys" (ref_ty (sliceT uint64T) (zero_val (sliceT uint64T))) <|
+    let_ "$a0" (load (sliceT uint64T) (load ptrT (var "keysRef"))) <|
+    seq_ (do_ (store (sliceT uint64T) (var "keys") (var "$a0"))) <|
+    seq_ (return_ (load (sliceT uint64T) (var "keys"))) <|
+    do_ unit)
+
+def getRandom : Val :=
+  rec_ "getRandom" ["_"] <|
+    exception_do (let_ "r" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (machine.RandomUint64 unit) <|
+    seq_ (do_ (store uint64T (var "r") (var "$a0"))) <|
+    seq_ (return_ (load uint64T (var "r"))) <|
+    do_ unit)
+
+/- defer.go -/
+
+def deferUnlock : Val :=
+  rec_ "deferUnlock" ["m"] <|
+    exception_do (let_ "m" (ref_ty ptrT (var "m")) <|
+    with_defer (seq_ (do_ ((sync.Mutex__Lock (load ptrT (var "m"))) unit)) <|
+    let_ "$f" (sync.Mutex__Unlock (load ptrT (var "m"))) <|
+    seq_ (do_ (store funcT (var "$defer") (let_ "$oldf" (load funcT (var "$defer")) <|
+      lam ["_"] <|
+        seq_ ((var "$f") unit) <|
+        (var "$oldf") unit))) <|
+    seq_ (return_ (u64 1)) <|
+    do_ unit))
+
+def consumeSlice : Val :=
+  rec_ "consumeSlice" ["s"] <|
+    exception_do (let_ "s" (ref_ty (sliceT uint64T) (var "s")) <|
+    do_ unit)
+
+def deferWithArgs : Val :=
+  rec_ "deferWithArgs" ["s"] <|
+    exception_do (let_ "s" (ref_ty (sliceT uint64T) (var "s")) <|
+    with_defer (let_ "$arg0" (load (sliceT uint64T) (var "s")) <|
+    let_ "$f" consumeSlice <|
+    seq_ (do_ (store funcT (var "$defer") (let_ "$oldf" (load funcT (var "$defer")) <|
+      lam ["_"] <|
+        seq_ ((var "$f") (var "$arg0")) <|
+        (var "$oldf") unit))) <|
+    let_ "$a0" slice.nil <|
+    seq_ (do_ (store (sliceT uint64T) (var "s") (var "$a0"))) <|
+    do_ unit))
+
+def recoverFromPanic : Val :=
+  rec_ "recoverFromPanic" ["_"] <|
+    exception_do (let_ "recovered" (ref_ty boolT (zero_val boolT)) <|
+    seq_ (do_ (with_defer (let_ "$f" (lam ["_"] <|
+      exception_do (let_ "r" (ref_ty interfaceT (zero_val interfaceT)) <|
+      let_ "$a0" (recover unit) <|
+      seq_ (do_ (store interfaceT (var "r") (var "$a0"))) <|
+      seq_ (if_ (binop .ne (load interfaceT (var "r")) interface.nil)
+        (let_ "$a0" (bool true) <|
+        seq_ (do_ (store boolT (var "recovered") (var "$a0"))) <|
+        do_ unit)
+        (do_ unit)) <|
+      do_ unit)) <|
+    seq_ (do_ (store funcT (var "$defer") (let_ "$oldf" (load funcT (var "$defer")) <|
+      lam ["_"] <|
+        seq_ ((var "$f") unit) <|
+        (var "$oldf") unit))) <|
+    seq_ (do_ (PanicValue (interface.make "string" [] (str "oops")))) <|
+    do_ unit))) <|
+    return_ (load boolT (var "recovered")))
+
+def recoverToValue : Val :=
+  rec_ "recoverToValue" ["x"] <|
+    exception_do (let_ "x" (ref_ty uint64T (var "x")) <|
+    let_ "y" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "ok" (ref_ty boolT (zero_val boolT)) <|
+    seq_ (do_ (with_defer (let_ "$f" (lam ["_"] <|
+      exception_do (seq_ (if_ (binop .ne (recover unit) interface.nil)
+        (let_ "$a0" (u64 0) <|
+        seq_ (do_ (store uint64T (var "y") (var "$a0"))) <|
+        let_ "$a0" (bool false) <|
+        seq_ (do_ (store boolT (var "ok") (var "$a0"))) <|
+        do_ unit)
+        (do_ unit)) <|
+      do_ unit)) <|
+    seq_ (do_ (store funcT (var "$defer") (let_ "$oldf" (load funcT (var "$defer")) <|
+      lam ["_"] <|
+        seq_ ((var "$f") unit) <|
+        (var "$oldf") unit))) <|
+    seq_ (if_ (binop .eq (load uint64T (var "x")) (u64 0))
+      (seq_ (do_ (PanicValue (interface.make "uint64" [] (load uint64T (var "x"))))) <|
+      do_ unit)
+      (do_ unit)) <|
+    let_ "$r0" (load uint64T (var "x")) <|
+    let_ "$r1" (bool true) <|
+    seq_ (do_ (store uint64T (var "y") (var "$r0"))) <|
+    seq_ (do_ (store boolT (var "ok") (var "$r1"))) <|
+    seq_ (return_ unit) <|
+    do_ unit))) <|
+    return_ (tuple [load uint64T (var "y"), load boolT (var "ok")]))
+
+/- directives.go -/
+
+/- The implementation of this function is trusted rather than translated.
+
+   opaque: func trustedHash(data []byte) uint64 -/
+axiom trustedHash : Val
+
+/- extern: func checksum(data []byte) uint64 -/
+def checksum : Val := Prelude.checksum
+
+def counter : GoType := structT [
+      ("n", uint64T)
+    ]
+
+/- opaque: func (c *counter) incrementAtomically() -/
+axiom counter__incrementAtomically : Val
+
+def counter__mset : List (String × Val) := []
+
+def counter__mset_ptr : List (String × Val) := [("incrementAtomically", counter__incrementAtomically)]
+
+def useDirectives : Val :=
+  rec_ "useDirectives" ["c"] <|
+    exception_do (let_ "c" (ref_ty ptrT (var "c")) <|
+    seq_ (do_ ((counter__incrementAtomically (load ptrT (var "c"))) unit)) <|
+    seq_ (return_ (checksum slice.nil)) <|
+    do_ unit)
+
+/- disk.go -/
+
+def diskWrapper : GoType := structT [
+      ("d", disk.Disk)
+    ]
+
+def diskArgument : Val :=
+  rec_ "diskArgument" ["d"] <|
+    exception_do (let_ "d" (ref_ty disk.Disk (var "d")) <|
+    let_ "b" (ref_ty (sliceT byteT) (zero_val (sliceT byteT))) <|
+    let_ "$a0" ((__Read (load disk.Disk (var "d"))) (u64 0)) <|
+    seq_ (do_ (store (sliceT byteT) (var "b") (var "$a0"))) <|
+    seq_ (do_ ((__Write (load disk.Disk (var "d"))) (u64 1) (load (sliceT byteT) (var "b")))) <|
+    do_ unit)
+
+/- empty_functions.go -/
+
+def empty : Val :=
+  rec_ "empty" ["_"] <|
+    exception_do (do_ unit)
+
+def emptyReturn : Val :=
+  rec_ "emptyReturn" ["_"] <|
+    exception_do (seq_ (return_ unit) <|
+    do_ unit)
+
+/- encoding.go -/
+
+def Enc : GoType := structT [
+      ("p", sliceT byteT)
+    ]
+
+def Enc__consume : Val :=
+  rec_ "Enc__consume" ["e", "n"] <|
+    exception_do (let_ "e" (ref_ty ptrT (var "e")) <|
+    let_ "n" (ref_ty uint64T (var "n")) <|
+    let_ "b" (ref_ty (sliceT byteT) (zero_val (sliceT byteT))) <|
+    let_ "$a0" (let_ "$s" (load (sliceT byteT) (struct.field_ref Enc "p" (load ptrT (var "e")))) <|
+    slice.slice byteT (var "$s") (u64 0) (load uint64T (var "n"))) <|
+    seq_ (do_ (store (sliceT byteT) (var "b") (var "$a0"))) <|
+    let_ "$a0" (let_ "$s" (load (sliceT byteT) (struct.field_ref Enc "p" (load ptrT (var "e")))) <|
+    slice.slice byteT (var "$s") (load uint64T (var "n")) (slice.len (var "$s"))) <|
+    seq_ (do_ (store (sliceT byteT) (struct.field_ref Enc "p" (load ptrT (var "e"))) (var "$a0"))) <|
+    seq_ (return_ (load (sliceT byteT) (var "b"))) <|
+    do_ unit)
+
+def Enc__UInt64 : Val :=
+  rec_ "Enc__UInt64" ["e", "x"] <|
+    exception_do (let_ "e" (ref_ty ptrT (var "e")) <|
+    let_ "x" (ref_ty uint64T (var "x")) <|
+    seq_ (do_ (machine.UInt64Put ((Enc__consume (load ptrT (var "e"))) (u64 8)) (load uint64T (var "x")))) <|
+    do_ unit)
+
+def Enc__UInt32 : Val :=
+  rec_ "Enc__UInt32" ["e", "x"] <|
+    exception_do (let_ "e" (ref_ty ptrT (var "e")) <|
+    let_ "x" (ref_ty uint32T (var "x")) <|
+    seq_ (do_ (machine.UInt32Put ((Enc__consume (load ptrT (var "e"))) (u64 4)) (load uint32T (var "x")))) <|
+    do_ unit)
+
+def Enc__mset : List (String × Val) := []
+
+def Enc__mset_ptr : List (String × Val) := [("consume", Enc__consume), ("UInt64", Enc__UInt64), ("UInt32", Enc__UInt32)]
+
+def Dec : GoType := structT [
+      ("p", sliceT byteT)
+    ]
+
+def Dec__consume : Val :=
+  rec_ "Dec__consume" ["d", "n"] <|
+    exception_do (let_ "d" (ref_ty ptrT (var "d")) <|
+    let_ "n" (ref_ty uint64T (var "n")) <|
+    let_ "b" (ref_ty (sliceT byteT) (zero_val (sliceT byteT))) <|
+    let_ "$a0" (let_ "$s" (load (sliceT byteT) (struct.field_ref Dec "p" (load ptrT (var "d")))) <|
+    slice.slice byteT (var "$s") (u64 0) (load uint64T (var "n"))) <|
+    seq_ (do_ (store (sliceT byteT) (var "b") (var "$a0"))) <|
+    let_ "$a0" (let_ "$s" (load (sliceT byteT) (struct.field_ref Dec "p" (load ptrT (var "d")))) <|
+    slice.slice byteT (var "$s") (load uint64T (var "n")) (slice.len (var "$s"))) <|
+    seq_ (do_ (store (sliceT byteT) (struct.field_ref Dec "p" (load ptrT (var "d"))) (var "$a0"))) <|
+    seq_ (return_ (load (sliceT byteT) (var "b"))) <|
+    do_ unit)
+
+def Dec__UInt64 : Val :=
+  rec_ "Dec__UInt64" ["d", "_"] <|
+    exception_do (let_ "d" (ref_ty ptrT (var "d")) <|
+    seq_ (return_ (machine.UInt64Get ((Dec__consume (load ptrT (var "d"))) (u64 8)))) <|
+    do_ unit)
+
+def Dec__UInt32 : Val :=
+  rec_ "Dec__UInt32" ["d", "_"] <|
+    exception_do (let_ "d" (ref_ty ptrT (var "d")) <|
+    seq_ (return_ (machine.UInt32Get ((Dec__consume (load ptrT (var "d"))) (u64 4)))) <|
+    do_ unit)
+
+def Dec__mset : List (String × Val) := []
+
+def Dec__mset_ptr : List (String × Val) := [("consume", Dec__consume), ("UInt64", Dec__UInt64), ("UInt32", Dec__UInt32)]
+
+/- errors.go -/
+
+def ErrNotFound : Expr := errors.Sentinel (str "github.com/goose-lang/goose/testdata/examples/unittest.ErrNotFound") (str "not found")
+
+def parseMessage : Expr := str "parse error"
+
+def parseError : GoType := structT [
+      ("line", uint64T)
+    ]
+
+def parseError__Error : Val :=
+  rec_ "parseError__Error" ["e", "_"] <|
+    exception_do (let_ "e" (ref_ty ptrT (var "e")) <|
+    seq_ (return_ (load stringT (var "parseMessage"))) <|
+    do_ unit)
+
+def parseError__mset : List (String × Val) := []
+
+def parseError__mset_ptr : List (String × Val) := [("Error", parseError__Error)]
+
+def findKey : Val :=
+  rec_ "findKey" ["m", "k"] <|
+    exception_do (let_ "k" (ref_ty stringT (var "k")) <|
+    let_ "m" (ref_ty (mapT stringT uint64T) (var "m")) <|
+    let_ "ok" (ref_ty boolT (zero_val boolT)) <|
+    let_ "v" (ref_ty uint64T (zero_val uint64T)) <|
+    lets_ ["$a0", "$a1"] (Fst (map.get (load (mapT stringT uint64T) (var "m")) (load stringT (var "k")))) <|
+    seq_ (do_ (store boolT (var "ok") (var "$a1"))) <|
+    seq_ (do_ (store uint64T (var "v") (var "$a0"))) <|
+    seq_ (if_ (not_ (load boolT (var "ok")))
+      (seq_ (return_ (tuple [u64 0, ErrNotFound])) <|
+      do_ unit)
+      (do_ unit)) <|
+    seq_ (return_ (tuple [load uint64T (var "v"), interface.nil])) <|
+    do_ unit)
+
+def parse : Val :=
+  rec_ "parse" ["line"] <|
+    exception_do (let_ "line" (ref_ty uint64T (var "line")) <|
+    seq_ (if_ (binop .eq (load uint64T (var "line")) (u64 0))
+      (seq_ (return_ (interface.make "github.com/goose-lang/goose/testdata/examples/unittest.parseError'ptr" parseError__mset_ptr (ref_ty parseError (struct.make parseError [
+        ("line", load uint64T (var "line"))
+      ])))) <|
+      do_ unit)
+      (do_ unit)) <|
+    seq_ (return_ interface.nil) <|
+    do_ unit)
+
+def errorMessage : Val :=
+  rec_ "errorMessage" ["err"] <|
+    exception_do (let_ "err" (ref_ty error (var "err")) <|
+    seq_ (if_ (binop .ne (load error (var "err")) interface.nil)
+      (seq_ (return_ ((interface.get "Error" (load error (var "err"))) unit)) <|
+      do_ unit)
+      (do_ unit)) <|
+    seq_ (return_ (str "")) <|
+    do_ unit)
+
+def wrapError : Val :=
+  rec_ "wrapError" ["op", "err"] <|
+    exception_do (let_ "err" (ref_ty error (var "err")) <|
+    let_ "op" (ref_ty stringT (var "op")) <|
+    seq_ (return_ (let_ "$fmt0" (load stringT (var "op")) <|
+    let_ "$fmt1" (load error (var "err")) <|
+    errors.Wrap (binop .append (binop .append (var "$fmt0") (str " failed: ")) ((interface.get "Error" (var "$fmt1")) unit)) (var "$fmt1"))) <|
+    do_ unit)
+
+def isNotFound : Val :=
+  rec_ "isNotFound" ["err"] <|
+    exception_do (let_ "err" (ref_ty error (var "err")) <|
+    seq_ (return_ (binop .lor (binop .eq (load error (var "err")) ErrNotFound) (errors.Is (load error (var "err")) ErrNotFound))) <|
+    do_ unit)
+
+def asParseError : Val :=
+  rec_ "asParseError" ["err"] <|
+    exception_do (let_ "err" (ref_ty error (var "err")) <|
+    let_ "pe" (ref_ty ptrT (zero_val ptrT)) <|
+    seq_ (return_ (errors.As (load error (var "err")) (interface.make "github.com/goose-lang/goose/testdata/examples/unittest.parseError'ptr'ptr" [] (var "pe")))) <|
+    do_ unit)
+
+/- higher_order.go -/
+
+def TakesFunctionType : Val :=
+  rec_ "TakesFunctionType" ["f"] <|
+    exception_do (let_ "f" (ref_ty funcT (var "f")) <|
+    seq_ (do_ ((load funcT (var "f")) unit)) <|
+    do_ unit)
+
+/- interfaces.go -/
+
+def Fooer : GoType := interfaceT
+
+def concreteFooer : GoType := structT [
+      ("a", uint64T)
+    ]
+
+def concreteFooer__Foo : Val :=
+  rec_ "concreteFooer__Foo" ["f", "_"] <|
+    exception_do (let_ "f" (ref_ty ptrT (var "f")) <|
+    do_ unit)
+
+def concreteFooer__mset : List (String × Val) := []
+
+def concreteFooer__mset_ptr : List (String × Val) := [("Foo", concreteFooer__Foo)]
+
+def fooConsumer : Val :=
+  rec_ "fooConsumer" ["f"] <|
+    exception_do (let_ "f" (ref_ty Fooer (var "f")) <|
+    seq_ (do_ ((interface.get "Foo" (load Fooer (var "f"))) unit)) <|
+    do_ unit)
+
+def m : Val :=
+  rec_ "m" ["_"] <|
+    exception_do (let_ "c" (ref_ty ptrT (zero_val ptrT)) <|
+    let_ "$a0" (ref_ty concreteFooer (struct.make concreteFooer [
+    ])) <|
+    seq_ (do_ (store ptrT (var "c") (var "$a0"))) <|
+    seq_ (do_ (fooConsumer (interface.make "github.com/goose-lang/goose/testdata/examples/unittest.concreteFooer'ptr" concreteFooer__mset_ptr (load ptrT (var "c"))))) <|
+    let_ "f" (ref_ty Fooer (interface.make "github.com/goose-lang/goose/testdata/examples/unittest.concreteFooer'ptr" concreteFooer__mset_ptr (load ptrT (var "c")))) <|
+    seq_ (do_ (fooConsumer (load Fooer (var "f")))) <|
+    seq_ (do_ ((concreteFooer__Foo (load ptrT (var "c"))) unit)) <|
+    seq_ (do_ ((interface.get "Foo" (load Fooer (var "f"))) unit)) <|
+    do_ unit)
+
+/- ints.go -/
+
+def useInts : Val :=
+  rec_ "useInts" ["x", "y"] <|
+    exception_do (let_ "y" (ref_ty uint32T (var "y")) <|
+    let_ "x" (ref_ty uint64T (var "x")) <|
+    let_ "z" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (to_u64 (load uint32T (var "y"))) <|
+    seq_ (do_ (store uint64T (var "z") (var "$a0"))) <|
+    let_ "$a0" (binop .plus (load uint64T (var "z")) (u64 1)) <|
+    seq_ (do_ (store uint64T (var "z") (var "$a0"))) <|
+    let_ "y2" (ref_ty uint32T (zero_val uint32T)) <|
+    let_ "$a0" (binop .plus (load uint32T (var "y")) (u32 3)) <|
+    seq_ (do_ (store uint32T (var "y2") (var "$a0"))) <|
+    seq_ (return_ (tuple [load uint64T (var "z"), load uint32T (var "y2")])) <|
+    do_ unit)
+
+def my_u32 : GoType := uint32T
+
+def also_u32 : GoType := my_u32
+
+def ConstWithAbbrevType : Expr := u32 3
+
+/- iterators.go -/
+
+def orderedSet : GoType := structT [
+      ("keys", sliceT uint64T)
+    ]
+
+def orderedSet__All : Val :=
+  rec_ "orderedSet__All" ["s", "_"] <|
+    exception_do (let_ "s" (ref_ty ptrT (var "s")) <|
+    seq_ (return_ (lam ["yield"] <|
+      exception_do (let_ "yield" (ref_ty funcT (var "yield")) <|
+      seq_ (do_ (let_ "$range" (load (sliceT uint64T) (struct.field_ref orderedSet "keys" (load ptrT (var "s")))) <|
+      slice.for_range uint64T (var "$range") <| lam ["_", "k"] <|
+        let_ "k" (ref_ty uint64T (var "k")) <|
+        seq_ (if_ (not_ ((load funcT (var "yield")) (load uint64T (var "k"))))
+          (seq_ (return_ unit) <|
+          do_ unit)
+          (do_ unit)) <|
+        do_ unit)) <|
+      do_ unit))) <|
+    do_ unit)
+
+def orderedSet__Enumerate : Val :=
+  rec_ "orderedSet__Enumerate" ["s", "_"] <|
+    exception_do (let_ "s" (ref_ty ptrT (var "s")) <|
+    seq_ (return_ (lam ["yield"] <|
+      exception_do (let_ "yield" (ref_ty funcT (var "yield")) <|
+      let_ "i" (ref_ty uint64T (zero_val uint64T)) <|
+      seq_ (do_ (let_ "$range" (load (sliceT uint64T) (struct.field_ref orderedSet "keys" (load ptrT (var "s")))) <|
+      slice.for_range uint64T (var "$range") <| lam ["_", "k"] <|
+        let_ "k" (ref_ty uint64T (var "k")) <|
+        seq_ (if_ (not_ ((load funcT (var "yield")) (load uint64T (var "i")) (load uint64T (var "k"))))
+          (seq_ (return_ unit) <|
+          do_ unit)
+          (do_ unit)) <|
+        seq_ (do_ (store uint64T (var "i") (binop .plus (load uint64T (var "i")) (u64 1)))) <|
+        do_ unit)) <|
+      do_ unit))) <|
+    do_ unit)
+
+def orderedSet__mset : List (String × Val) := []
+
+def orderedSet__mset_ptr : List (String × Val) := [("All", orderedSet__All), ("Enumerate", orderedSet__Enumerate)]
+
+def sumBelow : Val :=
+  rec_ "sumBelow" ["s", "max"] <|
+    exception_do (let_ "max" (ref_ty uint64T (var "max")) <|
+    let_ "s" (ref_ty ptrT (var "s")) <|
+    let_ "sum" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (do_ (((orderedSet__All (load ptrT (var "s"))) unit) (lam ["k"] <|
+      exception_do (let_ "k" (ref_ty uint64T (var "k")) <|
+      seq_ (if_ (binop .ge (load uint64T (var "k")) (load uint64T (var "max")))
+        (seq_ (return_ (bool false)) <|
+        do_ unit)
+        (do_ unit)) <|
+      seq_ (if_ (binop .eq (load uint64T (var "k")) (u64 0))
+        (seq_ (return_ (bool true)) <|
+        do_ unit)
+        (do_ unit)) <|
+      seq_ (do_ (store uint64T (var "sum") (binop .plus (load uint64T (var "sum")) (load uint64T (var "k"))))) <|
+      return_ (bool true))))) <|
+    seq_ (return_ (load uint64T (var "sum"))) <|
+    do_ unit)
+
+def countElements : Val :=
+  rec_ "countElements" ["s"] <|
+    exception_do (let_ "s" (ref_ty ptrT (var "s")) <|
+    let_ "n" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (do_ (((orderedSet__All (load ptrT (var "s"))) unit) (lam ["_"] <|
+      exception_do (seq_ (do_ (store uint64T (var "n") (binop .plus (load uint64T (var "n")) (u64 1)))) <|
+      return_ (bool true))))) <|
+    seq_ (return_ (load uint64T (var "n"))) <|
+    do_ unit)
+
+def findIndex : Val :=
+  rec_ "findIndex" ["s", "key"] <|
+    exception_do (let_ "key" (ref_ty uint64T (var "key")) <|
+    let_ "s" (ref_ty ptrT (var "s")) <|
+    seq_ (do_ (let_ "$returned" (ref_ty boolT (bool false)) <|
+    let_ "$ret" (ref_ty (tupleT [uint64T, boolT]) (zero_val (tupleT [uint64T, boolT]))) <|
+    (seq_ (do_ (((orderedSet__Enumerate (load ptrT (var "s"))) unit) (lam ["i", "k"] <|
+      exception_do (let_ "i" (ref_ty uint64T (var "i")) <|
+      let_ "k" (ref_ty uint64T (var "k")) <|
+      seq_ (if_ (binop .eq (load uint64T (var "k")) (load uint64T (var "key")))
+        (seq_ (do_ (store (tupleT [uint64T, boolT]) (var "$ret") (tuple [load uint64T (var "i"), bool true]))) <|
+        seq_ (do_ (store boolT (var "$returned") (bool true))) <|
+        seq_ (return_ (bool false)) <|
+        do_ unit)
+        (do_ unit)) <|
+      return_ (bool true))))) <|
+    (if_ (load boolT (var "$returned"))
+      (return_ (load (tupleT [uint64T, boolT]) (var "$ret")))
+      (do_ unit))))) <|
+    seq_ (return_ (tuple [u64 0, bool false])) <|
+    do_ unit)
+
+def findPair : Val :=
+  rec_ "findPair" ["s", "t", "sum"] <|
+    exception_do (let_ "sum" (ref_ty uint64T (var "sum")) <|
+    let_ "t" (ref_ty ptrT (var "t")) <|
+    let_ "s" (ref_ty ptrT (var "s")) <|
+    seq_ (do_ (let_ "$returned" (ref_ty boolT (bool false)) <|
+    let_ "$ret" (ref_ty boolT (zero_val boolT)) <|
+    (seq_ (do_ (((orderedSet__All (load ptrT (var "s"))) unit) (lam ["a"] <|
+      exception_do (let_ "a" (ref_ty uint64T (var "a")) <|
+      seq_ (do_ (seq_ (do_ (((orderedSet__All (load ptrT (var "t"))) unit) (lam ["b"] <|
+        exception_do (let_ "b" (ref_ty uint64T (var "b")) <|
+        seq_ (if_ (binop .eq (binop .plus (load uint64T (var "a")) (load uint64T (var "b"))) (load uint64T (var "sum")))
+          (seq_ (do_ (store boolT (var "$ret") (bool true))) <|
+          seq_ (do_ (store boolT (var "$returned") (bool true))) <|
+          seq_ (return_ (bool false)) <|
+          do_ unit)
+          (do_ unit)) <|
+        return_ (bool true))))) <|
+      (if_ (load boolT (var "$returned"))
+        (return_ (bool false))
+        (do_ unit)))) <|
+      return_ (bool true))))) <|
+    (if_ (load boolT (var "$returned"))
+      (return_ (load boolT (var "$ret")))
+      (do_ unit))))) <|
+    seq_ (return_ (bool false)) <|
+    do_ unit)
+
+/- literals.go -/
+
+def allTheLiterals : GoType := structT [
+      ("int", uint64T),
+      ("s", stringT),
+      ("b", boolT)
+    ]
+
+def normalLiterals : Val :=
+  rec_ "normalLiterals" ["_"] <|
+    exception_do (seq_ (return_ (struct.make allTheLiterals [
+      ("int", u64 0),
+      ("s", str "foo"),
+      ("b", bool true)
+    ])) <|
+    do_ unit)
+
+def specialLiterals : Val :=
+  rec_ "specialLiterals" ["_"] <|
+    exception_do (seq_ (return_ (struct.make allTheLiterals [
+      ("int", u64 4096),
+      ("s", str ""),
+      ("b", bool false)
+    ])) <|
+    do_ unit)
+
+def oddLiterals : Val :=
+  rec_ "oddLiterals" ["_"] <|
+    exception_do (seq_ (return_ (struct.make allTheLiterals [
+      ("int", u64 5),
+      ("s", str "backquote string"),
+      ("b", bool false)
+    ])) <|
+    do_ unit)
+
+def unKeyedLiteral : Val :=
+  rec_ "unKeyedLiteral" ["_"] <|
+    exception_do (seq_ (return_ (struct.make allTheLiterals [
+      ("int", u64 0),
+      ("s", str "a"),
+      ("b", bool false)
+    ])) <|
+    do_ unit)
+
+/- locks.go -/
+
+def useLocks : Val :=
+  rec_ "useLocks" ["_"] <|
+    exception_do (let_ "m" (ref_ty ptrT (zero_val ptrT)) <|
+    let_ "$a0" (ref_ty sync.Mutex (zero_val sync.Mutex)) <|
+    seq_ (do_ (store ptrT (var "m") (var "$a0"))) <|
+    seq_ (do_ ((sync.Mutex__Lock (load ptrT (var "m"))) unit)) <|
+    seq_ (do_ ((sync.Mutex__Unlock (load ptrT (var "m"))) unit)) <|
+    do_ unit)
+
+def useCondVar : Val :=
+  rec_ "useCondVar" ["_"] <|
+    exception_do (let_ "m" (ref_ty ptrT (zero_val ptrT)) <|
+    let_ "$a0" (ref_ty sync.Mutex (zero_val sync.Mutex)) <|
+    seq_ (do_ (store ptrT (var "m") (var "$a0"))) <|
+    let_ "c" (ref_ty ptrT (zero_val ptrT)) <|
+    let_ "$a0" (sync.NewCond (load ptrT (var "m"))) <|
+    seq_ (do_ (store ptrT (var "c") (var "$a0"))) <|
+    seq_ (do_ ((sync.Mutex__Lock (load ptrT (var "m"))) unit)) <|
+    seq_ (do_ ((sync.Cond__Signal (load ptrT (var "c"))) unit)) <|
+    seq_ (do_ ((sync.Cond__Wait (load ptrT (var "c"))) unit)) <|
+    seq_ (do_ ((sync.Mutex__Unlock (load ptrT (var "m"))) unit)) <|
+    do_ unit)
+
+def hasCondVar : GoType := structT [
+      ("cond", ptrT)
+    ]
+
+/- log_debugging.go -/
+
+def ToBeDebugged : Val :=
+  rec_ "ToBeDebugged" ["x"] <|
+    exception_do (let_ "x" (ref_ty uint64T (var "x")) <|
+    seq_ (do_ (log.Println (str "starting function"))) <|
+    seq_ (do_ (log.Printf (str "called with %d") (load uint64T (var "x")))) <|
+    seq_ (do_ (log.Println (str "ending function"))) <|
+    seq_ (return_ (load uint64T (var "x"))) <|
+    do_ unit)
+
+def DoNothing : Val :=
+  rec_ "DoNothing" ["_"] <|
+    exception_do (seq_ (do_ (log.Println (str "doing nothing"))) <|
+    do_ unit)
+
+/- loops.go -/
+
+/- DoSomething is an impure function -/
+def DoSomething : Val :=
+  rec_ "DoSomething" ["s"] <|
+    exception_do (let_ "s" (ref_ty stringT (var "s")) <|
+    do_ unit)
+
+def standardForLoop : Val :=
+  rec_ "standardForLoop" ["s"] <|
+    exception_do (let_ "s" (ref_ty (sliceT uint64T) (var "s")) <|
+    let_ "sumPtr" (ref_ty ptrT (zero_val ptrT)) <|
+    let_ "$a0" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (do_ (store ptrT (var "sumPtr") (var "$a0"))) <|
+    seq_ (let_ "i" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 0) <|
+    seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+    (for_ (lam ["_"] <| bool true) (lam ["_"] <| Skip) <|
+      lam ["_"] <|
+      seq_ (if_ (binop .lt (load uint64T (var "i")) (slice.len (load (sliceT uint64T) (var "s"))))
+        (let_ "sum" (ref_ty uint64T (zero_val uint64T)) <|
+        let_ "$a0" (load uint64T (load ptrT (var "sumPtr"))) <|
+        seq_ (do_ (store uint64T (var "sum") (var "$a0"))) <|
+        let_ "x" (ref_ty uint64T (zero_val uint64T)) <|
+        let_ "$a0" (load uint64T (slice.elem_ref uint64T (load (sliceT uint64T) (var "s")) (load uint64T (var "i")))) <|
+        seq_ (do_ (store uint64T (var "x") (var "$a0"))) <|
+        let_ "$a0" (binop .plus (load uint64T (var "sum")) (load uint64T (var "x"))) <|
+        seq_ (do_ (store uint64T (load ptrT (var "sumPtr")) (var "$a0"))) <|
+        let_ "$a0" (binop .plus (load uint64T (var "i")) (u64 1)) <|
+        seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+        seq_ continue_ <|
+        do_ unit)
+        (do_ unit)) <|
+      seq_ break_ <|
+      do_ unit)) <|
+    let_ "sum" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (load uint64T (load ptrT (var "sumPtr"))) <|
+    seq_ (do_ (store uint64T (var "sum") (var "$a0"))) <|
+    seq_ (return_ (load uint64T (var "sum"))) <|
+    do_ unit)
+
+def conditionalInLoop : Val :=
+  rec_ "conditionalInLoop" ["_"] <|
+    exception_do (seq_ (let_ "i" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 0) <|
+    seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+    (for_ (lam ["_"] <| bool true) (lam ["_"] <| Skip) <|
+      lam ["_"] <|
+      seq_ (if_ (binop .lt (load uint64T (var "i")) (u64 3))
+        (seq_ (do_ (DoSomething (str "i is small"))) <|
+        do_ unit)
+        (do_ unit)) <|
+      seq_ (if_ (binop .gt (load uint64T (var "i")) (u64 5))
+        (seq_ break_ <|
+        do_ unit)
+        (do_ unit)) <|
+      let_ "$a0" (binop .plus (load uint64T (var "i")) (u64 1)) <|
+      seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+      seq_ continue_ <|
+      do_ unit)) <|
+    do_ unit)
+
+def conditionalInLoopElse : Val :=
+  rec_ "conditionalInLoopElse" ["_"] <|
+    exception_do (seq_ (let_ "i" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 0) <|
+    seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+    (for_ (lam ["_"] <| bool true) (lam ["_"] <| Skip) <|
+      lam ["_"] <|
+      seq_ (if_ (binop .gt (load uint64T (var "i")) (u64 5))
+        (seq_ break_ <|
+        do_ unit)
+        (let_ "$a0" (binop .plus (load uint64T (var "i")) (u64 1)) <|
+        seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+        seq_ continue_ <|
+        do_ unit)) <|
+      do_ unit)) <|
+    do_ unit)
+
+def nestedConditionalInLoopImplicitContinue : Val :=
+  rec_ "nestedConditionalInLoopImplicitContinue" ["_"] <|
+    exception_do (seq_ (let_ "i" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 0) <|
+    seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+    (for_ (lam ["_"] <| bool true) (lam ["_"] <| Skip) <|
+      lam ["_"] <|
+      seq_ (if_ (binop .gt (load uint64T (var "i")) (u64 5))
+        (seq_ (if_ (binop .gt (load uint64T (var "i")) (u64 10))
+          (seq_ break_ <|
+          do_ unit)
+          (do_ unit)) <|
+        do_ unit)
+        (let_ "$a0" (binop .plus (load uint64T (var "i")) (u64 1)) <|
+        seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+        seq_ continue_ <|
+        do_ unit)) <|
+      do_ unit)) <|
+    do_ unit)
+
+def ImplicitLoopContinue : Val :=
+  rec_ "ImplicitLoopContinue" ["_"] <|
+    exception_do (seq_ (let_ "i" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 0) <|
+    seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+    (for_ (lam ["_"] <| bool true) (lam ["_"] <| Skip) <|
+      lam ["_"] <|
+      seq_ (if_ (binop .lt (load uint64T (var "i")) (u64 4))
+        (let_ "$a0" (u64 0) <|
+        seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+        do_ unit)
+        (do_ unit)) <|
+      do_ unit)) <|
+    do_ unit)
+
+def ImplicitLoopContinue2 : Val :=
+  rec_ "ImplicitLoopContinue2" ["_"] <|
+    exception_do (seq_ (let_ "i" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 0) <|
+    seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+    (for_ (lam ["_"] <| bool true) (lam ["_"] <| Skip) <|
+      lam ["_"] <|
+      seq_ (if_ (binop .lt (load uint64T (var "i")) (u64 4))
+        (let_ "$a0" (u64 0) <|
+        seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+        seq_ continue_ <|
+        do_ unit)
+        (do_ unit)) <|
+      do_ unit)) <|
+    do_ unit)
+
+def ImplicitLoopContinueAfterIfBreak : Val :=
+  rec_ "ImplicitLoopContinueAfterIfBreak" ["i"] <|
+    exception_do (let_ "i" (ref_ty uint64T (var "i")) <|
+    seq_ (for_ (lam ["_"] <| bool true) (lam ["_"] <| Skip) <|
+      lam ["_"] <|
+      seq_ (if_ (binop .gt (load uint64T (var "i")) (u64 0))
+        (seq_ break_ <|
+        do_ unit)
+        (do_ unit)) <|
+      do_ unit) <|
+    do_ unit)
+
+def nestedLoops : Val :=
+  rec_ "nestedLoops" ["_"] <|
+    exception_do (seq_ (let_ "i" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 0) <|
+    seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+    (for_ (lam ["_"] <| bool true) (lam ["_"] <| Skip) <|
+      lam ["_"] <|
+      seq_ (let_ "j" (ref_ty uint64T (zero_val uint64T)) <|
+      let_ "$a0" (u64 0) <|
+      seq_ (do_ (store uint64T (var "j") (var "$a0"))) <|
+      (for_ (lam ["_"] <| bool true) (lam ["_"] <| Skip) <|
+        lam ["_"] <|
+        seq_ (if_ (bool true)
+          (seq_ break_ <|
+          do_ unit)
+          (do_ unit)) <|
+        let_ "$a0" (binop .plus (load uint64T (var "j")) (u64 1)) <|
+        seq_ (do_ (store uint64T (var "j") (var "$a0"))) <|
+        seq_ continue_ <|
+        do_ unit)) <|
+      let_ "$a0" (binop .plus (load uint64T (var "i")) (u64 1)) <|
+      seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+      seq_ continue_ <|
+      do_ unit)) <|
+    do_ unit)
+
+def nestedGoStyleLoops : Val :=
+  rec_ "nestedGoStyleLoops" ["_"] <|
+    exception_do (seq_ (let_ "i" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 0) <|
+    seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+    (for_ (lam ["_"] <| binop .lt (load uint64T (var "i")) (u64 10)) (lam ["_"] <| seq_ (do_ (store uint64T (var "i") (binop .plus (load uint64T (var "i")) (u64 1)))) <|
+    unit) <|
+      lam ["_"] <|
+      seq_ (let_ "j" (ref_ty uint64T (zero_val uint64T)) <|
+      let_ "$a0" (u64 0) <|
+      seq_ (do_ (store uint64T (var "j") (var "$a0"))) <|
+      (for_ (lam ["_"] <| binop .lt (load uint64T (var "j")) (load uint64T (var "i"))) (lam ["_"] <| seq_ (do_ (store uint64T (var "j") (binop .plus (load uint64T (var "j")) (u64 1)))) <|
+      unit) <|
+        lam ["_"] <|
+        seq_ (if_ (bool true)
+          (seq_ break_ <|
+          do_ unit)
+          (do_ unit)) <|
+        seq_ continue_ <|
+        do_ unit)) <|
+      do_ unit)) <|
+    do_ unit)
+
+def sumSlice : Val :=
+  rec_ "sumSlice" ["xs"] <|
+    exception_do (let_ "xs" (ref_ty (sliceT uint64T) (var "xs")) <|
+    let_ "sum" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (do_ (let_ "$range" (load (sliceT uint64T) (var "xs")) <|
+    slice.for_range uint64T (var "$range") <| lam ["_", "x"] <|
+      let_ "x" (ref_ty uint64T (var "x")) <|
+      seq_ (do_ (store uint64T (var "sum") (binop .plus (load uint64T (var "sum")) (load uint64T (var "x"))))) <|
+      do_ unit)) <|
+    seq_ (return_ (load uint64T (var "sum"))) <|
+    do_ unit)
+
+def breakFromLoop : Val :=
+  rec_ "breakFromLoop" ["_"] <|
+    exception_do (seq_ (for_ (lam ["_"] <| bool true) (lam ["_"] <| Skip) <|
+      lam ["_"] <|
+      seq_ (if_ (bool true)
+        (seq_ break_ <|
+        do_ unit)
+        (do_ unit)) <|
+      seq_ continue_ <|
+      do_ unit) <|
+    do_ unit)
+
+/- maps.go -/
+
+def clearMap : Val :=
+  rec_ "clearMap" ["m"] <|
+    exception_do (let_ "m" (ref_ty (mapT uint64T uint64T) (var "m")) <|
+    seq_ (do_ (machine.MapClear (load (mapT uint64T uint64T) (var "m")))) <|
+    do_ unit)
+
+def IterateMapKeys : Val :=
+  rec_ "IterateMapKeys" ["m", "sum"] <|
+    exception_do (let_ "sum" (ref_ty ptrT (var "sum")) <|
+    let_ "m" (ref_ty (mapT uint64T uint64T) (var "m")) <|
+    seq_ (do_ (MapIter (load (mapT uint64T uint64T) (var "m")) <| lam ["k", "_"] <|
+      let_ "oldSum" (ref_ty uint64T (zero_val uint64T)) <|
+      let_ "$a0" (load uint64T (load ptrT (var "sum"))) <|
+      seq_ (do_ (store uint64T (var "oldSum") (var "$a0"))) <|
+      let_ "$a0" (binop .plus (load uint64T (var "oldSum")) (load uint64T (var "k"))) <|
+      seq_ (do_ (store uint64T (load ptrT (var "sum")) (var "$a0"))) <|
+      do_ unit)) <|
+    do_ unit)
+
+def MapSize : Val :=
+  rec_ "MapSize" ["m"] <|
+    exception_do (let_ "m" (ref_ty (mapT uint64T boolT) (var "m")) <|
+    seq_ (return_ (MapLen (load (mapT uint64T boolT) (var "m")))) <|
+    do_ unit)
+
+def IntWrapper : GoType := uint64T
+
+def MapWrapper : GoType := mapT uint64T boolT
+
+def MapTypeAliases : Val :=
+  rec_ "MapTypeAliases" ["m1", "m2"] <|
+    exception_do (let_ "m2" (ref_ty MapWrapper (var "m2")) <|
+    let_ "m1" (ref_ty (mapT IntWrapper boolT) (var "m1")) <|
+    let_ "$a0" (Fst (map.get (load MapWrapper (var "m2")) (u64 0))) <|
+    seq_ (do_ (map.insert (load (mapT IntWrapper boolT) (var "m1")) (u64 4) (var "$a0"))) <|
+    do_ unit)
+
+def StringMap : Val :=
+  rec_ "StringMap" ["m"] <|
+    exception_do (let_ "m" (ref_ty (mapT stringT uint64T) (var "m")) <|
+    seq_ (return_ (Fst (map.get (load (mapT stringT uint64T) (var "m")) (str "foo")))) <|
+    do_ unit)
+
+/- multiple.go -/
+
+def returnTwo : Val :=
+  rec_ "returnTwo" ["p"] <|
+    exception_do (let_ "p" (ref_ty (sliceT byteT) (var "p")) <|
+    seq_ (return_ (tuple [u64 0, u64 0])) <|
+    do_ unit)
+
+def returnTwoWrapper : Val :=
+  rec_ "returnTwoWrapper" ["data"] <|
+    exception_do (let_ "data" (ref_ty (sliceT byteT) (var "data")) <|
+    let_ "b" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "a" (ref_ty uint64T (zero_val uint64T)) <|
+    lets_ ["$a0", "$a1"] (returnTwo (load (sliceT byteT) (var "data"))) <|
+    seq_ (do_ (store uint64T (var "b") (var "$a1"))) <|
+    seq_ (do_ (store uint64T (var "a") (var "$a0"))) <|
+    seq_ (return_ (tuple [load uint64T (var "a"), load uint64T (var "b")])) <|
+    do_ unit)
+
+def multipleVar : Val :=
+  rec_ "multipleVar" ["x", "y"] <|
+    exception_do (let_ "y" (ref_ty uint64T (var "y")) <|
+    let_ "x" (ref_ty uint64T (var "x")) <|
+    do_ unit)
+
+/- nil.go -/
+
+def AssignNilSlice : Val :=
+  rec_ "AssignNilSlice" ["_"] <|
+    exception_do (let_ "s" (ref_ty (sliceT (sliceT byteT)) (zero_val (sliceT (sliceT byteT)))) <|
+    let_ "$a0" (slice.make2 (sliceT byteT) (u64 4)) <|
+    seq_ (do_ (store (sliceT (sliceT byteT)) (var "s") (var "$a0"))) <|
+    let_ "$a0" slice.nil <|
+    seq_ (do_ (store (sliceT byteT) (slice.elem_ref (sliceT byteT) (load (sliceT (sliceT byteT)) (var "s")) (u64 2)) (var "$a0"))) <|
+    do_ unit)
+
+def AssignNilPointer : Val :=
+  rec_ "AssignNilPointer" ["_"] <|
+    exception_do (let_ "s" (ref_ty (sliceT ptrT) (zero_val (sliceT ptrT))) <|
+    let_ "$a0" (slice.make2 ptrT (u64 4)) <|
+    seq_ (do_ (store (sliceT ptrT) (var "s") (var "$a0"))) <|
+    let_ "$a0" slice.nil <|
+    seq_ (do_ (store ptrT (slice.elem_ref ptrT (load (sliceT ptrT) (var "s")) (u64 2)) (var "$a0"))) <|
+    do_ unit)
+
+def CompareSliceToNil : Val :=
+  rec_ "CompareSliceToNil" ["_"] <|
+    exception_do (let_ "s" (ref_ty (sliceT byteT) (zero_val (sliceT byteT))) <|
+    let_ "$a0" (slice.make2 byteT (u64 0)) <|
+    seq_ (do_ (store (sliceT byteT) (var "s") (var "$a0"))) <|
+    seq_ (return_ (binop .ne (load (sliceT byteT) (var "s")) slice.nil)) <|
+    do_ unit)
+
+def ComparePointerToNil : Val :=
+  rec_ "ComparePointerToNil" ["_"] <|
+    exception_do (let_ "s" (ref_ty ptrT (zero_val ptrT)) <|
+    let_ "$a0" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (do_ (store ptrT (var "s") (var "$a0"))) <|
+    seq_ (return_ (binop .ne (load ptrT (var "s")) null)) <|
+    do_ unit)
+
+/- operators.go -/
+
+def LogicalOperators : Val :=
+  rec_ "LogicalOperators" ["b1", "b2"] <|
+    exception_do (let_ "b2" (ref_ty boolT (var "b2")) <|
+    let_ "b1" (ref_ty boolT (var "b1")) <|
+    seq_ (return_ (binop .land (binop .land (load boolT (var "b1")) (binop .lor (load boolT (var "b2")) (load boolT (var "b1")))) (not_ (bool false)))) <|
+    do_ unit)
+
+def LogicalAndEqualityOperators : Val :=
+  rec_ "LogicalAndEqualityOperators" ["b1", "x"] <|
+    exception_do (let_ "x" (ref_ty uint64T (var "x")) <|
+    let_ "b1" (ref_ty boolT (var "b1")) <|
+    seq_ (return_ (binop .land (binop .eq (load uint64T (var "x")) (u64 3)) (binop .eq (load boolT (var "b1")) (bool true)))) <|
+    do_ unit)
+
+def ArithmeticShifts : Val :=
+  rec_ "ArithmeticShifts" ["x", "y"] <|
+    exception_do (let_ "y" (ref_ty uint64T (var "y")) <|
+    let_ "x" (ref_ty uint32T (var "x")) <|
+    seq_ (return_ (binop .plus (binop .plus (to_u64 (binop .shl (load uint32T (var "x")) (u64 3))) (binop .shl (load uint64T (var "y")) (to_u64 (load uint32T (var "x"))))) (binop .shl (load uint64T (var "y")) (u64 1)))) <|
+    do_ unit)
+
+def BitwiseOps : Val :=
+  rec_ "BitwiseOps" ["x", "y"] <|
+    exception_do (let_ "y" (ref_ty uint64T (var "y")) <|
+    let_ "x" (ref_ty uint32T (var "x")) <|
+    seq_ (return_ (binop .or (to_u64 (load uint32T (var "x"))) (binop .and (to_u64 (to_u32 (load uint64T (var "y")))) (u64 43)))) <|
+    do_ unit)
+
+def Comparison : Val :=
+  rec_ "Comparison" ["x", "y"] <|
+    exception_do (let_ "y" (ref_ty uint64T (var "y")) <|
+    let_ "x" (ref_ty uint64T (var "x")) <|
+    seq_ (if_ (binop .lt (load uint64T (var "x")) (load uint64T (var "y")))
+      (seq_ (return_ (bool true)) <|
+      do_ unit)
+      (do_ unit)) <|
+    seq_ (if_ (binop .eq (load uint64T (var "x")) (load uint64T (var "y")))
+      (seq_ (return_ (bool true)) <|
+      do_ unit)
+      (do_ unit)) <|
+    seq_ (if_ (binop .ne (load uint64T (var "x")) (load uint64T (var "y")))
+      (seq_ (return_ (bool true)) <|
+      do_ unit)
+      (do_ unit)) <|
+    seq_ (if_ (binop .gt (load uint64T (var "x")) (load uint64T (var "y")))
+      (seq_ (return_ (bool true)) <|
+      do_ unit)
+      (do_ unit)) <|
+    seq_ (if_ (binop .gt (binop .plus (load uint64T (var "x")) (u64 1)) (binop .minus (load uint64T (var "y")) (u64 2)))
+      (seq_ (return_ (bool true)) <|
+      do_ unit)
+      (do_ unit)) <|
+    seq_ (return_ (bool false)) <|
+    do_ unit)
+
+def AssignOps : Val :=
+  rec_ "AssignOps" ["_"] <|
+    exception_do (let_ "x" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (do_ (store uint64T (var "x") (binop .plus (load uint64T (var "x")) (u64 3)))) <|
+    seq_ (do_ (store uint64T (var "x") (binop .minus (load uint64T (var "x")) (u64 3)))) <|
+    seq_ (do_ (store uint64T (var "x") (binop .plus (load uint64T (var "x")) (u64 1)))) <|
+    seq_ (do_ (store uint64T (var "x") (binop .minus (load uint64T (var "x")) (u64 1)))) <|
+    do_ unit)
+
+/- package.go -/
+
+/- unittest has two package comments -/
+
+def wrapExternalStruct : GoType := structT [
+      ("e", marshal.Enc),
+      ("d", marshal.Dec)
+    ]
+
+def wrapExternalStruct__moveUint64 : Val :=
+  rec_ "wrapExternalStruct__moveUint64" ["w", "_"] <|
+    exception_do (let_ "w" (ref_ty wrapExternalStruct (var "w")) <|
+    seq_ (do_ ((marshal.Enc__PutInt (load marshal.Enc (struct.field_ref wrapExternalStruct "e" (var "w")))) ((marshal.Dec__GetInt (load marshal.Dec (struct.field_ref wrapExternalStruct "d" (var "w")))) unit))) <|
+    do_ unit)
+
+def wrapExternalStruct__mset : List (String × Val) := [("moveUint64", wrapExternalStruct__moveUint64)]
+
+def wrapExternalStruct__mset_ptr : List (String × Val) := [("moveUint64", (lam ["$recvAddr"] <|
+      wrapExternalStruct__moveUint64 (load wrapExternalStruct (var "$recvAddr"))))]
+
+/- panic.go -/
+
+def PanicAtTheDisco : Val :=
+  rec_ "PanicAtTheDisco" ["_"] <|
+    exception_do (seq_ (do_ (Panic "disco")) <|
+    do_ unit)
+
+/- proph.go -/
+
+def Oracle : Val :=
+  rec_ "Oracle" ["_"] <|
+    exception_do (let_ "p" (ref_ty ProphIdT (zero_val ProphIdT)) <|
+    let_ "$a0" (machine.NewProph unit) <|
+    seq_ (do_ (store ProphIdT (var "p") (var "$a0"))) <|
+    seq_ (do_ ((machine.prophId__ResolveBool (load ProphIdT (var "p"))) (bool false))) <|
+    seq_ (do_ ((machine.prophId__ResolveU64 (load ProphIdT (var "p"))) (u64 0))) <|
+    do_ unit)
+
+def typing : GoType := structT [
+      ("proph", ProphIdT)
+    ]
+
+/- range_loops.go -/
+
+def rangeInt : Val :=
+  rec_ "rangeInt" ["n"] <|
+    exception_do (let_ "n" (ref_ty uint64T (var "n")) <|
+    let_ "sum" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (do_ (let_ "$range" (load uint64T (var "n")) <|
+    let_ "$i" (ref_ty uint64T (u64 0)) <|
+    (for_ (lam ["_"] <| binop .lt (load uint64T (var "$i")) (var "$range")) (lam ["_"] <| store uint64T (var "$i") (binop .plus (load uint64T (var "$i")) (u64 1))) <|
+      lam ["_"] <|
+      let_ "i" (ref_ty uint64T (load uint64T (var "$i"))) <|
+      seq_ (do_ (store uint64T (var "sum") (binop .plus (load uint64T (var "sum")) (load uint64T (var "i"))))) <|
+      do_ unit))) <|
+    seq_ (return_ (load uint64T (var "sum"))) <|
+    do_ unit)
+
+def rangeConstant : Val :=
+  rec_ "rangeConstant" ["_"] <|
+    exception_do (let_ "sum" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (do_ (let_ "$range" (u64 10) <|
+    let_ "$i" (ref_ty intT (u64 0)) <|
+    (for_ (lam ["_"] <| binop .lt (load intT (var "$i")) (var "$range")) (lam ["_"] <| store intT (var "$i") (binop .plus (load intT (var "$i")) (u64 1))) <|
+      lam ["_"] <|
+      let_ "i" (ref_ty intT (load intT (var "$i"))) <|
+      seq_ (do_ (store uint64T (var "sum") (binop .plus (load uint64T (var "sum")) (load intT (var "i"))))) <|
+      do_ unit))) <|
+    seq_ (return_ (load uint64T (var "sum"))) <|
+    do_ unit)
+
+def rangeLabelled : Val :=
+  rec_ "rangeLabelled" ["s"] <|
+    exception_do (let_ "s" (ref_ty (sliceT uint64T) (var "s")) <|
+    let_ "sum" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (do_ (let_ "$range" (load (sliceT uint64T) (var "s")) <|
+    slice.for_range uint64T (var "$range") <| lam ["_", "x"] <|
+      let_ "x" (ref_ty uint64T (var "x")) <|
+      seq_ (if_ (binop .eq (load uint64T (var "x")) (u64 0))
+        (seq_ continue_ <|
+        do_ unit)
+        (do_ unit)) <|
+      seq_ (do_ (store uint64T (var "sum") (binop .plus (load uint64T (var "sum")) (load uint64T (var "x"))))) <|
+      do_ unit)) <|
+    seq_ (return_ (load uint64T (var "sum"))) <|
+    do_ unit)
+
+def rangeIntNoVar : Val :=
+  rec_ "rangeIntNoVar" ["n"] <|
+    exception_do (let_ "n" (ref_ty uint32T (var "n")) <|
+    let_ "count" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (do_ (let_ "$range" (load uint32T (var "n")) <|
+    let_ "$i" (ref_ty uint32T (u32 0)) <|
+    (for_ (lam ["_"] <| binop .lt (load uint32T (var "$i")) (var "$range")) (lam ["_"] <| store uint32T (var "$i") (binop .plus (load uint32T (var "$i")) (u32 1))) <|
+      lam ["_"] <|
+      seq_ (do_ (store uint64T (var "count") (binop .plus (load uint64T (var "count")) (u64 1)))) <|
+      do_ unit))) <|
+    seq_ (return_ (load uint64T (var "count"))) <|
+    do_ unit)
+
+def rangeSliceNoVars : Val :=
+  rec_ "rangeSliceNoVars" ["s"] <|
+    exception_do (let_ "s" (ref_ty (sliceT uint64T) (var "s")) <|
+    let_ "count" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (do_ (let_ "$range" (load (sliceT uint64T) (var "s")) <|
+    slice.for_range uint64T (var "$range") <| lam ["_", "_"] <|
+      seq_ (do_ (store uint64T (var "count") (binop .plus (load uint64T (var "count")) (u64 1)))) <|
+      do_ unit)) <|
+    seq_ (return_ (load uint64T (var "count"))) <|
+    do_ unit)
+
+def rangeMapNoVars : Val :=
+  rec_ "rangeMapNoVars" ["m"] <|
+    exception_do (let_ "m" (ref_ty (mapT uint64T boolT) (var "m")) <|
+    let_ "count" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (do_ (MapIter (load (mapT uint64T boolT) (var "m")) <| lam ["_", "_"] <|
+      seq_ (do_ (store uint64T (var "count") (binop .plus (load uint64T (var "count")) (u64 1)))) <|
+      do_ unit)) <|
+    seq_ (return_ (load uint64T (var "count"))) <|
+    do_ unit)
+
+def loopCompoundPost : Val :=
+  rec_ "loopCompoundPost" ["n"] <|
+    exception_do (let_ "n" (ref_ty uint64T (var "n")) <|
+    let_ "steps" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (let_ "i" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 1) <|
+    seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+    (for_ (lam ["_"] <| binop .lt (load uint64T (var "i")) (load uint64T (var "n"))) (lam ["_"] <| seq_ (do_ (store uint64T (var "i") (binop .mul (load uint64T (var "i")) (u64 2)))) <|
+    unit) <|
+      lam ["_"] <|
+      seq_ (do_ (store uint64T (var "steps") (binop .plus (load uint64T (var "steps")) (u64 1)))) <|
+      do_ unit)) <|
+    seq_ (return_ (load uint64T (var "steps"))) <|
+    do_ unit)
+
+def loopMultipleAssign : Val :=
+  rec_ "loopMultipleAssign" ["s"] <|
+    exception_do (let_ "s" (ref_ty (sliceT byteT) (var "s")) <|
+    seq_ (let_ "j" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "i" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 0) <|
+    let_ "$a1" (slice.len (load (sliceT byteT) (var "s"))) <|
+    seq_ (do_ (store uint64T (var "j") (var "$a1"))) <|
+    seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+    (for_ (lam ["_"] <| binop .lt (load uint64T (var "i")) (load uint64T (var "j"))) (lam ["_"] <| let_ "$a0" (binop .plus (load uint64T (var "i")) (u64 1)) <|
+    let_ "$a1" (binop .minus (load uint64T (var "j")) (u64 1)) <|
+    seq_ (do_ (store uint64T (var "j") (var "$a1"))) <|
+    seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+    unit) <|
+      lam ["_"] <|
+      let_ "x" (ref_ty byteT (zero_val byteT)) <|
+      let_ "$a0" (load byteT (slice.elem_ref byteT (load (sliceT byteT) (var "s")) (load uint64T (var "i")))) <|
+      seq_ (do_ (store byteT (var "x") (var "$a0"))) <|
+      let_ "$a0" (load byteT (slice.elem_ref byteT (load (sliceT byteT) (var "s")) (binop .minus (load uint64T (var "j")) (u64 1)))) <|
+      seq_ (do_ (store byteT (slice.elem_ref byteT (load (sliceT byteT) (var "s")) (load uint64T (var "i"))) (var "$a0"))) <|
+      let_ "$a0" (load byteT (var "x")) <|
+      seq_ (do_ (store byteT (slice.elem_ref byteT (load (sliceT byteT) (var "s")) (binop .minus (load uint64T (var "j")) (u64 1))) (var "$a0"))) <|
+      do_ unit)) <|
+    do_ unit)
+
+/- reassign.go -/
+
+def composite : GoType := structT [
+      ("a", uint64T),
+      ("b", uint64T)
+    ]
+
+def ReassignVars : Val :=
+  rec_ "ReassignVars" ["_"] <|
+    exception_do (let_ "x" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "y" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 0) <|
+    seq_ (do_ (store uint64T (var "y") (var "$a0"))) <|
+    let_ "$a0" (u64 3) <|
+    seq_ (do_ (store uint64T (var "x") (var "$a0"))) <|
+    let_ "z" (ref_ty composite (struct.make composite [
+      ("a", load uint64T (var "x")),
+      ("b", load uint64T (var "y"))
+    ])) <|
+    let_ "$a0" (struct.make composite [
+      ("a", load uint64T (var "y")),
+      ("b", load uint64T (var "x"))
+    ]) <|
+    seq_ (do_ (store composite (var "z") (var "$a0"))) <|
+    let_ "$a0" (load uint64T (struct.field_ref composite "a" (var "z"))) <|
+    seq_ (do_ (store uint64T (var "x") (var "$a0"))) <|
+    do_ unit)
+
+/- recursion.go -/
+
+def sumTo : Val :=
+  rec_ "sumTo" ["n"] <|
+    exception_do (let_ "n" (ref_ty uint64T (var "n")) <|
+    seq_ (if_ (binop .eq (load uint64T (var "n")) (u64 0))
+      (seq_ (return_ (u64 0)) <|
+      do_ unit)
+      (do_ unit)) <|
+    seq_ (return_ (binop .plus (load uint64T (var "n")) ((var "sumTo") (binop .minus (load uint64T (var "n")) (u64 1))))) <|
+    do_ unit)
+
+/- mutually recursive: isEven, isOdd -/
+def isEven'mutual : Val :=
+  rec_ "isEven'mutual" ["$fn"] <|
+    (if_ (binop .eq (var "$fn") (u64 0))
+      (lam ["n"] <|
+        exception_do (let_ "n" (ref_ty uint64T (var "n")) <|
+        seq_ (if_ (binop .eq (load uint64T (var "n")) (u64 0))
+          (seq_ (return_ (bool true)) <|
+          do_ unit)
+          (do_ unit)) <|
+        seq_ (return_ (((var "isEven'mutual") (u64 1)) (binop .minus (load uint64T (var "n")) (u64 1)))) <|
+        do_ unit))
+      (lam ["n"] <|
+        exception_do (let_ "n" (ref_ty uint64T (var "n")) <|
+        seq_ (if_ (binop .eq (load uint64T (var "n")) (u64 0))
+          (seq_ (return_ (bool false)) <|
+          do_ unit)
+          (do_ unit)) <|
+        seq_ (return_ (((var "isEven'mutual") (u64 0)) (binop .minus (load uint64T (var "n")) (u64 1)))) <|
+        do_ unit)))
+
+def isEven : Val :=
+  rec_ "isEven" ["n"] <|
+    isEven'mutual (u64 0) (var "n")
+
+def isOdd : Val :=
+  rec_ "isOdd" ["n"] <|
+    isEven'mutual (u64 1) (var "n")
+
+def listNode : GoType := structT [
+      ("val", uint64T),
+      ("next", ptrT)
+    ]
+
+def listNode__length : Val :=
+  rec_ "listNode__length" ["l", "_"] <|
+    exception_do (let_ "l" (ref_ty ptrT (var "l")) <|
+    seq_ (if_ (binop .eq (load ptrT (var "l")) null)
+      (seq_ (return_ (u64 0)) <|
+      do_ unit)
+      (do_ unit)) <|
+    seq_ (return_ (binop .plus (u64 1) (((var "listNode__length") (load ptrT (struct.field_ref listNode "next" (load ptrT (var "l"))))) unit))) <|
+    do_ unit)
+
+def listNode__mset : List (String × Val) := []
+
+def listNode__mset_ptr : List (String × Val) := [("length", listNode__length)]
+
+def treeNode : GoType := structT [
+      ("children", sliceT ptrT),
+      ("byName", mapT stringT ptrT)
+    ]
+
+def treeNode__size : Val :=
+  rec_ "treeNode__size" ["t", "_"] <|
+    exception_do (let_ "t" (ref_ty treeNode (var "t")) <|
+    let_ "n" (ref_ty uint64T (u64 1)) <|
+    seq_ (do_ (let_ "$range" (load (sliceT treeNode) (struct.field_ref treeNode "children" (var "t"))) <|
+    slice.for_range treeNode (var "$range") <| lam ["_", "c"] <|
+      let_ "c" (ref_ty treeNode (var "c")) <|
+      seq_ (do_ (store uint64T (var "n") (binop .plus (load uint64T (var "n")) (((var "treeNode__size") (load treeNode (var "c"))) unit)))) <|
+      do_ unit)) <|
+    seq_ (return_ (load uint64T (var "n"))) <|
+    do_ unit)
+
+def treeNode__mset : List (String × Val) := [("size", treeNode__size)]
+
+def treeNode__mset_ptr : List (String × Val) := [("size", (lam ["$recvAddr"] <|
+      treeNode__size (load treeNode (var "$recvAddr"))))]
+
+def forest : GoType := structT [
+      ("trees", sliceT ptrT)
+    ]
+
+def tree : GoType := structT [
+      ("label", uint64T),
+      ("sub", forest)
+    ]
+
+/- replicated_disk.go -/
+
+def Block : GoType := structT [
+      ("Value", uint64T)
+    ]
+
+def Disk1 : Expr := u64 0
+
+def Disk2 : Expr := u64 0
+
+def DiskSize : Expr := u64 1000
+
+/- TwoDiskWrite is a dummy function to represent the base layer's disk write -/
+def TwoDiskWrite : Val :=
+  rec_ "TwoDiskWrite" ["diskId", "a", "v"] <|
+    exception_do (let_ "v" (ref_ty Block (var "v")) <|
+    let_ "a" (ref_ty uint64T (var "a")) <|
+    let_ "diskId" (ref_ty uint64T (var "diskId")) <|
+    seq_ (return_ (bool true)) <|
+    do_ unit)
+
+/- TwoDiskRead is a dummy function to represent the base layer's disk read -/
+def TwoDiskRead : Val :=
+  rec_ "TwoDiskRead" ["diskId", "a"] <|
+    exception_do (let_ "a" (ref_ty uint64T (var "a")) <|
+    let_ "diskId" (ref_ty uint64T (var "diskId")) <|
+    seq_ (return_ (tuple [struct.make Block [
+             ("Value", u64 0)
+           ], bool true])) <|
+    do_ unit)
+
+/- TwoDiskLock is a dummy function to represent locking an address in the
+   base layer -/
+def TwoDiskLock : Val :=
+  rec_ "TwoDiskLock" ["a"] <|
+    exception_do (let_ "a" (ref_ty uint64T (var "a")) <|
+    do_ unit)
+
+/- TwoDiskUnlock is a dummy function to represent unlocking an address in the
+   base layer -/
+def TwoDiskUnlock : Val :=
+  rec_ "TwoDiskUnlock" ["a"] <|
+    exception_do (let_ "a" (ref_ty uint64T (var "a")) <|
+    do_ unit)
+
+def ReplicatedDiskRead : Val :=
+  rec_ "ReplicatedDiskRead" ["a"] <|
+    exception_do (let_ "a" (ref_ty uint64T (var "a")) <|
+    seq_ (do_ (TwoDiskLock (load uint64T (var "a")))) <|
+    let_ "ok" (ref_ty boolT (zero_val boolT)) <|
+    let_ "v" (ref_ty Block (zero_val Block)) <|
+    lets_ ["$a0", "$a1"] (TwoDiskRead Disk1 (load uint64T (var "a"))) <|
+    seq_ (do_ (store boolT (var "ok") (var "$a1"))) <|
+    seq_ (do_ (store Block (var "v") (var "$a0"))) <|
+    seq_ (if_ (load boolT (var "ok"))
+      (seq_ (do_ (TwoDiskUnlock (load uint64T (var "a")))) <|
+      seq_ (return_ (load Block (var "v"))) <|
+      do_ unit)
+      (do_ unit)) <|
+    let_ "_" (ref_ty boolT (zero_val boolT)) <|
+    let_ "v2" (ref_ty Block (zero_val Block)) <|
+    lets_ ["$a0", "$a1"] (TwoDiskRead Disk2 (load uint64T (var "a"))) <|
+    seq_ (do_ (var "$a1")) <|
+    seq_ (do_ (store Block (var "v2") (var "$a0"))) <|
+    seq_ (do_ (TwoDiskUnlock (load uint64T (var "a")))) <|
+    seq_ (return_ (load Block (var "v2"))) <|
+    do_ unit)
+
+def ReplicatedDiskWrite : Val :=
+  rec_ "ReplicatedDiskWrite" ["a", "v"] <|
+    exception_do (let_ "v" (ref_ty Block (var "v")) <|
+    let_ "a" (ref_ty uint64T (var "a")) <|
+    seq_ (do_ (TwoDiskLock (load uint64T (var "a")))) <|
+    seq_ (do_ (TwoDiskWrite Disk1 (load uint64T (var "a")) (load Block (var "v")))) <|
+    seq_ (do_ (TwoDiskWrite Disk2 (load uint64T (var "a")) (load Block (var "v")))) <|
+    seq_ (do_ (TwoDiskUnlock (load uint64T (var "a")))) <|
+    do_ unit)
+
+def ReplicatedDiskRecover : Val :=
+  rec_ "ReplicatedDiskRecover" ["_"] <|
+    exception_do (seq_ (let_ "a" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 0) <|
+    seq_ (do_ (store uint64T (var "a") (var "$a0"))) <|
+    (for_ (lam ["_"] <| bool true) (lam ["_"] <| Skip) <|
+      lam ["_"] <|
+      seq_ (if_ (binop .gt (load uint64T (var "a")) DiskSize)
+        (seq_ break_ <|
+        do_ unit)
+        (do_ unit)) <|
+      let_ "ok" (ref_ty boolT (zero_val boolT)) <|
+      let_ "v" (ref_ty Block (zero_val Block)) <|
+      lets_ ["$a0", "$a1"] (TwoDiskRead Disk1 (load uint64T (var "a"))) <|
+      seq_ (do_ (store boolT (var "ok") (var "$a1"))) <|
+      seq_ (do_ (store Block (var "v") (var "$a0"))) <|
+      seq_ (if_ (load boolT (var "ok"))
+        (seq_ (do_ (TwoDiskWrite Disk2 (load uint64T (var "a")) (load Block (var "v")))) <|
+        do_ unit)
+        (do_ unit)) <|
+      let_ "$a0" (binop .plus (load uint64T (var "a")) (u64 1)) <|
+      seq_ (do_ (store uint64T (var "a") (var "$a0"))) <|
+      seq_ continue_ <|
+      do_ unit)) <|
+    do_ unit)
+
+/- slices.go -/
+
+def SliceAlias : GoType := sliceT boolT
+
+def sliceOps : Val :=
+  rec_ "sliceOps" ["_"] <|
+    exception_do (let_ "x" (ref_ty (sliceT uint64T) (zero_val (sliceT uint64T))) <|
+    let_ "$a0" (slice.make2 uint64T (u64 10)) <|
+    seq_ (do_ (store (sliceT uint64T) (var "x") (var "$a0"))) <|
+    let_ "v1" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (load uint64T (slice.elem_ref uint64T (load (sliceT uint64T) (var "x")) (u64 2))) <|
+    seq_ (do_ (store uint64T (var "v1") (var "$a0"))) <|
+    let_ "v2" (ref_ty (sliceT uint64T) (zero_val (sliceT uint64T))) <|
+    let_ "$a0" (let_ "$s" (load (sliceT uint64T) (var "x")) <|
+    slice.slice uint64T (var "$s") (u64 2) (u64 3)) <|
+    seq_ (do_ (store (sliceT uint64T) (var "v2") (var "$a0"))) <|
+    let_ "v3" (ref_ty (sliceT uint64T) (zero_val (sliceT uint64T))) <|
+    let_ "$a0" (let_ "$s" (load (sliceT uint64T) (var "x")) <|
+    slice.slice uint64T (var "$s") (u64 0) (u64 3)) <|
+    seq_ (do_ (store (sliceT uint64T) (var "v3") (var "$a0"))) <|
+    let_ "v4" (ref_ty ptrT (zero_val ptrT)) <|
+    let_ "$a0" (SliceRef uint64T (load (sliceT uint64T) (var "x")) (u64 2)) <|
+    seq_ (do_ (store ptrT (var "v4") (var "$a0"))) <|
+    seq_ (return_ (binop .plus (binop .plus (binop .plus (binop .plus (binop .plus (load uint64T (var "v1")) (load uint64T (slice.elem_ref uint64T (load (sliceT uint64T) (var "v2")) (u64 0)))) (load uint64T (slice.elem_ref uint64T (load (sliceT uint64T) (var "v3")) (u64 1)))) (load uint64T (load ptrT (var "v4")))) (slice.len (load (sliceT uint64T) (var "x")))) (slice.cap (load (sliceT uint64T) (var "x"))))) <|
+    do_ unit)
+
+def makeSingletonSlice : Val :=
+  rec_ "makeSingletonSlice" ["x"] <|
+    exception_do (let_ "x" (ref_ty uint64T (var "x")) <|
+    seq_ (return_ (slice.literal uint64T [load uint64T (var "x")])) <|
+    do_ unit)
+
+def thing : GoType := structT [
+      ("x", uint64T)
+    ]
+
+def sliceOfThings : GoType := structT [
+      ("things", sliceT thing)
+    ]
+
+def sliceOfThings__getThingRef : Val :=
+  rec_ "sliceOfThings__getThingRef" ["ts", "i"] <|
+    exception_do (let_ "ts" (ref_ty sliceOfThings (var "ts")) <|
+    let_ "i" (ref_ty uint64T (var "i")) <|
+    seq_ (return_ (SliceRef thing (load (sliceT thing) (struct.field_ref sliceOfThings "things" (var "ts"))) (load uint64T (var "i")))) <|
+    do_ unit)
+
+def sliceOfThings__mset : List (String × Val) := [("getThingRef", sliceOfThings__getThingRef)]
+
+def sliceOfThings__mset_ptr : List (String × Val) := [("getThingRef", (lam ["$recvAddr"] <|
+      sliceOfThings__getThingRef (load sliceOfThings (var "$recvAddr"))))]
+
+def makeAlias : Val :=
+  rec_ "makeAlias" ["_"] <|
+    exception_do (seq_ (return_ (slice.make2 boolT (u64 10))) <|
+    do_ unit)
+
+/- spawn.go -/
+
+/- Skip is a placeholder for some impure code -/
+def Skip : Val :=
+  rec_ "Skip" ["_"] <|
+    exception_do (do_ unit)
+
+def simpleSpawn : Val :=
+  rec_ "simpleSpawn" ["_"] <|
+    exception_do (let_ "l" (ref_ty ptrT (zero_val ptrT)) <|
+    let_ "$a0" (ref_ty sync.Mutex (zero_val sync.Mutex)) <|
+    seq_ (do_ (store ptrT (var "l") (var "$a0"))) <|
+    let_ "v" (ref_ty ptrT (zero_val ptrT)) <|
+    let_ "$a0" (ref_ty uint64T (zero_val uint64T)) <|
+    seq_ (do_ (store ptrT (var "v") (var "$a0"))) <|
+    let_ "$go" (lam ["_"] <|
+      exception_do (seq_ (do_ ((sync.Mutex__Lock (load ptrT (var "l"))) unit)) <|
+      let_ "x" (ref_ty uint64T (zero_val uint64T)) <|
+      let_ "$a0" (load uint64T (load ptrT (var "v"))) <|
+      seq_ (do_ (store uint64T (var "x") (var "$a0"))) <|
+      seq_ (if_ (binop .gt (load uint64T (var "x")) (u64 0))
+        (seq_ (do_ (Skip unit)) <|
+        do_ unit)
+        (do_ unit)) <|
+      seq_ (do_ ((sync.Mutex__Unlock (load ptrT (var "l"))) unit)) <|
+      do_ unit)) <|
+    seq_ (do_ (fork ((var "$go") unit))) <|
+    seq_ (do_ ((sync.Mutex__Lock (load ptrT (var "l"))) unit)) <|
+    let_ "$a0" (u64 1) <|
+    seq_ (do_ (store uint64T (load ptrT (var "v")) (var "$a0"))) <|
+    seq_ (do_ ((sync.Mutex__Unlock (load ptrT (var "l"))) unit)) <|
+    do_ unit)
+
+def threadCode : Val :=
+  rec_ "threadCode" ["tid"] <|
+    exception_do (let_ "tid" (ref_ty uint64T (var "tid")) <|
+    do_ unit)
+
+def loopSpawn : Val :=
+  rec_ "loopSpawn" ["_"] <|
+    exception_do (seq_ (let_ "i" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 0) <|
+    seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+    (for_ (lam ["_"] <| binop .lt (load uint64T (var "i")) (u64 10)) (lam ["_"] <| seq_ (do_ (store uint64T (var "i") (binop .plus (load uint64T (var "i")) (u64 1)))) <|
+    unit) <|
+      lam ["_"] <|
+      let_ "i" (ref_ty uint64T (zero_val uint64T)) <|
+      let_ "$a0" (load uint64T (var "i")) <|
+      seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+      let_ "$go" (lam ["_"] <|
+        exception_do (seq_ (do_ (threadCode (load uint64T (var "i")))) <|
+        do_ unit)) <|
+      seq_ (do_ (fork ((var "$go") unit))) <|
+      do_ unit)) <|
+    seq_ (let_ "dummy" (ref_ty boolT (zero_val boolT)) <|
+    let_ "$a0" (bool true) <|
+    seq_ (do_ (store boolT (var "dummy") (var "$a0"))) <|
+    (for_ (lam ["_"] <| bool true) (lam ["_"] <| Skip) <|
+      lam ["_"] <|
+      let_ "$a0" (not_ (load boolT (var "dummy"))) <|
+      seq_ (do_ (store boolT (var "dummy") (var "$a0"))) <|
+      seq_ continue_ <|
+      do_ unit)) <|
+    do_ unit)
+
+/- strings.go -/
+
+def stringAppend : Val :=
+  rec_ "stringAppend" ["s", "x"] <|
+    exception_do (let_ "x" (ref_ty uint64T (var "x")) <|
+    let_ "s" (ref_ty stringT (var "s")) <|
+    seq_ (return_ (binop .append (binop .append (binop .append (str "prefix ") (load stringT (var "s"))) (str " ")) (machine.UInt64ToString (load uint64T (var "x"))))) <|
+    do_ unit)
+
+def stringLength : Val :=
+  rec_ "stringLength" ["s"] <|
+    exception_do (let_ "s" (ref_ty stringT (var "s")) <|
+    seq_ (return_ (StringLength (load stringT (var "s")))) <|
+    do_ unit)
+
+/- struct_method.go -/
+
+def Point : GoType := structT [
+      ("x", uint64T),
+      ("y", uint64T)
+    ]
+
+def Point__Add : Val :=
+  rec_ "Point__Add" ["c", "z"] <|
+    exception_do (let_ "c" (ref_ty Point (var "c")) <|
+    let_ "z" (ref_ty uint64T (var "z")) <|
+    seq_ (return_ (binop .plus (binop .plus (load uint64T (struct.field_ref Point "x" (var "c"))) (load uint64T (struct.field_ref Point "y" (var "c")))) (load uint64T (var "z")))) <|
+    do_ unit)
+
+def Point__GetField : Val :=
+  rec_ "Point__GetField" ["c", "_"] <|
+    exception_do (let_ "c" (ref_ty Point (var "c")) <|
+    let_ "x" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (load uint64T (struct.field_ref Point "x" (var "c"))) <|
+    seq_ (do_ (store uint64T (var "x") (var "$a0"))) <|
+    let_ "y" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (load uint64T (struct.field_ref Point "y" (var "c"))) <|
+    seq_ (do_ (store uint64T (var "y") (var "$a0"))) <|
+    seq_ (return_ (binop .plus (load uint64T (var "x")) (load uint64T (var "y")))) <|
+    do_ unit)
+
+def Point__mset : List (String × Val) := [("Add", Point__Add), ("GetField", Point__GetField)]
+
+def Point__mset_ptr : List (String × Val) := [("Add", (lam ["$recvAddr"] <|
+      Point__Add (load Point (var "$recvAddr")))), ("GetField", (lam ["$recvAddr"] <|
+      Point__GetField (load Point (var "$recvAddr"))))]
+
+def UseAdd : Val :=
+  rec_ "UseAdd" ["_"] <|
+    exception_do (let_ "c" (ref_ty Point (zero_val Point)) <|
+    let_ "$a0" (struct.make Point [
+      ("x", u64 2),
+      ("y", u64 3)
+    ]) <|
+    seq_ (do_ (store Point (var "c") (var "$a0"))) <|
+    let_ "r" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" ((Point__Add (load Point (var "c"))) (u64 4)) <|
+    seq_ (do_ (store uint64T (var "r") (var "$a0"))) <|
+    seq_ (return_ (load uint64T (var "r"))) <|
+    do_ unit)
+
+def UseAddWithLiteral : Val :=
+  rec_ "UseAddWithLiteral" ["_"] <|
+    exception_do (let_ "r" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" ((Point__Add (struct.make Point [
+      ("x", u64 2),
+      ("y", u64 3)
+    ])) (u64 4)) <|
+    seq_ (do_ (store uint64T (var "r") (var "$a0"))) <|
+    seq_ (return_ (load uint64T (var "r"))) <|
+    do_ unit)
+
+/- struct_pointers.go -/
+
+def TwoInts : GoType := structT [
+      ("x", uint64T),
+      ("y", uint64T)
+    ]
+
+def S : GoType := structT [
+      ("a", uint64T),
+      ("b", TwoInts),
+      ("c", boolT)
+    ]
+
+def NewS : Val :=
+  rec_ "NewS" ["_"] <|
+    exception_do (seq_ (return_ (ref_ty S (struct.make S [
+      ("a", u64 2),
+      ("b", struct.make TwoInts [
+        ("x", u64 1),
+        ("y", u64 2)
+      ]),
+      ("c", bool true)
+    ]))) <|
+    do_ unit)
+
+def S__readA : Val :=
+  rec_ "S__readA" ["s", "_"] <|
+    exception_do (let_ "s" (ref_ty ptrT (var "s")) <|
+    seq_ (return_ (load uint64T (struct.field_ref S "a" (load ptrT (var "s"))))) <|
+    do_ unit)
+
+def S__readB : Val :=
+  rec_ "S__readB" ["s", "_"] <|
+    exception_do (let_ "s" (ref_ty ptrT (var "s")) <|
+    seq_ (return_ (load TwoInts (struct.field_ref S "b" (load ptrT (var "s"))))) <|
+    do_ unit)
+
+def S__readBVal : Val :=
+  rec_ "S__readBVal" ["s", "_"] <|
+    exception_do (let_ "s" (ref_ty S (var "s")) <|
+    seq_ (return_ (load TwoInts (struct.field_ref S "b" (var "s")))) <|
+    do_ unit)
+
+def S__writeB : Val :=
+  rec_ "S__writeB" ["s", "two"] <|
+    exception_do (let_ "s" (ref_ty ptrT (var "s")) <|
+    let_ "two" (ref_ty TwoInts (var "two")) <|
+    let_ "$a0" (load TwoInts (var "two")) <|
+    seq_ (do_ (store TwoInts (struct.field_ref S "b" (load ptrT (var "s"))) (var "$a0"))) <|
+    do_ unit)
+
+def S__negateC : Val :=
+  rec_ "S__negateC" ["s", "_"] <|
+    exception_do (let_ "s" (ref_ty ptrT (var "s")) <|
+    let_ "$a0" (not_ (load boolT (struct.field_ref S "c" (load ptrT (var "s"))))) <|
+    seq_ (do_ (store boolT (struct.field_ref S "c" (load ptrT (var "s"))) (var "$a0"))) <|
+    do_ unit)
+
+def S__refC : Val :=
+  rec_ "S__refC" ["s", "_"] <|
+    exception_do (let_ "s" (ref_ty ptrT (var "s")) <|
+    seq_ (return_ (struct.field_ref S "c" (load ptrT (var "s")))) <|
+    do_ unit)
+
+def S__mset : List (String × Val) := [("readBVal", S__readBVal)]
+
+def S__mset_ptr : List (String × Val) := [("readA", S__readA), ("readB", S__readB), ("readBVal", (lam ["$recvAddr"] <|
+      S__readBVal (load S (var "$recvAddr")))), ("writeB", S__writeB), ("negateC", S__negateC), ("refC", S__refC)]
+
+def localSRef : Val :=
+  rec_ "localSRef" ["_"] <|
+    exception_do (let_ "s" (ref_ty S (zero_val S)) <|
+    seq_ (return_ (struct.field_ref S "b" (var "s"))) <|
+    do_ unit)
+
+def setField : Val :=
+  rec_ "setField" ["_"] <|
+    exception_do (let_ "s" (ref_ty S (zero_val S)) <|
+    let_ "$a0" (u64 0) <|
+    seq_ (do_ (store uint64T (struct.field_ref S "a" (var "s")) (var "$a0"))) <|
+    let_ "$a0" (bool true) <|
+    seq_ (do_ (store boolT (struct.field_ref S "c" (var "s")) (var "$a0"))) <|
+    seq_ (return_ (load S (var "s"))) <|
+    do_ unit)
+
+/- sync_primitives.go -/
+
+def cache : GoType := structT [
+      ("mu", sync.RWMutex),
+      ("entries", mapT uint64T uint64T),
+      ("hits", atomic.Uint64),
+      ("init", sync.Once)
+    ]
+
+def cache__get : Val :=
+  rec_ "cache__get" ["c", "k"] <|
+    exception_do (let_ "c" (ref_ty ptrT (var "c")) <|
+    let_ "k" (ref_ty uint64T (var "k")) <|
+    seq_ (do_ ((sync.RWMutex__RLock (struct.field_ref cache "mu" (load ptrT (var "c")))) unit)) <|
+    let_ "ok" (ref_ty boolT (zero_val boolT)) <|
+    let_ "v" (ref_ty uint64T (zero_val uint64T)) <|
+    lets_ ["$a0", "$a1"] (Fst (map.get (load (mapT uint64T uint64T) (struct.field_ref cache "entries" (load ptrT (var "c")))) (load uint64T (var "k")))) <|
+    seq_ (do_ (store boolT (var "ok") (var "$a1"))) <|
+    seq_ (do_ (store uint64T (var "v") (var "$a0"))) <|
+    seq_ (do_ ((sync.RWMutex__RUnlock (struct.field_ref cache "mu" (load ptrT (var "c")))) unit)) <|
+    seq_ (if_ (load boolT (var "ok"))
+      (seq_ (do_ ((atomic.Uint64__Add (struct.field_ref cache "hits" (load ptrT (var "c")))) (u64 1))) <|
+      do_ unit)
+      (do_ unit)) <|
+    seq_ (return_ (tuple [load uint64T (var "v"), load boolT (var "ok")])) <|
+    do_ unit)
+
+def cache__put : Val :=
+  rec_ "cache__put" ["c", "k", "v"] <|
+    exception_do (let_ "c" (ref_ty ptrT (var "c")) <|
+    let_ "v" (ref_ty uint64T (var "v")) <|
+    let_ "k" (ref_ty uint64T (var "k")) <|
+    seq_ (do_ ((sync.Once__Do (struct.field_ref cache "init" (load ptrT (var "c")))) (lam ["_"] <|
+      exception_do (let_ "$a0" (map.make uint64T uint64T unit) <|
+      seq_ (do_ (store (mapT uint64T uint64T) (struct.field_ref cache "entries" (load ptrT (var "c"))) (var "$a0"))) <|
+      do_ unit)))) <|
+    seq_ (do_ ((sync.RWMutex__Lock (struct.field_ref cache "mu" (load ptrT (var "c")))) unit)) <|
+    let_ "$a0" (load uint64T (var "v")) <|
+    seq_ (do_ (map.insert (load (mapT uint64T uint64T) (struct.field_ref cache "entries" (load ptrT (var "c")))) (load uint64T (var "k")) (var "$a0"))) <|
+    seq_ (do_ ((sync.RWMutex__Unlock (struct.field_ref cache "mu" (load ptrT (var "c")))) unit)) <|
+    do_ unit)
+
+def cache__numHits : Val :=
+  rec_ "cache__numHits" ["c", "_"] <|
+    exception_do (let_ "c" (ref_ty ptrT (var "c")) <|
+    seq_ (return_ ((atomic.Uint64__Load (struct.field_ref cache "hits" (load ptrT (var "c")))) unit)) <|
+    do_ unit)
+
+def cache__mset : List (String × Val) := []
+
+def cache__mset_ptr : List (String × Val) := [("get", cache__get), ("put", cache__put), ("numHits", cache__numHits)]
+
+def atomicCounter : Val :=
+  rec_ "atomicCounter" ["counter"] <|
+    exception_do (let_ "counter" (ref_ty ptrT (var "counter")) <|
+    seq_ (do_ (atomic.AddUint64 (load ptrT (var "counter")) (u64 1))) <|
+    seq_ (return_ (atomic.LoadUint64 (load ptrT (var "counter")))) <|
+    do_ unit)
+
+def waitForWorkers : Val :=
+  rec_ "waitForWorkers" ["n"] <|
+    exception_do (let_ "n" (ref_ty uint64T (var "n")) <|
+    let_ "wg" (ref_ty sync.WaitGroup (zero_val sync.WaitGroup)) <|
+    seq_ (let_ "i" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 0) <|
+    seq_ (do_ (store uint64T (var "i") (var "$a0"))) <|
+    (for_ (lam ["_"] <| binop .lt (load uint64T (var "i")) (load uint64T (var "n"))) (lam ["_"] <| seq_ (do_ (store uint64T (var "i") (binop .plus (load uint64T (var "i")) (u64 1)))) <|
+    unit) <|
+      lam ["_"] <|
+      seq_ (do_ ((sync.WaitGroup__Add (var "wg")) (u64 1))) <|
+      let_ "$go" (lam ["_"] <|
+        exception_do (seq_ (do_ ((sync.WaitGroup__Done (var "wg")) unit)) <|
+        do_ unit)) <|
+      seq_ (do_ (fork ((var "$go") unit))) <|
+      do_ unit)) <|
+    seq_ (do_ ((sync.WaitGroup__Wait (var "wg")) unit)) <|
+    do_ unit)
+
+/- synchronization.go -/
+
+/- DoSomeLocking uses the entire lock API -/
+def DoSomeLocking : Val :=
+  rec_ "DoSomeLocking" ["l"] <|
+    exception_do (let_ "l" (ref_ty ptrT (var "l")) <|
+    seq_ (do_ ((sync.Mutex__Lock (load ptrT (var "l"))) unit)) <|
+    seq_ (do_ ((sync.Mutex__Unlock (load ptrT (var "l"))) unit)) <|
+    do_ unit)
+
+def makeLock : Val :=
+  rec_ "makeLock" ["_"] <|
+    exception_do (let_ "l" (ref_ty ptrT (zero_val ptrT)) <|
+    let_ "$a0" (ref_ty sync.Mutex (zero_val sync.Mutex)) <|
+    seq_ (do_ (store ptrT (var "l") (var "$a0"))) <|
+    seq_ (do_ (DoSomeLocking (load ptrT (var "l")))) <|
+    do_ unit)
+
+/- time.go -/
+
+def sleep : Val :=
+  rec_ "sleep" ["_"] <|
+    exception_do (seq_ (do_ (machine.Sleep (u64 1000))) <|
+    do_ unit)
+
+/- topsort.go -/
+
+def A : GoType := structT [
+    ]
+
+def B : GoType := structT [
+      ("a", sliceT A)
+    ]
+
+/- trailing_call.go -/
+
+def mkInt : Val :=
+  rec_ "mkInt" ["_"] <|
+    exception_do (seq_ (return_ (u64 42)) <|
+    do_ unit)
+
+def mkNothing : Val :=
+  rec_ "mkNothing" ["_"] <|
+    exception_do (seq_ (do_ (mkInt unit)) <|
+    do_ unit)
+
+/- type_alias.go -/
+
+def my_u64 : GoType := uint64T
+
+def Timestamp : GoType := uint64T
+
+def UseTypeAbbrev : GoType := uint64T
+
+def UseNamedType : GoType := Timestamp
+
+def convertToAlias : Val :=
+  rec_ "convertToAlias" ["_"] <|
+    exception_do (let_ "x" (ref_ty uint64T (zero_val uint64T)) <|
+    let_ "$a0" (u64 2) <|
+    seq_ (do_ (store uint64T (var "x") (var "$a0"))) <|
+    seq_ (return_ (load uint64T (var "x"))) <|
+    do_ unit)
+
+end unittest
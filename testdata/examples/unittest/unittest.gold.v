@@ -1,13 +1,54 @@
 (* autogenerated from github.com/goose-lang/goose/testdata/examples/unittest *)
 From New.golang Require Import defn.
+From New.code Require errors.
+From New.code Require fmt.
 From New.code Require github_com.goose_lang.goose.machine.
 From New.code Require github_com.goose_lang.goose.machine.disk.
 From New.code Require github_com.tchajed.marshal.
 From New.code Require log.
 From New.code Require sync.
+From New.code Require sync.atomic.
 
 From New Require Import disk_prelude.
 
+(* closures.go *)
+
+Definition closureParams : val :=
+  rec: "closureParams" <> :=
+    exception_do (let: "add" := ref_ty funcT (zero_val funcT) in
+    let: "$a0" := (λ: "x" "y",
+      exception_do (let: "x" := ref_ty uint64T "x" in
+      let: "y" := ref_ty uint64T "y" in
+      do:  "x" <-[uint64T] ((![uint64T] "x") + (![uint64T] "y"));;;
+      return: (![uint64T] "x");;;
+      do:  #())
+      ) in
+    do:  "add" <-[funcT] "$a0";;;
+    return: ((![funcT] "add") #1 #2);;;
+    do:  #()).
+
+Definition closureEarlyReturn : val :=
+  rec: "closureEarlyReturn" "xs" :=
+    exception_do (let: "xs" := ref_ty (sliceT uint64T) "xs" in
+    let: "contains" := ref_ty funcT (zero_val funcT) in
+    let: "$a0" := (λ: "y",
+      exception_do (let: "y" := ref_ty uint64T "y" in
+      do:  let: "$range" := ![sliceT uint64T] "xs" in
+      slice.for_range uint64T "$range" (λ: <> "x",
+        let: "x" := ref_ty uint64T "x" in
+        (if: (![uint64T] "x") = (![uint64T] "y")
+        then
+          return: (#true);;;
+          do:  #()
+        else do:  #());;;
+        do:  #());;;
+      return: (#false);;;
+      do:  #())
+      ) in
+    do:  "contains" <-[funcT] "$a0";;;
+    return: ((![funcT] "contains") #3);;;
+    do:  #()).
+
 (* comments.go *)
 
 (* unittest is a package full of many independent and small translation examples *)
@@ -243,7 +284,8 @@ Definition testCopyDifferentLengths : val :=
     let: "n" := ref_ty uint64T (zero_val uint64T) in
     let: "$a0" := slice.copy byteT (![sliceT byteT] "y") (![sliceT byteT] "x") in
     do:  "n" <-[uint64T] "$a0";;;
-    return: (((![uint64T] "n") = #10) && ((![byteT] (slice.elem_ref byteT (![sliceT byteT] "y") #3)) = #(U8 1)));;;
+    return: (((![uint64T] "n") = #10) &&
+               ((![byteT] (slice.elem_ref byteT (![sliceT byteT] "y") #3)) = #(U8 1)));;;
     do:  #()).
 
 (* data_structures.go *)
@@ -281,7 +323,8 @@ Definition useSliceIndexing : val :=
 
 Definition useMap : val :=
   rec: "useMap" <> :=
-    exception_do (let: "m" := ref_ty (mapT uint64T (sliceT byteT)) (zero_val (mapT uint64T (sliceT byteT))) in
+    exception_do (let: "m" :=
+      ref_ty (mapT uint64T (sliceT byteT)) (zero_val (mapT uint64T (sliceT byteT))) in
     let: "$a0" := map.make uint64T (sliceT byteT) #() in
     do:  "m" <-[mapT uint64T (sliceT byteT)] "$a0";;;
     let: "$a0" := slice.nil in
@@ -349,7 +392,8 @@ Definition iterMapKeys : val :=
       let: "$a0" := ![sliceT uint64T] (![ptrT] "keysRef") in
       do:  "keys" <-[sliceT uint64T] "$a0";;;
       let: "newKeys" := ref_ty (sliceT uint64T) (zero_val (sliceT uint64T)) in
-      let: "$a0" := slice.append uint64T (![sliceT uint64T] "keys") (slice.literal uint64T [![uint64T] "k"]) in
+      let: "$a0" :=
+        slice.append uint64T (![sliceT uint64T] "keys") (slice.literal uint64T [![uint64T] "k"]) in
       do:  "newKeys" <-[sliceT uint64T] "$a0";;;
       let: "$a0" := ![sliceT uint64T] "newKeys" in
       do:  (![ptrT] "keysRef") <-[sliceT uint64T] "$a0";;;
@@ -368,6 +412,128 @@ Definition getRandom : val :=
     return: (![uint64T] "r");;;
     do:  #()).
 
+(* defer.go *)
+
+Definition deferUnlock : val :=
+  rec: "deferUnlock" "m" :=
+    exception_do (let: "m" := ref_ty ptrT "m" in
+    with_defer: (do:  (sync.Mutex__Lock (![ptrT] "m")) #();;;
+    let: "$f" := sync.Mutex__Unlock (![ptrT] "m") in
+    do:  "$defer" <-[funcT] (let: "$oldf" := ![funcT] "$defer" in
+      (λ: <>,
+        "$f" #();;
+        "$oldf" #()
+      ));;;
+    return: (#1);;;
+    do:  #())).
+
+Definition consumeSlice : val :=
+  rec: "consumeSlice" "s" :=
+    exception_do (let: "s" := ref_ty (sliceT uint64T) "s" in
+    do:  #()).
+
+Definition deferWithArgs : val :=
+  rec: "deferWithArgs" "s" :=
+    exception_do (let: "s" := ref_ty (sliceT uint64T) "s" in
+    with_defer: (let: "$arg0" := ![sliceT uint64T] "s" in
+    let: "$f" := consumeSlice in
+    do:  "$defer" <-[funcT] (let: "$oldf" := ![funcT] "$defer" in
+      (λ: <>,
+        "$f" "$arg0";;
+        "$oldf" #()
+      ));;;
+    let: "$a0" := slice.nil in
+    do:  "s" <-[sliceT uint64T] "$a0";;;
+    do:  #())).
+
+Definition recoverFromPanic : val :=
+  rec: "recoverFromPanic" <> :=
+    exception_do (let: "recovered" := ref_ty boolT (zero_val boolT) in
+    do:  with_defer: (let: "$f" := (λ: <>,
+      exception_do (let: "r" := ref_ty interfaceT (zero_val interfaceT) in
+      let: "$a0" := recover #() in
+      do:  "r" <-[interfaceT] "$a0";;;
+      (if: (![interfaceT] "r") ≠ interface.nil
+      then
+        let: "$a0" := #true in
+        do:  "recovered" <-[boolT] "$a0";;;
+        do:  #()
+      else do:  #());;;
+      do:  #())
+      ) in
+    do:  "$defer" <-[funcT] (let: "$oldf" := ![funcT] "$defer" in
+      (λ: <>,
+        "$f" #();;
+        "$oldf" #()
+      ));;;
+    do:  PanicValue (interface.make "string" [] #(str "oops"));;;
+    do:  #());;;
+    return: (![boolT] "recovered")).
+
+Definition recoverToValue : val :=
+  rec: "recoverToValue" "x" :=
+    exception_do (let: "x" := ref_ty uint64T "x" in
+    let: "y" := ref_ty uint64T (zero_val uint64T) in
+    let: "ok" := ref_ty boolT (zero_val boolT) in
+    do:  with_defer: (let: "$f" := (λ: <>,
+      exception_do ((if: (recover #()) ≠ interface.nil
+      then
+        let: "$a0" := #0 in
+        do:  "y" <-[uint64T] "$a0";;;
+        let: "$a0" := #false in
+        do:  "ok" <-[boolT] "$a0";;;
+        do:  #()
+      else do:  #());;;
+      do:  #())
+      ) in
+    do:  "$defer" <-[funcT] (let: "$oldf" := ![funcT] "$defer" in
+      (λ: <>,
+        "$f" #();;
+        "$oldf" #()
+      ));;;
+    (if: (![uint64T] "x") = #0
+    then
+      do:  PanicValue (interface.make "uint64" [] (![uint64T] "x"));;;
+      do:  #()
+    else do:  #());;;
+    let: "$r0" := ![uint64T] "x" in
+    let: "$r1" := #true in
+    do:  "y" <-[uint64T] "$r0";;;
+    do:  "ok" <-[boolT] "$r1";;;
+    return: (#());;;
+    do:  #());;;
+    return: (![uint64T] "y", ![boolT] "ok")).
+
+(* directives.go *)
+
+(* The implementation of this function is trusted rather than translated.
+
+   opaque: func trustedHash(data []byte) uint64 *)
+Axiom trustedHash : val.
+
+(* extern: func checksum(data []byte) uint64 *)
+Definition checksum : val := Prelude.checksum.
+
+Definition counter : go_type := structT [
+  "n" :: uint64T
+].
+
+(* opaque: func (c *counter) incrementAtomically() *)
+Axiom counter__incrementAtomically : val.
+
+Definition counter__mset : list (string * val) := [].
+
+Definition counter__mset_ptr : list (string * val) := [
+  ("incrementAtomically", counter__incrementAtomically%V)
+].
+
+Definition useDirectives : val :=
+  rec: "useDirectives" "c" :=
+    exception_do (let: "c" := ref_ty ptrT "c" in
+    do:  (counter__incrementAtomically (![ptrT] "c")) #();;;
+    return: (checksum slice.nil);;;
+    do:  #()).
+
 (* disk.go *)
 
 Definition diskWrapper : go_type := structT [
@@ -428,6 +594,14 @@ Definition Enc__UInt32 : val :=
     do:  machine.UInt32Put ((Enc__consume (![ptrT] "e")) #4) (![uint32T] "x");;;
     do:  #()).
 
+Definition Enc__mset : list (string * val) := [].
+
+Definition Enc__mset_ptr : list (string * val) := [
+  ("consume", Enc__consume%V);
+  ("UInt64", Enc__UInt64%V);
+  ("UInt32", Enc__UInt32%V)
+].
+
 Definition Dec : go_type := structT [
   "p" :: sliceT byteT
 ].
@@ -458,6 +632,108 @@ Definition Dec__UInt32 : val :=
     return: (machine.UInt32Get ((Dec__consume (![ptrT] "d")) #4));;;
     do:  #()).
 
+Definition Dec__mset : list (string * val) := [].
+
+Definition Dec__mset_ptr : list (string * val) := [
+  ("consume", Dec__consume%V);
+  ("UInt64", Dec__UInt64%V);
+  ("UInt32", Dec__UInt32%V)
+].
+
+(* errors.go *)
+
+Definition ErrNotFound : expr := errors.Sentinel
+             #(str "github.com/goose-lang/goose/testdata/examples/unittest.ErrNotFound")
+             #(str "not found").
+
+Definition parseMessage : expr := #(str "parse error").
+
+Definition parseError : go_type := structT [
+  "line" :: uint64T
+].
+
+Definition parseError__Error : val :=
+  rec: "parseError__Error" "e" <> :=
+    exception_do (let: "e" := ref_ty ptrT "e" in
+    return: (![stringT] "parseMessage");;;
+    do:  #()).
+
+Definition parseError__mset : list (string * val) := [].
+
+Definition parseError__mset_ptr : list (string * val) := [
+  ("Error", parseError__Error%V)
+].
+
+Definition findKey : val :=
+  rec: "findKey" "m" "k" :=
+    exception_do (let: "k" := ref_ty stringT "k" in
+    let: "m" := ref_ty (mapT stringT uint64T) "m" in
+    let: "ok" := ref_ty boolT (zero_val boolT) in
+    let: "v" := ref_ty uint64T (zero_val uint64T) in
+    let: ("$a0", "$a1") := Fst (map.get (![mapT stringT uint64T] "m") (![stringT] "k")) in
+    do:  "ok" <-[boolT] "$a1";;;
+    do:  "v" <-[uint64T] "$a0";;;
+    (if: (~ (![boolT] "ok"))
+    then
+      return: (#0, ErrNotFound);;;
+      do:  #()
+    else do:  #());;;
+    return: (![uint64T] "v", interface.nil);;;
+    do:  #()).
+
+Definition parse : val :=
+  rec: "parse" "line" :=
+    exception_do (let: "line" := ref_ty uint64T "line" in
+    (if: (![uint64T] "line") = #0
+    then
+      return: (interface.make "github.com/goose-lang/goose/testdata/examples/unittest.parseError'ptr" parseError__mset_ptr (ref_ty parseError (struct.make parseError [{
+         "line" ::= ![uint64T] "line"
+       }])));;;
+      do:  #()
+    else do:  #());;;
+    return: (interface.nil);;;
+    do:  #()).
+
+Definition errorMessage : val :=
+  rec: "errorMessage" "err" :=
+    exception_do (let: "err" := ref_ty error "err" in
+    (if: (![error] "err") ≠ interface.nil
+    then
+      return: ((interface.get "Error" (![error] "err")) #());;;
+      do:  #()
+    else do:  #());;;
+    return: (#(str ""));;;
+    do:  #()).
+
+Definition wrapError : val :=
+  rec: "wrapError" "op" "err" :=
+    exception_do (let: "err" := ref_ty error "err" in
+    let: "op" := ref_ty stringT "op" in
+    return: ((let: "$fmt0" := ![stringT] "op" in
+     let: "$fmt1" := ![error] "err" in
+     errors.Wrap
+       (("$fmt0" + #(str " failed: ")) + ((interface.get "Error" "$fmt1") #()))
+       "$fmt1"));;;
+    do:  #()).
+
+Definition isNotFound : val :=
+  rec: "isNotFound" "err" :=
+    exception_do (let: "err" := ref_ty error "err" in
+    return: (((![error] "err") = ErrNotFound) || (errors.Is (![error] "err") ErrNotFound));;;
+    do:  #()).
+
+Definition asParseError : val :=
+  rec: "asParseError" "err" :=
+    exception_do (let: "err" := ref_ty error "err" in
+    let: "pe" := ref_ty ptrT (zero_val ptrT) in
+    return: (errors.As
+               (![error] "err")
+               (interface.make
+                 "github.com/goose-lang/goose/testdata/examples/unittest.parseError'ptr'ptr"
+                 []
+                 "pe"));;;
+    do:  #()).
+
 (* higher_order.go *)
 
 Definition TakesFunctionType : val :=
@@ -479,10 +755,16 @@ Definition concreteFooer__Foo : val :=
     exception_do (let: "f" := ref_ty ptrT "f" in
     do:  #()).
 
+Definition concreteFooer__mset : list (string * val) := [].
+
+Definition concreteFooer__mset_ptr : list (string * val) := [
+  ("Foo", concreteFooer__Foo%V)
+].
+
 Definition fooConsumer : val :=
   rec: "fooConsumer" "f" :=
     exception_do (let: "f" := ref_ty Fooer "f" in
-    do:  (__Foo (![Fooer] "f")) #();;;
+    do:  (interface.get "Foo" (![Fooer] "f")) #();;;
     do:  #()).
 
 Definition m : val :=
@@ -491,11 +773,21 @@ Definition m : val :=
     let: "$a0" := ref_ty concreteFooer (struct.make concreteFooer [{
     }]) in
     do:  "c" <-[ptrT] "$a0";;;
-    do:  fooConsumer (![ptrT] "c");;;
-    let: "f" := ref_ty ptrT (![ptrT] "c") in
+    do:  fooConsumer
+      (interface.make
+        "github.com/goose-lang/goose/testdata/examples/unittest.concreteFooer'ptr"
+        concreteFooer__mset_ptr
+        (![ptrT] "c"));;;
+    let: "f" :=
+      ref_ty
+        Fooer
+        (interface.make
+          "github.com/goose-lang/goose/testdata/examples/unittest.concreteFooer'ptr"
+          concreteFooer__mset_ptr
+          (![ptrT] "c")) in
     do:  fooConsumer (![Fooer] "f");;;
     do:  (concreteFooer__Foo (![ptrT] "c")) #();;;
-    do:  (__Foo (![Fooer] "f")) #();;;
+    do:  (interface.get "Foo" (![Fooer] "f")) #();;;
     do:  #()).
 
 (* ints.go *)
@@ -521,6 +813,146 @@ Definition also_u32 : go_type := my_u32.
 
 Definition ConstWithAbbrevType : expr := #(U32 3).
 
+(* iterators.go *)
+
+Definition orderedSet : go_type := structT [
+  "keys" :: sliceT uint64T
+].
+
+Definition orderedSet__All : val :=
+  rec: "orderedSet__All" "s" <> :=
+    exception_do (let: "s" := ref_ty ptrT "s" in
+    return: ((λ: "yield",
+       exception_do (let: "yield" := ref_ty funcT "yield" in
+       do:  let: "$range" := ![sliceT uint64T] (struct.field_ref orderedSet "keys" (![ptrT] "s")) in
+       slice.for_range uint64T "$range" (λ: <> "k",
+         let: "k" := ref_ty uint64T "k" in
+         (if: (~ ((![funcT] "yield") (![uint64T] "k")))
+         then
+           return: (#());;;
+           do:  #()
+         else do:  #());;;
+         do:  #());;;
+       do:  #())
+       ));;;
+    do:  #()).
+
+Definition orderedSet__Enumerate : val :=
+  rec: "orderedSet__Enumerate" "s" <> :=
+    exception_do (let: "s" := ref_ty ptrT "s" in
+    return: ((λ: "yield",
+       exception_do (let: "yield" := ref_ty funcT "yield" in
+       let: "i" := ref_ty uint64T (zero_val uint64T) in
+       do:  let: "$range" := ![sliceT uint64T] (struct.field_ref orderedSet "keys" (![ptrT] "s")) in
+       slice.for_range uint64T "$range" (λ: <> "k",
+         let: "k" := ref_ty uint64T "k" in
+         (if: (~ ((![funcT] "yield") (![uint64T] "i") (![uint64T] "k")))
+         then
+           return: (#());;;
+           do:  #()
+         else do:  #());;;
+         do:  "i" <-[uint64T] ((![uint64T] "i") + #1);;;
+         do:  #());;;
+       do:  #())
+       ));;;
+    do:  #()).
+
+Definition orderedSet__mset : list (string * val) := [].
+
+Definition orderedSet__mset_ptr : list (string * val) := [
+  ("All", orderedSet__All%V);
+  ("Enumerate", orderedSet__Enumerate%V)
+].
+
+Definition sumBelow : val :=
+  rec: "sumBelow" "s" "max" :=
+    exception_do (let: "max" := ref_ty uint64T "max" in
+    let: "s" := ref_ty ptrT "s" in
+    let: "sum" := ref_ty uint64T (zero_val uint64T) in
+    do:  ((orderedSet__All (![ptrT] "s")) #()) (λ: "k",
+      exception_do (let: "k" := ref_ty uint64T "k" in
+      (if: (![uint64T] "k") ≥ (![uint64T] "max")
+      then
+        return: #false;;;
+        do:  #()
+      else do:  #());;;
+      (if: (![uint64T] "k") = #0
+      then
+        return: #true;;;
+        do:  #()
+      else do:  #());;;
+      do:  "sum" <-[uint64T] ((![uint64T] "sum") + (![uint64T] "k"));;;
+      return: #true)
+      );;;
+    return: (![uint64T] "sum");;;
+    do:  #()).
+
+Definition countElements : val :=
+  rec: "countElements" "s" :=
+    exception_do (let: "s" := ref_ty ptrT "s" in
+    let: "n" := ref_ty uint64T (zero_val uint64T) in
+    do:  ((orderedSet__All (![ptrT] "s")) #()) (λ: <>,
+      exception_do (do:  "n" <-[uint64T] ((![uint64T] "n") + #1);;;
+      return: #true)
+      );;;
+    return: (![uint64T] "n");;;
+    do:  #()).
+
+Definition findIndex : val :=
+  rec: "findIndex" "s" "key" :=
+    exception_do (let: "key" := ref_ty uint64T "key" in
+    let: "s" := ref_ty ptrT "s" in
+    do:  let: "$returned" := ref_ty boolT #false in
+    let: "$ret" := ref_ty (uint64T * boolT) (zero_val (uint64T * boolT)) in
+    (do:  ((orderedSet__Enumerate (![ptrT] "s")) #()) (λ: "i" "k",
+      exception_do (let: "i" := ref_ty uint64T "i" in
+      let: "k" := ref_ty uint64T "k" in
+      (if: (![uint64T] "k") = (![uint64T] "key")
+      then
+        do:  "$ret" <-[(uint64T * boolT)] (![uint64T] "i", #true);;;
+        do:  "$returned" <-[boolT] #true;;;
+        return: #false;;;
+        do:  #()
+      else do:  #());;;
+      return: #true)
+      );;;
+    (if: ![boolT] "$returned"
+    then return: ![(uint64T * boolT)] "$ret"
+    else do:  #()));;;
+    return: (#0, #false);;;
+    do:  #()).
+
+Definition findPair : val :=
+  rec: "findPair" "s" "t" "sum" :=
+    exception_do (let: "sum" := ref_ty uint64T "sum" in
+    let: "t" := ref_ty ptrT "t" in
+    let: "s" := ref_ty ptrT "s" in
+    do:  let: "$returned" := ref_ty boolT #false in
+    let: "$ret" := ref_ty boolT (zero_val boolT) in
+    (do:  ((orderedSet__All (![ptrT] "s")) #()) (λ: "a",
+      exception_do (let: "a" := ref_ty uint64T "a" in
+      do:  (do:  ((orderedSet__All (![ptrT] "t")) #()) (λ: "b",
+        exception_do (let: "b" := ref_ty uint64T "b" in
+        (if: ((![uint64T] "a") + (![uint64T] "b")) = (![uint64T] "sum")
+        then
+          do:  "$ret" <-[boolT] (#true);;;
+          do:  "$returned" <-[boolT] #true;;;
+          return: #false;;;
+          do:  #()
+        else do:  #());;;
+        return: #true)
+        );;;
+      (if: ![boolT] "$returned"
+      then return: #false
+      else do:  #()));;;
+      return: #true)
+      );;;
+    (if: ![boolT] "$returned"
+    then return: ![boolT] "$ret"
+    else do:  #()));;;
+    return: (#false);;;
+    do:  #()).
+
 (* literals.go *)
 
 Definition allTheLiterals : go_type := structT [
@@ -600,7 +1032,7 @@ Definition ToBeDebugged : val :=
   rec: "ToBeDebugged" "x" :=
     exception_do (let: "x" := ref_ty uint64T "x" in
     do:  log.Println #(str "starting function");;;
-    do:  log.Printf #(str "called with %!!(MISSING)d(MISSING)") (![uint64T] "x");;;
+    do:  log.Printf #(str "called with %d") (![uint64T] "x");;;
     do:  log.Println #(str "ending function");;;
     return: (![uint64T] "x");;;
     do:  #()).
@@ -634,7 +1066,8 @@ Definition standardForLoop : val :=
         let: "$a0" := ![uint64T] (![ptrT] "sumPtr") in
         do:  "sum" <-[uint64T] "$a0";;;
         let: "x" := ref_ty uint64T (zero_val uint64T) in
-        let: "$a0" := ![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "s") (![uint64T] "i")) in
+        let: "$a0" :=
+          ![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "s") (![uint64T] "i")) in
         do:  "x" <-[uint64T] "$a0";;;
         let: "$a0" := (![uint64T] "sum") + (![uint64T] "x") in
         do:  (![ptrT] "sumPtr") <-[uint64T] "$a0";;;
@@ -791,7 +1224,8 @@ Definition nestedGoStyleLoops : val :=
       (let: "j" := ref_ty uint64T (zero_val uint64T) in
       let: "$a0" := #0 in
       do:  "j" <-[uint64T] "$a0";;;
-      (for: (λ: <>, (![uint64T] "j") < (![uint64T] "i")); (λ: <>, do:  "j" <-[uint64T] ((![uint64T] "j") + #1);;;
+      (for: (λ: <>, (![uint64T] "j") < (![uint64T] "i")); (λ: <>, do:  "j" <-[uint64T]
+        ((![uint64T] "j") + #1);;;
       #()) := λ: <>,
         (if: #true
         then
@@ -953,7 +1387,8 @@ Definition ArithmeticShifts : val :=
   rec: "ArithmeticShifts" "x" "y" :=
     exception_do (let: "y" := ref_ty uint64T "y" in
     let: "x" := ref_ty uint32T "x" in
-    return: (((to_u64 ((![uint32T] "x") ≪ #3)) + ((![uint64T] "y") ≪ (to_u64 (![uint32T] "x")))) + ((![uint64T] "y") ≪ #1));;;
+    return: (((to_u64 ((![uint32T] "x") ≪ #3)) + ((![uint64T] "y") ≪ (to_u64 (![uint32T] "x")))) +
+               ((![uint64T] "y") ≪ #1));;;
     do:  #()).
 
 Definition BitwiseOps : val :=
@@ -1016,9 +1451,19 @@ Definition wrapExternalStruct : go_type := structT [
 Definition wrapExternalStruct__moveUint64 : val :=
   rec: "wrapExternalStruct__moveUint64" "w" <> :=
     exception_do (let: "w" := ref_ty wrapExternalStruct "w" in
-    do:  (marshal.Enc__PutInt (![marshal.Enc] (struct.field_ref wrapExternalStruct "e" "w"))) ((marshal.Dec__GetInt (![marshal.Dec] (struct.field_ref wrapExternalStruct "d" "w"))) #());;;
+    do:  (marshal.Enc__PutInt (![marshal.Enc] (struct.field_ref wrapExternalStruct "e" "w")))
+      ((marshal.Dec__GetInt (![marshal.Dec] (struct.field_ref wrapExternalStruct "d" "w"))) #());;;
     do:  #()).
 
+Definition wrapExternalStruct__mset : list (string * val) := [
+  ("moveUint64", wrapExternalStruct__moveUint64%V)
+].
+
+Definition wrapExternalStruct__mset_ptr : list (string * val) := [
+  ("moveUint64", (λ: "$recvAddr",
+    wrapExternalStruct__moveUint64 (![wrapExternalStruct] "$recvAddr"))%V)
+].
+
 (* panic.go *)
 
 Definition PanicAtTheDisco : val :=
@@ -1041,6 +1486,125 @@ Definition typing : go_type := structT [
   "proph" :: ProphIdT
 ].
 
+(* range_loops.go *)
+
+Definition rangeInt : val :=
+  rec: "rangeInt" "n" :=
+    exception_do (let: "n" := ref_ty uint64T "n" in
+    let: "sum" := ref_ty uint64T (zero_val uint64T) in
+    do:  let: "$range" := ![uint64T] "n" in
+    let: "$i" := ref_ty uint64T #0 in
+    (for: (λ: <>, (![uint64T] "$i") < "$range"); (λ: <>, "$i" <-[uint64T]
+      ((![uint64T] "$i") + #1)) := λ: <>,
+      let: "i" := ref_ty uint64T (![uint64T] "$i") in
+      do:  "sum" <-[uint64T] ((![uint64T] "sum") + (![uint64T] "i"));;;
+      do:  #());;;
+    return: (![uint64T] "sum");;;
+    do:  #()).
+
+Definition rangeConstant : val :=
+  rec: "rangeConstant" <> :=
+    exception_do (let: "sum" := ref_ty uint64T (zero_val uint64T) in
+    do:  let: "$range" := #10 in
+    let: "$i" := ref_ty intT #0 in
+    (for: (λ: <>, (![intT] "$i") < "$range"); (λ: <>, "$i" <-[intT] ((![intT] "$i") + #1)) := λ: <>,
+      let: "i" := ref_ty intT (![intT] "$i") in
+      do:  "sum" <-[uint64T] ((![uint64T] "sum") + (![intT] "i"));;;
+      do:  #());;;
+    return: (![uint64T] "sum");;;
+    do:  #()).
+
+Definition rangeLabelled : val :=
+  rec: "rangeLabelled" "s" :=
+    exception_do (let: "s" := ref_ty (sliceT uint64T) "s" in
+    let: "sum" := ref_ty uint64T (zero_val uint64T) in
+    do:  let: "$range" := ![sliceT uint64T] "s" in
+    slice.for_range uint64T "$range" (λ: <> "x",
+      let: "x" := ref_ty uint64T "x" in
+      (if: (![uint64T] "x") = #0
+      then
+        continue: #();;;
+        do:  #()
+      else do:  #());;;
+      do:  "sum" <-[uint64T] ((![uint64T] "sum") + (![uint64T] "x"));;;
+      do:  #());;;
+    return: (![uint64T] "sum");;;
+    do:  #()).
+
+Definition rangeIntNoVar : val :=
+  rec: "rangeIntNoVar" "n" :=
+    exception_do (let: "n" := ref_ty uint32T "n" in
+    let: "count" := ref_ty uint64T (zero_val uint64T) in
+    do:  let: "$range" := ![uint32T] "n" in
+    let: "$i" := ref_ty uint32T #(U32 0) in
+    (for: (λ: <>, (![uint32T] "$i") < "$range"); (λ: <>, "$i" <-[uint32T]
+      ((![uint32T] "$i") + #(U32 1))) := λ: <>,
+      do:  "count" <-[uint64T] ((![uint64T] "count") + #1);;;
+      do:  #());;;
+    return: (![uint64T] "count");;;
+    do:  #()).
+
+Definition rangeSliceNoVars : val :=
+  rec: "rangeSliceNoVars" "s" :=
+    exception_do (let: "s" := ref_ty (sliceT uint64T) "s" in
+    let: "count" := ref_ty uint64T (zero_val uint64T) in
+    do:  let: "$range" := ![sliceT uint64T] "s" in
+    slice.for_range uint64T "$range" (λ: <> <>,
+      do:  "count" <-[uint64T] ((![uint64T] "count") + #1);;;
+      do:  #());;;
+    return: (![uint64T] "count");;;
+    do:  #()).
+
+Definition rangeMapNoVars : val :=
+  rec: "rangeMapNoVars" "m" :=
+    exception_do (let: "m" := ref_ty (mapT uint64T boolT) "m" in
+    let: "count" := ref_ty uint64T (zero_val uint64T) in
+    do:  MapIter (![mapT uint64T boolT] "m") (λ: <> <>,
+      do:  "count" <-[uint64T] ((![uint64T] "count") + #1);;;
+      do:  #());;;
+    return: (![uint64T] "count");;;
+    do:  #()).
+
+Definition loopCompoundPost : val :=
+  rec: "loopCompoundPost" "n" :=
+    exception_do (let: "n" := ref_ty uint64T "n" in
+    let: "steps" := ref_ty uint64T (zero_val uint64T) in
+    (let: "i" := ref_ty uint64T (zero_val uint64T) in
+    let: "$a0" := #1 in
+    do:  "i" <-[uint64T] "$a0";;;
+    (for: (λ: <>, (![uint64T] "i") < (![uint64T] "n")); (λ: <>, do:  "i" <-[uint64T]
+      ((![uint64T] "i") * #2);;;
+    #()) := λ: <>,
+      do:  "steps" <-[uint64T] ((![uint64T] "steps") + #1);;;
+      do:  #()));;;
+    return: (![uint64T] "steps");;;
+    do:  #()).
+
+Definition loopMultipleAssign : val :=
+  rec: "loopMultipleAssign" "s" :=
+    exception_do (let: "s" := ref_ty (sliceT byteT) "s" in
+    (let: "j" := ref_ty uint64T (zero_val uint64T) in
+    let: "i" := ref_ty uint64T (zero_val uint64T) in
+    let: "$a0" := #0 in
+    let: "$a1" := slice.len (![sliceT byteT] "s") in
+    do:  "j" <-[uint64T] "$a1";;;
+    do:  "i" <-[uint64T] "$a0";;;
+    (for: (λ: <>, (![uint64T] "i") < (![uint64T] "j")); (λ: <>, let: "$a0" :=
+      (![uint64T] "i") + #1 in
+    let: "$a1" := (![uint64T] "j") - #1 in
+    do:  "j" <-[uint64T] "$a1";;;
+    do:  "i" <-[uint64T] "$a0";;;
+    #()) := λ: <>,
+      let: "x" := ref_ty byteT (zero_val byteT) in
+      let: "$a0" := ![byteT] (slice.elem_ref byteT (![sliceT byteT] "s") (![uint64T] "i")) in
+      do:  "x" <-[byteT] "$a0";;;
+      let: "$a0" := ![byteT] (slice.elem_ref byteT (![sliceT byteT] "s") ((![uint64T] "j") - #1)) in
+      do:  (slice.elem_ref byteT (![sliceT byteT] "s") (![uint64T] "i")) <-[byteT] "$a0";;;
+      let: "$a0" := ![byteT] "x" in
+      do:  (slice.elem_ref byteT (![sliceT byteT] "s") ((![uint64T] "j") - #1)) <-[byteT] "$a0";;;
+      do:  #()));;;
+    do:  #()).
+
 (* reassign.go *)
 
 Definition composite : go_type := structT [
@@ -1069,6 +1633,112 @@ Definition ReassignVars : val :=
     do:  "x" <-[uint64T] "$a0";;;
     do:  #()).
 
+(* recursion.go *)
+
+Definition sumTo : val :=
+  rec: "sumTo" "n" :=
+    exception_do (let: "n" := ref_ty uint64T "n" in
+    (if: (![uint64T] "n") = #0
+    then
+      return: (#0);;;
+      do:  #()
+    else do:  #());;;
+    return: ((![uint64T] "n") + ("sumTo" ((![uint64T] "n") - #1)));;;
+    do:  #()).
+
+(* mutually recursive: isEven, isOdd *)
+Definition isEven'mutual : val :=
+  rec: "isEven'mutual" "$fn" :=
+    (if: "$fn" = #0
+    then
+      (λ: "n",
+        exception_do (let: "n" := ref_ty uint64T "n" in
+        (if: (![uint64T] "n") = #0
+        then
+          return: (#true);;;
+          do:  #()
+        else do:  #());;;
+        return: (("isEven'mutual" #1) ((![uint64T] "n") - #1));;;
+        do:  #())
+        )
+    else
+      (λ: "n",
+        exception_do (let: "n" := ref_ty uint64T "n" in
+        (if: (![uint64T] "n") = #0
+        then
+          return: (#false);;;
+          do:  #()
+        else do:  #());;;
+        return: (("isEven'mutual" #0) ((![uint64T] "n") - #1));;;
+        do:  #())
+        )).
+
+Definition isEven : val :=
+  rec: "isEven" "n" :=
+    isEven'mutual #0 "n".
+
+Definition isOdd : val :=
+  rec: "isOdd" "n" :=
+    isEven'mutual #1 "n".
+
+Definition listNode : go_type := structT [
+  "val" :: uint64T;
+  "next" :: ptrT
+].
+
+Definition listNode__length : val :=
+  rec: "listNode__length" "l" <> :=
+    exception_do (let: "l" := ref_ty ptrT "l" in
+    (if: (![ptrT] "l") = #null
+    then
+      return: (#0);;;
+      do:  #()
+    else do:  #());;;
+    return: (#1 +
+               (("listNode__length" (![ptrT] (struct.field_ref listNode "next" (![ptrT] "l"))))
+                 #()));;;
+    do:  #()).
+
+Definition listNode__mset : list (string * val) := [].
+
+Definition listNode__mset_ptr : list (string * val) := [
+  ("length", listNode__length%V)
+].
+
+Definition treeNode : go_type := structT [
+  "children" :: sliceT ptrT;
+  "byName" :: mapT stringT ptrT
+].
+
+Definition treeNode__size : val :=
+  rec: "treeNode__size" "t" <> :=
+    exception_do (let: "t" := ref_ty treeNode "t" in
+    let: "n" := ref_ty uint64T #1 in
+    do:  let: "$range" := ![sliceT treeNode] (struct.field_ref treeNode "children" "t") in
+    slice.for_range treeNode "$range" (λ: <> "c",
+      let: "c" := ref_ty treeNode "c" in
+      do:  "n" <-[uint64T] ((![uint64T] "n") + (("treeNode__size" (![treeNode] "c")) #()));;;
+      do:  #());;;
+    return: (![uint64T] "n");;;
+    do:  #()).
+
+Definition treeNode__mset : list (string * val) := [
+  ("size", treeNode__size%V)
+].
+
+Definition treeNode__mset_ptr : list (string * val) := [
+  ("size", (λ: "$recvAddr", treeNode__size (![treeNode] "$recvAddr"))%V)
+].
+
+Definition forest : go_type := structT [
+  "trees" :: sliceT ptrT
+].
+
+Definition tree : go_type := structT [
+  "label" :: uint64T;
+  "sub" :: forest
+].
+
 (* replicated_disk.go *)
 
 Definition Block : go_type := structT [
@@ -1198,7 +1868,12 @@ Definition sliceOps : val :=
     let: "v4" := ref_ty ptrT (zero_val ptrT) in
     let: "$a0" := SliceRef uint64T (![sliceT uint64T] "x") #2 in
     do:  "v4" <-[ptrT] "$a0";;;
-    return: ((((((![uint64T] "v1") + (![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "v2") #0))) + (![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "v3") #1))) + (![uint64T] (![ptrT] "v4"))) + (slice.len (![sliceT uint64T] "x"))) + (slice.cap (![sliceT uint64T] "x")));;;
+    return: ((((((![uint64T] "v1") +
+               (![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "v2") #0))) +
+               (![uint64T] (slice.elem_ref uint64T (![sliceT uint64T] "v3") #1))) +
+               (![uint64T] (![ptrT] "v4"))) +
+               (slice.len (![sliceT uint64T] "x"))) +
+               (slice.cap (![sliceT uint64T] "x")));;;
     do:  #()).
 
 Definition makeSingletonSlice : val :=
@@ -1219,9 +1894,20 @@ Definition sliceOfThings__getThingRef : val :=
   rec: "sliceOfThings__getThingRef" "ts" "i" :=
     exception_do (let: "ts" := ref_ty sliceOfThings "ts" in
     let: "i" := ref_ty uint64T "i" in
-    return: (SliceRef thing (![sliceT thing] (struct.field_ref sliceOfThings "things" "ts")) (![uint64T] "i"));;;
+    return: (SliceRef
+               thing
+               (![sliceT thing] (struct.field_ref sliceOfThings "things" "ts"))
+               (![uint64T] "i"));;;
     do:  #()).
 
+Definition sliceOfThings__mset : list (string * val) := [
+  ("getThingRef", sliceOfThings__getThingRef%V)
+].
+
+Definition sliceOfThings__mset_ptr : list (string * val) := [
+  ("getThingRef", (λ: "$recvAddr", sliceOfThings__getThingRef (![sliceOfThings] "$recvAddr"))%V)
+].
+
 Definition makeAlias : val :=
   rec: "makeAlias" <> :=
     exception_do (return: (slice.make2 boolT #10);;;
@@ -1243,7 +1929,7 @@ Definition simpleSpawn : val :=
     let: "$a0" := ref_ty uint64T (zero_val uint64T) in
     do:  "v" <-[ptrT] "$a0";;;
     let: "$go" := (λ: <>,
-      do:  (sync.Mutex__Lock (![ptrT] "l")) #();;;
+      exception_do (do:  (sync.Mutex__Lock (![ptrT] "l")) #();;;
       let: "x" := ref_ty uint64T (zero_val uint64T) in
       let: "$a0" := ![uint64T] (![ptrT] "v") in
       do:  "x" <-[uint64T] "$a0";;;
@@ -1253,7 +1939,7 @@ Definition simpleSpawn : val :=
         do:  #()
       else do:  #());;;
       do:  (sync.Mutex__Unlock (![ptrT] "l")) #();;;
-      do:  #()
+      do:  #())
       ) in
     do:  Fork ("$go" #());;;
     do:  (sync.Mutex__Lock (![ptrT] "l")) #();;;
@@ -1278,8 +1964,8 @@ Definition loopSpawn : val :=
       let: "$a0" := ![uint64T] "i" in
       do:  "i" <-[uint64T] "$a0";;;
       let: "$go" := (λ: <>,
-        do:  threadCode (![uint64T] "i");;;
-        do:  #()
+        exception_do (do:  threadCode (![uint64T] "i");;;
+        do:  #())
         ) in
       do:  Fork ("$go" #());;;
       do:  #()));;;
@@ -1299,7 +1985,8 @@ Definition stringAppend : val :=
   rec: "stringAppend" "s" "x" :=
     exception_do (let: "x" := ref_ty uint64T "x" in
     let: "s" := ref_ty stringT "s" in
-    return: (((#(str "prefix ") + (![stringT] "s")) + #(str " ")) + (machine.UInt64ToString (![uint64T] "x")));;;
+    return: (((#(str "prefix ") + (![stringT] "s")) + #(str " ")) +
+               (machine.UInt64ToString (![uint64T] "x")));;;
     do:  #()).
 
 Definition stringLength : val :=
@@ -1319,7 +2006,9 @@ Definition Point__Add : val :=
   rec: "Point__Add" "c" "z" :=
     exception_do (let: "c" := ref_ty Point "c" in
     let: "z" := ref_ty uint64T "z" in
-    return: (((![uint64T] (struct.field_ref Point "x" "c")) + (![uint64T] (struct.field_ref Point "y" "c"))) + (![uint64T] "z"));;;
+    return: (((![uint64T] (struct.field_ref Point "x" "c")) +
+               (![uint64T] (struct.field_ref Point "y" "c"))) +
+               (![uint64T] "z"));;;
     do:  #()).
 
 Definition Point__GetField : val :=
@@ -1334,6 +2023,16 @@ Definition Point__GetField : val :=
     return: ((![uint64T] "x") + (![uint64T] "y"));;;
     do:  #()).
 
+Definition Point__mset : list (string * val) := [
+  ("Add", Point__Add%V);
+  ("GetField", Point__GetField%V)
+].
+
+Definition Point__mset_ptr : list (string * val) := [
+  ("Add", (λ: "$recvAddr", Point__Add (![Point] "$recvAddr"))%V);
+  ("GetField", (λ: "$recvAddr", Point__GetField (![Point] "$recvAddr"))%V)
+].
+
 Definition UseAdd : val :=
   rec: "UseAdd" <> :=
     exception_do (let: "c" := ref_ty Point (zero_val Point) in
@@ -1423,6 +2122,19 @@ Definition S__refC : val :=
     return: (struct.field_ref S "c" (![ptrT] "s"));;;
     do:  #()).
 
+Definition S__mset : list (string * val) := [
+  ("readBVal", S__readBVal%V)
+].
+
+Definition S__mset_ptr : list (string * val) := [
+  ("readA", S__readA%V);
+  ("readB", S__readB%V);
+  ("readBVal", (λ: "$recvAddr", S__readBVal (![S] "$recvAddr"))%V);
+  ("writeB", S__writeB%V);
+  ("negateC", S__negateC%V);
+  ("refC", S__refC%V)
+].
+
 Definition localSRef : val :=
   rec: "localSRef" <> :=
     exception_do (let: "s" := ref_ty S (zero_val S) in
@@ -1439,6 +2151,98 @@ Definition setField : val :=
     return: (![S] "s");;;
     do:  #()).
 
+(* sync_primitives.go *)
+
+Definition cache : go_type := structT [
+  "mu" :: sync.RWMutex;
+  "entries" :: mapT uint64T uint64T;
+  "hits" :: atomic.Uint64;
+  "init" :: sync.Once
+].
+
+Definition cache__get : val :=
+  rec: "cache__get" "c" "k" :=
+    exception_do (let: "c" := ref_ty ptrT "c" in
+    let: "k" := ref_ty uint64T "k" in
+    do:  (sync.RWMutex__RLock (struct.field_ref cache "mu" (![ptrT] "c"))) #();;;
+    let: "ok" := ref_ty boolT (zero_val boolT) in
+    let: "v" := ref_ty uint64T (zero_val uint64T) in
+    let: ("$a0", "$a1") :=
+      Fst
+        (map.get
+          (![mapT uint64T uint64T] (struct.field_ref cache "entries" (![ptrT] "c")))
+          (![uint64T] "k")) in
+    do:  "ok" <-[boolT] "$a1";;;
+    do:  "v" <-[uint64T] "$a0";;;
+    do:  (sync.RWMutex__RUnlock (struct.field_ref cache "mu" (![ptrT] "c"))) #();;;
+    (if: ![boolT] "ok"
+    then
+      do:  (atomic.Uint64__Add (struct.field_ref cache "hits" (![ptrT] "c"))) #1;;;
+      do:  #()
+    else do:  #());;;
+    return: (![uint64T] "v", ![boolT] "ok");;;
+    do:  #()).
+
+Definition cache__put : val :=
+  rec: "cache__put" "c" "k" "v" :=
+    exception_do (let: "c" := ref_ty ptrT "c" in
+    let: "v" := ref_ty uint64T "v" in
+    let: "k" := ref_ty uint64T "k" in
+    do:  (sync.Once__Do (struct.field_ref cache "init" (![ptrT] "c"))) (λ: <>,
+      exception_do (let: "$a0" := map.make uint64T uint64T #() in
+      do:  (struct.field_ref cache "entries" (![ptrT] "c")) <-[mapT uint64T uint64T] "$a0";;;
+      do:  #())
+      );;;
+    do:  (sync.RWMutex__Lock (struct.field_ref cache "mu" (![ptrT] "c"))) #();;;
+    let: "$a0" := ![uint64T] "v" in
+    do:  map.insert
+      (![mapT uint64T uint64T] (struct.field_ref cache "entries" (![ptrT] "c")))
+      (![uint64T] "k")
+      "$a0";;;
+    do:  (sync.RWMutex__Unlock (struct.field_ref cache "mu" (![ptrT] "c"))) #();;;
+    do:  #()).
+
+Definition cache__numHits : val :=
+  rec: "cache__numHits" "c" <> :=
+    exception_do (let: "c" := ref_ty ptrT "c" in
+    return: ((atomic.Uint64__Load (struct.field_ref cache "hits" (![ptrT] "c"))) #());;;
+    do:  #()).
+
+Definition cache__mset : list (string * val) := [].
+
+Definition cache__mset_ptr : list (string * val) := [
+  ("get", cache__get%V);
+  ("put", cache__put%V);
+  ("numHits", cache__numHits%V)
+].
+
+Definition atomicCounter : val :=
+  rec: "atomicCounter" "counter" :=
+    exception_do (let: "counter" := ref_ty ptrT "counter" in
+    do:  atomic.AddUint64 (![ptrT] "counter") #1;;;
+    return: (atomic.LoadUint64 (![ptrT] "counter"));;;
+    do:  #()).
+
+Definition waitForWorkers : val :=
+  rec: "waitForWorkers" "n" :=
+    exception_do (let: "n" := ref_ty uint64T "n" in
+    let: "wg" := ref_ty sync.WaitGroup (zero_val sync.WaitGroup) in
+    (let: "i" := ref_ty uint64T (zero_val uint64T) in
+    let: "$a0" := #0 in
+    do:  "i" <-[uint64T] "$a0";;;
+    (for: (λ: <>, (![uint64T] "i") < (![uint64T] "n")); (λ: <>, do:  "i" <-[uint64T]
+      ((![uint64T] "i") + #1);;;
+    #()) := λ: <>,
+      do:  (sync.WaitGroup__Add "wg") #1;;;
+      let: "$go" := (λ: <>,
+        exception_do (do:  (sync.WaitGroup__Done "wg") #();;;
+        do:  #())
+        ) in
+      do:  Fork ("$go" #());;;
+      do:  #()));;;
+    do:  (sync.WaitGroup__Wait "wg") #();;;
+    do:  #()).
+
 (* synchronization.go *)
 
 (* DoSomeLocking uses the entire lock API *)
@@ -116,7 +116,11 @@ Definition Log__Read : val :=
     do:  (Log__lock (![Log] "l")) #();;;
     let: "ok" := ref_ty boolT (zero_val boolT) in
     let: "v" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
-    let: ("$a0", "$a1") := Fst (map.get (![mapT uint64T (sliceT byteT)] (struct.field_ref Log "cache" "l")) (![uint64T] "a")) in
+    let: ("$a0", "$a1") :=
+      Fst
+        (map.get
+          (![mapT uint64T (sliceT byteT)] (struct.field_ref Log "cache" "l"))
+          (![uint64T] "a")) in
     do:  "ok" <-[boolT] "$a1";;;
     do:  "v" <-[sliceT byteT] "$a0";;;
     (if: ![boolT] "ok"
@@ -127,7 +131,8 @@ Definition Log__Read : val :=
     else do:  #());;;
     do:  (Log__unlock (![Log] "l")) #();;;
     let: "dv" := ref_ty (sliceT byteT) (zero_val (sliceT byteT)) in
-    let: "$a0" := (__Read (![disk.Disk] (struct.field_ref Log "d" "l"))) (logLength + (![uint64T] "a")) in
+    let: "$a0" :=
+      (__Read (![disk.Disk] (struct.field_ref Log "d" "l"))) (logLength + (![uint64T] "a")) in
     do:  "dv" <-[sliceT byteT] "$a0";;;
     return: (![sliceT byteT] "dv");;;
     do:  #()).
@@ -162,10 +167,17 @@ Definition Log__Write : val :=
     let: "nextAddr" := ref_ty uint64T (zero_val uint64T) in
     let: "$a0" := #1 + (#2 * (![uint64T] "length")) in
     do:  "nextAddr" <-[uint64T] "$a0";;;
-    do:  (__Write (![disk.Disk] (struct.field_ref Log "d" "l"))) (![uint64T] "nextAddr") (![sliceT byteT] "aBlock");;;
-    do:  (__Write (![disk.Disk] (struct.field_ref Log "d" "l"))) ((![uint64T] "nextAddr") + #1) (![sliceT byteT] "v");;;
+    do:  (__Write (![disk.Disk] (struct.field_ref Log "d" "l")))
+      (![uint64T] "nextAddr")
+      (![sliceT byteT] "aBlock");;;
+    do:  (__Write (![disk.Disk] (struct.field_ref Log "d" "l")))
+      ((![uint64T] "nextAddr") + #1)
+      (![sliceT byteT] "v");;;
     let: "$a0" := ![sliceT byteT] "v" in
-    do:  map.insert (![mapT uint64T (sliceT byteT)] (struct.field_ref Log "cache" "l")) (![uint64T] "a") "$a0";;;
+    do:  map.insert
+      (![mapT uint64T (sliceT byteT)] (struct.field_ref Log "cache" "l"))
+      (![uint64T] "a")
+      "$a0";;;
     let: "$a0" := (![uint64T] "length") + #1 in
     do:  (![ptrT] (struct.field_ref Log "length" "l")) <-[uint64T] "$a0";;;
     do:  (Log__unlock (![Log] "l")) #();;;
@@ -257,6 +269,28 @@ Definition Log__Apply : val :=
     do:  (Log__unlock (![Log] "l")) #();;;
     do:  #()).
 
+Definition Log__mset : list (string * val) := [
+  ("lock", Log__lock%V);
+  ("unlock", Log__unlock%V);
+  ("BeginTxn", Log__BeginTxn%V);
+  ("Read", Log__Read%V);
+  ("Size", Log__Size%V);
+  ("Write", Log__Write%V);
+  ("Commit", Log__Commit%V);
+  ("Apply", Log__Apply%V)
+].
+
+Definition Log__mset_ptr : list (string * val) := [
+  ("lock", (λ: "$recvAddr", Log__lock (![Log] "$recvAddr"))%V);
+  ("unlock", (λ: "$recvAddr", Log__unlock (![Log] "$recvAddr"))%V);
+  ("BeginTxn", (λ: "$recvAddr", Log__BeginTxn (![Log] "$recvAddr"))%V);
+  ("Read", (λ: "$recvAddr", Log__Read (![Log] "$recvAddr"))%V);
+  ("Size", (λ: "$recvAddr", Log__Size (![Log] "$recvAddr"))%V);
+  ("Write", (λ: "$recvAddr", Log__Write (![Log] "$recvAddr"))%V);
+  ("Commit", (λ: "$recvAddr", Log__Commit (![Log] "$recvAddr"))%V);
+  ("Apply", (λ: "$recvAddr", Log__Apply (![Log] "$recvAddr"))%V)
+].
+
 (* Open recovers the log following a crash or shutdown *)
 Definition Open : val :=
   rec: "Open" <> :=
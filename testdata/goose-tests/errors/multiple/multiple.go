@@ -0,0 +1,19 @@
+package multiple
+
+func IfInit(x uint64) uint64 {
+	if y := x + 1; y > 2 {
+		return y
+	}
+	return 0
+}
+
+func Select() {
+	select {}
+}
+
+func Labeled() {
+outer:
+	for {
+		break outer
+	}
+}
@@ -0,0 +1,11 @@
+// Package geom is in a directory with a different name.
+package geom
+
+type Rect struct {
+	Width  uint64
+	Height uint64
+}
+
+func (r Rect) Area() uint64 {
+	return r.Width * r.Height
+}
@@ -0,0 +1,26 @@
+// Package structs declares structs that are used from another package.
+package structs
+
+type Point struct {
+	X uint64
+	Y uint64
+}
+
+func (p Point) Sum() uint64 {
+	return p.X + p.Y
+}
+
+func (p *Point) Scale(k uint64) {
+	p.X = p.X * k
+	p.Y = p.Y * k
+}
+
+type Segment struct {
+	Start Point
+	End   *Point
+	Label string
+}
+
+func NewSegment(start Point, end Point) *Segment {
+	return &Segment{Start: start, End: &end}
+}
@@ -0,0 +1,12 @@
+package use_structs
+
+import "example.com/goose-demo/m/geometry"
+
+func Square(n uint64) geom.Rect {
+	return geom.Rect{Width: n, Height: n}
+}
+
+func SquareArea(n uint64) uint64 {
+	r := Square(n)
+	return r.Area() + r.Width
+}
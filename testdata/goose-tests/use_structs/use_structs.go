@@ -0,0 +1,64 @@
+// Package use_structs uses the structs of package structs.
+package use_structs
+
+import "example.com/goose-demo/m/structs"
+
+func Origin() structs.Point {
+	return structs.Point{}
+}
+
+func MakePoint(x uint64, y uint64) structs.Point {
+	return structs.Point{X: x, Y: y}
+}
+
+func Unkeyed() structs.Point {
+	return structs.Point{1, 2}
+}
+
+func NewPoint() *structs.Point {
+	return &structs.Point{X: 1}
+}
+
+func GetX(p structs.Point) uint64 {
+	return p.X
+}
+
+func GetYPtr(p *structs.Point) uint64 {
+	return p.Y
+}
+
+func SetX(p *structs.Point, x uint64) {
+	p.X = x
+}
+
+func Length(s *structs.Segment) uint64 {
+	return (s.End.X - s.Start.X) + (s.End.Y - s.Start.Y)
+}
+
+func Nested() structs.Segment {
+	return structs.Segment{
+		Start: structs.Point{X: 1, Y: 2},
+		End:   &structs.Point{X: 3, Y: 4},
+		Label: "s",
+	}
+}
+
+func Methods() uint64 {
+	p := MakePoint(1, 2)
+	p.Scale(2)
+	s := structs.NewSegment(p, structs.Point{})
+	return p.Sum() + s.Start.Sum()
+}
+
+type Wrapper struct {
+	P    structs.Point
+	Segs []*structs.Segment
+}
+
+func Wrap(p structs.Point) Wrapper {
+	return Wrapper{P: p}
+}
+
+func WrappedX(w *Wrapper) uint64 {
+	return w.P.X
+}
@@ -0,0 +1,7 @@
+package example
+
+import "sync/atomic"
+
+func load(n *atomic.Int64) {
+	n.Load() // ERROR unsupported "type *sync/atomic.Int64 from sync/atomic"
+}
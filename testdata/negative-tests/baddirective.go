@@ -0,0 +1,6 @@
+package example
+
+//goose:trusted // ERROR unsupported "unknown goose directive trusted"
+func f() uint64 {
+	return 0
+}
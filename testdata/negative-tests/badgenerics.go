@@ -1,3 +1,3 @@
 package example
 
-type IntMap[T any] map[uint64]T // ERROR generic named type
+type IntMap[T any] map[uint64]T // ERROR future "generic named type"
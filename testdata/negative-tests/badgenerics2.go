@@ -1,5 +1,5 @@
 package example
 
-type Box[T any] struct { // ERROR generic named type
+type Box[T any] struct { // ERROR future "generic named type"
 	v T
 }
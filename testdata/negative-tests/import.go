@@ -2,4 +2,4 @@ package example
 
 // sneaky import
 
-import _ "sync/atomic" // ERROR renaming
+import _ "sync/atomic" // ERROR unsupported "renaming imports"
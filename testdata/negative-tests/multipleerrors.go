@@ -0,0 +1,18 @@
+package example
+
+func multipleErrors(x uint64) uint64 {
+	var y uint64
+	if z := x; z > 0 { // ERROR unsupported "if statement initializations"
+		y = z
+	}
+	if z := y; z > 1 { // ERROR unsupported "if statement initializations"
+		y = z
+	}
+	return y
+}
+
+func anotherError() {
+	go func() {
+		select {} // ERROR unsupported "statement *ast.SelectStmt"
+	}()
+}
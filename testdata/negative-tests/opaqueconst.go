@@ -0,0 +1,4 @@
+package example
+
+//goose:opaque
+const limit uint64 = 10 // ERROR unsupported "only functions can be opaque"
@@ -0,0 +1,8 @@
+package example
+
+//goose:opaque
+type counter struct{ n uint64 } // ERROR unsupported "//goose:opaque on type declaration (only functions can be opaque)"
+
+func (c *counter) inc() {
+	c.n++
+}
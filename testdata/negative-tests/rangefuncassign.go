@@ -0,0 +1,8 @@
+package example
+
+func last(xs func(yield func(uint64) bool)) uint64 {
+	var x uint64
+	for x = range xs { // ERROR unsupported "range with pre-existing variables"
+	}
+	return x
+}
@@ -0,0 +1,15 @@
+package example
+
+func firstPair(xs func(yield func(uint64) bool), ys []uint64) uint64 {
+	var found uint64
+outer:
+	for _, y := range ys {
+		for x := range xs {
+			if x == y {
+				found = x
+				break outer // ERROR unsupported "labelled break to an outer loop"
+			}
+		}
+	}
+	return found
+}
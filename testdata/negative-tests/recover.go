@@ -0,0 +1,5 @@
+package example
+
+func recoverInHelper() {
+	_ = recover() // ERROR unsupported "recover outside of a deferred function literal"
+}
@@ -0,0 +1,7 @@
+package example
+
+import "sync"
+
+func tryLock(mu *sync.RWMutex) bool {
+	return mu.TryLock() // ERROR unsupported "method TryLock of sync.RWMutex"
+}
@@ -109,9 +109,9 @@ func (ctx Ctx) glangType(n locatable, t types.Type) glang.Type {
 		ctx.dep.addDep(ctx.qualifiedName(t.Obj()))
 		return glang.TypeIdent(ctx.qualifiedName(t.Obj()))
 	case *types.Slice:
-		return glang.SliceType{Value: ctx.glangType(n, t.Elem())}
+		return glang.SliceType{Value: ctx.elemType(n, t.Elem())}
 	case *types.Map:
-		return glang.MapType{Key: ctx.glangType(n, t.Key()), Value: ctx.glangType(n, t.Elem())}
+		return glang.MapType{Key: ctx.elemType(n, t.Key()), Value: ctx.elemType(n, t.Elem())}
 	case *types.Signature:
 		return glang.FuncType{}
 	case *types.Interface:
@@ -122,6 +122,15 @@ func (ctx Ctx) glangType(n locatable, t types.Type) glang.Type {
 	return nil // unreachable
 }
 
+// elemType translates the element type of a slice or map, erasing recursive
+// occurrences of the struct types being defined (see recursion.go)
+func (ctx Ctx) elemType(n locatable, t types.Type) glang.Type {
+	if info, ok := ctx.getStructInfo(t); ok && ctx.recTypes[info.name] {
+		return glang.PtrType{}
+	}
+	return ctx.glangType(n, t)
+}
+
 func sliceElem(t types.Type) types.Type {
 	if t, ok := t.Underlying().(*types.Slice); ok {
 		return t.Elem()
@@ -188,6 +197,94 @@ func isWaitGroup(t types.Type) bool {
 	return false
 }
 
+func isRWMutexRef(t types.Type) bool {
+	if t, ok := t.(*types.Pointer); ok {
+		if t, ok := t.Elem().(*types.Named); ok {
+			name := t.Obj()
+			return name.Pkg().Name() == "sync" &&
+				name.Name() == "RWMutex"
+		}
+	}
+	return false
+}
+
+func isOnceRef(t types.Type) bool {
+	if t, ok := t.(*types.Pointer); ok {
+		if t, ok := t.Elem().(*types.Named); ok {
+			name := t.Obj()
+			return name.Pkg().Name() == "sync" &&
+				name.Name() == "Once"
+		}
+	}
+	return false
+}
+
+// isAtomicRef checks for a pointer to one of the supported types in
+// sync/atomic, like atomic.Uint64 (see syncMethods)
+func isAtomicRef(t types.Type) bool {
+	if t, ok := t.(*types.Pointer); ok {
+		if t, ok := t.Elem().(*types.Named); ok {
+			name := t.Obj()
+			_, ok := syncMethods["atomic."+name.Name()]
+			return ok && name.Pkg() != nil &&
+				name.Pkg().Path() == "sync/atomic"
+		}
+	}
+	return false
+}
+
+// isAtomicType checks for any type in sync/atomic, or a pointer to one
+func isAtomicType(t types.Type) bool {
+	if pt, ok := t.(*types.Pointer); ok {
+		t = pt.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil &&
+		named.Obj().Pkg().Path() == "sync/atomic"
+}
+
+// syncMethods are the supported methods of the sync and sync/atomic types,
+// each of which has a dedicated GooseLang implementation.
+var syncMethods = map[string][]string{
+	"sync.Mutex":     {"Lock", "Unlock"},
+	"sync.RWMutex":   {"Lock", "Unlock", "RLock", "RUnlock"},
+	"sync.Cond":      {"Wait", "Signal", "Broadcast"},
+	"sync.WaitGroup": {"Add", "Done", "Wait"},
+	"sync.Once":      {"Do"},
+	"atomic.Uint64":  {"Load", "Store", "Add", "Swap", "CompareAndSwap"},
+	"atomic.Uint32":  {"Load", "Store", "Add", "Swap", "CompareAndSwap"},
+	"atomic.Bool":    {"Load", "Store", "Swap", "CompareAndSwap"},
+	"atomic.Pointer": {"Load", "Store", "Swap", "CompareAndSwap"},
+}
+
+// syncPrimitive identifies one of the synchronization types with a dedicated
+// GooseLang model, either by value or through a pointer.
+//
+// Returns the qualified name of the type.
+func syncPrimitive(t types.Type) (string, bool) {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		ptr = types.NewPointer(t)
+	}
+	if named, ok := ptr.Elem().(*types.Named); !ok || named.Obj().Pkg() == nil {
+		return "", false
+	}
+	if !(isLockRef(ptr) || isRWMutexRef(ptr) || isCondVar(ptr) ||
+		isWaitGroup(ptr) || isOnceRef(ptr) || isAtomicRef(ptr)) {
+		return "", false
+	}
+	name := ptr.Elem().(*types.Named).Obj()
+	return name.Pkg().Name() + "." + name.Name(), true
+}
+
+// atomicFuncs are the supported functions from sync/atomic
+var atomicFuncs = map[string]bool{
+	"LoadUint64": true, "StoreUint64": true, "AddUint64": true,
+	"SwapUint64": true, "CompareAndSwapUint64": true,
+	"LoadUint32": true, "StoreUint32": true, "AddUint32": true,
+	"SwapUint32": true, "CompareAndSwapUint32": true,
+}
+
 func isProphId(t types.Type) bool {
 	if t, ok := t.(*types.Pointer); ok {
 		if t, ok := t.Elem().(*types.Named); ok {
@@ -199,6 +296,41 @@ func isProphId(t types.Type) bool {
 	return false
 }
 
+// isErrorType checks if t is the built-in error interface
+func isErrorType(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
+
+// isTranslatedInterface checks if t is an interface whose values goose
+// represents with interface.make: error and the interfaces of translated
+// packages.
+//
+// The interfaces of FFIs and the standard library (such as disk.Disk and
+// sync.Locker) are part of their models, so their values are used as is and
+// their methods are called directly.
+func (ctx Ctx) isTranslatedInterface(t types.Type) bool {
+	if !types.IsInterface(t) {
+		return false
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return true
+	}
+	return ctx.isTranslatedPkg(named.Obj().Pkg().Path())
+}
+
+// isTranslatedPkg checks if the package at path is translated by goose rather
+// than modeled by an FFI or part of the standard library
+func (ctx Ctx) isTranslatedPkg(path string) bool {
+	if path == ctx.pkgPath {
+		return true
+	}
+	if _, ok := ctx.externals[path]; ok {
+		return false
+	}
+	return ctx.translated[path]
+}
+
 func isByteSlice(t types.Type) bool {
 	if t, ok := t.(*types.Slice); ok {
 		if elTy, ok := t.Elem().Underlying().(*types.Basic); ok {